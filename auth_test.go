@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// mutating routes anyone can call
+var publicMutatingRoutes = map[string]bool{
+	"POST /v1/users": true,
+	"POST /v2/users": true,
+	// the confirmation and unsubscribe links of the email digests, the token in the url is the credential
+	"POST /v1/digest/confirm/{token}":     true,
+	"POST /v1/digest/unsubscribe/{token}": true,
+}
+
+var urlParamPattern = regexp.MustCompile(`\{[^}]+\}`)
+
+// every route that changes something has to be behind middlewareAuth
+// without an Authorization header it never reaches the db, so no db is needed
+func TestMutatingRoutesRequireAuth(t *testing.T) {
+	router := apiConfig{}.router()
+	for _, operation := range sortedKeys(routerOperations(t)) {
+		method, route, _ := strings.Cut(operation, " ")
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || publicMutatingRoutes[operation] {
+			continue
+		}
+		path := urlParamPattern.ReplaceAllStringFunc(route, func(string) string { return uuid.NewString() })
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader("{}")))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without an api key responded %d, want %d", operation, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package client is a Go client for the blog aggregator HTTP API.
+//
+// It talks to the /v2 versions of the original endpoints and to the endpoints added since,
+// all of which respond with snake_case keys and RFC 3339 timestamps.
+// Authenticated calls send the api key given to WithAPIKey as "Authorization: ApiKey <key>".
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(apiKey))
+//	posts, err := c.ListPosts(ctx, &client.ListPostsOptions{Limit: 10})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the api, the zero value is not usable, use New.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sets the api key sent with every request.
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithHTTPClient sets the http.Client used to send requests, http.DefaultClient by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns a client for the api served at baseURL, e.g. "http://localhost:8080".
+// It panics if baseURL can't be parsed.
+func New(baseURL string, opts ...Option) *Client {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		panic(fmt.Sprintf("client: invalid base url %q: %v", baseURL, err))
+	}
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		userAgent:  "blog_aggregator-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIKey returns the api key the client authenticates with.
+func (c *Client) APIKey() string {
+	return c.apiKey
+}
+
+// WithAPIKey returns a copy of the client that authenticates with apiKey.
+func (c *Client) WithAPIKey(apiKey string) *Client {
+	copied := *c
+	copied.apiKey = apiKey
+	return &copied
+}
+
+// Error is returned for responses with a non 2xx status code.
+type Error struct {
+	StatusCode int
+	// Message is the "error" field of the response body, or the body itself if it isn't json.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("blog_aggregator api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound reports whether err is an api error with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an api error with status 401.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// response holds what callers may need besides the decoded body.
+type response struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// endpoint resolves path (relative to the base url) and query into a url.
+func (c *Client) endpoint(path string, query url.Values) *url.URL {
+	u := *c.baseURL
+	u.Path = c.baseURL.Path + path
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// newRequest builds a request to u with an optional json body.
+func (c *Client) newRequest(ctx context.Context, method string, u *url.URL, body interface{}) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		dat, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("client: encoding request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(dat)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	}
+	return req, nil
+}
+
+// newError makes the error for a response that isn't 2xx from its body.
+func newError(statusCode int, dat []byte) *Error {
+	apiErr := &Error{StatusCode: statusCode}
+	var errBody struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(dat, &errBody) == nil && errBody.Error != "" {
+		apiErr.Message = errBody.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(dat))
+	}
+	return apiErr
+}
+
+// do sends the request and decodes a 2xx json body into out, if out is not nil.
+// If out is a *[]byte it gets the body as is instead.
+func (c *Client) do(req *http.Request, out interface{}) (*response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	dat, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newError(resp.StatusCode, dat)
+	}
+
+	if raw, ok := out.(*[]byte); ok {
+		*raw = dat
+	} else if out != nil && len(dat) > 0 {
+		if err := json.Unmarshal(dat, out); err != nil {
+			return nil, fmt.Errorf("client: decoding response body: %w", err)
+		}
+	}
+	return &response{StatusCode: resp.StatusCode, Header: resp.Header}, nil
+}
+
+// call is newRequest followed by do.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) (*response, error) {
+	req, err := c.newRequest(ctx, method, c.endpoint(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, out)
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthHeaderAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"no token provided"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"f46f3480-ae95-4a5d-b570-81530f513acd","created_at":"2023-06-01T14:57:42Z","updated_at":"2023-06-01T14:57:42Z","name":"examplename"}`)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).GetUser(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "no token provided" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if !IsUnauthorized(err) {
+		t.Error("IsUnauthorized should be true")
+	}
+
+	user, err := New(server.URL, WithAPIKey("secret")).GetUser(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "examplename" {
+		t.Errorf("got name %q", user.Name)
+	}
+}
+
+func TestPagerFollowsLinkHeader(t *testing.T) {
+	pages := map[string]string{
+		"":  `[{"title":"1"},{"title":"2"}]`,
+		"2": `[{"title":"3"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		if cursor == "" {
+			w.Header().Set("Link", `</v2/posts?cursor=2>; rel="next"`)
+		}
+		fmt.Fprint(w, pages[cursor])
+	}))
+	defer server.Close()
+
+	posts, err := New(server.URL).PostsPager(&ListPostsOptions{Limit: 2}).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 3 || posts[2].Title != "3" {
+		t.Errorf("unexpected posts %+v", posts)
+	}
+}
+
+func TestListPostsCursors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sort") != SortOldest || r.URL.Query().Get("category") != "go" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Link", `</v2/posts?cursor=abc&sort=oldest>; rel="next", </v2/posts?cursor=xyz&sort=oldest>; rel="prev"`)
+		fmt.Fprint(w, `[{"title":"1"}]`)
+	}))
+	defer server.Close()
+
+	page, err := New(server.URL).ListPosts(context.Background(), &ListPostsOptions{Sort: SortOldest, Category: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Next != "abc" || page.Prev != "xyz" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
+
+func TestLinkURL(t *testing.T) {
+	header := http.Header{}
+	header.Add("Link", `</a>; rel="prev", </b>; rel="next"`)
+	if got := linkURL(header, "next"); got != "/b" {
+		t.Errorf("next: got %q", got)
+	}
+	if got := linkURL(header, "prev"); got != "/a" {
+		t.Errorf("prev: got %q", got)
+	}
+	if got := linkURL(header, "last"); got != "" {
+		t.Errorf("last: got %q", got)
+	}
+}
+
+func TestImportReportOrJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "text/x-opml" {
+			t.Errorf("got content type %q", r.Header.Get("Content-Type"))
+		}
+		if string(body) == "big" {
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, `{"id":"2816a44c-3c97-44d6-9522-545f4cc963dd","kind":"opml","status":"pending","total":120,"processed":0,"report":null}`)
+			return
+		}
+		fmt.Fprint(w, `{"created":1,"already_followed":0,"invalid":0,"failed":0,"results":[{"title":"Go","url":"https://go.dev/blog/feed.atom","folder":"","status":"created"}]}`)
+	}))
+	defer server.Close()
+	c := New(server.URL)
+
+	small, err := c.ImportOPML(context.Background(), strings.NewReader("small"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small.Job != nil || small.Report == nil || small.Report.Created != 1 || small.Report.Results[0].Status != ImportCreated {
+		t.Errorf("expected a report, got %+v", small)
+	}
+
+	big, err := c.ImportOPML(context.Background(), strings.NewReader("big"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if big.Report != nil || big.Job == nil || big.Job.Total != 120 || big.Job.Report != nil {
+		t.Errorf("expected a pending job, got %+v", big)
+	}
+}
+
+func TestStreamReadsEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Last-Event-ID") != "41" {
+			t.Errorf("got Last-Event-ID %q", r.Header.Get("Last-Event-ID"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": ping\n\n")
+		fmt.Fprint(w, "id: 42\nevent: read_state\ndata: {\"read\":true,\"post_ids\":[\"2816a44c-3c97-44d6-9522-545f4cc963dd\"]}\n\n")
+		fmt.Fprint(w, "id: 43\nevent: feed_error\ndata: {\"url\":\"https://example.com\",\"error\":\"404\"}\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := New(server.URL).Stream(context.Background(), 41)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	event, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	readState := ReadStateEvent{}
+	if err := json.Unmarshal(event.Data, &readState); err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != 42 || event.Event != EventReadState || !readState.Read || len(readState.PostIDs) != 1 {
+		t.Errorf("got event %+v, data %+v", event, readState)
+	}
+
+	event, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ID != 43 || event.Event != EventFeedError || stream.LastEventID != 43 {
+		t.Errorf("got event %+v, last id %d", event, stream.LastEventID)
+	}
+
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the stream, got %v", err)
+	}
+}
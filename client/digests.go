@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Frequencies of the email digests.
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestSettings is when to send the email digests, only Email is required.
+type DigestSettings struct {
+	Email string `json:"email"`
+	// Frequency is DigestDaily, the default, or DigestWeekly.
+	Frequency string `json:"frequency,omitempty"`
+	// Time is the HH:MM local time in Timezone, 08:00 UTC by default.
+	Time     string `json:"time,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Weekday is the day of a weekly digest like "monday", the default.
+	Weekday string `json:"weekday,omitempty"`
+}
+
+// DigestSubscription is the user's digest schedule.
+type DigestSubscription struct {
+	Email      string     `json:"email"`
+	Frequency  string     `json:"frequency"`
+	Time       string     `json:"time"`
+	Timezone   string     `json:"timezone"`
+	Weekday    string     `json:"weekday"`
+	NextSendAt time.Time  `json:"next_send_at"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+	// UnsubscribeURL is the link in every digest, it works without an api key.
+	UnsubscribeURL string `json:"unsubscribe_url"`
+	// Confirmed is false until the link in the confirmation email is opened, no digests are sent before.
+	Confirmed bool      `json:"confirmed"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Statuses of a digest.
+const (
+	DigestPending = "pending"
+	DigestSending = "sending"
+	DigestSent    = "sent"
+	DigestFailed  = "failed"
+)
+
+// Digest is a recorded digest, a post is only ever in one.
+type Digest struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Frequency string    `json:"frequency"`
+	Subject   string    `json:"subject"`
+	// Status is one of the Digest constants, Error is about the last attempt.
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	// SentAt is nil until the digest is sent.
+	SentAt  *time.Time  `json:"sent_at"`
+	PostIDs []uuid.UUID `json:"post_ids"`
+}
+
+// SubscribeDigest opts into email digests of unread posts, or changes their schedule.
+// A new address is sent a confirmation link and gets no digests until it's opened.
+func (c *Client) SubscribeDigest(ctx context.Context, settings DigestSettings) (*DigestSubscription, error) {
+	sub := &DigestSubscription{}
+	if _, err := c.call(ctx, http.MethodPut, "/v1/digest", nil, settings, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetDigestSubscription gets the user's digest schedule, an error with status 404 if they didn't opt in.
+func (c *Client) GetDigestSubscription(ctx context.Context) (*DigestSubscription, error) {
+	sub := &DigestSubscription{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/digest", nil, nil, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// UnsubscribeDigest stops the email digests.
+func (c *Client) UnsubscribeDigest(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/digest", nil, nil, nil)
+	return err
+}
+
+// ListDigests gets the user's digests, newest first, limit is 1 to 100 or 0 for the default of 20.
+func (c *Client) ListDigests(ctx context.Context, limit int) ([]Digest, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	digests := []Digest{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/digest/history", query, nil, &digests); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
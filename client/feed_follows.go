@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// FollowFeed follows the feed, if it is already followed the existing feed follow is returned.
+// Push feeds that the user didn't create are followed with FollowPushFeed.
+func (c *Client) FollowFeed(ctx context.Context, feedID uuid.UUID) (*FeedFollow, error) {
+	return c.FollowPushFeed(ctx, feedID, "")
+}
+
+// FollowPushFeed follows a push feed with its invite token, like FollowFeed.
+func (c *Client) FollowPushFeed(ctx context.Context, feedID uuid.UUID, inviteToken string) (*FeedFollow, error) {
+	body := struct {
+		FeedID      uuid.UUID `json:"feed_id"`
+		InviteToken string    `json:"invite_token,omitempty"`
+	}{FeedID: feedID, InviteToken: inviteToken}
+	feedFollow := &FeedFollow{}
+	if _, err := c.call(ctx, http.MethodPost, "/v2/feed_follows", nil, body, feedFollow); err != nil {
+		return nil, err
+	}
+	return feedFollow, nil
+}
+
+// ListFeedFollows lists the feeds the user follows.
+func (c *Client) ListFeedFollows(ctx context.Context) ([]FeedFollow, error) {
+	feedFollows := []FeedFollow{}
+	if _, err := c.call(ctx, http.MethodGet, "/v2/feed_follows", nil, nil, &feedFollows); err != nil {
+		return nil, err
+	}
+	return feedFollows, nil
+}
+
+// Notification preferences of a feed follow.
+const (
+	NotifyAll  = "all"
+	NotifyNone = "none"
+)
+
+// UpdateFeedFollowOptions are the settings to change on a feed follow, nil fields are unchanged.
+type UpdateFeedFollowOptions struct {
+	// Title is shown instead of the feed's name, an empty title goes back to the feed's name.
+	Title            *string `json:"title,omitempty"`
+	Pinned           *bool   `json:"pinned,omitempty"`
+	Notify           *string `json:"notify,omitempty"`
+	HideFromTimeline *bool   `json:"hide_from_timeline,omitempty"`
+}
+
+// UpdateFeedFollow changes the user's settings for a followed feed.
+func (c *Client) UpdateFeedFollow(ctx context.Context, feedFollowID uuid.UUID, opts UpdateFeedFollowOptions) (*FeedFollow, error) {
+	feedFollow := &FeedFollow{}
+	if _, err := c.call(ctx, http.MethodPatch, "/v1/feed_follows/"+feedFollowID.String(), nil, opts, feedFollow); err != nil {
+		return nil, err
+	}
+	return feedFollow, nil
+}
+
+// UnfollowFeed deletes the feed follow.
+func (c *Client) UnfollowFeed(ctx context.Context, feedFollowID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v2/feed_follows/"+feedFollowID.String(), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CreateFeedResponse is the feed and the caller's follow of it.
+type CreateFeedResponse struct {
+	Feed       Feed       `json:"feed"`
+	FeedFollow FeedFollow `json:"feed_follow"`
+	// Created is false if a feed with the url already existed and only the follow was created.
+	Created bool `json:"-"`
+}
+
+// CreateFeed creates a feed and follows it.
+// If a feed with the url already exists that feed is followed instead.
+func (c *Client) CreateFeed(ctx context.Context, name, url string) (*CreateFeedResponse, error) {
+	body := struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}{Name: name, URL: url}
+	created := &CreateFeedResponse{}
+	resp, err := c.call(ctx, http.MethodPost, "/v2/feeds", nil, body, created)
+	if err != nil {
+		return nil, err
+	}
+	created.Created = resp.StatusCode == http.StatusCreated
+	return created, nil
+}
+
+// ListFeeds lists all feeds, it doesn't need an api key.
+func (c *Client) ListFeeds(ctx context.Context) ([]Feed, error) {
+	feeds := []Feed{}
+	if _, err := c.call(ctx, http.MethodGet, "/v2/feeds", nil, nil, &feeds); err != nil {
+		return nil, err
+	}
+	return feeds, nil
+}
+
+// UpdateFeedOptions are the fields to change on a feed, nil fields are unchanged.
+type UpdateFeedOptions struct {
+	Name *string `json:"name,omitempty"`
+	URL  *string `json:"url,omitempty"`
+	// FetchFullContent downloads the page of each new post and extracts its article.
+	FetchFullContent *bool `json:"fetch_full_content,omitempty"`
+	// ContentSelector is the CSS selector for the article, an empty one finds it automatically.
+	ContentSelector *string `json:"content_selector,omitempty"`
+	// Selectors replace the selectors of a scraped feed.
+	Selectors *Selectors `json:"selectors,omitempty"`
+}
+
+// UpdateFeedResponse is the feed after an update.
+type UpdateFeedResponse struct {
+	Feed Feed `json:"feed"`
+	// Merged is true if another feed already had the new url and the feed was merged into it,
+	// Feed is then that other feed.
+	Merged bool `json:"merged"`
+}
+
+// UpdateFeed renames a feed, changes its url or whether it fetches full content,
+// only the user who created it or an admin can.
+func (c *Client) UpdateFeed(ctx context.Context, feedID uuid.UUID, opts UpdateFeedOptions) (*UpdateFeedResponse, error) {
+	updated := &UpdateFeedResponse{}
+	if _, err := c.call(ctx, http.MethodPatch, "/v1/feeds/"+feedID.String(), nil, opts, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteFeed deletes a feed, only the user who created it or an admin can.
+// If other users still follow it, the feed is handed over to one of them instead.
+func (c *Client) DeleteFeed(ctx context.Context, feedID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/feeds/"+feedID.String(), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Folder groups feed follows, a feed follow can be in any number of folders.
+type Folder struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Name          string      `json:"name"`
+	Position      int         `json:"position"`
+	FeedFollowIDs []uuid.UUID `json:"feed_follow_ids"`
+	// UnreadCount is the number of unread posts in the folder's feeds.
+	UnreadCount int64 `json:"unread_count"`
+}
+
+type folderName struct {
+	Name string `json:"name"`
+}
+
+// CreateFolder creates an empty folder after the user's other folders.
+func (c *Client) CreateFolder(ctx context.Context, name string) (*Folder, error) {
+	folder := &Folder{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/folders", nil, folderName{Name: name}, folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// ListFolders gets the user's folders in order.
+func (c *Client) ListFolders(ctx context.Context) ([]Folder, error) {
+	folders := []Folder{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/folders", nil, nil, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// RenameFolder renames a folder.
+func (c *Client) RenameFolder(ctx context.Context, folderID uuid.UUID, name string) (*Folder, error) {
+	folder := &Folder{}
+	if _, err := c.call(ctx, http.MethodPatch, "/v1/folders/"+folderID.String(), nil, folderName{Name: name}, folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// ReorderFolders sets the order of the user's folders, ids has to list every folder once.
+func (c *Client) ReorderFolders(ctx context.Context, ids []uuid.UUID) ([]Folder, error) {
+	body := struct {
+		FolderIDs []uuid.UUID `json:"folder_ids"`
+	}{FolderIDs: ids}
+	folders := []Folder{}
+	if _, err := c.call(ctx, http.MethodPut, "/v1/folders/order", nil, body, &folders); err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// DeleteFolder deletes a folder, the feed follows in it are kept.
+func (c *Client) DeleteFolder(ctx context.Context, folderID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/folders/"+folderID.String(), nil, nil, nil)
+	return err
+}
+
+// AddToFolder puts a feed follow in a folder.
+func (c *Client) AddToFolder(ctx context.Context, folderID, feedFollowID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodPut, "/v1/folders/"+folderID.String()+"/feed_follows/"+feedFollowID.String(), nil, nil, nil)
+	return err
+}
+
+// RemoveFromFolder takes a feed follow out of a folder, the feed stays followed.
+func (c *Client) RemoveFromFolder(ctx context.Context, folderID, feedFollowID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/folders/"+folderID.String()+"/feed_follows/"+feedFollowID.String(), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Outcomes of an imported entry.
+const (
+	ImportCreated         = "created"
+	ImportAlreadyFollowed = "already_followed"
+	ImportInvalid         = "invalid"
+	ImportFailed          = "failed"
+	// ImportImported is the status of a starred or read item that was brought over.
+	ImportImported = "imported"
+	// ImportNotFound is the status of an item whose post isn't known, posts are only added to feeds the user created.
+	ImportNotFound = "not_found"
+)
+
+// Formats the server can import with Import.
+const (
+	FormatOPML         = "opml"
+	FormatFeedly       = "feedly"
+	FormatGoogleReader = "greader"
+	FormatMiniflux     = "miniflux"
+	FormatFreshRSS     = "freshrss"
+)
+
+// Statuses of an import job.
+const (
+	ImportJobPending = "pending"
+	ImportJobRunning = "running"
+	ImportJobDone    = "done"
+	ImportJobFailed  = "failed"
+)
+
+// ImportResult is the outcome of importing one entry.
+type ImportResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Folder is the folder the feed was put in, empty for none.
+	Folder string `json:"folder"`
+	// Status is one of ImportCreated, ImportAlreadyFollowed, ImportInvalid or ImportFailed.
+	Status string `json:"status"`
+	// Error is why the entry is invalid or failed.
+	Error        string     `json:"error"`
+	FeedID       *uuid.UUID `json:"feed_id"`
+	FeedFollowID *uuid.UUID `json:"feed_follow_id"`
+}
+
+// ImportItemResult is the outcome of importing one starred or read item.
+type ImportItemResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// FeedURL is the feed the item came from, it is created if it doesn't exist but isn't followed.
+	FeedURL string `json:"feed_url"`
+	Starred bool   `json:"starred"`
+	Read    bool   `json:"read"`
+	// Status is one of ImportImported, ImportInvalid, ImportNotFound or ImportFailed.
+	Status string `json:"status"`
+	// Error is why the item is invalid, not found or failed.
+	Error  string     `json:"error"`
+	PostID *uuid.UUID `json:"post_id"`
+}
+
+// ImportReport is the outcome of every entry of an import.
+type ImportReport struct {
+	Created         int            `json:"created"`
+	AlreadyFollowed int            `json:"already_followed"`
+	Invalid         int            `json:"invalid"`
+	Failed          int            `json:"failed"`
+	Results         []ImportResult `json:"results"`
+	// Starred and Read count the items brought over, from formats that have them.
+	Starred      int `json:"starred"`
+	Read         int `json:"read"`
+	ItemsInvalid int `json:"items_invalid"`
+	ItemsFailed  int `json:"items_failed"`
+	// ItemsNotFound counts items whose post isn't known and that can't be added to a feed the user didn't create.
+	ItemsNotFound int                `json:"items_not_found"`
+	ItemResults   []ImportItemResult `json:"item_results"`
+}
+
+// ImportJob is an import running in the background.
+type ImportJob struct {
+	ID   uuid.UUID `json:"id"`
+	Kind string    `json:"kind"`
+	// Status is one of ImportJobPending, ImportJobRunning, ImportJobDone or ImportJobFailed.
+	Status    string `json:"status"`
+	Total     int    `json:"total"`
+	Processed int    `json:"processed"`
+	// Report is nil until the job is done.
+	Report     *ImportReport `json:"report"`
+	Error      string        `json:"error"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	FinishedAt *time.Time    `json:"finished_at"`
+}
+
+// Import is the response to starting an import, small imports run right away and have a Report,
+// bigger ones run in the background and have a Job to poll with GetImportJob.
+type Import struct {
+	Report *ImportReport
+	Job    *ImportJob
+}
+
+// upload posts the body as is and decodes either an import report or an import job.
+func (c *Client) upload(ctx context.Context, path string, query url.Values, contentType string, body io.Reader) (*Import, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, c.endpoint(path, query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(body)
+	req.Header.Set("Content-Type", contentType)
+
+	var raw json.RawMessage
+	resp, err := c.do(req, &raw)
+	if err != nil {
+		return nil, err
+	}
+	imp := &Import{}
+	if resp.StatusCode == http.StatusAccepted {
+		imp.Job = &ImportJob{}
+		err = json.Unmarshal(raw, imp.Job)
+	} else {
+		imp.Report = &ImportReport{}
+		err = json.Unmarshal(raw, imp.Report)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("client: decoding response body: %w", err)
+	}
+	return imp, nil
+}
+
+// ImportOPML imports the subscriptions in an OPML document, following every feed in it
+// and putting them in folders named after the outlines they are nested in.
+func (c *Client) ImportOPML(ctx context.Context, opml io.Reader) (*Import, error) {
+	return c.upload(ctx, "/v1/opml", nil, "text/x-opml", opml)
+}
+
+// ImportFile is one file of another reader's export.
+type ImportFile struct {
+	Name string
+	Body io.Reader
+}
+
+// Import imports another reader's export, one of the Format constants. Subscriptions are imported
+// like ImportOPML, and starred items and read state are brought over where the format has them.
+// Exports made of several files, like a feedly OPML and its saved entries, are uploaded together.
+func (c *Client) Import(ctx context.Context, format string, files ...ImportFile) (*Import, error) {
+	buf := &bytes.Buffer{}
+	form := multipart.NewWriter(buf)
+	for _, file := range files {
+		part, err := form.CreateFormFile("file", file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(part, file.Body); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+	return c.upload(ctx, "/v1/imports", url.Values{"format": {format}}, form.FormDataContentType(), buf)
+}
+
+// GetImportJob gets an import job started by one of the import methods.
+func (c *Client) GetImportJob(ctx context.Context, jobID uuid.UUID) (*ImportJob, error) {
+	job := &ImportJob{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/imports/"+jobID.String(), nil, nil, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
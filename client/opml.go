@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// BlogrollShare is the public url of a user's shared blogroll.
+type BlogrollShare struct {
+	Token string `json:"token"`
+	// URL is relative to the server, like /v1/blogrolls/{token}.
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// getRaw gets a document that isn't json as is.
+func (c *Client) getRaw(ctx context.Context, path, accept string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.endpoint(path, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	var dat []byte
+	if _, err := c.do(req, &dat); err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// ExportOPML gets the user's follows as an OPML 2.0 document, nested in their folders.
+func (c *Client) ExportOPML(ctx context.Context) ([]byte, error) {
+	return c.getRaw(ctx, "/v1/opml", "text/x-opml")
+}
+
+// ShareBlogroll makes the user's follows public as an OPML blogroll.
+// Every call makes a new url and the old one stops working.
+func (c *Client) ShareBlogroll(ctx context.Context) (*BlogrollShare, error) {
+	share := &BlogrollShare{}
+	if _, err := c.call(ctx, http.MethodPut, "/v1/opml/share", nil, nil, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// GetBlogrollShare gets the url of the user's blogroll, an error with status 404 if it isn't shared.
+func (c *Client) GetBlogrollShare(ctx context.Context) (*BlogrollShare, error) {
+	share := &BlogrollShare{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/opml/share", nil, nil, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// UnshareBlogroll stops sharing the user's blogroll.
+func (c *Client) UnshareBlogroll(ctx context.Context) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/opml/share", nil, nil, nil)
+	return err
+}
+
+// GetBlogroll gets a shared blogroll as an OPML document, it doesn't need an api key.
+func (c *Client) GetBlogroll(ctx context.Context, token string) ([]byte, error) {
+	return c.getRaw(ctx, "/v1/blogrolls/"+token, "text/x-opml")
+}
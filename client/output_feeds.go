@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Formats of an output feed.
+const (
+	OutputRSS  = "rss"
+	OutputAtom = "atom"
+	OutputJSON = "json"
+)
+
+// OutputFeedShare holds the private urls the user's timeline, or one of their folders, is served at as a feed.
+// The urls are relative to the server and work without an api key.
+type OutputFeedShare struct {
+	Token string `json:"token"`
+	// URL picks the format from the Accept header.
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+	// FolderID is nil for the whole timeline.
+	FolderID *uuid.UUID `json:"folder_id"`
+	RSSURL   string     `json:"rss_url"`
+	AtomURL  string     `json:"atom_url"`
+	JSONURL  string     `json:"json_url"`
+}
+
+func outputFeedPath(folderID uuid.UUID) string {
+	if folderID == uuid.Nil {
+		return "/v1/output_feed"
+	}
+	return "/v1/folders/" + folderID.String() + "/output_feed"
+}
+
+// ShareOutputFeed serves the user's timeline, or the folder unless folderID is uuid.Nil, as a feed.
+// Every call makes a new token and the old urls stop working.
+func (c *Client) ShareOutputFeed(ctx context.Context, folderID uuid.UUID) (*OutputFeedShare, error) {
+	share := &OutputFeedShare{}
+	if _, err := c.call(ctx, http.MethodPut, outputFeedPath(folderID), nil, nil, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// GetOutputFeedShare gets the urls of the timeline's or folder's output feed,
+// an error with status 404 if it isn't shared.
+func (c *Client) GetOutputFeedShare(ctx context.Context, folderID uuid.UUID) (*OutputFeedShare, error) {
+	share := &OutputFeedShare{}
+	if _, err := c.call(ctx, http.MethodGet, outputFeedPath(folderID), nil, nil, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// UnshareOutputFeed makes the urls of the timeline's or folder's output feed stop working.
+func (c *Client) UnshareOutputFeed(ctx context.Context, folderID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, outputFeedPath(folderID), nil, nil, nil)
+	return err
+}
+
+// GetOutputFeed gets an output feed in one of the Output formats, it doesn't need an api key.
+func (c *Client) GetOutputFeed(ctx context.Context, token, format string) ([]byte, error) {
+	return c.getRaw(ctx, "/v1/output/"+token+"."+format, "*/*")
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Page is one page of a list endpoint.
+type Page[T any] struct {
+	Items []T
+	// Next is the cursor of the next page, empty on the last page.
+	Next string
+	// Prev is the cursor of the previous page, empty on the first page.
+	Prev string
+}
+
+func newPage[T any](items []T, resp *response) *Page[T] {
+	return &Page[T]{
+		Items: items,
+		Next:  linkCursor(resp.Header, "next"),
+		Prev:  linkCursor(resp.Header, "prev"),
+	}
+}
+
+// Pager walks the pages of a list endpoint by following the
+// Link: <url>; rel="next" response header.
+//
+//	pager := c.PostsPager(&client.ListPostsOptions{Limit: 100})
+//	for pager.HasMore() {
+//		posts, err := pager.Next(ctx)
+//		...
+//	}
+type Pager[T any] struct {
+	c       *Client
+	nextURL string
+	started bool
+	first   func(ctx context.Context) (*http.Request, error)
+	decode  func(c *Client, req *http.Request) ([]T, *response, error)
+}
+
+// HasMore reports whether Next will fetch another page.
+func (p *Pager[T]) HasMore() bool {
+	return !p.started || p.nextURL != ""
+}
+
+// Next fetches the next page, it returns no items and no error when there are no more pages.
+func (p *Pager[T]) Next(ctx context.Context) ([]T, error) {
+	if !p.HasMore() {
+		return nil, nil
+	}
+
+	var req *http.Request
+	var err error
+	if !p.started {
+		req, err = p.first(ctx)
+	} else {
+		var next *url.URL
+		next, err = p.c.baseURL.Parse(p.nextURL)
+		if err == nil {
+			req, err = p.c.newRequest(ctx, http.MethodGet, next, nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, resp, err := p.decode(p.c, req)
+	if err != nil {
+		return nil, err
+	}
+	p.started = true
+	p.nextURL = linkURL(resp.Header, "next")
+	return items, nil
+}
+
+// All fetches every remaining page.
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	all := []T{}
+	for p.HasMore() {
+		items, err := p.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+	}
+	return all, nil
+}
+
+// linkURL returns the url of the Link header entry with the given rel, or "".
+func linkURL(header http.Header, rel string) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, r := range strings.Fields(strings.Trim(val, `"`)) {
+					if r == rel {
+						return strings.Trim(target, "<>")
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// linkCursor returns the cursor query parameter of the Link header entry with the given rel, or "".
+func linkCursor(header http.Header, rel string) string {
+	link := linkURL(header, rel)
+	if link == "" {
+		return ""
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return parsed.Query().Get("cursor")
+}
+
+// pageQuery turns limit into a query, leaving it out when not set.
+func pageQuery(limit int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
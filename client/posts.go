@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sort orders of the timeline.
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// ListPostsOptions are the query parameters of GET /v2/posts, zero values are left out.
+type ListPostsOptions struct {
+	// Limit is the page size, the server defaults to 50.
+	Limit int
+	// Sort is SortNewest (the default) or SortOldest.
+	Sort string
+	// Cursor is a Page's Next or Prev cursor.
+	Cursor string
+	// FeedID only returns posts from this feed.
+	FeedID uuid.UUID
+	// FolderID only returns posts from the feeds in this folder.
+	FolderID uuid.UUID
+	// SearchID only returns posts matching this saved search.
+	SearchID uuid.UUID
+	// Since only returns posts published at or after it.
+	Since time.Time
+	// Until only returns posts published before it.
+	Until time.Time
+	// Author only returns posts by this author, case insensitive.
+	Author string
+	// Category only returns posts with this category.
+	Category string
+	// Unread, if set, only returns unread (true) or read (false) posts.
+	Unread *bool
+	// Starred, if set, only returns starred (true) or not starred (false) posts.
+	Starred *bool
+	// Hidden, if true, only returns the posts hidden by the user's filter rules, which are left out otherwise.
+	Hidden *bool
+}
+
+func (o *ListPostsOptions) query() url.Values {
+	if o == nil {
+		return url.Values{}
+	}
+	query := pageQuery(o.Limit)
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	if o.FeedID != uuid.Nil {
+		query.Set("feed_id", o.FeedID.String())
+	}
+	if o.FolderID != uuid.Nil {
+		query.Set("folder_id", o.FolderID.String())
+	}
+	if o.SearchID != uuid.Nil {
+		query.Set("search_id", o.SearchID.String())
+	}
+	if !o.Since.IsZero() {
+		query.Set("since", o.Since.Format(time.RFC3339))
+	}
+	if !o.Until.IsZero() {
+		query.Set("until", o.Until.Format(time.RFC3339))
+	}
+	if o.Author != "" {
+		query.Set("author", o.Author)
+	}
+	if o.Category != "" {
+		query.Set("category", o.Category)
+	}
+	if o.Unread != nil {
+		query.Set("unread", strconv.FormatBool(*o.Unread))
+	}
+	if o.Starred != nil {
+		query.Set("starred", strconv.FormatBool(*o.Starred))
+	}
+	if o.Hidden != nil {
+		query.Set("hidden", strconv.FormatBool(*o.Hidden))
+	}
+	return query
+}
+
+// ListPosts gets a page of posts from the feeds the user follows and the posts they starred, newest first by default.
+// Pass the page's Next or Prev as ListPostsOptions.Cursor to get the next or previous page.
+func (c *Client) ListPosts(ctx context.Context, opts *ListPostsOptions) (*Page[Post], error) {
+	posts := []Post{}
+	resp, err := c.call(ctx, http.MethodGet, "/v2/posts", opts.query(), nil, &posts)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(posts, resp), nil
+}
+
+// PostsPager returns a Pager over the posts from the feeds the user follows.
+func (c *Client) PostsPager(opts *ListPostsOptions) *Pager[Post] {
+	return &Pager[Post]{
+		c: c,
+		first: func(ctx context.Context) (*http.Request, error) {
+			return c.newRequest(ctx, http.MethodGet, c.endpoint("/v2/posts", opts.query()), nil)
+		},
+		decode: func(c *Client, req *http.Request) ([]Post, *response, error) {
+			posts := []Post{}
+			resp, err := c.do(req, &posts)
+			return posts, resp, err
+		},
+	}
+}
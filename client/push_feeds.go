@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreatePushFeedResponse is a new push feed, the caller's follow of it and its write and invite tokens.
+type CreatePushFeedResponse struct {
+	Feed       Feed       `json:"feed"`
+	FeedFollow FeedFollow `json:"feed_follow"`
+	// WriteToken pushes items into the feed, it's only returned here and by RotateWriteToken.
+	WriteToken string `json:"write_token"`
+	// InviteToken lets others follow the feed with FollowPushFeed, it's only returned here and by RotateInviteToken.
+	InviteToken string `json:"invite_token"`
+}
+
+// PushItem is an item to push into a push feed, only Title is required.
+type PushItem struct {
+	Title string `json:"title"`
+	// URL is optional, an item with the url of a post already in the feed isn't posted again.
+	URL string `json:"url,omitempty"`
+	// Description and Content are html.
+	Description string   `json:"description,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	// PublishedAt is now if it's nil.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// PushItemsResponse is the posts created from pushed items.
+type PushItemsResponse struct {
+	Posts []Post `json:"posts"`
+	// Duplicates are the urls of the items that already had a post in the feed.
+	Duplicates []string `json:"duplicates"`
+}
+
+// CreatePushFeed creates a feed with no upstream that items are pushed into with PushItems, and follows it.
+// Push feeds aren't listed by ListFeeds, other users follow them with FollowPushFeed and the invite token.
+func (c *Client) CreatePushFeed(ctx context.Context, name string) (*CreatePushFeedResponse, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{Name: name}
+	created := &CreatePushFeedResponse{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/push_feeds", nil, body, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// RotateWriteToken replaces the write token of a push feed and returns the new one, the old one stops working.
+func (c *Client) RotateWriteToken(ctx context.Context, feedID uuid.UUID) (string, error) {
+	var out struct {
+		WriteToken string `json:"write_token"`
+	}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/feeds/"+feedID.String()+"/write_token", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out.WriteToken, nil
+}
+
+// RotateInviteToken replaces the invite token of a push feed and returns the new one,
+// the old one can't be used to follow the feed anymore.
+func (c *Client) RotateInviteToken(ctx context.Context, feedID uuid.UUID) (string, error) {
+	var out struct {
+		InviteToken string `json:"invite_token"`
+	}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/feeds/"+feedID.String()+"/invite_token", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out.InviteToken, nil
+}
+
+// PushItems posts items to a push feed, up to 100 at a time.
+// It authenticates with the feed's write token instead of the client's api key, so it works on a client without one.
+func (c *Client) PushItems(ctx context.Context, feedID uuid.UUID, writeToken string, items []PushItem) (*PushItemsResponse, error) {
+	body := struct {
+		Items []PushItem `json:"items"`
+	}{Items: items}
+	req, err := c.newRequest(ctx, http.MethodPost, c.endpoint("/v1/feeds/"+feedID.String()+"/items", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+writeToken)
+	pushed := &PushItemsResponse{}
+	if _, err := c.do(req, pushed); err != nil {
+		return nil, err
+	}
+	return pushed, nil
+}
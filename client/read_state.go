@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UnreadCount is the number of unread posts in a followed feed.
+type UnreadCount struct {
+	FeedFollowID uuid.UUID `json:"feed_follow_id"`
+	FeedID       uuid.UUID `json:"feed_id"`
+	UnreadCount  int64     `json:"unread_count"`
+}
+
+// MarkAllReadOptions limit which posts MarkAllPostsRead marks, zero values are left out.
+type MarkAllReadOptions struct {
+	// FeedID only marks posts from this feed.
+	FeedID uuid.UUID
+	// Before only marks posts published before it.
+	Before time.Time
+}
+
+type updatedCount struct {
+	Updated int64 `json:"updated"`
+}
+
+type postIDs struct {
+	PostIDs []uuid.UUID `json:"post_ids"`
+}
+
+// MarkPostRead marks a post from a followed feed as read.
+func (c *Client) MarkPostRead(ctx context.Context, postID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodPost, "/v1/posts/"+postID.String()+"/read", nil, nil, nil)
+	return err
+}
+
+// MarkPostUnread marks a post as unread.
+func (c *Client) MarkPostUnread(ctx context.Context, postID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/posts/"+postID.String()+"/read", nil, nil, nil)
+	return err
+}
+
+// MarkPostsRead marks posts as read and returns how many are now read.
+// Posts that aren't in a followed feed are skipped.
+func (c *Client) MarkPostsRead(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	updated := updatedCount{}
+	_, err := c.call(ctx, http.MethodPost, "/v1/posts/read", nil, postIDs{PostIDs: ids}, &updated)
+	return updated.Updated, err
+}
+
+// MarkPostsUnread marks posts as unread and returns how many were read before.
+func (c *Client) MarkPostsUnread(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	updated := updatedCount{}
+	_, err := c.call(ctx, http.MethodPost, "/v1/posts/unread", nil, postIDs{PostIDs: ids}, &updated)
+	return updated.Updated, err
+}
+
+// MarkAllPostsRead marks every post in the followed feeds as read and returns how many were newly marked.
+func (c *Client) MarkAllPostsRead(ctx context.Context, opts *MarkAllReadOptions) (int64, error) {
+	body := struct {
+		FeedID *uuid.UUID `json:"feed_id,omitempty"`
+		Before *time.Time `json:"before,omitempty"`
+	}{}
+	if opts != nil && opts.FeedID != uuid.Nil {
+		body.FeedID = &opts.FeedID
+	}
+	if opts != nil && !opts.Before.IsZero() {
+		body.Before = &opts.Before
+	}
+	updated := updatedCount{}
+	_, err := c.call(ctx, http.MethodPost, "/v1/posts/mark_all_read", nil, body, &updated)
+	return updated.Updated, err
+}
+
+// UnreadCounts gets the number of unread posts in each followed feed.
+func (c *Client) UnreadCounts(ctx context.Context) ([]UnreadCount, error) {
+	counts := []UnreadCount{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/feed_follows/unread_counts", nil, nil, &counts); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fields and ops of a filter condition.
+const (
+	FilterFieldTitle       = "title"
+	FilterFieldDescription = "description"
+	FilterFieldContent     = "content"
+	FilterFieldAuthor      = "author"
+	FilterFieldURL         = "url"
+	FilterFieldCategory    = "category"
+
+	FilterOpContains = "contains"
+	FilterOpEquals   = "equals"
+	FilterOpMatches  = "matches"
+)
+
+// FilterCondition is a test a post has to pass for a rule to apply to it.
+// FilterOpContains and FilterOpEquals ignore case, FilterOpMatches takes a regexp like "sponsored" or "/sponsored/i".
+type FilterCondition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// FilterRule hides, stars or tags the posts of the followed feeds that match all of its conditions.
+type FilterRule struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// FeedID is nil for every followed feed.
+	FeedID     *uuid.UUID        `json:"feed_id"`
+	Conditions []FilterCondition `json:"conditions"`
+	// Hide leaves the posts out of ListPosts unless ListPostsOptions.Hidden is set.
+	Hide bool `json:"hide"`
+	// Star only stars new posts.
+	Star bool     `json:"star"`
+	Tags []string `json:"tags"`
+	// MatchCount is the number of posts the rule matched.
+	MatchCount int64     `json:"match_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// FilterRuleParams are the fields to set, creating a rule needs a Name, Conditions or a FeedID, and an action.
+type FilterRuleParams struct {
+	Name       string             `json:"name,omitempty"`
+	FeedID     *uuid.UUID         `json:"feed_id,omitempty"`
+	Conditions *[]FilterCondition `json:"conditions,omitempty"`
+	Hide       *bool              `json:"hide,omitempty"`
+	Star       *bool              `json:"star,omitempty"`
+	Tags       *[]string          `json:"tags,omitempty"`
+}
+
+// FilterRuleDryRun is what a rule would do to the newest posts of the followed feeds.
+type FilterRuleDryRun struct {
+	// Scanned is the number of posts the rule was run on.
+	Scanned int `json:"scanned"`
+	Matched int `json:"matched"`
+	// Posts are up to 50 of the matching posts, newest first.
+	Posts []struct {
+		ID          uuid.UUID `json:"id"`
+		FeedID      uuid.UUID `json:"feed_id"`
+		Title       string    `json:"title"`
+		URL         string    `json:"url"`
+		PublishedAt time.Time `json:"published_at"`
+	} `json:"posts"`
+}
+
+func filterRulePath(ruleID uuid.UUID) string {
+	return "/v1/rules/" + ruleID.String()
+}
+
+// CreateFilterRule creates a filter rule, it's run on the newest posts of the followed feeds right away.
+func (c *Client) CreateFilterRule(ctx context.Context, params FilterRuleParams) (*FilterRule, error) {
+	rule := &FilterRule{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/rules", nil, params, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListFilterRules gets the user's filter rules by name.
+func (c *Client) ListFilterRules(ctx context.Context) ([]FilterRule, error) {
+	rules := []FilterRule{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/rules", nil, nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UpdateFilterRule changes the fields of a filter rule that are set in params.
+func (c *Client) UpdateFilterRule(ctx context.Context, ruleID uuid.UUID, params FilterRuleParams) (*FilterRule, error) {
+	rule := &FilterRule{}
+	if _, err := c.call(ctx, http.MethodPatch, filterRulePath(ruleID), nil, params, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteFilterRule deletes a filter rule.
+func (c *Client) DeleteFilterRule(ctx context.Context, ruleID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, filterRulePath(ruleID), nil, nil, nil)
+	return err
+}
+
+// DryRunFilterRule shows what a rule would do without saving it, the Name is optional.
+func (c *Client) DryRunFilterRule(ctx context.Context, params FilterRuleParams) (*FilterRuleDryRun, error) {
+	dryRun := &FilterRuleDryRun{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/rules/dry_run", nil, params, dryRun); err != nil {
+		return nil, err
+	}
+	return dryRun, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedPost is a post in the read later queue.
+type SavedPost struct {
+	Position int       `json:"position"`
+	SavedAt  time.Time `json:"saved_at"`
+	Post     Post      `json:"post"`
+}
+
+// StarPost stars a post, starred posts stay in the timeline after their feed is unfollowed.
+func (c *Client) StarPost(ctx context.Context, postID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodPost, "/v1/posts/"+postID.String()+"/star", nil, nil, nil)
+	return err
+}
+
+// UnstarPost unstars a post.
+func (c *Client) UnstarPost(ctx context.Context, postID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/posts/"+postID.String()+"/star", nil, nil, nil)
+	return err
+}
+
+// SavePost adds a post to the end of the read later queue and returns the queue.
+func (c *Client) SavePost(ctx context.Context, postID uuid.UUID) ([]SavedPost, error) {
+	body := struct {
+		PostID uuid.UUID `json:"post_id"`
+	}{PostID: postID}
+	queue := []SavedPost{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/read_later", nil, body, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// ListSavedPosts gets the read later queue in order.
+func (c *Client) ListSavedPosts(ctx context.Context) ([]SavedPost, error) {
+	queue := []SavedPost{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/read_later", nil, nil, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// ReorderSavedPosts sets the order of the read later queue, ids has to list every post in it once.
+func (c *Client) ReorderSavedPosts(ctx context.Context, ids []uuid.UUID) ([]SavedPost, error) {
+	queue := []SavedPost{}
+	if _, err := c.call(ctx, http.MethodPut, "/v1/read_later/order", nil, postIDs{PostIDs: ids}, &queue); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// UnsavePost removes a post from the read later queue.
+func (c *Client) UnsavePost(ctx context.Context, postID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, "/v1/read_later/"+postID.String(), nil, nil, nil)
+	return err
+}
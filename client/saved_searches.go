@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a search the new posts of the followed feeds are matched against,
+// list its posts with ListPostsOptions.SearchID.
+type SavedSearch struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Query is keywords and "quoted phrases", matched as whole words ignoring case in the title and description.
+	Query string `json:"query"`
+	// Match is "any" or "all" of the terms.
+	Match string `json:"match"`
+	// Author is empty to match every author.
+	Author string `json:"author"`
+	// FeedID is nil to search every followed feed.
+	FeedID *uuid.UUID `json:"feed_id"`
+	// Notify sends new matches to Stream as EventSearchMatch.
+	Notify      bool      `json:"notify"`
+	UnreadCount int64     `json:"unread_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SavedSearchParams are the fields to set, Name and Query are required to create a search.
+type SavedSearchParams struct {
+	Name   string `json:"name,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Match  string `json:"match,omitempty"`
+	Author string `json:"author,omitempty"`
+	// FeedID only searches one followed feed.
+	FeedID *uuid.UUID `json:"feed_id,omitempty"`
+	Notify *bool      `json:"notify,omitempty"`
+}
+
+func savedSearchPath(searchID uuid.UUID) string {
+	return "/v1/searches/" + searchID.String()
+}
+
+// CreateSavedSearch saves a search, it's run on the newest posts of the followed feeds right away.
+func (c *Client) CreateSavedSearch(ctx context.Context, params SavedSearchParams) (*SavedSearch, error) {
+	search := &SavedSearch{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/searches", nil, params, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// ListSavedSearches gets the user's saved searches by name.
+func (c *Client) ListSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	searches := []SavedSearch{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/searches", nil, nil, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+// UpdateSavedSearch changes the fields of a saved search that are set in params.
+func (c *Client) UpdateSavedSearch(ctx context.Context, searchID uuid.UUID, params SavedSearchParams) (*SavedSearch, error) {
+	search := &SavedSearch{}
+	if _, err := c.call(ctx, http.MethodPatch, savedSearchPath(searchID), nil, params, search); err != nil {
+		return nil, err
+	}
+	return search, nil
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (c *Client) DeleteSavedSearch(ctx context.Context, searchID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, savedSearchPath(searchID), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// The kinds of feeds.
+const (
+	FeedKindRSS     = "rss"
+	FeedKindScraped = "scraped"
+	FeedKindPush    = "push"
+)
+
+// Selectors are the CSS selectors that find the items in the page of a scraped feed.
+// Every selector but Item is matched inside an item.
+type Selectors struct {
+	// Item matches each item, required.
+	Item string `json:"item"`
+	// Title matches the title of an item, required.
+	Title string `json:"title"`
+	// Link matches the link to an item, empty for the first link in the item.
+	Link string `json:"link,omitempty"`
+	// Date matches the publication date of an item.
+	Date string `json:"date,omitempty"`
+	// Summary matches the description of an item.
+	Summary string `json:"summary,omitempty"`
+}
+
+// ScrapedItem is an item the selectors found in a page.
+type ScrapedItem struct {
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	// Published is nil if the item has no date or it couldn't be read.
+	Published *time.Time `json:"published"`
+	// Summary is sanitized html.
+	Summary string `json:"summary"`
+}
+
+// ScrapePreview is the title of a page and the items the selectors found in it.
+type ScrapePreview struct {
+	Title string        `json:"title"`
+	Items []ScrapedItem `json:"items"`
+}
+
+// CreateScrapedFeed creates a feed of a web page that doesn't have one, and follows it.
+// The page is polled like other feeds and each item the selectors find in it becomes a post.
+func (c *Client) CreateScrapedFeed(ctx context.Context, name, url string, selectors Selectors) (*CreateFeedResponse, error) {
+	body := struct {
+		Name      string    `json:"name"`
+		URL       string    `json:"url"`
+		Selectors Selectors `json:"selectors"`
+	}{Name: name, URL: url, Selectors: selectors}
+	created := &CreateFeedResponse{Created: true}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/scraped_feeds", nil, body, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// PreviewScrapedFeed downloads the page and returns the items the selectors find in it, without saving anything.
+func (c *Client) PreviewScrapedFeed(ctx context.Context, url string, selectors Selectors) (*ScrapePreview, error) {
+	body := struct {
+		URL       string    `json:"url"`
+		Selectors Selectors `json:"selectors"`
+	}{URL: url, Selectors: selectors}
+	preview := &ScrapePreview{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/scraped_feeds/preview", nil, body, preview); err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Scopes of a search.
+const (
+	SearchScopeFollowed = "followed"
+	SearchScopeAll      = "all"
+)
+
+// SearchResult is a post matching a search.
+type SearchResult struct {
+	Post
+	// Rank is higher for better matches.
+	Rank float32 `json:"rank"`
+	// TitleHighlight and Snippet are html with the matches in <mark>,
+	// Snippet is up to two fragments of the description and content.
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"`
+}
+
+// SearchOptions are the optional parameters of Search.
+type SearchOptions struct {
+	// Limit is the page size, the server defaults to 20.
+	Limit int
+	// Cursor is a Page's Next or Prev cursor.
+	Cursor string
+	// Scope is SearchScopeFollowed (the default) or SearchScopeAll, which is only for admins.
+	Scope string
+}
+
+// Search does a full-text search of the posts in the feeds the user follows, best match first.
+// In q words must all match, "quoted phrases" match in order, word* matches prefixes and -word excludes.
+func (c *Client) Search(ctx context.Context, q string, opts *SearchOptions) (*Page[SearchResult], error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+	query := pageQuery(opts.Limit)
+	query.Set("q", q)
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.Scope != "" {
+		query.Set("scope", opts.Scope)
+	}
+	results := []SearchResult{}
+	resp, err := c.call(ctx, http.MethodGet, "/v1/search", query, nil, &results)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(results, resp), nil
+}
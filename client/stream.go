@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of stream events.
+const (
+	EventNewPost     = "new_post"
+	EventReadState   = "read_state"
+	EventFeedError   = "feed_error"
+	EventSearchMatch = "search_match"
+)
+
+// longest line of a stream Next can read
+const maxStreamLine = 4 << 20
+
+// StreamEvent is one event of a stream.
+type StreamEvent struct {
+	ID int64
+	// Event is one of the Event constants.
+	Event string
+	// Data is a Post for EventNewPost, a ReadStateEvent for EventReadState, a FeedErrorEvent for EventFeedError
+	// and a SearchMatchEvent for EventSearchMatch.
+	Data json.RawMessage
+}
+
+// ReadStateEvent is the data of an EventReadState, either PostIDs changed,
+// or with All every post in the followed feeds (or FeedID's) published before Before.
+type ReadStateEvent struct {
+	Read    bool        `json:"read"`
+	PostIDs []uuid.UUID `json:"post_ids"`
+	All     bool        `json:"all"`
+	FeedID  *uuid.UUID  `json:"feed_id"`
+	Before  *time.Time  `json:"before"`
+}
+
+// FeedErrorEvent is the data of an EventFeedError.
+type FeedErrorEvent struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	URL    string    `json:"url"`
+	Error  string    `json:"error"`
+}
+
+// SearchMatchEvent is the data of an EventSearchMatch, a new post matching a saved search with Notify.
+type SearchMatchEvent struct {
+	SearchID   uuid.UUID `json:"search_id"`
+	SearchName string    `json:"search_name"`
+	Post       Post      `json:"post"`
+}
+
+// Stream reads server-sent events from GET /v1/stream.
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	// LastEventID is the id of the last event read, pass it to Stream to resume after it.
+	LastEventID int64
+}
+
+// Stream pushes new posts and feed errors of the feeds the user follows, and their read state changes.
+// lastEventID resumes after an event seen before, 0 starts with the events from now on.
+// The stream ends when ctx is done.
+func (c *Client) Stream(ctx context.Context, lastEventID int64) (*Stream, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.endpoint("/v1/stream", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(lastEventID, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		dat, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newError(resp.StatusCode, dat)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	// a post's description can be longer than the default line limit
+	scanner.Buffer(nil, maxStreamLine)
+	return &Stream{body: resp.Body, scanner: scanner, LastEventID: lastEventID}, nil
+}
+
+// Next blocks until the next event, it returns io.EOF when the server ends the stream.
+func (s *Stream) Next() (*StreamEvent, error) {
+	event := &StreamEvent{}
+	data := []string{}
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		if line == "" {
+			// a blank line ends an event, comments like pings don't make one
+			if event.Event == "" && len(data) == 0 {
+				continue
+			}
+			event.Data = json.RawMessage(strings.Join(data, "\n"))
+			if event.ID > 0 {
+				s.LastEventID = event.ID
+			}
+			return event, nil
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.ID, _ = strconv.ParseInt(value, 10, 64)
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close ends the stream.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
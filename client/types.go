@@ -0,0 +1,85 @@
+package client
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is a user of the aggregator.
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+// CreatedUser is returned once when a user is created, it is the only time the api key is returned.
+type CreatedUser struct {
+	User
+	APIKey string `json:"api_key"`
+}
+
+// Feed is a blog's rss/atom feed.
+type Feed struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Name          string     `json:"name"`
+	URL           string     `json:"url"`
+	UserID        uuid.UUID  `json:"user_id"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	// SiteURL is the website the feed belongs to, empty until the feed is fetched.
+	SiteURL string `json:"site_url"`
+	// FetchFullContent is whether the page of each new post is downloaded for its FullContent.
+	FetchFullContent bool `json:"fetch_full_content"`
+	// ContentSelector is the CSS selector for the article in the pages, empty to find it automatically.
+	ContentSelector string `json:"content_selector"`
+	// Kind is FeedKindRSS, FeedKindScraped or FeedKindPush.
+	Kind string `json:"kind"`
+	// Selectors are nil unless the feed is scraped.
+	Selectors *Selectors `json:"selectors"`
+}
+
+// FeedFollow is a user following a feed.
+type FeedFollow struct {
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Title is shown instead of the feed's name, nil to show the feed's name.
+	Title  *string `json:"title"`
+	Pinned bool    `json:"pinned"`
+	// Notify is NotifyAll or NotifyNone.
+	Notify string `json:"notify"`
+	// HideFromTimeline keeps the feed's posts out of ListPosts unless filtering by feed or folder.
+	HideFromTimeline bool `json:"hide_from_timeline"`
+}
+
+// Post is a post from a feed.
+type Post struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	// Description is sanitized html, its relative links are resolved against URL.
+	Description string `json:"description"`
+	// DescriptionText is the description as plain text.
+	DescriptionText string `json:"description_text"`
+	// FullContent is the sanitized article from the post's page, if its feed fetches full content.
+	FullContent string    `json:"full_content"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Author      string    `json:"author"`
+	Categories  []string  `json:"categories"`
+	// Read is whether the user has read the post.
+	Read bool `json:"read"`
+	// Starred is whether the user starred the post.
+	Starred bool `json:"starred"`
+	// Saved is whether the post is in the user's read later queue.
+	Saved bool `json:"saved"`
+	// Tags are from the user's filter rules that matched the post.
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateUser creates a user, the returned CreatedUser holds its api key.
+// It doesn't need an api key, use Client.WithAPIKey to act as the new user.
+func (c *Client) CreateUser(ctx context.Context, name string) (*CreatedUser, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{Name: name}
+	user := &CreatedUser{}
+	if _, err := c.call(ctx, http.MethodPost, "/v2/users", nil, body, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser gets the user the api key belongs to.
+func (c *Client) GetUser(ctx context.Context) (*User, error) {
+	user := &User{}
+	if _, err := c.call(ctx, http.MethodGet, "/v2/users", nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
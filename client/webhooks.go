@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of a webhook delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliverySending   = "sending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a url the posts the fetcher finds in the user's followed feeds are POSTed to.
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	// Secret signs the deliveries, see VerifyWebhookSignature.
+	Secret string `json:"secret"`
+	// FeedID, FolderID and Keyword are nil or empty to not filter the posts by them.
+	FeedID   *uuid.UUID `json:"feed_id"`
+	FolderID *uuid.UUID `json:"folder_id"`
+	Keyword  string     `json:"keyword"`
+}
+
+// CreateWebhookParams is what to register, only URL is required.
+type CreateWebhookParams struct {
+	URL string `json:"url"`
+	// FeedID only sends the posts of one followed feed.
+	FeedID *uuid.UUID `json:"feed_id,omitempty"`
+	// FolderID only sends the posts of the feeds in one folder.
+	FolderID *uuid.UUID `json:"folder_id,omitempty"`
+	// Keyword only sends the posts with it as whole words in the title or description, ignoring case.
+	Keyword string `json:"keyword,omitempty"`
+}
+
+// WebhookPayload is the body POSTed to a webhook.
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	WebhookID uuid.UUID `json:"webhook_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Post      Post      `json:"post"`
+}
+
+// WebhookDelivery is one post sent to a webhook, retried with backoff until it's delivered or failed.
+type WebhookDelivery struct {
+	ID        uuid.UUID      `json:"id"`
+	WebhookID uuid.UUID      `json:"webhook_id"`
+	PostID    uuid.UUID      `json:"post_id"`
+	Payload   WebhookPayload `json:"payload"`
+	// Status is one of the Delivery constants.
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// NextAttemptAt is nil once the delivery is delivered or failed.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	// ResponseStatus and Error are about the last attempt, ResponseStatus is nil if there was no response.
+	ResponseStatus *int       `json:"response_status"`
+	Error          string     `json:"error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func webhookPath(webhookID uuid.UUID) string {
+	return "/v1/webhooks/" + webhookID.String()
+}
+
+// CreateWebhook registers a webhook.
+func (c *Client) CreateWebhook(ctx context.Context, params CreateWebhookParams) (*Webhook, error) {
+	webhook := &Webhook{}
+	if _, err := c.call(ctx, http.MethodPost, "/v1/webhooks", nil, params, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// ListWebhooks gets the user's webhooks, oldest first.
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	if _, err := c.call(ctx, http.MethodGet, "/v1/webhooks", nil, nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetWebhook gets one of the user's webhooks.
+func (c *Client) GetWebhook(ctx context.Context, webhookID uuid.UUID) (*Webhook, error) {
+	webhook := &Webhook{}
+	if _, err := c.call(ctx, http.MethodGet, webhookPath(webhookID), nil, nil, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	_, err := c.call(ctx, http.MethodDelete, webhookPath(webhookID), nil, nil, nil)
+	return err
+}
+
+// ListWebhookDeliveries gets the newest deliveries of a webhook, limit is 1 to 200 or 0 for the default of 50.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]WebhookDelivery, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	deliveries := []WebhookDelivery{}
+	if _, err := c.call(ctx, http.MethodGet, webhookPath(webhookID)+"/deliveries", query, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RedeliverWebhook sends a delivery's payload again right away, as a new delivery.
+func (c *Client) RedeliverWebhook(ctx context.Context, webhookID, deliveryID uuid.UUID) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	path := webhookPath(webhookID) + "/deliveries/" + deliveryID.String() + "/redeliver"
+	if _, err := c.call(ctx, http.MethodPost, path, nil, nil, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// VerifyWebhookSignature checks the X-Webhook-Signature header of a delivery against its body,
+// and that its X-Webhook-Timestamp is no older than maxAge, use 0 to not check the age.
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, maxAge time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil {
+		return errors.New("invalid X-Webhook-Timestamp")
+	}
+	if maxAge > 0 && time.Since(time.Unix(timestamp, 0)) > maxAge {
+		return errors.New("delivery too old")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(header.Get("X-Webhook-Signature")), []byte(want)) {
+		return errors.New("invalid X-Webhook-Signature")
+	}
+	return nil
+}
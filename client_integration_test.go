@@ -0,0 +1,196 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/broker"
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/fetch"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// an apiConfig backed by the db at DATABASE_URL
+// the db needs the migrations in sql/schema applied, the test is skipped without DATABASE_URL
+func newTestAPIConfig(t *testing.T) apiConfig {
+	t.Helper()
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping test against the db")
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// the tests serve feeds and pages from local servers
+	fetcher := fetch.New("blog_aggregator-test")
+	fetcher.AllowPrivate = true
+	fetcher.HostInterval = 0
+	return apiConfig{DB: database.New(db), Conn: db, Broker: broker.NewMemory(), Fetcher: fetcher}
+}
+
+// starts the real router on a newTestAPIConfig
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(newTestAPIConfig(t).router())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// creates a new user and returns a client authenticated as them
+func newTestUser(t *testing.T, server *httptest.Server, name string) *client.Client {
+	t.Helper()
+	created, err := client.New(server.URL).CreateUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.New(server.URL, client.WithAPIKey(created.APIKey))
+}
+
+// creates a feed followed by the user with a post for each title, published an hour apart
+// returns the feed and its posts, oldest first
+func newTestFeedWithPosts(t *testing.T, apiCfg apiConfig, user *client.Client, titles ...string) (*client.Feed, []client.Post) {
+	t.Helper()
+	ctx := context.Background()
+	base := "https://example.com/" + uuid.NewString()
+	created, err := user.CreateFeed(ctx, "Feed", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := time.Now().UTC().Add(-time.Duration(len(titles)) * time.Hour).Truncate(time.Second)
+	items := []*gofeed.Item{}
+	for i, title := range titles {
+		publishedAt := published.Add(time.Duration(i) * time.Hour)
+		items = append(items, &gofeed.Item{Title: title, Link: base + "/posts/" + uuid.NewString(), PublishedParsed: &publishedAt})
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: items}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	page, err := user.ListPosts(ctx, &client.ListPostsOptions{FeedID: created.Feed.ID, Sort: client.SortOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != len(titles) {
+		t.Fatalf("got %d posts, want %d", len(page.Items), len(titles))
+	}
+	return &created.Feed, page.Items
+}
+
+func TestClientAgainstRouter(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	created, err := client.New(server.URL).CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.APIKey == "" {
+		t.Fatal("created user has no api key")
+	}
+	alice := client.New(server.URL, client.WithAPIKey(created.APIKey))
+
+	user, err := alice.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != created.ID || user.Name != "alice" {
+		t.Errorf("got user %+v, want %+v", user, created.User)
+	}
+
+	// a unique url creates the feed
+	feedURL := "https://example.com/" + uuid.NewString() + "/index.xml"
+	newFeed, err := alice.CreateFeed(ctx, "Example", feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !newFeed.Created || newFeed.Feed.URL != feedURL || newFeed.FeedFollow.FeedID != newFeed.Feed.ID {
+		t.Errorf("unexpected create feed response %+v", newFeed)
+	}
+	if newFeed.Feed.LastFetchedAt != nil {
+		t.Errorf("new feed should not have been fetched yet")
+	}
+
+	// the same url from another user only follows the existing feed
+	bob := newTestUser(t, server, "bob")
+	existingFeed, err := bob.CreateFeed(ctx, "Example again", feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existingFeed.Created || existingFeed.Feed.ID != newFeed.Feed.ID {
+		t.Errorf("expected the existing feed, got %+v", existingFeed)
+	}
+
+	feeds, err := alice.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, feed := range feeds {
+		found = found || feed.ID == newFeed.Feed.ID
+	}
+	if !found {
+		t.Errorf("created feed %s missing from ListFeeds", newFeed.Feed.ID)
+	}
+
+	// following again, either way, returns the same follow instead of another one
+	again, err := bob.CreateFeed(ctx, "Example again", feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.FeedFollow.ID != existingFeed.FeedFollow.ID {
+		t.Errorf("creating the feed again made a new follow %s, want %s", again.FeedFollow.ID, existingFeed.FeedFollow.ID)
+	}
+	followAgain, err := bob.FollowFeed(ctx, newFeed.Feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if followAgain.ID != existingFeed.FeedFollow.ID {
+		t.Errorf("following again made a new follow %s, want %s", followAgain.ID, existingFeed.FeedFollow.ID)
+	}
+
+	follows, err := bob.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].ID != existingFeed.FeedFollow.ID {
+		t.Errorf("unexpected follows %+v", follows)
+	}
+
+	if err := bob.UnfollowFeed(ctx, existingFeed.FeedFollow.ID); err != nil {
+		t.Fatal(err)
+	}
+	follows, err = bob.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 0 {
+		t.Errorf("expected no follows after unfollowing, got %+v", follows)
+	}
+
+	// nothing has been fetched, the posts page is empty but not an error
+	posts, err := alice.PostsPager(&client.ListPostsOptions{Limit: 10}).All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 0 {
+		t.Errorf("expected no posts, got %d", len(posts))
+	}
+
+	// requests with a bad api key are typed errors
+	_, err = client.New(server.URL, client.WithAPIKey("not a key")).GetUser(ctx)
+	var apiErr *client.Error
+	if err == nil || !errors.As(err, &apiErr) {
+		t.Errorf("expected a *client.Error, got %v", err)
+	}
+}
@@ -0,0 +1,683 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/digest"
+	"blog_aggregator/internal/mailer"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	digestDaily  = "daily"
+	digestWeekly = "weekly"
+
+	// posts in one digest at most, the rest wait for the next one
+	digestMaxPosts = 200
+	// subscriptions the worker sends at a time
+	digestBatch = 20
+	// how long to wait before trying again when sending fails
+	digestRetryDelay = 15 * time.Minute
+	// a digest is marked failed after this many attempts, and its posts can be in the next one
+	digestMaxAttempts = 8
+
+	digestStatusPending = "pending"
+	digestStatusSent    = "sent"
+	digestStatusFailed  = "failed"
+
+	defaultDigestHistoryLimit = 20
+	maxDigestHistoryLimit     = 100
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+type digestSubscriptionResponse struct {
+	Email     string `json:"email"`
+	Frequency string `json:"frequency"`
+	// HH:MM in Timezone
+	Time     string `json:"time"`
+	Timezone string `json:"timezone"`
+	// only used by weekly digests
+	Weekday        string     `json:"weekday"`
+	NextSendAt     time.Time  `json:"next_send_at"`
+	LastSentAt     *time.Time `json:"last_sent_at"`
+	UnsubscribeURL string     `json:"unsubscribe_url"`
+	// false until the link in the confirmation email is opened, no digests are sent before
+	Confirmed bool      `json:"confirmed"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newDigestSubscriptionResponse(sub database.DigestSubscription) digestSubscriptionResponse {
+	resp := digestSubscriptionResponse{
+		Email:          sub.Email,
+		Frequency:      sub.Frequency,
+		Time:           sub.SendTime,
+		Timezone:       sub.Timezone,
+		Weekday:        strings.ToLower(time.Weekday(sub.Weekday).String()),
+		NextSendAt:     sub.NextSendAt.UTC(),
+		UnsubscribeURL: unsubscribeURL(sub),
+		Confirmed:      sub.ConfirmedAt.Valid,
+		CreatedAt:      sub.CreatedAt.UTC(),
+		UpdatedAt:      sub.UpdatedAt.UTC(),
+	}
+	if sub.LastSentAt.Valid {
+		lastSentAt := sub.LastSentAt.Time.UTC()
+		resp.LastSentAt = &lastSentAt
+	}
+	return resp
+}
+
+type digestResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Frequency string    `json:"frequency"`
+	Subject   string    `json:"subject"`
+	// pending, sending, sent or failed, error is about the last attempt
+	Status    string      `json:"status"`
+	Attempts  int         `json:"attempts"`
+	Error     string      `json:"error"`
+	CreatedAt time.Time   `json:"created_at"`
+	SentAt    *time.Time  `json:"sent_at"`
+	PostIDs   []uuid.UUID `json:"post_ids"`
+}
+
+// the link in every digest that unsubscribes without an api key
+func unsubscribeURL(sub database.DigestSubscription) string {
+	return sub.BaseUrl + "/v1/digest/unsubscribe/" + sub.UnsubscribeToken
+}
+
+// the link in the confirmation email, the address gets no digests until it's opened
+func confirmURL(sub database.DigestSubscription) string {
+	return sub.BaseUrl + "/v1/digest/confirm/" + sub.ConfirmToken.String
+}
+
+// emails the confirmation link to the address of a subscription that isn't confirmed yet
+func (apiCfg apiConfig) sendDigestConfirmation(sub database.DigestSubscription) error {
+	link := confirmURL(sub)
+	return apiCfg.Mailer.Send(mailer.Message{
+		To:      sub.Email,
+		Subject: "Confirm your digest subscription",
+		Text: fmt.Sprintf("Open this link to get %s digests of your unread posts at this address:\n\n%s\n\n"+
+			"If you didn't ask for them, ignore this email, nothing is sent until the link is opened.\n", sub.Frequency, link),
+		HTML: fmt.Sprintf("<p>Open <a href=\"%s\">this link</a> to get %s digests of your unread posts at this address.</p>"+
+			"<p>If you didn't ask for them, ignore this email, nothing is sent until the link is opened.</p>",
+			template.HTMLEscapeString(link), sub.Frequency),
+	})
+}
+
+// parses a HH:MM time of day
+func parseSendTime(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, errors.New("time must be HH:MM")
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// the first time after now that a digest is due, at sendTime in loc, on weekday for weekly digests
+func nextDigestAt(now time.Time, frequency, sendTime string, loc *time.Location, weekday time.Weekday) time.Time {
+	hour, minute, _ := parseSendTime(sendTime)
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if frequency == digestWeekly {
+		next = time.Date(next.Year(), next.Month(), next.Day()+(int(weekday)-int(next.Weekday())+7)%7, hour, minute, 0, 0, loc)
+	}
+	for !next.After(now) {
+		days := 1
+		if frequency == digestWeekly {
+			days = 7
+		}
+		// adding days to the date rather than hours keeps the local time across daylight saving changes
+		next = time.Date(next.Year(), next.Month(), next.Day()+days, hour, minute, 0, 0, loc)
+	}
+	return next
+}
+
+// how long before subscribing the posts in digests go back
+func digestPeriod(frequency string) time.Duration {
+	if frequency == digestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// groups the posts by folder and then by feed, keeping their order
+func groupDigestPosts(rows []database.GetDigestPostsRow) []digest.Folder {
+	folders := []digest.Folder{}
+	for _, row := range rows {
+		if len(folders) == 0 || folders[len(folders)-1].Name != row.FolderName {
+			folders = append(folders, digest.Folder{Name: row.FolderName})
+		}
+		folder := &folders[len(folders)-1]
+		if len(folder.Feeds) == 0 || folder.Feeds[len(folder.Feeds)-1].Title != row.FeedTitle {
+			folder.Feeds = append(folder.Feeds, digest.Feed{Title: row.FeedTitle})
+		}
+		feed := &folder.Feeds[len(folder.Feeds)-1]
+		feed.Posts = append(feed.Posts, digest.Post{
+			Title:       row.Post.Title,
+			URL:         row.Post.Url,
+			Author:      row.Post.Author,
+			PublishedAt: row.Post.PublishedAt,
+		})
+	}
+	return folders
+}
+
+// sends the subscription's digest of the unread posts that weren't in one yet, nothing if there are none
+// posts past digestMaxPosts wait for the next digest
+// the digest and its posts are recorded before it's emailed, if sending fails it's sent again from the record
+func (apiCfg apiConfig) sendDigest(ctx context.Context, sub database.DigestSubscription) error {
+	rows, err := apiCfg.DB.GetDigestPosts(ctx, database.GetDigestPostsParams{
+		UserID: sub.UserID,
+		Since:  sub.CreatedAt.Add(-digestPeriod(sub.Frequency)),
+		Limit:  digestMaxPosts,
+	})
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	user, err := apiCfg.DB.GetUserByID(ctx, sub.UserID)
+	if err != nil {
+		return err
+	}
+
+	d := digest.Digest{
+		UserName:       user.Name,
+		Frequency:      sub.Frequency,
+		Folders:        groupDigestPosts(rows),
+		UnsubscribeURL: unsubscribeURL(sub),
+	}
+	text, html, err := digest.Render(d)
+	if err != nil {
+		return err
+	}
+	postIDs := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		postIDs = append(postIDs, row.Post.ID)
+	}
+
+	created := database.Digest{}
+	err = apiCfg.inTx(ctx, func(q *database.Queries) error {
+		var err error
+		created, err = q.CreateDigest(ctx, database.CreateDigestParams{
+			ID:        uuid.New(),
+			UserID:    sub.UserID,
+			Email:     sub.Email,
+			Frequency: sub.Frequency,
+			Subject:   d.Subject(),
+			TextBody:  text,
+			HtmlBody:  html,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		return q.AddDigestPosts(ctx, database.AddDigestPostsParams{
+			DigestID: created.ID,
+			UserID:   sub.UserID,
+			PostIds:  postIDs,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	_, err = apiCfg.sendDigestEmail(ctx, created)
+	return err
+}
+
+// emails a recorded digest that's sending and records the outcome
+// failed attempts are retried after digestRetryDelay, the digest isn't sent if the user unsubscribed since
+func (apiCfg apiConfig) sendDigestEmail(ctx context.Context, d database.Digest) (database.Digest, error) {
+	params := database.FinishDigestAttemptParams{
+		ID:     d.ID,
+		Status: digestStatusSent,
+	}
+	sub, err := apiCfg.DB.GetDigestSubscription(ctx, d.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		params.Status = digestStatusFailed
+		params.Error = "unsubscribed before the digest was sent"
+		return apiCfg.DB.FinishDigestAttempt(ctx, params)
+	}
+	if err != nil {
+		return database.Digest{}, err
+	}
+
+	err = apiCfg.Mailer.Send(mailer.Message{
+		To:      d.Email,
+		Subject: d.Subject,
+		Text:    d.TextBody,
+		HTML:    d.HtmlBody,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeURL(sub) + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+	currTime := time.Now()
+	switch {
+	case err == nil:
+		params.SentAt = sql.NullTime{Time: currTime, Valid: true}
+	case int(d.Attempts)+1 >= digestMaxAttempts:
+		params.Status = digestStatusFailed
+		params.Error = err.Error()
+	default:
+		params.Status = digestStatusPending
+		params.Error = err.Error()
+		params.NextAttemptAt = sql.NullTime{Time: currTime.Add(digestRetryDelay), Valid: true}
+	}
+
+	finished := database.Digest{}
+	err = apiCfg.inTx(ctx, func(q *database.Queries) error {
+		var err error
+		finished, err = q.FinishDigestAttempt(ctx, params)
+		if err != nil {
+			return err
+		}
+		switch params.Status {
+		case digestStatusSent:
+			return q.SetDigestLastSentAt(ctx, database.SetDigestLastSentAtParams{
+				UserID:     d.UserID,
+				LastSentAt: params.SentAt,
+			})
+		case digestStatusFailed:
+			return q.DeleteDigestPostsOfDigest(ctx, d.ID)
+		}
+		return nil
+	})
+	return finished, err
+}
+
+// sends the recorded digests that are due to be sent again, returns how many were attempted
+func (apiCfg apiConfig) resendDueDigests(ctx context.Context) (int, error) {
+	digests, err := apiCfg.DB.ClaimDueDigests(ctx, database.ClaimDueDigestsParams{
+		Now:   time.Now(),
+		Limit: digestBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range digests {
+		if _, err := apiCfg.sendDigestEmail(ctx, d); err != nil {
+			log.Printf("digestWorker: sending digest %s: %v\n", d.ID, err)
+		}
+	}
+	return len(digests), nil
+}
+
+// sends the digests that are due, returns how many subscriptions were due
+func (apiCfg apiConfig) sendDueDigests(ctx context.Context) (int, error) {
+	now := time.Now()
+	subs, err := apiCfg.DB.GetDueDigestSubscriptions(ctx, database.GetDueDigestSubscriptionsParams{
+		NextSendAt: now,
+		Limit:      digestBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sub := range subs {
+		loc, err := time.LoadLocation(sub.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		next := nextDigestAt(now, sub.Frequency, sub.SendTime, loc, time.Weekday(sub.Weekday))
+		claimed, err := apiCfg.DB.ClaimDigestSubscription(ctx, database.ClaimDigestSubscriptionParams{
+			NextSendAt: next.Local(),
+			UserID:     sub.UserID,
+			DueAt:      sub.NextSendAt,
+		})
+		if err != nil {
+			log.Println("digestWorker: ", err)
+			continue
+		}
+		if claimed == 0 {
+			continue
+		}
+
+		if err := apiCfg.sendDigest(ctx, sub); err != nil {
+			log.Printf("digestWorker: sending the digest of user %s: %v\n", sub.UserID, err)
+			err := apiCfg.DB.SetDigestNextSendAt(ctx, database.SetDigestNextSendAtParams{
+				UserID:     sub.UserID,
+				NextSendAt: now.Add(digestRetryDelay),
+			})
+			if err != nil {
+				log.Println("digestWorker: ", err)
+			}
+		}
+	}
+	return len(subs), nil
+}
+
+// worker that sends the email digests as they come due, and the ones to send again
+// digests that were being sent when the server stopped are sent again
+func (apiCfg apiConfig) digestWorker(delay int) {
+	requeued, err := apiCfg.DB.RequeueSendingDigests(context.Background(), sql.NullTime{Time: time.Now(), Valid: true})
+	if err != nil {
+		log.Println("digestWorker: ", err)
+	} else if requeued > 0 {
+		log.Printf("digestWorker: resending %d interrupted digests\n", requeued)
+	}
+
+	go func(delay int) {
+		for {
+			resent, err := apiCfg.resendDueDigests(context.Background())
+			if err != nil {
+				log.Println("digestWorker: ", err)
+			}
+			due, err := apiCfg.sendDueDigests(context.Background())
+			if err != nil {
+				log.Println("digestWorker: ", err)
+			}
+			if due < digestBatch && resent < digestBatch {
+				// caught up, wait before checking again
+				time.Sleep(time.Duration(delay) * time.Second)
+			}
+		}
+	}(delay)
+}
+
+// PUT /v1/digest
+// authed
+// expects an email, and optionally frequency (daily or weekly), time (HH:MM), timezone and weekday
+// opts into email digests of unread posts, or changes the schedule, defaults are daily at 08:00 UTC on mondays
+// a new address is sent a confirmation link and gets no digests until it's opened, 502 if that email can't be sent
+// 503 if the server has no SMTP server set up
+func (apiCfg apiConfig) putDigestHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	if apiCfg.Mailer == nil {
+		respondWithError(w, http.StatusServiceUnavailable, errors.New("email isn't set up on this server"))
+		return
+	}
+	type parameters struct {
+		Email     string `json:"email"`
+		Frequency string `json:"frequency"`
+		Time      string `json:"time"`
+		Timezone  string `json:"timezone"`
+		Weekday   string `json:"weekday"`
+	}
+	params := parameters{Frequency: digestDaily, Time: "08:00", Timezone: "UTC", Weekday: "monday"}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	addr, err := mail.ParseAddress(params.Email)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("email must be a valid email address"))
+		return
+	}
+	if params.Frequency != digestDaily && params.Frequency != digestWeekly {
+		respondWithError(w, http.StatusBadRequest, errors.New("frequency must be daily or weekly"))
+		return
+	}
+	if _, _, err := parseSendTime(params.Time); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	loc, err := time.LoadLocation(params.Timezone)
+	if err != nil || params.Timezone == "" || params.Timezone == "Local" {
+		respondWithError(w, http.StatusBadRequest, errors.New("timezone must be an IANA time zone like Europe/Berlin"))
+		return
+	}
+	weekday, ok := weekdays[strings.ToLower(params.Weekday)]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, errors.New("weekday must be a day of the week like monday"))
+		return
+	}
+
+	token := make([]byte, 24)
+	if _, err := rand.Read(token); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	confirmToken := make([]byte, 24)
+	if _, err := rand.Read(confirmToken); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	now := time.Now()
+	sub, err := apiCfg.DB.UpsertDigestSubscription(context.Background(), database.UpsertDigestSubscriptionParams{
+		UserID:           user.ID,
+		Email:            addr.Address,
+		Frequency:        params.Frequency,
+		SendTime:         params.Time,
+		Timezone:         params.Timezone,
+		Weekday:          int32(weekday),
+		UnsubscribeToken: hex.EncodeToString(token),
+		ConfirmToken:     sql.NullString{String: hex.EncodeToString(confirmToken), Valid: true},
+		BaseUrl:          requestOrigin(r),
+		NextSendAt:       nextDigestAt(now, params.Frequency, params.Time, loc, weekday).Local(),
+		CreatedAt:        now,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !sub.ConfirmedAt.Valid {
+		if err := apiCfg.sendDigestConfirmation(sub); err != nil {
+			respondWithError(w, http.StatusBadGateway, fmt.Errorf("couldn't send the confirmation email: %w", err))
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, newDigestSubscriptionResponse(sub))
+}
+
+// GET /v1/digest
+// authed
+// get the user's digest schedule, 404 if they didn't opt in
+func (apiCfg apiConfig) getDigestHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	sub, err := apiCfg.DB.GetDigestSubscription(context.Background(), user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("not subscribed to digests"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newDigestSubscriptionResponse(sub))
+}
+
+// DELETE /v1/digest
+// authed
+// stop sending the user digests, the history of sent digests is kept
+func (apiCfg apiConfig) deleteDigestHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	deleted, err := apiCfg.DB.DeleteDigestSubscription(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("not subscribed to digests"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v1/digest/history
+// authed
+// the digests recorded for the user and the posts in each, newest first, including the ones not sent yet or that failed
+// optional query param: limit (default 20, at most 100)
+func (apiCfg apiConfig) getDigestHistoryHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit := defaultDigestHistoryLimit
+	if tmp := r.URL.Query().Get("limit"); tmp != "" {
+		parsed, err := strconv.Atoi(tmp)
+		if err != nil || parsed < 1 || parsed > maxDigestHistoryLimit {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("limit must be an integer between 1 and %d", maxDigestHistoryLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	rows, err := apiCfg.DB.GetDigests(context.Background(), database.GetDigestsParams{
+		UserID: user.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	digests := make([]digestResponse, 0, len(rows))
+	for _, row := range rows {
+		postIDs := row.PostIds
+		if postIDs == nil {
+			postIDs = []uuid.UUID{}
+		}
+		resp := digestResponse{
+			ID:        row.Digest.ID,
+			Email:     row.Digest.Email,
+			Frequency: row.Digest.Frequency,
+			Subject:   row.Digest.Subject,
+			Status:    row.Digest.Status,
+			Attempts:  int(row.Digest.Attempts),
+			Error:     row.Digest.Error,
+			CreatedAt: row.Digest.CreatedAt.UTC(),
+			PostIDs:   postIDs,
+		}
+		if row.Digest.SentAt.Valid {
+			sentAt := row.Digest.SentAt.Time.UTC()
+			resp.SentAt = &sentAt
+		}
+		digests = append(digests, resp)
+	}
+	respondWithJSON(w, http.StatusOK, digests)
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 40px auto;">
+{{if .Done}}<p>{{.Email}} won't get any more digests.</p>
+{{else if .Email}}<p>Stop sending digests to {{.Email}}?</p>
+<form method="post"><button type="submit">Unsubscribe</button></form>
+{{else}}<p>This unsubscribe link doesn't work anymore, you may already be unsubscribed.</p>
+{{end}}</body>
+</html>
+`))
+
+func respondWithUnsubscribePage(w http.ResponseWriter, code int, email string, done bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	unsubscribePage.Execute(w, struct {
+		Email string
+		Done  bool
+	}{Email: email, Done: done})
+}
+
+// GET /v1/digest/unsubscribe/{token}
+// public, the page the unsubscribe link of a digest opens, asks before unsubscribing
+func (apiCfg apiConfig) getUnsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	sub, err := apiCfg.DB.GetDigestSubscriptionByToken(context.Background(), chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithUnsubscribePage(w, http.StatusNotFound, "", false)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithUnsubscribePage(w, http.StatusOK, sub.Email, false)
+}
+
+// POST /v1/digest/unsubscribe/{token}
+// public, unsubscribes from digests, from the page or a mail client's one-click unsubscribe
+func (apiCfg apiConfig) unsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	sub, err := apiCfg.DB.GetDigestSubscriptionByToken(ctx, chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithUnsubscribePage(w, http.StatusNotFound, "", false)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if _, err := apiCfg.DB.DeleteDigestSubscriptionByToken(ctx, sub.UnsubscribeToken); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithUnsubscribePage(w, http.StatusOK, sub.Email, true)
+}
+
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Confirm digests</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 40px auto;">
+{{if .Done}}<p>{{.Email}} will get the digests.</p>
+{{else if .Email}}<p>Send digests to {{.Email}}?</p>
+<form method="post"><button type="submit">Confirm</button></form>
+{{else}}<p>This confirmation link doesn't work anymore, it may be confirmed already or a newer one was sent.</p>
+{{end}}</body>
+</html>
+`))
+
+func respondWithConfirmPage(w http.ResponseWriter, code int, email string, done bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	confirmPage.Execute(w, struct {
+		Email string
+		Done  bool
+	}{Email: email, Done: done})
+}
+
+// GET /v1/digest/confirm/{token}
+// public, the page the link of the confirmation email opens, asks before confirming so link scanners don't confirm
+func (apiCfg apiConfig) getConfirmDigestHandler(w http.ResponseWriter, r *http.Request) {
+	sub, err := apiCfg.DB.GetDigestSubscriptionByConfirmToken(context.Background(), sql.NullString{String: chi.URLParam(r, "token"), Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithConfirmPage(w, http.StatusNotFound, "", false)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithConfirmPage(w, http.StatusOK, sub.Email, false)
+}
+
+// POST /v1/digest/confirm/{token}
+// public, confirms the address of a digest subscription so digests are sent to it
+func (apiCfg apiConfig) confirmDigestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	sub, err := apiCfg.DB.GetDigestSubscriptionByConfirmToken(ctx, sql.NullString{String: chi.URLParam(r, "token"), Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithConfirmPage(w, http.StatusNotFound, "", false)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = apiCfg.DB.ConfirmDigestSubscription(ctx, database.ConfirmDigestSubscriptionParams{
+		UserID:      sub.UserID,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithConfirmPage(w, http.StatusOK, sub.Email, true)
+}
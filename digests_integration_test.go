@@ -0,0 +1,392 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/mailer"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// keeps the messages it's asked to send, or fails with err
+type testMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+	err  error
+	// called with each message before it's sent
+	onSend func(msg mailer.Message)
+}
+
+func (m *testMailer) Send(msg mailer.Message) error {
+	if m.onSend != nil {
+		m.onSend(msg)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// the messages sent to the address, other tests may have due digests too
+func (m *testMailer) sentTo(to string) []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	msgs := []mailer.Message{}
+	for _, msg := range m.sent {
+		if msg.To == to {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+var confirmLinkPattern = regexp.MustCompile(`https?://\S+/v1/digest/confirm/[0-9a-f]+`)
+
+// the confirmation link last sent to the address, the confirmation emails are taken out so sentTo only has digests
+func (m *testMailer) takeConfirmLink(t *testing.T, to string) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link := ""
+	kept := []mailer.Message{}
+	for _, msg := range m.sent {
+		if found := confirmLinkPattern.FindString(msg.Text); msg.To == to && found != "" {
+			link = found
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	m.sent = kept
+	if link == "" {
+		t.Fatalf("no confirmation email sent to %s", to)
+	}
+	return link
+}
+
+// opens the confirmation link sent to the address
+func (m *testMailer) confirm(t *testing.T, to string) {
+	t.Helper()
+	resp, err := http.Post(m.takeConfirmLink(t, to), "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("confirming responded %d", resp.StatusCode)
+	}
+}
+
+func TestDigests(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	testMailer := &testMailer{}
+	apiCfg.Mailer = testMailer
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	user, err := alice.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := "alice+" + uuid.NewString() + "@example.com"
+
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(titles ...string) {
+		items := []*gofeed.Item{}
+		for _, title := range titles {
+			items = append(items, &gofeed.Item{Title: title, Link: base + "/" + title})
+		}
+		apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: items}}}
+		apiCfg.CreatePostsFromFetchedFeeds()
+	}
+	// makes the digest due and runs the worker once
+	send := func() {
+		err := apiCfg.DB.SetDigestNextSendAt(ctx, database.SetDigestNextSendAtParams{
+			UserID:     user.ID,
+			NextSendAt: time.Now().Add(-time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := apiCfg.sendDueDigests(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sub, err := alice.SubscribeDigest(ctx, client.DigestSettings{Email: email, Frequency: client.DigestWeekly, Time: "07:30", Timezone: "Europe/Berlin", Weekday: "friday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Weekday != "friday" || sub.NextSendAt.Before(time.Now()) || !strings.HasPrefix(sub.UnsubscribeURL, server.URL) || sub.Confirmed {
+		t.Errorf("got subscription %+v", sub)
+	}
+	testMailer.confirm(t, email)
+
+	// the read post isn't in the digest
+	fetch("first", "second", "read")
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: created.Feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range page.Items {
+		if post.Title == "read" {
+			if err := alice.MarkPostRead(ctx, post.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	send()
+	msgs := testMailer.sentTo(email)
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 digest, got %d", len(msgs))
+	}
+	if msgs[0].Subject != "Your weekly digest: 2 unread posts" || !strings.Contains(msgs[0].Text, "first") || strings.Contains(msgs[0].HTML, base+"/read") {
+		t.Errorf("got digest %q:\n%s", msgs[0].Subject, msgs[0].Text)
+	}
+	if msgs[0].Headers["List-Unsubscribe"] != "<"+sub.UnsubscribeURL+">" {
+		t.Errorf("got List-Unsubscribe %q", msgs[0].Headers["List-Unsubscribe"])
+	}
+
+	// nothing new, no email
+	send()
+	if len(testMailer.sentTo(email)) != 1 {
+		t.Error("expected no digest without new posts")
+	}
+	// only the new post is sent
+	fetch("third")
+	send()
+	msgs = testMailer.sentTo(email)
+	if len(msgs) != 2 || msgs[1].Subject != "Your weekly digest: 1 unread post" || strings.Contains(msgs[1].Text, "second") {
+		t.Fatalf("expected a digest of the new post, got %d digests", len(msgs))
+	}
+
+	history, err := alice.ListDigests(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || len(history[0].PostIDs) != 1 || len(history[1].PostIDs) != 2 || history[0].Email != email {
+		t.Errorf("got history %+v", history)
+	}
+
+	// the unsubscribe link works without an api key
+	resp, err := http.Post(sub.UnsubscribeURL, "application/x-www-form-urlencoded", strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unsubscribing responded %d", resp.StatusCode)
+	}
+	if _, err := alice.GetDigestSubscription(ctx); !client.IsNotFound(err) {
+		t.Errorf("expected 404 after unsubscribing, got %v", err)
+	}
+}
+
+func TestDigestsAreRecordedBeforeSending(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	testMailer := &testMailer{}
+	apiCfg.Mailer = testMailer
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	user, err := alice.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := "alice+" + uuid.NewString() + "@example.com"
+	if _, err := alice.SubscribeDigest(ctx, client.DigestSettings{Email: email}); err != nil {
+		t.Fatal(err)
+	}
+	testMailer.confirm(t, email)
+	testMailer.mu.Lock()
+	testMailer.err = errors.New("smtp server is down")
+	testMailer.mu.Unlock()
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "first", Link: base + "/first"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	// the digest and its posts are committed by the time it's emailed
+	recorded := 0
+	testMailer.onSend = func(msg mailer.Message) {
+		err := apiCfg.Conn.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM digests JOIN digest_posts ON digest_posts.digest_id = digests.id
+			WHERE digests.user_id = $1 AND digests.status = 'sending'`, user.ID).Scan(&recorded)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	err = apiCfg.DB.SetDigestNextSendAt(ctx, database.SetDigestNextSendAtParams{
+		UserID:     user.ID,
+		NextSendAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.sendDueDigests(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != 1 {
+		t.Errorf("expected the digest and its post to be recorded before sending, got %d rows", recorded)
+	}
+
+	// sending failed, the digest is sent again later
+	history, err := alice.ListDigests(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != client.DigestPending || history[0].Attempts != 1 || history[0].Error != "smtp server is down" || history[0].SentAt != nil || len(history[0].PostIDs) != 1 {
+		t.Fatalf("expected a pending digest, got %+v", history)
+	}
+	sub, err := alice.GetDigestSubscription(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.LastSentAt != nil {
+		t.Errorf("expected no last_sent_at before the digest is sent, got %v", sub.LastSentAt)
+	}
+	if resent, err := apiCfg.resendDueDigests(ctx); err != nil || resent != 0 {
+		t.Errorf("expected nothing to resend before the retry delay, got %d, %v", resent, err)
+	}
+
+	testMailer.mu.Lock()
+	testMailer.err = nil
+	testMailer.mu.Unlock()
+	if _, err := apiCfg.Conn.ExecContext(ctx, "UPDATE digests SET next_attempt_at = $2 WHERE id = $1", history[0].ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.resendDueDigests(ctx); err != nil {
+		t.Fatal(err)
+	}
+	msgs := testMailer.sentTo(email)
+	if len(msgs) != 1 || msgs[0].Subject != history[0].Subject || !strings.Contains(msgs[0].Text, "first") {
+		t.Fatalf("expected the recorded digest to be sent, got %+v", msgs)
+	}
+	history, err = alice.ListDigests(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Status != client.DigestSent || history[0].Attempts != 2 || history[0].SentAt == nil || history[0].Error != "" {
+		t.Errorf("expected the digest to be sent, got %+v", history)
+	}
+	sub, err = alice.GetDigestSubscription(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.LastSentAt == nil {
+		t.Error("expected last_sent_at once the digest is sent")
+	}
+}
+
+// digests only go to an address once the link emailed to it is opened, so nobody can send them to someone else
+func TestDigestsNeedAConfirmedAddress(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	testMailer := &testMailer{}
+	apiCfg.Mailer = testMailer
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	user, err := alice.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestFeedWithPosts(t, apiCfg, alice, "first")
+	send := func() {
+		t.Helper()
+		err := apiCfg.DB.SetDigestNextSendAt(ctx, database.SetDigestNextSendAtParams{
+			UserID:     user.ID,
+			NextSendAt: time.Now().Add(-time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := apiCfg.sendDueDigests(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	victim := "victim+" + uuid.NewString() + "@example.com"
+	sub, err := alice.SubscribeDigest(ctx, client.DigestSettings{Email: victim})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Confirmed {
+		t.Errorf("expected an unconfirmed subscription, got %+v", sub)
+	}
+	link := testMailer.takeConfirmLink(t, victim)
+	send()
+	if msgs := testMailer.sentTo(victim); len(msgs) != 0 {
+		t.Errorf("expected no digest before confirming, got %+v", msgs)
+	}
+	// the page of the link only asks, so link scanners don't confirm
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("the confirmation page responded %d", resp.StatusCode)
+	}
+	if sub, err := alice.GetDigestSubscription(ctx); err != nil || sub.Confirmed {
+		t.Errorf("expected the page not to confirm, got %+v, %v", sub, err)
+	}
+
+	// changing the address sends a new link, the old one stops working
+	email := "alice+" + uuid.NewString() + "@example.com"
+	if _, err := alice.SubscribeDigest(ctx, client.DigestSettings{Email: email}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Post(link, "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("the old confirmation link responded %d, want 404", resp.StatusCode)
+	}
+	testMailer.confirm(t, email)
+	sub, err = alice.GetDigestSubscription(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sub.Confirmed || sub.Email != email {
+		t.Errorf("expected a confirmed subscription, got %+v", sub)
+	}
+	send()
+	if msgs := testMailer.sentTo(email); len(msgs) != 1 {
+		t.Errorf("expected a digest once confirmed, got %d emails", len(msgs))
+	}
+
+	// changing only the schedule keeps the address confirmed
+	sub, err = alice.SubscribeDigest(ctx, client.DigestSettings{Email: email, Frequency: client.DigestWeekly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sub.Confirmed || len(testMailer.sentTo(email)) != 1 {
+		t.Errorf("expected the address to stay confirmed without a new email, got %+v", sub)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"testing"
+	"time"
+)
+
+func TestNextDigestAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	// a thursday
+	now := time.Date(2023, 3, 23, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		frequency, sendTime string
+		loc                 *time.Location
+		weekday             time.Weekday
+		want                time.Time
+	}{
+		// later today
+		{digestDaily, "18:30", time.UTC, time.Monday, time.Date(2023, 3, 23, 18, 30, 0, 0, time.UTC)},
+		// already past today
+		{digestDaily, "08:00", time.UTC, time.Monday, time.Date(2023, 3, 24, 8, 0, 0, 0, time.UTC)},
+		// 10:00 utc is 11:00 in berlin
+		{digestDaily, "11:00", berlin, time.Monday, time.Date(2023, 3, 24, 11, 0, 0, 0, berlin)},
+		{digestDaily, "11:01", berlin, time.Monday, time.Date(2023, 3, 23, 11, 1, 0, 0, berlin)},
+		// berlin moves to summer time on march 26th, the digest stays at 08:00 local
+		{digestWeekly, "08:00", berlin, time.Monday, time.Date(2023, 3, 27, 8, 0, 0, 0, berlin)},
+		{digestWeekly, "12:00", time.UTC, time.Thursday, time.Date(2023, 3, 23, 12, 0, 0, 0, time.UTC)},
+		{digestWeekly, "09:00", time.UTC, time.Thursday, time.Date(2023, 3, 30, 9, 0, 0, 0, time.UTC)},
+		{digestWeekly, "09:00", time.UTC, time.Sunday, time.Date(2023, 3, 26, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got := nextDigestAt(now, test.frequency, test.sendTime, test.loc, test.weekday)
+		if !got.Equal(test.want) {
+			t.Errorf("nextDigestAt(%s at %s in %s on %s) = %v, want %v", test.frequency, test.sendTime, test.loc, test.weekday, got, test.want)
+		}
+	}
+	if got := nextDigestAt(now, digestWeekly, "08:00", berlin, time.Monday); got.Sub(now) != 4*24*time.Hour-4*time.Hour {
+		t.Errorf("expected the summer time offset in %v", got)
+	}
+}
+
+func TestGroupDigestPosts(t *testing.T) {
+	rows := []database.GetDigestPostsRow{
+		{Post: database.Post{Title: "a"}, FolderName: "Tech", FeedTitle: "Go blog"},
+		{Post: database.Post{Title: "b"}, FolderName: "Tech", FeedTitle: "Go blog"},
+		{Post: database.Post{Title: "c"}, FolderName: "Tech", FeedTitle: "Rust blog"},
+		{Post: database.Post{Title: "d"}, FolderName: "", FeedTitle: "Go blog"},
+	}
+	folders := groupDigestPosts(rows)
+	if len(folders) != 2 || folders[0].Name != "Tech" || folders[1].Name != "" {
+		t.Fatalf("got folders %+v", folders)
+	}
+	if len(folders[0].Feeds) != 2 || len(folders[0].Feeds[0].Posts) != 2 || folders[0].Feeds[1].Posts[0].Title != "c" {
+		t.Errorf("got feeds %+v", folders[0].Feeds)
+	}
+	if len(folders[1].Feeds) != 1 || folders[1].Feeds[0].Posts[0].Title != "d" {
+		t.Errorf("got feeds %+v", folders[1].Feeds)
+	}
+}
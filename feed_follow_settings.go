@@ -0,0 +1,98 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	notifyAll  = "all"
+	notifyNone = "none"
+
+	maxFeedFollowTitleLength = 200
+)
+
+// PATCH /v1/feed_follows/{feedFollowID}
+// authed
+// edits the user's settings for a followed feed, fields left out are unchanged
+// expects any of {"title": "...", "pinned": true, "notify": "all" or "none", "hide_from_timeline": true}
+// an empty title goes back to showing the feed's name
+// 404 if the feed follow isn't the user's
+func (apiCfg apiConfig) updateFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Title            *string `json:"title"`
+		Pinned           *bool   `json:"pinned"`
+		Notify           *string `json:"notify"`
+		HideFromTimeline *bool   `json:"hide_from_timeline"`
+	}
+
+	feedFollowID, err := uuidFromURL(r, "feedFollowID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	feedFollow, err := apiCfg.DB.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		ID:     feedFollowID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("feed follow not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updateParams := database.UpdateFeedFollowSettingsParams{
+		ID:               feedFollow.ID,
+		UserID:           user.ID,
+		Title:            feedFollow.Title,
+		Pinned:           feedFollow.Pinned,
+		Notify:           feedFollow.Notify,
+		HideFromTimeline: feedFollow.HideFromTimeline,
+		UpdatedAt:        time.Now(),
+	}
+	if params.Title != nil {
+		title := strings.TrimSpace(*params.Title)
+		if len(title) > maxFeedFollowTitleLength {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("title can be at most %d characters", maxFeedFollowTitleLength))
+			return
+		}
+		updateParams.Title = sql.NullString{String: title, Valid: title != ""}
+	}
+	if params.Pinned != nil {
+		updateParams.Pinned = *params.Pinned
+	}
+	if params.Notify != nil {
+		if *params.Notify != notifyAll && *params.Notify != notifyNone {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("notify must be %s or %s", notifyAll, notifyNone))
+			return
+		}
+		updateParams.Notify = *params.Notify
+	}
+	if params.HideFromTimeline != nil {
+		updateParams.HideFromTimeline = *params.HideFromTimeline
+	}
+
+	feedFollow, err = apiCfg.DB.UpdateFeedFollowSettings(context.Background(), updateParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newFeedFollowResponse(feedFollow))
+}
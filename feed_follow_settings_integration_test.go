@@ -0,0 +1,100 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFeedFollowSettings(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	first, _ := newTestFeedWithPosts(t, apiCfg, alice, "First post")
+	quiet, quietPosts := newTestFeedWithPosts(t, apiCfg, alice, "Quiet post")
+	followIDs := map[uuid.UUID]uuid.UUID{}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, follow := range follows {
+		followIDs[follow.FeedID] = follow.ID
+		if follow.Title != nil || follow.Pinned || follow.Notify != client.NotifyAll || follow.HideFromTimeline {
+			t.Errorf("got follow %+v, want the default settings", follow)
+		}
+	}
+
+	title := "  Mine  "
+	pinned, hide := true, true
+	notify := client.NotifyNone
+	updated, err := alice.UpdateFeedFollow(ctx, followIDs[quiet.ID], client.UpdateFeedFollowOptions{
+		Title:            &title,
+		Pinned:           &pinned,
+		Notify:           &notify,
+		HideFromTimeline: &hide,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title == nil || *updated.Title != "Mine" || !updated.Pinned || updated.Notify != client.NotifyNone || !updated.HideFromTimeline {
+		t.Errorf("got follow %+v", updated)
+	}
+
+	// pinned follows come first
+	follows, err = alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 2 || follows[0].FeedID != quiet.ID || follows[1].FeedID != first.ID {
+		t.Errorf("got follows %+v, want the pinned one first", follows)
+	}
+
+	// hidden feeds are left out of the timeline unless it's filtered by the feed
+	page, err := alice.ListPosts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range page.Items {
+		if post.FeedID == quiet.ID {
+			t.Errorf("got post %q of a feed hidden from the timeline", post.Title)
+		}
+	}
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: quiet.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != quietPosts[0].ID {
+		t.Errorf("got posts %+v, want the hidden feed's post", page.Items)
+	}
+
+	// fields left out are unchanged, an empty title goes back to the feed's name
+	empty := ""
+	updated, err = alice.UpdateFeedFollow(ctx, followIDs[quiet.ID], client.UpdateFeedFollowOptions{Title: &empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != nil || !updated.Pinned || updated.Notify != client.NotifyNone {
+		t.Errorf("got follow %+v", updated)
+	}
+
+	long := strings.Repeat("x", maxFeedFollowTitleLength+1)
+	bad := "sometimes"
+	for _, opts := range []client.UpdateFeedFollowOptions{{Title: &long}, {Notify: &bad}} {
+		_, err := alice.UpdateFeedFollow(ctx, followIDs[quiet.ID], opts)
+		if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("got error %v, want a 400", err)
+		}
+	}
+	if _, err := bob.UpdateFeedFollow(ctx, followIDs[quiet.ID], client.UpdateFeedFollowOptions{Pinned: &pinned}); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+}
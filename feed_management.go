@@ -0,0 +1,292 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/extract"
+	"blog_aggregator/internal/scrape"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// where the posts of a feed come from: a feed's url, a web page scraped with selectors, or items pushed through the api
+const (
+	feedKindRSS     = "rss"
+	feedKindScraped = "scraped"
+	feedKindPush    = "push"
+)
+
+// returned from inside a transaction when the feed doesn't exist or the user can't manage it
+var errFeedNotFound = errors.New("feed not found")
+
+// whether s is an absolute http or https url
+func isFeedURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// gets the feed if the user may edit or delete it, errFeedNotFound otherwise
+func getManagedFeed(ctx context.Context, q *database.Queries, user database.User, feedID uuid.UUID) (database.Feed, error) {
+	feed, err := q.GetFeed(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, errFeedNotFound
+	}
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if !userCanManageFeed(user, feed) {
+		return database.Feed{}, errFeedNotFound
+	}
+	return feed, nil
+}
+
+// moves the follows and folders of one feed onto another and deletes the first one with its posts,
+// the posts aren't moved because the target may not be the caller's and its own fetches fill it
+func mergeFeed(ctx context.Context, q *database.Queries, fromFeedID, toFeedID uuid.UUID) error {
+	err := q.MoveFeedFollowFoldersToFeed(ctx, database.MoveFeedFollowFoldersToFeedParams{
+		FromFeedID: fromFeedID,
+		ToFeedID:   toFeedID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.MoveFeedFollowsToFeed(ctx, database.MoveFeedFollowsToFeedParams{
+		FromFeedID: fromFeedID,
+		ToFeedID:   toFeedID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := q.DeletePostStarsOfFeed(ctx, fromFeedID); err != nil {
+		return err
+	}
+	if err := q.DeleteSavedPostsOfFeed(ctx, fromFeedID); err != nil {
+		return err
+	}
+	return q.DeleteFeed(ctx, fromFeedID)
+}
+
+// PATCH /v1/feeds/{feedID}
+// authed
+// expects any of {"name": "...", "url": "...", "fetch_full_content": true, "content_selector": "...", "selectors": {...}},
+// fields left out are unchanged, selectors replace all of a scraped feed's selectors and 400 for other feeds
+// with fetch_full_content the page of each new post is downloaded and its article stored as the post's full_content,
+// found with content_selector if it isn't empty
+// only the user who created the feed or an admin can edit it, 404 for anyone else
+// if another feed already has the new url this feed is merged into it: follows and folders move over, its posts are deleted
+// and the existing feed, with its name, is returned with "merged": true
+func (apiCfg apiConfig) updateFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name             *string           `json:"name"`
+		Url              *string           `json:"url"`
+		FetchFullContent *bool             `json:"fetch_full_content"`
+		ContentSelector  *string           `json:"content_selector"`
+		Selectors        *scrape.Selectors `json:"selectors"`
+	}
+	type returnVal struct {
+		Feed   feedResponse `json:"feed"`
+		Merged bool         `json:"merged"`
+	}
+
+	feedID, err := uuidFromURL(r, "feedID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	if params.Name != nil && len(strings.TrimSpace(*params.Name)) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("name cannot be empty"))
+		return
+	}
+	if params.Url != nil && !isFeedURL(*params.Url) {
+		respondWithError(w, http.StatusBadRequest, errors.New("url must be an http or https url"))
+		return
+	}
+	if params.ContentSelector != nil {
+		*params.ContentSelector = strings.TrimSpace(*params.ContentSelector)
+		if *params.ContentSelector != "" && extract.ValidSelector(*params.ContentSelector) != nil {
+			respondWithError(w, http.StatusBadRequest, errors.New("content_selector must be a css selector"))
+			return
+		}
+	}
+	if params.Selectors != nil {
+		if err := params.Selectors.Validate(); err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	retVal := returnVal{}
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		ctx := context.Background()
+		feed, err := getManagedFeed(ctx, q, user, feedID)
+		if err != nil {
+			return err
+		}
+
+		if params.Name != nil {
+			feed, err = q.UpdateFeedName(ctx, database.UpdateFeedNameParams{
+				ID:        feed.ID,
+				Name:      strings.TrimSpace(*params.Name),
+				UpdatedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if params.FetchFullContent != nil || params.ContentSelector != nil {
+			fetchFullContent, contentSelector := feed.FetchFullContent, feed.ContentSelector
+			if params.FetchFullContent != nil {
+				fetchFullContent = *params.FetchFullContent
+			}
+			if params.ContentSelector != nil {
+				contentSelector = *params.ContentSelector
+			}
+			feed, err = q.UpdateFeedFullContent(ctx, database.UpdateFeedFullContentParams{
+				ID:               feed.ID,
+				FetchFullContent: fetchFullContent,
+				ContentSelector:  contentSelector,
+				UpdatedAt:        time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if params.Selectors != nil {
+			if feed.Kind != feedKindScraped {
+				return errNotScrapedFeed
+			}
+			selectors, err := json.Marshal(params.Selectors)
+			if err != nil {
+				return err
+			}
+			feed, err = q.UpdateFeedSelectors(ctx, database.UpdateFeedSelectorsParams{
+				ID:        feed.ID,
+				Selectors: selectors,
+				UpdatedAt: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if params.Url != nil && feed.Kind == feedKindPush {
+			return errPushFeedURL
+		}
+		if params.Url != nil && *params.Url != feed.Url {
+			existing, err := q.GetFeedByURL(ctx, *params.Url)
+			if err == nil {
+				if err := mergeFeed(ctx, q, feed.ID, existing.ID); err != nil {
+					return err
+				}
+				feed = existing
+				retVal.Merged = true
+			} else if errors.Is(err, sql.ErrNoRows) {
+				feed, err = q.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+					ID:        feed.ID,
+					Url:       *params.Url,
+					UpdatedAt: time.Now(),
+				})
+				if err != nil {
+					return err
+				}
+			} else {
+				return err
+			}
+		}
+
+		retVal.Feed = newFeedResponse(feed)
+		return nil
+	})
+	if errors.Is(err, errFeedNotFound) {
+		respondWithError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, errNotScrapedFeed) || errors.Is(err, errPushFeedURL) {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, retVal)
+}
+
+// DELETE /v1/feeds/{feedID}
+// authed
+// only the user who created the feed or an admin can delete it, 404 for anyone else
+// the creator's follow is removed, and if other users still follow the feed
+// or have its posts starred or saved, the feed is handed over to one of them instead of being deleted
+// an admin that didn't create the feed deletes it on the creator's behalf, the same way
+// responds 204 either way
+func (apiCfg apiConfig) deleteFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuidFromURL(r, "feedID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		ctx := context.Background()
+		feed, err := getManagedFeed(ctx, q, user, feedID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteFeedFollowByFeed(ctx, database.DeleteFeedFollowByFeedParams{
+			UserID: feed.UserID,
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		nextOwner, err := q.GetNextFeedOwner(ctx, database.GetNextFeedOwnerParams{
+			FeedID:         feed.ID,
+			ExcludedUserID: feed.UserID,
+		})
+		if err == nil {
+			return q.SetFeedOwner(ctx, database.SetFeedOwnerParams{
+				ID:        feed.ID,
+				UserID:    nextOwner,
+				UpdatedAt: time.Now(),
+			})
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// nobody else needs the feed, the stars and saves on its posts go with it
+		if err := q.DeletePostStarsOfFeed(ctx, feed.ID); err != nil {
+			return err
+		}
+		if err := q.DeleteSavedPostsOfFeed(ctx, feed.ID); err != nil {
+			return err
+		}
+		return q.DeleteFeed(ctx, feed.ID)
+	})
+	if errors.Is(err, errFeedNotFound) {
+		respondWithError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	filterFieldTitle       = "title"
+	filterFieldDescription = "description"
+	filterFieldContent     = "content"
+	filterFieldAuthor      = "author"
+	filterFieldURL         = "url"
+	filterFieldCategory    = "category"
+
+	filterOpContains = "contains"
+	filterOpEquals   = "equals"
+	filterOpMatches  = "matches"
+
+	// newest posts of the followed feeds a rule is run on when it's saved
+	filterRuleBackfillPosts = 5000
+	// newest posts of the followed feeds a dry run looks at, and how many of the matches it returns
+	filterRuleDryRunPosts   = 500
+	filterRuleDryRunResults = 50
+)
+
+var errFilterRuleNotFound = errors.New("filter rule not found")
+
+// a test a post has to pass for a rule to apply to it
+type filterCondition struct {
+	Field string `json:"field"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// a filter rule with its conditions decoded
+type filterRule struct {
+	Name       string
+	FeedID     uuid.NullUUID
+	Conditions []filterCondition
+	Hide       bool
+	Star       bool
+	Tags       []string
+}
+
+func newFilterRule(rule database.FilterRule) (filterRule, error) {
+	conditions := []filterCondition{}
+	if err := json.Unmarshal(rule.Conditions, &conditions); err != nil {
+		return filterRule{}, err
+	}
+	return filterRule{
+		Name:       rule.Name,
+		FeedID:     rule.FeedID,
+		Conditions: conditions,
+		Hide:       rule.Hide,
+		Star:       rule.Star,
+		Tags:       rule.Tags,
+	}, nil
+}
+
+type filterRuleResponse struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// null for every followed feed
+	FeedID     *uuid.UUID        `json:"feed_id"`
+	Conditions []filterCondition `json:"conditions"`
+	Hide       bool              `json:"hide"`
+	Star       bool              `json:"star"`
+	Tags       []string          `json:"tags"`
+	MatchCount int64             `json:"match_count"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func newFilterRuleResponse(dbRule database.FilterRule, rule filterRule, matchCount int64) filterRuleResponse {
+	resp := filterRuleResponse{
+		ID:         dbRule.ID,
+		Name:       rule.Name,
+		Conditions: rule.Conditions,
+		Hide:       rule.Hide,
+		Star:       rule.Star,
+		Tags:       rule.Tags,
+		MatchCount: matchCount,
+		CreatedAt:  dbRule.CreatedAt.UTC(),
+		UpdatedAt:  dbRule.UpdatedAt.UTC(),
+	}
+	if rule.FeedID.Valid {
+		resp.FeedID = &rule.FeedID.UUID
+	}
+	return resp
+}
+
+// compiles a matches value, either a go regexp or one written like /sponsored/i
+func parseFilterPattern(value string) (*regexp.Regexp, error) {
+	if end := strings.LastIndexByte(value, '/'); strings.HasPrefix(value, "/") && end > 0 {
+		flags := value[end+1:]
+		if strings.Trim(flags, "imsU") == "" {
+			pattern := value[1:end]
+			if flags != "" {
+				pattern = "(?" + flags + ")" + pattern
+			}
+			return regexp.Compile(pattern)
+		}
+	}
+	return regexp.Compile(value)
+}
+
+// the values of a post a condition on the field looks at
+func filterFieldValues(post database.Post, field string) []string {
+	switch field {
+	case filterFieldTitle:
+		return []string{post.Title}
+	case filterFieldDescription:
+		return []string{post.Description}
+	case filterFieldContent:
+		return []string{post.Content}
+	case filterFieldAuthor:
+		return []string{post.Author}
+	case filterFieldURL:
+		return []string{post.Url}
+	case filterFieldCategory:
+		return post.Categories
+	}
+	return nil
+}
+
+// compiles the rule into a func telling whether it applies to a post
+// a post has to be in the rule's feed and pass all of its conditions, on any of the field's values for categories
+// contains and equals ignore case
+func (rule filterRule) matcher() (func(post database.Post) bool, error) {
+	tests := []func(value string) bool{}
+	for _, condition := range rule.Conditions {
+		value := condition.Value
+		switch condition.Op {
+		case filterOpContains:
+			lower := strings.ToLower(value)
+			tests = append(tests, func(s string) bool { return strings.Contains(strings.ToLower(s), lower) })
+		case filterOpEquals:
+			tests = append(tests, func(s string) bool { return strings.EqualFold(s, value) })
+		case filterOpMatches:
+			re, err := parseFilterPattern(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+			}
+			tests = append(tests, re.MatchString)
+		default:
+			return nil, fmt.Errorf("op must be %s, %s or %s", filterOpContains, filterOpEquals, filterOpMatches)
+		}
+	}
+
+	return func(post database.Post) bool {
+		if rule.FeedID.Valid && rule.FeedID.UUID != post.FeedID {
+			return false
+		}
+		for i, condition := range rule.Conditions {
+			passed := false
+			for _, value := range filterFieldValues(post, condition.Field) {
+				if tests[i](value) {
+					passed = true
+					break
+				}
+			}
+			if !passed {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// validates a rule, errors are the client's fault
+func (rule filterRule) validate() error {
+	if rule.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(rule.Conditions) == 0 && !rule.FeedID.Valid {
+		return errors.New("rule needs a condition or a feed_id")
+	}
+	for _, condition := range rule.Conditions {
+		switch condition.Field {
+		case filterFieldTitle, filterFieldDescription, filterFieldContent, filterFieldAuthor, filterFieldURL, filterFieldCategory:
+		default:
+			return fmt.Errorf("field must be one of %s, %s, %s, %s, %s or %s",
+				filterFieldTitle, filterFieldDescription, filterFieldContent, filterFieldAuthor, filterFieldURL, filterFieldCategory)
+		}
+		if condition.Value == "" {
+			return errors.New("condition value cannot be empty")
+		}
+	}
+	if !rule.Hide && !rule.Star && len(rule.Tags) == 0 {
+		return errors.New("rule needs to hide, star or tag the posts")
+	}
+	_, err := rule.matcher()
+	return err
+}
+
+// records the post as a match of every rule of the feed's followers it matches, and stars it for the rules that star
+// failing is logged and doesn't fail the fetch
+func (apiCfg apiConfig) applyFilterRules(ctx context.Context, post database.Post) {
+	rules, err := apiCfg.DB.GetFilterRulesForFeed(ctx, post.FeedID)
+	if err != nil {
+		log.Println("applyFilterRules: ", err)
+		return
+	}
+	for _, dbRule := range rules {
+		rule, err := newFilterRule(dbRule)
+		if err != nil {
+			log.Println("applyFilterRules: ", err)
+			continue
+		}
+		matches, err := rule.matcher()
+		if err != nil {
+			log.Println("applyFilterRules: ", err)
+			continue
+		}
+		if !matches(post) {
+			continue
+		}
+		err = apiCfg.DB.AddFilterRuleMatches(ctx, database.AddFilterRuleMatchesParams{
+			RuleID:    dbRule.ID,
+			PostIds:   []uuid.UUID{post.ID},
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Println("applyFilterRules: ", err)
+			continue
+		}
+		if rule.Star {
+			err := apiCfg.DB.ImportPostStar(ctx, database.ImportPostStarParams{
+				UserID:    dbRule.UserID,
+				PostID:    post.ID,
+				StarredAt: time.Now(),
+			})
+			if err != nil {
+				log.Println("applyFilterRules: ", err)
+			}
+		}
+	}
+}
+
+// runs the user's rules on all posts of a feed they just followed, the rules only saw the feeds followed when they were saved
+// like backfillFilterRule posts already in the feed aren't starred
+func (apiCfg apiConfig) applyFilterRulesToFeed(ctx context.Context, user database.User, feedID uuid.UUID) error {
+	rules, err := apiCfg.DB.GetUserFilterRulesForFeed(ctx, database.GetUserFilterRulesForFeedParams{
+		UserID: user.ID,
+		FeedID: uuid.NullUUID{UUID: feedID, Valid: true},
+	})
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	posts, err := apiCfg.DB.GetPostsOfFeed(ctx, feedID)
+	if err != nil {
+		return err
+	}
+	for _, dbRule := range rules {
+		rule, err := newFilterRule(dbRule)
+		if err != nil {
+			return err
+		}
+		matches, err := rule.matcher()
+		if err != nil {
+			return err
+		}
+		postIDs := []uuid.UUID{}
+		for _, post := range posts {
+			if matches(post) {
+				postIDs = append(postIDs, post.ID)
+			}
+		}
+		if len(postIDs) == 0 {
+			continue
+		}
+		err = apiCfg.DB.AddFilterRuleMatches(ctx, database.AddFilterRuleMatchesParams{
+			RuleID:    dbRule.ID,
+			PostIds:   postIDs,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the newest posts of the followed feeds, or of the rule's feed, that the rule matches
+func (apiCfg apiConfig) postsMatchingFilterRule(ctx context.Context, user database.User, rule filterRule, scan int32) (matched []database.Post, scanned int, err error) {
+	matches, err := rule.matcher()
+	if err != nil {
+		return nil, 0, err
+	}
+	posts, err := apiCfg.DB.GetPostsToSearch(ctx, database.GetPostsToSearchParams{
+		UserID: user.ID,
+		FeedID: rule.FeedID,
+		Limit:  scan,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	matched = []database.Post{}
+	for _, post := range posts {
+		if matches(post) {
+			matched = append(matched, post)
+		}
+	}
+	return matched, len(posts), nil
+}
+
+// runs a rule that was just saved on the newest posts of the followed feeds, replacing its matches
+// posts already in the feeds aren't starred, only the new ones are
+func (apiCfg apiConfig) backfillFilterRule(ctx context.Context, user database.User, ruleID uuid.UUID, rule filterRule) (int64, error) {
+	posts, _, err := apiCfg.postsMatchingFilterRule(ctx, user, rule, filterRuleBackfillPosts)
+	if err != nil {
+		return 0, err
+	}
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+
+	err = apiCfg.inTx(ctx, func(q *database.Queries) error {
+		if err := q.DeleteFilterRuleMatches(ctx, ruleID); err != nil {
+			return err
+		}
+		return q.AddFilterRuleMatches(ctx, database.AddFilterRuleMatchesParams{
+			RuleID:    ruleID,
+			PostIds:   postIDs,
+			CreatedAt: time.Now(),
+		})
+	})
+	return int64(len(postIDs)), err
+}
+
+// the fields of a filter rule in a request body, nil for the ones left out
+type filterRuleParams struct {
+	Name *string `json:"name"`
+	// a uuid, or null for every followed feed
+	FeedID     json.RawMessage    `json:"feed_id"`
+	Conditions *[]filterCondition `json:"conditions"`
+	Hide       *bool              `json:"hide"`
+	Star       *bool              `json:"star"`
+	Tags       *[]string          `json:"tags"`
+}
+
+// applies the params to the rule and validates the result
+// errors are the client's fault and meant to be responded with a 400
+func (apiCfg apiConfig) applyFilterRuleParams(ctx context.Context, user database.User, rule *filterRule, params filterRuleParams) error {
+	if params.Name != nil {
+		rule.Name = strings.TrimSpace(*params.Name)
+	}
+	if params.FeedID != nil {
+		feedID := uuid.NullUUID{}
+		if err := json.Unmarshal(params.FeedID, &feedID); err != nil {
+			return errors.New("feed_id must be a valid uuid or null")
+		}
+		rule.FeedID = feedID
+	}
+	if params.Conditions != nil {
+		rule.Conditions = *params.Conditions
+	}
+	if params.Hide != nil {
+		rule.Hide = *params.Hide
+	}
+	if params.Star != nil {
+		rule.Star = *params.Star
+	}
+	if params.Tags != nil {
+		tags := []string{}
+		seen := map[string]bool{}
+		for _, tag := range *params.Tags {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				return errors.New("tags cannot be empty")
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		rule.Tags = tags
+	}
+
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	if rule.FeedID.Valid {
+		_, err := apiCfg.DB.GetFeedFollowByFeed(ctx, database.GetFeedFollowByFeedParams{
+			UserID: user.ID,
+			FeedID: rule.FeedID.UUID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("feed_id must be a feed you follow")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// true if err is the unique (user_id, name) violation of the filter_rules table
+func isDuplicateFilterRuleName(err error) bool {
+	return err.Error() == "pq: duplicate key value violates unique constraint \"filter_rules_user_id_name_key\""
+}
+
+// matches the rule on the existing posts and responds with it
+func (apiCfg apiConfig) respondWithFilterRule(w http.ResponseWriter, code int, user database.User, dbRule database.FilterRule, rule filterRule) {
+	matchCount, err := apiCfg.backfillFilterRule(context.Background(), user, dbRule.ID, rule)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, code, newFilterRuleResponse(dbRule, rule, matchCount))
+}
+
+// POST /v1/rules
+// authed
+// expects a name, conditions on the posts' fields, optionally a feed_id, and what to do with the matching posts: hide, star and tags
+// the rule applies to the posts already fetched and to every new one, only new posts are starred
+// 409 if the user already has a rule with that name
+func (apiCfg apiConfig) createFilterRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	params := filterRuleParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	ctx := context.Background()
+	rule := filterRule{Conditions: []filterCondition{}, Tags: []string{}}
+	if err := apiCfg.applyFilterRuleParams(ctx, user, &rule, params); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	dbRule, err := apiCfg.DB.CreateFilterRule(ctx, database.CreateFilterRuleParams{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		Name:       rule.Name,
+		FeedID:     rule.FeedID,
+		Conditions: conditions,
+		Hide:       rule.Hide,
+		Star:       rule.Star,
+		Tags:       rule.Tags,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		if isDuplicateFilterRuleName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("filter rule with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	apiCfg.respondWithFilterRule(w, http.StatusCreated, user, dbRule, rule)
+}
+
+// GET /v1/rules
+// authed
+// get the user's filter rules by name, with the number of posts they matched
+func (apiCfg apiConfig) getFilterRulesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := apiCfg.DB.GetFilterRules(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rules := make([]filterRuleResponse, 0, len(rows))
+	for _, row := range rows {
+		rule, err := newFilterRule(row.FilterRule)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		rules = append(rules, newFilterRuleResponse(row.FilterRule, rule, row.MatchCount))
+	}
+	respondWithJSON(w, http.StatusOK, rules)
+}
+
+// PATCH /v1/rules/{ruleID}
+// authed
+// expects any of the fields of POST /v1/rules, the rule is run again on the existing posts
+// 404 if the rule isn't the user's, 409 if the user already has a rule with the new name
+func (apiCfg apiConfig) updateFilterRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	ruleID, err := uuidFromURL(r, "ruleID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	params := filterRuleParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	ctx := context.Background()
+	dbRule, err := apiCfg.DB.GetFilterRule(ctx, database.GetFilterRuleParams{
+		ID:     ruleID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errFilterRuleNotFound)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	rule, err := newFilterRule(dbRule)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := apiCfg.applyFilterRuleParams(ctx, user, &rule, params); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	dbRule, err = apiCfg.DB.UpdateFilterRule(ctx, database.UpdateFilterRuleParams{
+		ID:         ruleID,
+		UserID:     user.ID,
+		Name:       rule.Name,
+		FeedID:     rule.FeedID,
+		Conditions: conditions,
+		Hide:       rule.Hide,
+		Star:       rule.Star,
+		Tags:       rule.Tags,
+		UpdatedAt:  time.Now(),
+	})
+	if err != nil {
+		if isDuplicateFilterRuleName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("filter rule with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	apiCfg.respondWithFilterRule(w, http.StatusOK, user, dbRule, rule)
+}
+
+// DELETE /v1/rules/{ruleID}
+// authed
+// deletes the filter rule, the posts it hid show up again and lose its tags, the ones it starred stay starred
+func (apiCfg apiConfig) deleteFilterRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	ruleID, err := uuidFromURL(r, "ruleID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteFilterRule(context.Background(), database.DeleteFilterRuleParams{
+		ID:     ruleID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errFilterRuleNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /v1/rules/dry_run
+// authed
+// expects a rule like POST /v1/rules, the name is optional, nothing is saved
+// responds with the newest posts of the followed feeds the rule would affect
+func (apiCfg apiConfig) dryRunFilterRuleHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type dryRunPost struct {
+		ID          uuid.UUID `json:"id"`
+		FeedID      uuid.UUID `json:"feed_id"`
+		Title       string    `json:"title"`
+		Url         string    `json:"url"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	type response struct {
+		// number of posts the rule was run on, and how many it matched
+		Scanned int          `json:"scanned"`
+		Matched int          `json:"matched"`
+		Posts   []dryRunPost `json:"posts"`
+	}
+
+	params := filterRuleParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	ctx := context.Background()
+	rule := filterRule{Name: "dry run", Conditions: []filterCondition{}, Tags: []string{}}
+	if err := apiCfg.applyFilterRuleParams(ctx, user, &rule, params); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	matched, scanned, err := apiCfg.postsMatchingFilterRule(ctx, user, rule, filterRuleDryRunPosts)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := response{Scanned: scanned, Matched: len(matched), Posts: []dryRunPost{}}
+	for _, post := range matched {
+		if len(resp.Posts) == filterRuleDryRunResults {
+			break
+		}
+		resp.Posts = append(resp.Posts, dryRunPost{
+			ID:          post.ID,
+			FeedID:      post.FeedID,
+			Title:       post.Title,
+			Url:         post.Url,
+			PublishedAt: post.PublishedAt.UTC(),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestFilterRules(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedID := created.Feed.ID
+	fetch := func(items ...*gofeed.Item) {
+		apiCfg.FetchedFeeds = []FeedTuple{{ID: feedID, Feed: &gofeed.Feed{Items: items}}}
+		apiCfg.CreatePostsFromFetchedFeeds()
+	}
+	fetch(&gofeed.Item{Title: "SPONSORED: an IDE", Link: base + "/ad"})
+
+	// a dry run saves nothing
+	hide := true
+	sponsored := []client.FilterCondition{{Field: client.FilterFieldTitle, Op: client.FilterOpMatches, Value: "/sponsored/i"}}
+	dryRun, err := alice.DryRunFilterRule(ctx, client.FilterRuleParams{FeedID: &feedID, Conditions: &sponsored, Hide: &hide})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Scanned != 1 || dryRun.Matched != 1 || dryRun.Posts[0].URL != base+"/ad" {
+		t.Errorf("got dry run %+v", dryRun)
+	}
+	if rules, err := alice.ListFilterRules(ctx); err != nil || len(rules) != 0 {
+		t.Errorf("got rules %+v, %v after a dry run", rules, err)
+	}
+
+	// hiding applies to the posts already fetched
+	hideRule, err := alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "no ads", FeedID: &feedID, Conditions: &sponsored, Hide: &hide})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hideRule.MatchCount != 1 {
+		t.Errorf("got rule %+v", hideRule)
+	}
+	star := true
+	golang := []client.FilterCondition{{Field: client.FilterFieldCategory, Op: client.FilterOpEquals, Value: "golang"}}
+	tags := []string{"go", " go "}
+	tagRule, err := alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "go", Conditions: &golang, Star: &star, Tags: &tags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagRule.Tags) != 1 || tagRule.MatchCount != 0 {
+		t.Errorf("got rule %+v", tagRule)
+	}
+	var apiErr *client.Error
+	_, err = alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "go", Conditions: &golang, Star: &star})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate name, got %v", err)
+	}
+	_, err = alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "nothing", Conditions: &golang})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a rule without an action, got %v", err)
+	}
+
+	// new posts are starred and tagged
+	fetch(
+		&gofeed.Item{Title: "Generics", Categories: []string{"Golang"}, Link: base + "/generics"},
+		&gofeed.Item{Title: "Sponsored post", Link: base + "/ad2"},
+	)
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].URL != base+"/generics" || !page.Items[0].Starred || len(page.Items[0].Tags) != 1 || page.Items[0].Tags[0] != "go" {
+		t.Errorf("got posts %+v", page.Items)
+	}
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID, Hidden: &hide})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("got hidden posts %+v", page.Items)
+	}
+
+	// deleting the rule shows the posts again
+	if err := alice.DeleteFilterRule(ctx, hideRule.ID); err != nil {
+		t.Fatal(err)
+	}
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 3 {
+		t.Errorf("got %d posts after deleting the rule", len(page.Items))
+	}
+
+	// changing a rule runs it again
+	tags = []string{"golang"}
+	tagRule, err = alice.UpdateFilterRule(ctx, tagRule.ID, client.FilterRuleParams{Tags: &tags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tagRule.Name != "go" || !tagRule.Star || tagRule.MatchCount != 1 || tagRule.Tags[0] != "golang" {
+		t.Errorf("got updated rule %+v", tagRule)
+	}
+	if _, err := alice.UpdateFilterRule(ctx, uuid.New(), client.FilterRuleParams{Tags: &tags}); !client.IsNotFound(err) {
+		t.Errorf("expected 404 for a missing rule, got %v", err)
+	}
+}
+
+// posts a hide rule matches stay out of the search, digests, webhooks and the stream, live or replayed
+func TestHiddenPostsStayHidden(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	testMailer := &testMailer{}
+	apiCfg.Mailer = testMailer
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	alice := newTestUser(t, server, "alice")
+	user, err := alice.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unique words so other tests' posts don't match
+	word := "zq" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hide := true
+	sponsored := []client.FilterCondition{{Field: client.FilterFieldTitle, Op: client.FilterOpMatches, Value: "/sponsored/i"}}
+	if _, err := alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "no ads", Conditions: &sponsored, Hide: &hide}); err != nil {
+		t.Fatal(err)
+	}
+	notify := true
+	search, err := alice.CreateSavedSearch(ctx, client.SavedSearchParams{Name: word, Query: word, Notify: &notify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := alice.CreateWebhook(ctx, client.CreateWebhookParams{URL: "https://hooks.example.com/" + uuid.NewString()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := "alice+" + uuid.NewString() + "@example.com"
+	if _, err := alice.SubscribeDigest(ctx, client.DigestSettings{Email: email}); err != nil {
+		t.Fatal(err)
+	}
+	testMailer.confirm(t, email)
+	stream, err := alice.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	startedAfter, err := apiCfg.DB.GetLastStreamEventID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Sponsored: " + word, Link: base + "/ad"},
+		{Title: "Kept: " + word, Link: base + "/kept"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	// the stream only gets the new_post and search_match events of the kept post
+	checkStream := func(stream *client.Stream) {
+		t.Helper()
+		event, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		post := client.Post{}
+		if err := json.Unmarshal(event.Data, &post); err != nil {
+			t.Fatal(err)
+		}
+		if event.Event != client.EventNewPost || post.URL != base+"/kept" {
+			t.Errorf("got %+v, want the new post that isn't hidden", event)
+		}
+		event, err = stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		match := client.SearchMatchEvent{}
+		if err := json.Unmarshal(event.Data, &match); err != nil {
+			t.Fatal(err)
+		}
+		if event.Event != client.EventSearchMatch || match.SearchID != search.ID || match.Post.URL != base+"/kept" {
+			t.Errorf("got %+v, want the search match of the post that isn't hidden", event)
+		}
+	}
+	checkStream(stream)
+	replay, err := alice.Stream(ctx, startedAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	checkStream(replay)
+
+	// search
+	page, err := alice.Search(ctx, word, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].URL != base+"/kept" {
+		t.Errorf("expected only the kept post in the search, got %+v", page.Items)
+	}
+
+	// webhooks
+	deliveries, err := alice.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Payload.Post.URL != base+"/kept" {
+		t.Errorf("expected only the kept post to be delivered, got %+v", deliveries)
+	}
+
+	// digests
+	err = apiCfg.DB.SetDigestNextSendAt(ctx, database.SetDigestNextSendAtParams{
+		UserID:     user.ID,
+		NextSendAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.sendDueDigests(ctx); err != nil {
+		t.Fatal(err)
+	}
+	msgs := testMailer.sentTo(email)
+	if len(msgs) != 1 || !strings.Contains(msgs[0].HTML, base+"/kept") || strings.Contains(msgs[0].HTML, base+"/ad") {
+		t.Errorf("expected a digest of only the kept post, got %+v", msgs)
+	}
+}
+
+// a rule hides the posts already in a feed that's followed after the rule was saved
+func TestRulesApplyToFeedsFollowedLater(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	hide := true
+	sponsored := []client.FilterCondition{{Field: client.FilterFieldTitle, Op: client.FilterOpMatches, Value: "/sponsored/i"}}
+	if _, err := bob.CreateFilterRule(ctx, client.FilterRuleParams{Name: "no ads", Conditions: &sponsored, Hide: &hide}); err != nil {
+		t.Fatal(err)
+	}
+	feed, posts := newTestFeedWithPosts(t, apiCfg, alice, "Sponsored: try our IDE", "Go 1.21 is out")
+	if _, err := bob.FollowFeed(ctx, feed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := bob.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != posts[1].ID {
+		t.Errorf("got posts %+v, want the sponsored one hidden", page.Items)
+	}
+	// the rule is bob's, alice still sees both
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("got posts %+v, want both for alice", page.Items)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseFilterPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"sponsored", "Sponsored: buy this", false},
+		{"/sponsored/i", "Sponsored: buy this", true},
+		{"/^go/", "go 1.21", true},
+		{"/^go/", "about go", false},
+		{"a/b", "a/b", true},
+		{"/x/z", "/x/z", true},
+	}
+	for _, test := range tests {
+		re, err := parseFilterPattern(test.pattern)
+		if err != nil {
+			t.Fatalf("parseFilterPattern(%q): %v", test.pattern, err)
+		}
+		if got := re.MatchString(test.input); got != test.want {
+			t.Errorf("parseFilterPattern(%q) on %q = %v", test.pattern, test.input, got)
+		}
+	}
+	if _, err := parseFilterPattern("/(/i"); err == nil {
+		t.Error("parseFilterPattern should fail on an invalid regexp")
+	}
+}
+
+func TestFilterRuleMatcher(t *testing.T) {
+	feedID := uuid.New()
+	post := database.Post{
+		FeedID:     feedID,
+		Title:      "Sponsored: the best IDE",
+		Url:        "https://example.com/ads/ide",
+		Author:     "Ad Team",
+		Categories: []string{"ads", "Golang"},
+	}
+	tests := []struct {
+		rule filterRule
+		want bool
+	}{
+		{filterRule{Conditions: []filterCondition{{"title", "matches", "/sponsored/i"}}}, true},
+		{filterRule{Conditions: []filterCondition{{"title", "matches", "sponsored"}}}, false},
+		{filterRule{Conditions: []filterCondition{{"category", "equals", "golang"}}}, true},
+		{filterRule{Conditions: []filterCondition{{"category", "equals", "go"}}}, false},
+		{filterRule{Conditions: []filterCondition{{"url", "contains", "/ADS/"}, {"author", "equals", "ad team"}}}, true},
+		{filterRule{Conditions: []filterCondition{{"url", "contains", "/ads/"}, {"author", "equals", "someone"}}}, false},
+		{filterRule{FeedID: uuid.NullUUID{UUID: feedID, Valid: true}}, true},
+		{filterRule{FeedID: uuid.NullUUID{UUID: uuid.New(), Valid: true}, Conditions: []filterCondition{{"title", "contains", "ide"}}}, false},
+	}
+	for _, test := range tests {
+		matches, err := test.rule.matcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matches(post); got != test.want {
+			t.Errorf("rule %+v matched %v", test.rule, got)
+		}
+	}
+}
+
+func TestFilterRuleValidate(t *testing.T) {
+	valid := filterRule{Name: "no ads", Conditions: []filterCondition{{"title", "matches", "/sponsored/i"}}, Hide: true}
+	if err := valid.validate(); err != nil {
+		t.Errorf("valid rule: %v", err)
+	}
+	invalid := []filterRule{
+		{Conditions: valid.Conditions, Hide: true},
+		{Name: "no conditions", Hide: true},
+		{Name: "no action", Conditions: valid.Conditions},
+		{Name: "bad field", Conditions: []filterCondition{{"body", "contains", "x"}}, Hide: true},
+		{Name: "bad op", Conditions: []filterCondition{{"title", "like", "x"}}, Hide: true},
+		{Name: "empty value", Conditions: []filterCondition{{"title", "contains", ""}}, Hide: true},
+		{Name: "bad pattern", Conditions: []filterCondition{{"title", "matches", "("}}, Hide: true},
+	}
+	for _, rule := range invalid {
+		if err := rule.validate(); err == nil {
+			t.Errorf("rule %+v should be invalid", rule)
+		}
+	}
+}
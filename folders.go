@@ -0,0 +1,307 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type folderResponse struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Name          string      `json:"name"`
+	Position      int         `json:"position"`
+	FeedFollowIDs []uuid.UUID `json:"feed_follow_ids"`
+	UnreadCount   int64       `json:"unread_count"`
+}
+
+func newFolderResponse(folder database.Folder, feedFollowIDs []uuid.UUID, unreadCount int64) folderResponse {
+	if feedFollowIDs == nil {
+		feedFollowIDs = []uuid.UUID{}
+	}
+	return folderResponse{
+		ID:            folder.ID,
+		CreatedAt:     folder.CreatedAt.UTC(),
+		UpdatedAt:     folder.UpdatedAt.UTC(),
+		Name:          folder.Name,
+		Position:      int(folder.Position),
+		FeedFollowIDs: feedFollowIDs,
+		UnreadCount:   unreadCount,
+	}
+}
+
+// true if err is the unique (user_id, name) violation of the folders table
+func isDuplicateFolderName(err error) bool {
+	return err.Error() == "pq: duplicate key value violates unique constraint \"folders_user_id_name_key\""
+}
+
+// decodes a {"name": "..."} body, the name is trimmed and cannot be empty
+func decodeFolderName(r *http.Request) (string, error) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		return "", errors.New("decoding json went wrong")
+	}
+	name := strings.TrimSpace(params.Name)
+	if len(name) == 0 {
+		return "", errors.New("name cannot be empty")
+	}
+	return name, nil
+}
+
+// the user's folders in order
+func (apiCfg apiConfig) getFolders(ctx context.Context, user database.User) ([]folderResponse, error) {
+	rows, err := apiCfg.DB.GetFolders(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := make([]folderResponse, 0, len(rows))
+	for _, row := range rows {
+		folders = append(folders, newFolderResponse(row.Folder, row.FeedFollowIds, row.UnreadCount))
+	}
+	return folders, nil
+}
+
+// POST /v1/folders
+// authed
+// expects a name, creates an empty folder at the end of the user's folders
+// 409 if the user already has a folder with that name
+func (apiCfg apiConfig) createFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	name, err := decodeFolderName(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	folder, err := apiCfg.DB.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		if isDuplicateFolderName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("folder with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newFolderResponse(folder, nil, 0))
+}
+
+// GET /v1/folders
+// authed
+// get the user's folders in order, with the feed follows in each and their unread post count
+func (apiCfg apiConfig) getFoldersHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folders, err := apiCfg.getFolders(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, folders)
+}
+
+// PATCH /v1/folders/{folderID}
+// authed
+// expects a name, renames the folder
+// 404 if the folder isn't the user's, 409 if the user already has a folder with that name
+func (apiCfg apiConfig) renameFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuidFromURL(r, "folderID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	name, err := decodeFolderName(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, err = apiCfg.DB.RenameFolder(context.Background(), database.RenameFolderParams{
+		ID:        folderID,
+		UserID:    user.ID,
+		Name:      name,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("folder not found"))
+			return
+		}
+		if isDuplicateFolderName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("folder with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	folders, err := apiCfg.getFolders(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, folder := range folders {
+		if folder.ID == folderID {
+			respondWithJSON(w, http.StatusOK, folder)
+			return
+		}
+	}
+	respondWithError(w, http.StatusNotFound, errors.New("folder not found"))
+}
+
+// DELETE /v1/folders/{folderID}
+// authed
+// deletes the folder, the feed follows in it are kept
+func (apiCfg apiConfig) deleteFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuidFromURL(r, "folderID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteFolder(context.Background(), database.DeleteFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("folder not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /v1/folders/order
+// authed
+// expects {"folder_ids": [...]} with every folder of the user in its new order
+// responds with the reordered folders
+func (apiCfg apiConfig) reorderFoldersHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FolderIDs []uuid.UUID `json:"folder_ids"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	// the new order has to hold exactly the user's folders
+	current, err := apiCfg.getFolders(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	owned := map[uuid.UUID]bool{}
+	for _, folder := range current {
+		owned[folder.ID] = true
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, folderID := range params.FolderIDs {
+		if !owned[folderID] || seen[folderID] {
+			respondWithError(w, http.StatusBadRequest, errors.New("folder_ids must list every folder once"))
+			return
+		}
+		seen[folderID] = true
+	}
+	if len(seen) != len(owned) {
+		respondWithError(w, http.StatusBadRequest, errors.New("folder_ids must list every folder once"))
+		return
+	}
+
+	err = apiCfg.DB.SetFolderPositions(context.Background(), database.SetFolderPositionsParams{
+		UserID:    user.ID,
+		FolderIds: params.FolderIDs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	folders, err := apiCfg.getFolders(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, folders)
+}
+
+// PUT /v1/folders/{folderID}/feed_follows/{feedFollowID}
+// authed
+// puts a feed follow in a folder, a feed follow can be in any number of folders
+// 404 if either isn't the user's
+func (apiCfg apiConfig) addFeedFollowToFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuidFromURL(r, "folderID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	feedFollowID, err := uuidFromURL(r, "feedFollowID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	added, err := apiCfg.DB.AddFeedFollowToFolder(context.Background(), database.AddFeedFollowToFolderParams{
+		FeedFollowID: feedFollowID,
+		UserID:       user.ID,
+		FolderID:     folderID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if added == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("folder or feed follow not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /v1/folders/{folderID}/feed_follows/{feedFollowID}
+// authed
+// takes a feed follow out of a folder, the feed stays followed
+// 404 if the feed follow isn't in one of the user's folders
+func (apiCfg apiConfig) removeFeedFollowFromFolderHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuidFromURL(r, "folderID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	feedFollowID, err := uuidFromURL(r, "feedFollowID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	removed, err := apiCfg.DB.RemoveFeedFollowFromFolder(context.Background(), database.RemoveFeedFollowFromFolderParams{
+		FeedFollowID: feedFollowID,
+		FolderID:     folderID,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("feed follow not in folder"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFolders(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	tech, techPosts := newTestFeedWithPosts(t, apiCfg, alice, "Go 1.22", "Rust 1.77")
+	news, _ := newTestFeedWithPosts(t, apiCfg, alice, "Elections")
+	followIDs := map[uuid.UUID]uuid.UUID{}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, follow := range follows {
+		followIDs[follow.FeedID] = follow.ID
+	}
+
+	folder, err := alice.CreateFolder(ctx, " Tech ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.Name != "Tech" {
+		t.Errorf("got name %q, want it trimmed", folder.Name)
+	}
+	_, err = alice.CreateFolder(ctx, "Tech")
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("got error %v, want a 409", err)
+	}
+	other, err := alice.CreateFolder(ctx, "Reading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.AddToFolder(ctx, folder.ID, followIDs[tech.ID]); err != nil {
+		t.Fatal(err)
+	}
+	// a follow can be in several folders
+	if err := alice.AddToFolder(ctx, other.ID, followIDs[tech.ID]); err != nil {
+		t.Fatal(err)
+	}
+
+	// the folder filter only returns posts of the feeds in the folder
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FolderID: folder.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != len(techPosts) {
+		t.Fatalf("got posts %+v, want the %d posts of the feed in the folder", page.Items, len(techPosts))
+	}
+	for _, post := range page.Items {
+		if post.FeedID != tech.ID {
+			t.Errorf("got post %q of feed %s outside the folder", post.Title, post.FeedID)
+		}
+	}
+	if _, err := alice.ListPosts(ctx, &client.ListPostsOptions{FolderID: uuid.New()}); err != nil {
+		t.Errorf("a folder that isn't the user's should be an empty page, got %v", err)
+	}
+
+	folders, err := alice.ListFolders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 || folders[0].ID != folder.ID || folders[1].ID != other.ID {
+		t.Fatalf("got folders %+v", folders)
+	}
+	if len(folders[0].FeedFollowIDs) != 1 || folders[0].UnreadCount != int64(len(techPosts)) {
+		t.Errorf("got folder %+v", folders[0])
+	}
+
+	folders, err = alice.ReorderFolders(ctx, []uuid.UUID{other.ID, folder.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folders[0].ID != other.ID || folders[1].ID != folder.ID {
+		t.Errorf("got folders %+v", folders)
+	}
+	_, err = alice.ReorderFolders(ctx, []uuid.UUID{other.ID})
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v, want a 400", err)
+	}
+
+	renamed, err := alice.RenameFolder(ctx, other.ID, "Later")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "Later" {
+		t.Errorf("got folder %+v", renamed)
+	}
+	_, err = alice.RenameFolder(ctx, other.ID, "Tech")
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("got error %v, want a 409", err)
+	}
+
+	if err := alice.RemoveFromFolder(ctx, folder.ID, followIDs[tech.ID]); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.RemoveFromFolder(ctx, folder.ID, followIDs[news.ID]); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404 for a follow that isn't in the folder", err)
+	}
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FolderID: folder.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("got posts %+v from an empty folder", page.Items)
+	}
+
+	// deleting a folder keeps the follows in it
+	if err := alice.DeleteFolder(ctx, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	follows, err = alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 2 {
+		t.Errorf("got follows %+v", follows)
+	}
+}
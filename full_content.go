@@ -0,0 +1,87 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/extract"
+	"blog_aggregator/internal/sanitize"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// what a post's full_content_status ends up as, pending and fetching are set by the queries
+const (
+	fullContentDone   = "done"
+	fullContentFailed = "failed"
+)
+
+// starts workers that download the pages of the new posts of feeds with fetch_full_content
+// and store the article extracted from each, delay is in seconds
+func (apiCfg apiConfig) fullContentWorker(workers int, delay int) {
+	requeued, err := apiCfg.DB.RequeueFetchingFullContent(context.Background())
+	if err != nil {
+		log.Println("fullContentWorker: ", err)
+	} else if requeued > 0 {
+		log.Printf("fullContentWorker: fetching %d interrupted posts again\n", requeued)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func(delay int) {
+			for {
+				fetched, err := apiCfg.fetchNextFullContent(context.Background())
+				if err != nil {
+					log.Println("fullContentWorker: ", err)
+				}
+				if !fetched {
+					// nothing waiting, wait before checking again
+					time.Sleep(time.Duration(delay) * time.Second)
+				}
+			}
+		}(delay)
+	}
+}
+
+// fetches the full content of the oldest post waiting for it, false if no post was waiting
+// a page that can't be fetched or has no article marks the post failed, err is only for db errors
+func (apiCfg apiConfig) fetchNextFullContent(ctx context.Context) (bool, error) {
+	post, err := apiCfg.DB.ClaimNextFullContentPost(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := fullContentDone
+	content, err := apiCfg.fullContent(ctx, post)
+	if err != nil {
+		log.Printf("fullContentWorker: post %s: %v\n", post.ID, err)
+		status = fullContentFailed
+	}
+	err = apiCfg.DB.SetPostFullContent(ctx, database.SetPostFullContentParams{
+		ID:                post.ID,
+		FullContent:       content,
+		FullContentStatus: status,
+	})
+	return true, err
+}
+
+// downloads the post's page and extracts its article, with the feed's selector if it has one
+func (apiCfg apiConfig) fullContent(ctx context.Context, post database.Post) (string, error) {
+	feed, err := apiCfg.DB.GetFeed(ctx, post.FeedID)
+	if err != nil {
+		return "", err
+	}
+	resp, err := apiCfg.Fetcher.Get(ctx, post.Url)
+	if err != nil {
+		return "", err
+	}
+	article, err := extract.Article(resp.Body, feed.ContentSelector)
+	if err != nil {
+		return "", err
+	}
+	// links are relative to where the page ended up after redirects
+	return sanitize.HTML(article, resp.URL), nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+const fullContentTestPage = `<!DOCTYPE html>
+<html>
+<body>
+  <nav><a href="/">Home</a> <a href="/archive">Archive</a></nav>
+  <article class="post">
+    <h1>Full post</h1>
+    <div class="post-body">
+      <p>This is the whole article, much longer than the teaser the feed publishes, with every paragraph of it.</p>
+      <p>It links to <a href="/other">another post</a> on the same blog, and keeps going for a while longer so it counts as an article.</p>
+      <p>Finally, a third paragraph, so that the text of the article is clearly longer than the navigation.</p>
+    </div>
+  </article>
+  <div class="sidebar"><a href="/about">About the author</a></div>
+</body>
+</html>`
+
+func TestFetchFullContent(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	blog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(fullContentTestPage))
+	}))
+	t.Cleanup(blog.Close)
+
+	created, err := alice.CreateFeed(ctx, "Teasers", blog.URL+"/feed.xml?"+uuid.NewString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedID := created.Feed.ID
+
+	// the selector has to be valid
+	invalid := "p[["
+	_, err = alice.UpdateFeed(ctx, feedID, client.UpdateFeedOptions{ContentSelector: &invalid})
+	apiErr := &client.Error{}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got error %v, want a 400", err)
+	}
+
+	on := true
+	updated, err := alice.UpdateFeed(ctx, feedID, client.UpdateFeedOptions{FetchFullContent: &on})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Feed.FetchFullContent || updated.Feed.ContentSelector != "" {
+		t.Fatalf("got feed %+v", updated.Feed)
+	}
+
+	path := "/posts/" + uuid.NewString()
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: feedID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Full post", Description: "A teaser", Link: blog.URL + path},
+		{Title: "Gone", Description: "Another teaser", Link: blog.URL + "/missing?" + uuid.NewString()},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+	for {
+		fetched, err := apiCfg.fetchNextFullContent(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !fetched {
+			break
+		}
+	}
+
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts := map[string]client.Post{}
+	for _, post := range page.Items {
+		posts[post.Title] = post
+	}
+	full := posts["Full post"].FullContent
+	if !strings.Contains(full, "This is the whole article") || !strings.Contains(full, `href="`+blog.URL+`/other"`) {
+		t.Errorf("got full content %q", full)
+	}
+	if strings.Contains(full, "Archive") || strings.Contains(full, "About the author") {
+		t.Errorf("full content has the page around the article: %q", full)
+	}
+	if posts["Gone"].FullContent != "" || posts["Gone"].Description != "Another teaser" {
+		t.Errorf("got post %+v", posts["Gone"])
+	}
+
+	// with a selector the article is what it matches
+	selector := "h1, .post-body"
+	updated, err = alice.UpdateFeed(ctx, feedID, client.UpdateFeedOptions{ContentSelector: &selector})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Feed.FetchFullContent || updated.Feed.ContentSelector != selector {
+		t.Fatalf("got feed %+v", updated.Feed)
+	}
+	link := blog.URL + "/posts/" + uuid.NewString()
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: feedID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Selected post", Description: "A teaser", Link: link},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+	if _, err := apiCfg.fetchNextFullContent(ctx); err != nil {
+		t.Fatal(err)
+	}
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].URL != link || !strings.HasPrefix(page.Items[0].FullContent, "<h1>Full post</h1>") {
+		t.Errorf("got post %+v", page.Items[0])
+	}
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/importer"
+	"blog_aggregator/internal/opml"
+	"blog_aggregator/internal/sanitize"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	importKindOPML = "opml"
+
+	importStatusPending = "pending"
+	importStatusRunning = "running"
+	importStatusDone    = "done"
+	importStatusFailed  = "failed"
+
+	importResultCreated         = "created"
+	importResultAlreadyFollowed = "already_followed"
+	importResultInvalid         = "invalid"
+	importResultFailed          = "failed"
+	importResultImported        = "imported"
+	importResultNotFound        = "not_found"
+
+	// imports with more entries than this run in the background as an import job
+	maxSyncImportEntries = 50
+	// largest upload accepted by the import endpoints
+	maxImportUploadBytes = 5 << 20
+	// how many entries an import job gets through between progress updates
+	importProgressInterval = 10
+)
+
+// the outcome of importing one entry
+type importResult struct {
+	Title        string     `json:"title"`
+	URL          string     `json:"url"`
+	Folder       string     `json:"folder"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	FeedID       *uuid.UUID `json:"feed_id"`
+	FeedFollowID *uuid.UUID `json:"feed_follow_id"`
+}
+
+// the outcome of importing one starred or read item
+type importItemResult struct {
+	Title   string     `json:"title"`
+	URL     string     `json:"url"`
+	FeedURL string     `json:"feed_url"`
+	Starred bool       `json:"starred"`
+	Read    bool       `json:"read"`
+	Status  string     `json:"status"`
+	Error   string     `json:"error,omitempty"`
+	PostID  *uuid.UUID `json:"post_id"`
+}
+
+type importReport struct {
+	Created         int            `json:"created"`
+	AlreadyFollowed int            `json:"already_followed"`
+	Invalid         int            `json:"invalid"`
+	Failed          int            `json:"failed"`
+	Results         []importResult `json:"results"`
+	// items brought over from formats that have them, not found are items whose post isn't in the db
+	// and that can't be added to a feed the user didn't create
+	Starred       int                `json:"starred"`
+	Read          int                `json:"read"`
+	ItemsInvalid  int                `json:"items_invalid"`
+	ItemsFailed   int                `json:"items_failed"`
+	ItemsNotFound int                `json:"items_not_found"`
+	ItemResults   []importItemResult `json:"item_results"`
+}
+
+type importJobResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	Total     int       `json:"total"`
+	Processed int       `json:"processed"`
+	// null until the job is done
+	Report     json.RawMessage `json:"report"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+}
+
+func newImportJobResponse(job database.ImportJob) importJobResponse {
+	return importJobResponse{
+		ID:         job.ID,
+		Kind:       job.Kind,
+		Status:     job.Status,
+		Total:      int(job.Total),
+		Processed:  int(job.Processed),
+		Report:     job.Report,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt.UTC(),
+		UpdatedAt:  job.UpdatedAt.UTC(),
+		FinishedAt: nullTimeToPtr(job.FinishedAt),
+	}
+}
+
+// imports an export for one user, remembering the folders and feeds it has found or made
+type userImporter struct {
+	apiCfg  apiConfig
+	user    database.User
+	folders map[string]uuid.UUID
+	feeds   map[string]database.Feed
+}
+
+func newUserImporter(apiCfg apiConfig, user database.User) *userImporter {
+	return &userImporter{
+		apiCfg:  apiCfg,
+		user:    user,
+		folders: map[string]uuid.UUID{},
+		feeds:   map[string]database.Feed{},
+	}
+}
+
+// gets the feed with the url, creating it if it doesn't exist
+// feeds that are created get the site url from the export until the first fetch finds it
+func (imp *userImporter) feed(ctx context.Context, name, url, siteURL string) (database.Feed, error) {
+	if feed, ok := imp.feeds[url]; ok {
+		return feed, nil
+	}
+	if name == "" {
+		name = url
+	}
+	feed, created, err := imp.apiCfg.getOrCreateFeed(ctx, imp.user, name, url)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if created && siteURL != "" {
+		err := imp.apiCfg.DB.SetFeedSiteURL(ctx, database.SetFeedSiteURLParams{
+			ID:      feed.ID,
+			SiteUrl: siteURL,
+		})
+		if err != nil {
+			return database.Feed{}, err
+		}
+	}
+	imp.feeds[url] = feed
+	return feed, nil
+}
+
+// gets the user's folder with the name, creating it if they don't have one
+func (imp *userImporter) folderID(ctx context.Context, name string) (uuid.UUID, error) {
+	if id, ok := imp.folders[name]; ok {
+		return id, nil
+	}
+
+	folder, err := imp.apiCfg.DB.GetFolderByName(ctx, database.GetFolderByNameParams{
+		UserID: imp.user.ID,
+		Name:   name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		folder, err = imp.apiCfg.DB.CreateFolder(ctx, database.CreateFolderParams{
+			ID:        uuid.New(),
+			UserID:    imp.user.ID,
+			Name:      name,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		})
+		if err != nil && isDuplicateFolderName(err) {
+			// made by a request running at the same time
+			folder, err = imp.apiCfg.DB.GetFolderByName(ctx, database.GetFolderByNameParams{
+				UserID: imp.user.ID,
+				Name:   name,
+			})
+		}
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	imp.folders[name] = folder.ID
+	return folder.ID, nil
+}
+
+// creates the feed if it's missing, follows it and puts the follow in the subscription's folder
+// entries that are already followed are still put in the folder
+func (imp *userImporter) importSubscription(ctx context.Context, sub opml.Subscription) importResult {
+	result := importResult{
+		Title:  sub.Title,
+		URL:    sub.XMLURL,
+		Folder: sub.Folder,
+	}
+	if sub.XMLURL == "" {
+		result.Status = importResultInvalid
+		result.Error = "no feed url"
+		return result
+	}
+	if !isFeedURL(sub.XMLURL) {
+		result.Status = importResultInvalid
+		result.Error = "feed url must be an http or https url"
+		return result
+	}
+
+	fail := func(err error) importResult {
+		result.Status = importResultFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	feed, err := imp.feed(ctx, sub.Title, sub.XMLURL, sub.HTMLURL)
+	if err != nil {
+		return fail(err)
+	}
+	feedFollow, created, err := imp.apiCfg.followFeed(ctx, imp.user, feed.ID, "")
+	if err != nil {
+		return fail(err)
+	}
+	result.FeedID = &feed.ID
+	result.FeedFollowID = &feedFollow.ID
+	result.Status = importResultAlreadyFollowed
+	if created {
+		result.Status = importResultCreated
+	}
+
+	if sub.Folder != "" {
+		folderID, err := imp.folderID(ctx, sub.Folder)
+		if err != nil {
+			return fail(err)
+		}
+		_, err = imp.apiCfg.DB.AddFeedFollowToFolder(ctx, database.AddFeedFollowToFolderParams{
+			FeedFollowID: feedFollow.ID,
+			UserID:       imp.user.ID,
+			FolderID:     folderID,
+		})
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return result
+}
+
+// stars the item's post and marks it read as it was in the export
+// the item's feed is created if it's missing but isn't followed, starred posts show up in the timeline anyway
+// a missing post is only created in a feed the user created, other feeds' followers would see a post the export made up
+func (imp *userImporter) importItem(ctx context.Context, item importer.Item) importItemResult {
+	result := importItemResult{
+		Title:   item.Title,
+		URL:     item.URL,
+		FeedURL: item.FeedURL,
+		Starred: item.Starred,
+		Read:    item.Read,
+	}
+	if item.URL == "" {
+		result.Status = importResultInvalid
+		result.Error = "no url"
+		return result
+	}
+	if !isFeedURL(item.URL) {
+		result.Status = importResultInvalid
+		result.Error = "url must be an http or https url"
+		return result
+	}
+	if !isFeedURL(item.FeedURL) {
+		result.Status = importResultInvalid
+		result.Error = "feed url must be an http or https url"
+		return result
+	}
+
+	fail := func(err error) importItemResult {
+		result.Status = importResultFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	feed, err := imp.feed(ctx, item.FeedTitle, item.FeedURL, item.SiteURL)
+	if err != nil {
+		return fail(err)
+	}
+
+	now := time.Now()
+	post, err := imp.apiCfg.DB.GetPostByURL(ctx, item.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		if feed.UserID != imp.user.ID {
+			result.Status = importResultNotFound
+			result.Error = "the post isn't in the feed and only the user who created the feed can add it"
+			return result
+		}
+		publishedAt := item.PublishedAt
+		if publishedAt.IsZero() {
+			publishedAt = now
+		}
+		description := sanitize.HTML(item.Content, item.URL)
+		post, err = imp.apiCfg.DB.GetOrCreatePost(ctx, database.GetOrCreatePostParams{
+			ID:              uuid.New(),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Title:           item.Title,
+			Url:             item.URL,
+			Description:     description,
+			PublishedAt:     publishedAt,
+			FeedID:          feed.ID,
+			Author:          item.Author,
+			Categories:      []string{},
+			DescriptionText: sanitize.Text(description),
+		})
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if item.Starred {
+		err := imp.apiCfg.DB.ImportPostStar(ctx, database.ImportPostStarParams{
+			UserID:    imp.user.ID,
+			PostID:    post.ID,
+			StarredAt: now,
+		})
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if item.Read {
+		err := imp.apiCfg.DB.ImportPostRead(ctx, database.ImportPostReadParams{
+			UserID: imp.user.ID,
+			PostID: post.ID,
+			ReadAt: now,
+		})
+		if err != nil {
+			return fail(err)
+		}
+	}
+	result.Status = importResultImported
+	result.PostID = &post.ID
+	return result
+}
+
+// imports every subscription and then every item in order,
+// progress is called after each one with how many are done
+func (apiCfg apiConfig) importExport(ctx context.Context, user database.User, export importer.Export, progress func(processed int)) importReport {
+	imp := newUserImporter(apiCfg, user)
+	subs := export.Subscriptions
+	report := importReport{
+		Results:     make([]importResult, 0, len(subs)),
+		ItemResults: make([]importItemResult, 0, len(export.Items)),
+	}
+	for i, sub := range subs {
+		result := imp.importSubscription(ctx, sub)
+		switch result.Status {
+		case importResultCreated:
+			report.Created++
+		case importResultAlreadyFollowed:
+			report.AlreadyFollowed++
+		case importResultInvalid:
+			report.Invalid++
+		default:
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+	for i, item := range export.Items {
+		result := imp.importItem(ctx, item)
+		switch result.Status {
+		case importResultImported:
+			if result.Starred {
+				report.Starred++
+			}
+			if result.Read {
+				report.Read++
+			}
+		case importResultInvalid:
+			report.ItemsInvalid++
+		case importResultNotFound:
+			report.ItemsNotFound++
+		default:
+			report.ItemsFailed++
+		}
+		report.ItemResults = append(report.ItemResults, result)
+		if progress != nil {
+			progress(len(subs) + i + 1)
+		}
+	}
+	return report
+}
+
+// reads the uploaded files, either the whole body as one file or every "file" field of a multipart form
+func readUploads(w http.ResponseWriter, r *http.Request) ([]importer.File, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUploadBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return []importer.File{{Data: data}}, nil
+	}
+
+	if err := r.ParseMultipartForm(maxImportUploadBytes); err != nil {
+		return nil, err
+	}
+	headers := r.MultipartForm.File["file"]
+	if len(headers) == 0 {
+		return nil, errors.New("multipart upload needs a \"file\" field")
+	}
+	files := make([]importer.File, 0, len(headers))
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, importer.File{Name: header.Filename, Data: data})
+	}
+	return files, nil
+}
+
+// responds 413 if the upload was too big, 400 otherwise
+func respondWithUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("upload can be at most %d bytes", maxImportUploadBytes))
+		return
+	}
+	respondWithError(w, http.StatusBadRequest, err)
+}
+
+// runs a small import right away and responds with its report,
+// bigger ones become an import job and respond 202 with the job
+func (apiCfg apiConfig) startImport(w http.ResponseWriter, user database.User, kind string, export importer.Export) {
+	total := len(export.Subscriptions) + len(export.Items)
+	if total <= maxSyncImportEntries {
+		respondWithJSON(w, http.StatusOK, apiCfg.importExport(context.Background(), user, export, nil))
+		return
+	}
+
+	input, err := json.Marshal(export)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	job, err := apiCfg.DB.CreateImportJob(context.Background(), database.CreateImportJobParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Kind:      kind,
+		Input:     input,
+		Total:     int32(total),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/imports/"+job.ID.String())
+	respondWithJSON(w, http.StatusAccepted, newImportJobResponse(job))
+}
+
+// POST /v1/opml
+// authed
+// expects an OPML document, as the body or as the "file" field of a multipart form
+// creates missing feeds, follows them and puts them in folders named after the outlines they are nested in
+// feeds that are already followed are left as they are, except for being put in the folders
+// up to 50 feeds respond with the report of every outline,
+// more respond 202 with an import job to poll at GET /v1/imports/{jobID}
+func (apiCfg apiConfig) importOPMLHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	files, err := readUploads(w, r)
+	if err != nil {
+		respondWithUploadError(w, err)
+		return
+	}
+
+	export, err := importer.OPML{}.Import(files)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	apiCfg.startImport(w, user, importKindOPML, *export)
+}
+
+// POST /v1/imports?format={format}
+// authed
+// expects the export of another reader, as the body or as one or more "file" fields of a multipart form
+// formats are opml, feedly, greader (Inoreader and other Google Reader api exports), miniflux and freshrss
+// subscriptions are imported like POST /v1/opml, starred items are starred and read items marked read,
+// their posts are created if they aren't in the db yet and their feeds if those aren't, without following them
+// responds like POST /v1/opml
+func (apiCfg apiConfig) importHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	imp, ok := importer.Get(r.URL.Query().Get("format"))
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("format must be one of %s", strings.Join(importer.Formats(), ", ")))
+		return
+	}
+
+	files, err := readUploads(w, r)
+	if err != nil {
+		respondWithUploadError(w, err)
+		return
+	}
+
+	export, err := imp.Import(files)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	apiCfg.startImport(w, user, imp.Format(), *export)
+}
+
+// GET /v1/imports/{jobID}
+// authed
+// get an import job, the report is null until it's done
+// 404 if the job isn't the user's
+func (apiCfg apiConfig) getImportJobHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	jobID, err := uuidFromURL(r, "jobID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job, err := apiCfg.DB.GetImportJob(context.Background(), database.GetImportJobParams{
+		ID:     jobID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("import job not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newImportJobResponse(job))
+}
+
+// runs one claimed import job to the end
+func (apiCfg apiConfig) runImportJob(job database.ImportJob) {
+	ctx := context.Background()
+	finish := func(status string, report importReport, processed int, errMsg string) {
+		dat := json.RawMessage("null")
+		if status == importStatusDone {
+			dat, _ = json.Marshal(report)
+		}
+		err := apiCfg.DB.FinishImportJob(ctx, database.FinishImportJobParams{
+			ID:        job.ID,
+			Status:    status,
+			Processed: int32(processed),
+			Report:    dat,
+			Error:     errMsg,
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Println("importWorker: ", err)
+		}
+	}
+
+	user, err := apiCfg.DB.GetUserByID(ctx, job.UserID)
+	if err != nil {
+		finish(importStatusFailed, importReport{}, 0, err.Error())
+		return
+	}
+	export := importer.Export{}
+	if err := json.Unmarshal(job.Input, &export); err != nil {
+		finish(importStatusFailed, importReport{}, 0, err.Error())
+		return
+	}
+
+	report := apiCfg.importExport(ctx, user, export, func(processed int) {
+		if processed%importProgressInterval != 0 {
+			return
+		}
+		err := apiCfg.DB.UpdateImportJobProgress(ctx, database.UpdateImportJobProgressParams{
+			ID:        job.ID,
+			Processed: int32(processed),
+			UpdatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Println("importWorker: ", err)
+		}
+	})
+	finish(importStatusDone, report, int(job.Total), "")
+}
+
+// worker that runs pending import jobs one at a time
+// jobs that were running when the server stopped are started over
+func (apiCfg apiConfig) importWorker(delay int) {
+	requeued, err := apiCfg.DB.RequeueRunningImportJobs(context.Background(), time.Now())
+	if err != nil {
+		log.Println("importWorker: ", err)
+	} else if requeued > 0 {
+		log.Printf("importWorker: restarting %d interrupted import jobs\n", requeued)
+	}
+
+	go func(delay int) {
+		for {
+			job, err := apiCfg.DB.ClaimNextImportJob(context.Background(), time.Now())
+			if err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					log.Println("importWorker: ", err)
+				}
+				// nothing to do, wait before checking again
+				time.Sleep(time.Duration(delay) * time.Second)
+				continue
+			}
+			log.Printf("importWorker: running import job %s with %d entries\n", job.ID, job.Total)
+			apiCfg.runImportJob(job)
+		}
+	}(delay)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestImportOPML(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	base := "https://example.com/" + uuid.NewString()
+	doc := fmt.Sprintf(`<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Top" xmlUrl="%[1]s/top.xml"/>
+    <outline text="Tech">
+      <outline text="Go" xmlUrl="%[1]s/go.xml"/>
+      <outline text="Languages">
+        <outline text="Rust" xmlUrl="%[1]s/rust.xml"/>
+      </outline>
+    </outline>
+    <outline text="Bad" xmlUrl="ftp://example.com/feed.xml"/>
+  </body>
+</opml>`, base)
+
+	imported, err := alice.ImportOPML(ctx, strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := imported.Report
+	if report == nil {
+		t.Fatalf("a small import should respond with its report, got %+v", imported)
+	}
+	if report.Created != 3 || report.Invalid != 1 || report.AlreadyFollowed != 0 || report.Failed != 0 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+	wantStatuses := []string{client.ImportCreated, client.ImportCreated, client.ImportCreated, client.ImportInvalid}
+	for i, result := range report.Results {
+		if result.Status != wantStatuses[i] {
+			t.Errorf("result %d: got status %q, want %q", i, result.Status, wantStatuses[i])
+		}
+	}
+
+	folders, err := alice.ListFolders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, folder := range folders {
+		names = append(names, folder.Name)
+		if len(folder.FeedFollowIDs) != 1 {
+			t.Errorf("folder %q should hold one feed follow, got %d", folder.Name, len(folder.FeedFollowIDs))
+		}
+	}
+	if strings.Join(names, ",") != "Tech,Tech / Languages" {
+		t.Errorf("got folders %v", names)
+	}
+
+	// importing again follows nothing new
+	imported, err = alice.ImportOPML(ctx, strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Report == nil || imported.Report.AlreadyFollowed != 3 || imported.Report.Created != 0 {
+		t.Errorf("expected everything to be already followed, got %+v", imported.Report)
+	}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 3 {
+		t.Errorf("expected 3 follows, got %d", len(follows))
+	}
+
+	if _, err := alice.ImportOPML(ctx, strings.NewReader("not opml")); err == nil {
+		t.Error("expected an error for a file that isn't OPML")
+	}
+}
+
+func TestImportFromOtherReaders(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	base := "https://example.com/" + uuid.NewString()
+	subscriptions := fmt.Sprintf(`{"subscriptions": [
+		{"id": "feed/%[1]s/go.xml", "title": "Go", "categories": [{"id": "user/1/label/Tech", "label": "Tech"}]}
+	]}`, base)
+	starred := fmt.Sprintf(`{"id": "user/1/state/com.google/starred", "items": [
+		{"title": "Starred", "published": 1691539200, "alternate": [{"href": "%[1]s/starred"}],
+		 "categories": ["user/1/state/com.google/read"], "origin": {"streamId": "feed/%[1]s/unfollowed.xml", "title": "Unfollowed"}},
+		{"title": "No url", "origin": {"streamId": "feed/%[1]s/go.xml"}}
+	]}`, base)
+
+	imported, err := alice.Import(ctx, client.FormatGoogleReader,
+		client.ImportFile{Name: "subscriptions.json", Body: strings.NewReader(subscriptions)},
+		client.ImportFile{Name: "starred.json", Body: strings.NewReader(starred)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := imported.Report
+	if report == nil {
+		t.Fatalf("a small import should respond with its report, got %+v", imported)
+	}
+	if report.Created != 1 || report.Starred != 1 || report.Read != 1 || report.ItemsInvalid != 1 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+	if len(report.ItemResults) != 2 || report.ItemResults[0].Status != client.ImportImported || report.ItemResults[0].PostID == nil {
+		t.Fatalf("unexpected item results %+v", report.ItemResults)
+	}
+
+	// the starred post is in the timeline even though its feed isn't followed
+	starredOnly := true
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{Starred: &starredOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != *report.ItemResults[0].PostID || !page.Items[0].Read {
+		t.Errorf("expected the imported post starred and read, got %+v", page.Items)
+	}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 {
+		t.Errorf("items shouldn't follow their feeds, got %d follows", len(follows))
+	}
+
+	// importing again finds the same post
+	imported, err = alice.Import(ctx, client.FormatGoogleReader, client.ImportFile{Name: "starred.json", Body: strings.NewReader(starred)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Report == nil || len(imported.Report.ItemResults) != 2 || *imported.Report.ItemResults[0].PostID != *report.ItemResults[0].PostID {
+		t.Errorf("expected the same post, got %+v", imported.Report)
+	}
+
+	if _, err := alice.Import(ctx, "netnewswire", client.ImportFile{Name: "x", Body: strings.NewReader("{}")}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// an export can star posts of feeds other users created, but can't add posts to them
+func TestImportItemsOfOthersFeeds(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	feed, posts := newTestFeedWithPosts(t, apiCfg, alice, "Real")
+	starred := fmt.Sprintf(`{"id": "user/1/state/com.google/starred", "items": [
+		{"title": "Real", "alternate": [{"href": %[2]q}], "origin": {"streamId": "feed/%[1]s"}},
+		{"title": "Made up", "alternate": [{"href": %[3]q}], "summary": {"content": "fake news"}, "origin": {"streamId": "feed/%[1]s"}},
+		{"title": "Script", "alternate": [{"href": "javascript:alert(1)"}], "origin": {"streamId": "feed/%[1]s"}}
+	]}`, feed.URL, posts[0].URL, feed.URL+"/made-up")
+
+	imported, err := bob.Import(ctx, client.FormatGoogleReader, client.ImportFile{Name: "starred.json", Body: strings.NewReader(starred)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := imported.Report
+	if report == nil || len(report.ItemResults) != 3 {
+		t.Fatalf("got %+v", imported)
+	}
+	if result := report.ItemResults[0]; result.Status != client.ImportImported || result.PostID == nil || *result.PostID != posts[0].ID {
+		t.Errorf("got %+v, want the existing post starred", result)
+	}
+	if result := report.ItemResults[1]; result.Status != client.ImportNotFound || result.PostID != nil {
+		t.Errorf("got %+v, want the made up post not found", result)
+	}
+	if result := report.ItemResults[2]; result.Status != client.ImportInvalid {
+		t.Errorf("got %+v, want the javascript url invalid", result)
+	}
+	if report.Starred != 1 || report.ItemsNotFound != 1 || report.ItemsInvalid != 1 {
+		t.Errorf("unexpected counts %+v", report)
+	}
+
+	// the followers of the feed don't see the made up post
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != posts[0].ID {
+		t.Errorf("got posts %+v", page.Items)
+	}
+}
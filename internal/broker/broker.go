@@ -0,0 +1,95 @@
+// Package broker fans events out to the streams listening for them.
+//
+// Events are written to the db before they are published, with an id that grows,
+// so a stream that missed some can catch up from the db. Memory only reaches the
+// subscribers in this process. A Broker backed by Postgres LISTEN/NOTIFY, publishing
+// the ids of new events and loading them on every replica, can take its place.
+package broker
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event is something that happened to a user or to a feed.
+type Event struct {
+	ID   int64
+	Kind string
+	// UserID is set for events that only concern one user.
+	UserID uuid.NullUUID
+	// FeedID is set for events that concern everyone following the feed.
+	FeedID uuid.NullUUID
+	// PostID is set for events about a post.
+	PostID uuid.NullUUID
+	Data   json.RawMessage
+}
+
+// Broker delivers published events to every subscription.
+type Broker interface {
+	Publish(event Event)
+	// Subscribe buffers up to buffer events for a subscriber that is busy.
+	Subscribe(buffer int) *Subscription
+}
+
+// Subscription receives published events until it's closed.
+type Subscription struct {
+	events <-chan Event
+	once   sync.Once
+	cancel func()
+}
+
+// Events is closed when the subscription is, or when the subscriber fell more than
+// its buffer behind, in which case it should catch up from the db.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription, it can be called more than once.
+func (s *Subscription) Close() {
+	s.once.Do(s.cancel)
+}
+
+// Memory is a Broker for the subscribers in this process.
+type Memory struct {
+	mu   sync.Mutex
+	subs map[*Subscription]chan Event
+}
+
+// NewMemory makes a broker without subscribers.
+func NewMemory() *Memory {
+	return &Memory{subs: map[*Subscription]chan Event{}}
+}
+
+// Publish never blocks, subscribers that can't keep up are dropped.
+func (m *Memory) Publish(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sub, ch := range m.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(m.subs, sub)
+			close(ch)
+		}
+	}
+}
+
+func (m *Memory) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{events: ch}
+	sub.cancel = func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subs[sub]; ok {
+			delete(m.subs, sub)
+			close(ch)
+		}
+	}
+
+	m.mu.Lock()
+	m.subs[sub] = ch
+	m.mu.Unlock()
+	return sub
+}
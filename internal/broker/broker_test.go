@@ -0,0 +1,43 @@
+package broker
+
+import "testing"
+
+func TestMemoryDelivers(t *testing.T) {
+	m := NewMemory()
+	a := m.Subscribe(10)
+	b := m.Subscribe(10)
+	defer a.Close()
+
+	m.Publish(Event{ID: 1, Kind: "new_post"})
+	for _, sub := range []*Subscription{a, b} {
+		if event := <-sub.Events(); event.ID != 1 {
+			t.Errorf("got event %+v", event)
+		}
+	}
+
+	b.Close()
+	b.Close()
+	if _, ok := <-b.Events(); ok {
+		t.Error("expected a closed subscription's channel to be closed")
+	}
+	m.Publish(Event{ID: 2})
+	if event := <-a.Events(); event.ID != 2 {
+		t.Errorf("got event %+v", event)
+	}
+}
+
+func TestMemoryDropsSlowSubscribers(t *testing.T) {
+	m := NewMemory()
+	slow := m.Subscribe(1)
+	m.Publish(Event{ID: 1})
+	m.Publish(Event{ID: 2})
+
+	if event, ok := <-slow.Events(); !ok || event.ID != 1 {
+		t.Errorf("expected the buffered event first, got %+v, %v", event, ok)
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("expected a subscriber that fell behind to be closed")
+	}
+	// closing after being dropped is fine
+	slow.Close()
+}
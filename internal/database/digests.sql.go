@@ -0,0 +1,667 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: digests.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addDigestPosts = `-- name: AddDigestPosts :exec
+INSERT INTO digest_posts (digest_id, user_id, post_id)
+SELECT $1, $2, unnest($3::uuid[])
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type AddDigestPostsParams struct {
+	DigestID uuid.UUID
+	UserID   uuid.UUID
+	PostIds  []uuid.UUID
+}
+
+func (q *Queries) AddDigestPosts(ctx context.Context, arg AddDigestPostsParams) error {
+	_, err := q.db.ExecContext(ctx, addDigestPosts, arg.DigestID, arg.UserID, pq.Array(arg.PostIds))
+	return err
+}
+
+const claimDigestSubscription = `-- name: ClaimDigestSubscription :execrows
+UPDATE digest_subscriptions
+SET next_send_at = $1
+WHERE user_id = $2 AND next_send_at = $3
+`
+
+type ClaimDigestSubscriptionParams struct {
+	NextSendAt time.Time
+	UserID     uuid.UUID
+	DueAt      time.Time
+}
+
+// moves a due subscription to its next send time, 0 rows if another worker got to it first
+func (q *Queries) ClaimDigestSubscription(ctx context.Context, arg ClaimDigestSubscriptionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, claimDigestSubscription, arg.NextSendAt, arg.UserID, arg.DueAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimDueDigests = `-- name: ClaimDueDigests :many
+UPDATE digests
+SET status = 'sending'
+WHERE id IN (
+    SELECT digests.id FROM digests
+    WHERE digests.status = 'pending' AND digests.next_attempt_at <= $1::timestamp
+    ORDER BY digests.next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, email, frequency, sent_at, status, attempts, next_attempt_at, error, subject, text_body, html_body, created_at
+`
+
+type ClaimDueDigestsParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+// marks the pending digests that are due to be sent again as sending, safe to call from several workers
+func (q *Queries) ClaimDueDigests(ctx context.Context, arg ClaimDueDigestsParams) ([]Digest, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueDigests, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Digest
+	for rows.Next() {
+		var i Digest
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Email,
+			&i.Frequency,
+			&i.SentAt,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.Error,
+			&i.Subject,
+			&i.TextBody,
+			&i.HtmlBody,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const confirmDigestSubscription = `-- name: ConfirmDigestSubscription :exec
+UPDATE digest_subscriptions
+SET confirm_token = NULL, confirmed_at = $2
+WHERE user_id = $1
+`
+
+type ConfirmDigestSubscriptionParams struct {
+	UserID      uuid.UUID
+	ConfirmedAt sql.NullTime
+}
+
+func (q *Queries) ConfirmDigestSubscription(ctx context.Context, arg ConfirmDigestSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, confirmDigestSubscription, arg.UserID, arg.ConfirmedAt)
+	return err
+}
+
+const createDigest = `-- name: CreateDigest :one
+INSERT INTO digests (id, user_id, email, frequency, subject, text_body, html_body, status, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, 'sending', $8)
+RETURNING id, user_id, email, frequency, sent_at, status, attempts, next_attempt_at, error, subject, text_body, html_body, created_at
+`
+
+type CreateDigestParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Email     string
+	Frequency string
+	Subject   string
+	TextBody  string
+	HtmlBody  string
+	CreatedAt time.Time
+}
+
+// the digest is sending, by the worker that records it
+func (q *Queries) CreateDigest(ctx context.Context, arg CreateDigestParams) (Digest, error) {
+	row := q.db.QueryRowContext(ctx, createDigest,
+		arg.ID,
+		arg.UserID,
+		arg.Email,
+		arg.Frequency,
+		arg.Subject,
+		arg.TextBody,
+		arg.HtmlBody,
+		arg.CreatedAt,
+	)
+	var i Digest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SentAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.Error,
+		&i.Subject,
+		&i.TextBody,
+		&i.HtmlBody,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteDigestPostsOfDigest = `-- name: DeleteDigestPostsOfDigest :exec
+DELETE FROM digest_posts
+WHERE digest_id = $1
+`
+
+// the posts of a digest that failed can be in the next one
+func (q *Queries) DeleteDigestPostsOfDigest(ctx context.Context, digestID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteDigestPostsOfDigest, digestID)
+	return err
+}
+
+const deleteDigestSubscription = `-- name: DeleteDigestSubscription :execrows
+DELETE FROM digest_subscriptions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteDigestSubscription(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDigestSubscription, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteDigestSubscriptionByToken = `-- name: DeleteDigestSubscriptionByToken :execrows
+DELETE FROM digest_subscriptions
+WHERE unsubscribe_token = $1
+`
+
+func (q *Queries) DeleteDigestSubscriptionByToken(ctx context.Context, unsubscribeToken string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDigestSubscriptionByToken, unsubscribeToken)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishDigestAttempt = `-- name: FinishDigestAttempt :one
+UPDATE digests
+SET status = $1, attempts = attempts + 1, next_attempt_at = $2,
+    error = $3, sent_at = $4,
+    text_body = CASE WHEN $1 = 'pending' THEN text_body ELSE '' END,
+    html_body = CASE WHEN $1 = 'pending' THEN html_body ELSE '' END
+WHERE id = $5
+RETURNING id, user_id, email, frequency, sent_at, status, attempts, next_attempt_at, error, subject, text_body, html_body, created_at
+`
+
+type FinishDigestAttemptParams struct {
+	Status        string
+	NextAttemptAt sql.NullTime
+	Error         string
+	SentAt        sql.NullTime
+	ID            uuid.UUID
+}
+
+// the bodies are only kept while the digest is to be sent again
+func (q *Queries) FinishDigestAttempt(ctx context.Context, arg FinishDigestAttemptParams) (Digest, error) {
+	row := q.db.QueryRowContext(ctx, finishDigestAttempt,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.Error,
+		arg.SentAt,
+		arg.ID,
+	)
+	var i Digest
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SentAt,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.Error,
+		&i.Subject,
+		&i.TextBody,
+		&i.HtmlBody,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getDigestPosts = `-- name: GetDigestPosts :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed,
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_title,
+    COALESCE((
+        SELECT folders.name FROM folders
+        JOIN feed_follow_folders ON feed_follow_folders.folder_id = folders.id
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+        ORDER BY folders.position
+        LIMIT 1
+    ), '')::text AS folder_name,
+    COALESCE((
+        SELECT folders.position FROM folders
+        JOIN feed_follow_folders ON feed_follow_folders.folder_id = folders.id
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+        ORDER BY folders.position
+        LIMIT 1
+    ), 2147483647)::int AS folder_position
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE feed_follows.notify = 'all'
+    AND posts.created_at > $2
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = $1 AND post_reads.post_id = posts.id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM digest_posts
+        WHERE digest_posts.user_id = $1 AND digest_posts.post_id = posts.id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1 AND filter_rules.hide
+    )
+ORDER BY folder_position, folder_name, feed_title, posts.published_at DESC
+LIMIT $3
+`
+
+type GetDigestPostsParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+	Limit  int32
+}
+
+type GetDigestPostsRow struct {
+	Post           Post
+	FeedTitle      string
+	FolderName     string
+	FolderPosition int32
+}
+
+// unread posts of the feeds the user follows and gets notified about, that weren't in a digest yet
+// and aren't hidden by the user's filter rules
+// each post is under the first of the user's folders its feed is in, or under none
+func (q *Queries) GetDigestPosts(ctx context.Context, arg GetDigestPostsParams) ([]GetDigestPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPosts, arg.UserID, arg.Since, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestPostsRow
+	for rows.Next() {
+		var i GetDigestPostsRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Author,
+			pq.Array(&i.Post.Categories),
+			&i.Post.Content,
+			&i.Post.SearchVector,
+			&i.Post.DescriptionText,
+			&i.Post.Sanitized,
+			&i.Post.FullContent,
+			&i.Post.FullContentStatus,
+			&i.Post.Pushed,
+			&i.FeedTitle,
+			&i.FolderName,
+			&i.FolderPosition,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDigestSubscription = `-- name: GetDigestSubscription :one
+SELECT user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, base_url, next_send_at, last_sent_at, created_at, updated_at, confirm_token, confirmed_at FROM digest_subscriptions
+WHERE user_id = $1
+`
+
+func (q *Queries) GetDigestSubscription(ctx context.Context, userID uuid.UUID) (DigestSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getDigestSubscription, userID)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SendTime,
+		&i.Timezone,
+		&i.Weekday,
+		&i.UnsubscribeToken,
+		&i.BaseUrl,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ConfirmToken,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
+
+const getDigestSubscriptionByConfirmToken = `-- name: GetDigestSubscriptionByConfirmToken :one
+SELECT user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, base_url, next_send_at, last_sent_at, created_at, updated_at, confirm_token, confirmed_at FROM digest_subscriptions
+WHERE confirm_token = $1
+`
+
+func (q *Queries) GetDigestSubscriptionByConfirmToken(ctx context.Context, confirmToken sql.NullString) (DigestSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getDigestSubscriptionByConfirmToken, confirmToken)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SendTime,
+		&i.Timezone,
+		&i.Weekday,
+		&i.UnsubscribeToken,
+		&i.BaseUrl,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ConfirmToken,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
+
+const getDigestSubscriptionByToken = `-- name: GetDigestSubscriptionByToken :one
+SELECT user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, base_url, next_send_at, last_sent_at, created_at, updated_at, confirm_token, confirmed_at FROM digest_subscriptions
+WHERE unsubscribe_token = $1
+`
+
+func (q *Queries) GetDigestSubscriptionByToken(ctx context.Context, unsubscribeToken string) (DigestSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getDigestSubscriptionByToken, unsubscribeToken)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SendTime,
+		&i.Timezone,
+		&i.Weekday,
+		&i.UnsubscribeToken,
+		&i.BaseUrl,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ConfirmToken,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
+
+const getDigests = `-- name: GetDigests :many
+SELECT
+    digests.id, digests.user_id, digests.email, digests.frequency, digests.sent_at, digests.status, digests.attempts, digests.next_attempt_at, digests.error, digests.subject, digests.text_body, digests.html_body, digests.created_at,
+    ARRAY(
+        SELECT digest_posts.post_id FROM digest_posts
+        WHERE digest_posts.digest_id = digests.id
+        ORDER BY digest_posts.post_id
+    )::uuid[] AS post_ids
+FROM digests
+WHERE digests.user_id = $1
+ORDER BY digests.created_at DESC
+LIMIT $2
+`
+
+type GetDigestsParams struct {
+	UserID uuid.UUID
+	Limit  int32
+}
+
+type GetDigestsRow struct {
+	Digest  Digest
+	PostIds []uuid.UUID
+}
+
+func (q *Queries) GetDigests(ctx context.Context, arg GetDigestsParams) ([]GetDigestsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigests, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestsRow
+	for rows.Next() {
+		var i GetDigestsRow
+		if err := rows.Scan(
+			&i.Digest.ID,
+			&i.Digest.UserID,
+			&i.Digest.Email,
+			&i.Digest.Frequency,
+			&i.Digest.SentAt,
+			&i.Digest.Status,
+			&i.Digest.Attempts,
+			&i.Digest.NextAttemptAt,
+			&i.Digest.Error,
+			&i.Digest.Subject,
+			&i.Digest.TextBody,
+			&i.Digest.HtmlBody,
+			&i.Digest.CreatedAt,
+			pq.Array(&i.PostIds),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDueDigestSubscriptions = `-- name: GetDueDigestSubscriptions :many
+SELECT user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, base_url, next_send_at, last_sent_at, created_at, updated_at, confirm_token, confirmed_at FROM digest_subscriptions
+WHERE next_send_at <= $1 AND confirmed_at IS NOT NULL
+ORDER BY next_send_at
+LIMIT $2
+`
+
+type GetDueDigestSubscriptionsParams struct {
+	NextSendAt time.Time
+	Limit      int32
+}
+
+// only the confirmed ones get digests
+func (q *Queries) GetDueDigestSubscriptions(ctx context.Context, arg GetDueDigestSubscriptionsParams) ([]DigestSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getDueDigestSubscriptions, arg.NextSendAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DigestSubscription
+	for rows.Next() {
+		var i DigestSubscription
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Email,
+			&i.Frequency,
+			&i.SendTime,
+			&i.Timezone,
+			&i.Weekday,
+			&i.UnsubscribeToken,
+			&i.BaseUrl,
+			&i.NextSendAt,
+			&i.LastSentAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ConfirmToken,
+			&i.ConfirmedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requeueSendingDigests = `-- name: RequeueSendingDigests :execrows
+UPDATE digests
+SET status = 'pending', next_attempt_at = $1
+WHERE status = 'sending'
+`
+
+// digests that were being sent when the server stopped are sent again
+func (q *Queries) RequeueSendingDigests(ctx context.Context, nextAttemptAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueSendingDigests, nextAttemptAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setDigestLastSentAt = `-- name: SetDigestLastSentAt :exec
+UPDATE digest_subscriptions
+SET last_sent_at = $2
+WHERE user_id = $1
+`
+
+type SetDigestLastSentAtParams struct {
+	UserID     uuid.UUID
+	LastSentAt sql.NullTime
+}
+
+func (q *Queries) SetDigestLastSentAt(ctx context.Context, arg SetDigestLastSentAtParams) error {
+	_, err := q.db.ExecContext(ctx, setDigestLastSentAt, arg.UserID, arg.LastSentAt)
+	return err
+}
+
+const setDigestNextSendAt = `-- name: SetDigestNextSendAt :exec
+UPDATE digest_subscriptions
+SET next_send_at = $2
+WHERE user_id = $1
+`
+
+type SetDigestNextSendAtParams struct {
+	UserID     uuid.UUID
+	NextSendAt time.Time
+}
+
+func (q *Queries) SetDigestNextSendAt(ctx context.Context, arg SetDigestNextSendAtParams) error {
+	_, err := q.db.ExecContext(ctx, setDigestNextSendAt, arg.UserID, arg.NextSendAt)
+	return err
+}
+
+const upsertDigestSubscription = `-- name: UpsertDigestSubscription :one
+INSERT INTO digest_subscriptions (
+    user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, confirm_token, base_url, next_send_at, created_at, updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email, frequency = EXCLUDED.frequency, send_time = EXCLUDED.send_time,
+    timezone = EXCLUDED.timezone, weekday = EXCLUDED.weekday, base_url = EXCLUDED.base_url,
+    next_send_at = EXCLUDED.next_send_at, updated_at = EXCLUDED.updated_at,
+    confirm_token = CASE
+        WHEN digest_subscriptions.email = EXCLUDED.email AND digest_subscriptions.confirmed_at IS NOT NULL THEN NULL
+        ELSE EXCLUDED.confirm_token
+    END,
+    confirmed_at = CASE
+        WHEN digest_subscriptions.email = EXCLUDED.email THEN digest_subscriptions.confirmed_at
+        ELSE NULL
+    END
+RETURNING user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, base_url, next_send_at, last_sent_at, created_at, updated_at, confirm_token, confirmed_at
+`
+
+type UpsertDigestSubscriptionParams struct {
+	UserID           uuid.UUID
+	Email            string
+	Frequency        string
+	SendTime         string
+	Timezone         string
+	Weekday          int32
+	UnsubscribeToken string
+	ConfirmToken     sql.NullString
+	BaseUrl          string
+	NextSendAt       time.Time
+	CreatedAt        time.Time
+}
+
+// changing the schedule keeps the unsubscribe link and when the last digest was sent
+// a new address has to be confirmed again, and an unconfirmed one gets a new confirmation link
+func (q *Queries) UpsertDigestSubscription(ctx context.Context, arg UpsertDigestSubscriptionParams) (DigestSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertDigestSubscription,
+		arg.UserID,
+		arg.Email,
+		arg.Frequency,
+		arg.SendTime,
+		arg.Timezone,
+		arg.Weekday,
+		arg.UnsubscribeToken,
+		arg.ConfirmToken,
+		arg.BaseUrl,
+		arg.NextSendAt,
+		arg.CreatedAt,
+	)
+	var i DigestSubscription
+	err := row.Scan(
+		&i.UserID,
+		&i.Email,
+		&i.Frequency,
+		&i.SendTime,
+		&i.Timezone,
+		&i.Weekday,
+		&i.UnsubscribeToken,
+		&i.BaseUrl,
+		&i.NextSendAt,
+		&i.LastSentAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ConfirmToken,
+		&i.ConfirmedAt,
+	)
+	return i, err
+}
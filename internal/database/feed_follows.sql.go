@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,8 @@ import (
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, feed_id, user_id, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, feed_id, user_id, created_at, updated_at
+ON CONFLICT (user_id, feed_id) DO NOTHING
+RETURNING id, feed_id, user_id, created_at, updated_at, title, pinned, notify, hide_from_timeline
 `
 
 type CreateFeedFollowParams struct {
@@ -26,6 +28,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 }
 
+// returns no rows if the user already follows the feed
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (FeedFollow, error) {
 	row := q.db.QueryRowContext(ctx, createFeedFollow,
 		arg.ID,
@@ -41,24 +44,105 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Title,
+		&i.Pinned,
+		&i.Notify,
+		&i.HideFromTimeline,
 	)
 	return i, err
 }
 
-const deleteFeedFollow = `-- name: DeleteFeedFollow :exec
+const deleteFeedFollow = `-- name: DeleteFeedFollow :execrows
 DELETE FROM feed_follows
-WHERE id = $1
+WHERE id = $1 AND user_id = $2
 `
 
-func (q *Queries) DeleteFeedFollow(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteFeedFollow, id)
+type DeleteFeedFollowParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollow, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedFollowByFeed = `-- name: DeleteFeedFollowByFeed :exec
+DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type DeleteFeedFollowByFeedParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollowByFeed(ctx context.Context, arg DeleteFeedFollowByFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFollowByFeed, arg.UserID, arg.FeedID)
 	return err
 }
 
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, feed_id, user_id, created_at, updated_at, title, pinned, notify, hide_from_timeline FROM feed_follows
+WHERE id = $1 AND user_id = $2
+`
+
+type GetFeedFollowParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.ID, arg.UserID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.FeedID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Pinned,
+		&i.Notify,
+		&i.HideFromTimeline,
+	)
+	return i, err
+}
+
+const getFeedFollowByFeed = `-- name: GetFeedFollowByFeed :one
+SELECT id, feed_id, user_id, created_at, updated_at, title, pinned, notify, hide_from_timeline FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowByFeedParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollowByFeed(ctx context.Context, arg GetFeedFollowByFeedParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowByFeed, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.FeedID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Pinned,
+		&i.Notify,
+		&i.HideFromTimeline,
+	)
+	return i, err
+}
+
 const getFeedFollows = `-- name: GetFeedFollows :many
-SELECT id, feed_id, user_id, created_at, updated_at FROM feed_follows
+SELECT id, feed_id, user_id, created_at, updated_at, title, pinned, notify, hide_from_timeline FROM feed_follows
 WHERE user_id = $1
-ORDER BY id
+ORDER BY pinned DESC, id
 `
 
 func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
@@ -76,6 +160,10 @@ func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedF
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Title,
+			&i.Pinned,
+			&i.Notify,
+			&i.HideFromTimeline,
 		); err != nil {
 			return nil, err
 		}
@@ -89,3 +177,92 @@ func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedF
 	}
 	return items, nil
 }
+
+const moveFeedFollowFoldersToFeed = `-- name: MoveFeedFollowFoldersToFeed :exec
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT to_follows.id, feed_follow_folders.folder_id
+FROM feed_follow_folders
+JOIN feed_follows AS from_follows ON from_follows.id = feed_follow_folders.feed_follow_id
+JOIN feed_follows AS to_follows ON to_follows.user_id = from_follows.user_id AND to_follows.feed_id = $1
+WHERE from_follows.feed_id = $2
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING
+`
+
+type MoveFeedFollowFoldersToFeedParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// users following both feeds keep the folders of the follow that is going away
+func (q *Queries) MoveFeedFollowFoldersToFeed(ctx context.Context, arg MoveFeedFollowFoldersToFeedParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollowFoldersToFeed, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const moveFeedFollowsToFeed = `-- name: MoveFeedFollowsToFeed :exec
+WITH dropped AS (
+    DELETE FROM feed_follows AS from_follows
+    USING feed_follows AS to_follows
+    WHERE from_follows.feed_id = $2
+        AND to_follows.feed_id = $1
+        AND to_follows.user_id = from_follows.user_id
+    RETURNING from_follows.id
+)
+UPDATE feed_follows
+SET feed_id = $1
+WHERE feed_follows.feed_id = $2
+    AND feed_follows.id NOT IN (SELECT dropped.id FROM dropped)
+`
+
+type MoveFeedFollowsToFeedParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// follows of users that already follow the other feed are dropped
+func (q *Queries) MoveFeedFollowsToFeed(ctx context.Context, arg MoveFeedFollowsToFeedParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollowsToFeed, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const updateFeedFollowSettings = `-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title = $3, pinned = $4, notify = $5, hide_from_timeline = $6, updated_at = $7
+WHERE id = $1 AND user_id = $2
+RETURNING id, feed_id, user_id, created_at, updated_at, title, pinned, notify, hide_from_timeline
+`
+
+type UpdateFeedFollowSettingsParams struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	Title            sql.NullString
+	Pinned           bool
+	Notify           string
+	HideFromTimeline bool
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) UpdateFeedFollowSettings(ctx context.Context, arg UpdateFeedFollowSettingsParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollowSettings,
+		arg.ID,
+		arg.UserID,
+		arg.Title,
+		arg.Pinned,
+		arg.Notify,
+		arg.HideFromTimeline,
+		arg.UpdatedAt,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.FeedID,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Pinned,
+		&i.Notify,
+		&i.HideFromTimeline,
+	)
+	return i, err
+}
@@ -7,6 +7,8 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +17,7 @@ import (
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
 `
 
 type CreateFeedParams struct {
@@ -45,15 +47,205 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const createPushFeed = `-- name: CreatePushFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, kind, write_token, invite_token)
+VALUES ($1, $2, $3, $4, $5, $6, 'push', $7, $8)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type CreatePushFeedParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	WriteToken  sql.NullString `json:"-"`
+	InviteToken sql.NullString `json:"-"`
+}
+
+func (q *Queries) CreatePushFeed(ctx context.Context, arg CreatePushFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, createPushFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		arg.WriteToken,
+		arg.InviteToken,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const createScrapedFeed = `-- name: CreateScrapedFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, kind, selectors)
+VALUES ($1, $2, $3, $4, $5, $6, 'scraped', $7)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type CreateScrapedFeedParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Url       string
+	UserID    uuid.UUID
+	Selectors json.RawMessage
+}
+
+func (q *Queries) CreateScrapedFeed(ctx context.Context, arg CreateScrapedFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, createScrapedFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		arg.Selectors,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+// posts that are starred or saved have to be unstarred and unsaved first
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const deletePostStarsOfFeed = `-- name: DeletePostStarsOfFeed :exec
+DELETE FROM post_stars
+USING posts
+WHERE posts.id = post_stars.post_id AND posts.feed_id = $1
+`
+
+func (q *Queries) DeletePostStarsOfFeed(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deletePostStarsOfFeed, feedID)
+	return err
+}
+
+const deleteSavedPostsOfFeed = `-- name: DeleteSavedPostsOfFeed :exec
+DELETE FROM saved_posts
+USING posts
+WHERE posts.id = saved_posts.post_id AND posts.feed_id = $1
+`
+
+func (q *Queries) DeleteSavedPostsOfFeed(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteSavedPostsOfFeed, feedID)
+	return err
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token FROM feeds
+WHERE url = $1
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token FROM feeds
+WHERE kind <> 'push'
 ORDER BY id
 `
 
+// push feeds are private, they're followed by id
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	rows, err := q.db.QueryContext(ctx, getFeeds)
 	if err != nil {
@@ -71,6 +263,13 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.SiteUrl,
+			&i.FetchFullContent,
+			&i.ContentSelector,
+			&i.Kind,
+			&i.Selectors,
+			&i.WriteToken,
+			&i.InviteToken,
 		); err != nil {
 			return nil, err
 		}
@@ -84,3 +283,271 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	}
 	return items, nil
 }
+
+const getNextFeedOwner = `-- name: GetNextFeedOwner :one
+SELECT users.id
+FROM users
+LEFT JOIN feed_follows ON feed_follows.user_id = users.id AND feed_follows.feed_id = $1
+WHERE users.id <> $2
+    AND (
+        feed_follows.id IS NOT NULL
+        OR EXISTS (
+            SELECT 1 FROM post_stars JOIN posts ON posts.id = post_stars.post_id
+            WHERE post_stars.user_id = users.id AND posts.feed_id = $1
+        )
+        OR EXISTS (
+            SELECT 1 FROM saved_posts JOIN posts ON posts.id = saved_posts.post_id
+            WHERE saved_posts.user_id = users.id AND posts.feed_id = $1
+        )
+    )
+ORDER BY feed_follows.created_at NULLS LAST, users.created_at
+LIMIT 1
+`
+
+type GetNextFeedOwnerParams struct {
+	FeedID         uuid.UUID
+	ExcludedUserID uuid.UUID
+}
+
+// who a feed goes to when its owner deletes it: the earliest follower,
+// otherwise a user that starred or saved one of its posts
+func (q *Queries) GetNextFeedOwner(ctx context.Context, arg GetNextFeedOwnerParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedOwner, arg.FeedID, arg.ExcludedUserID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const setFeedInviteToken = `-- name: SetFeedInviteToken :one
+UPDATE feeds
+SET invite_token = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type SetFeedInviteTokenParams struct {
+	ID          uuid.UUID
+	InviteToken sql.NullString `json:"-"`
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) SetFeedInviteToken(ctx context.Context, arg SetFeedInviteTokenParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedInviteToken, arg.ID, arg.InviteToken, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const setFeedOwner = `-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = $3
+WHERE id = $1
+`
+
+type SetFeedOwnerParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetFeedOwner(ctx context.Context, arg SetFeedOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedOwner, arg.ID, arg.UserID, arg.UpdatedAt)
+	return err
+}
+
+const setFeedWriteToken = `-- name: SetFeedWriteToken :one
+UPDATE feeds
+SET write_token = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type SetFeedWriteTokenParams struct {
+	ID         uuid.UUID
+	WriteToken sql.NullString `json:"-"`
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) SetFeedWriteToken(ctx context.Context, arg SetFeedWriteTokenParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedWriteToken, arg.ID, arg.WriteToken, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const updateFeedFullContent = `-- name: UpdateFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2, content_selector = $3, updated_at = $4
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type UpdateFeedFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+	ContentSelector  string
+	UpdatedAt        time.Time
+}
+
+func (q *Queries) UpdateFeedFullContent(ctx context.Context, arg UpdateFeedFullContentParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFullContent,
+		arg.ID,
+		arg.FetchFullContent,
+		arg.ContentSelector,
+		arg.UpdatedAt,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const updateFeedName = `-- name: UpdateFeedName :one
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type UpdateFeedNameParams struct {
+	ID        uuid.UUID
+	Name      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedName(ctx context.Context, arg UpdateFeedNameParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedName, arg.ID, arg.Name, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const updateFeedSelectors = `-- name: UpdateFeedSelectors :one
+UPDATE feeds
+SET selectors = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type UpdateFeedSelectorsParams struct {
+	ID        uuid.UUID
+	Selectors json.RawMessage
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedSelectors(ctx context.Context, arg UpdateFeedSelectorsParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedSelectors, arg.ID, arg.Selectors, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = $2, updated_at = $3, last_fetched_at = NULL
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token
+`
+
+type UpdateFeedURLParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+// the new url is fetched on the next run
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedURL, arg.ID, arg.Url, arg.UpdatedAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.SiteUrl,
+		&i.FetchFullContent,
+		&i.ContentSelector,
+		&i.Kind,
+		&i.Selectors,
+		&i.WriteToken,
+		&i.InviteToken,
+	)
+	return i, err
+}
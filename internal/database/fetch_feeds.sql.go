@@ -14,12 +14,13 @@ import (
 )
 
 const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-WHERE last_fetched_at IS NULL or last_fetched_at < NOW() - INTERVAL '60 minutes'
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, site_url, fetch_full_content, content_selector, kind, selectors, write_token, invite_token FROM feeds
+WHERE kind <> 'push' AND (last_fetched_at IS NULL or last_fetched_at < NOW() - INTERVAL '60 minutes')
 ORDER BY last_fetched_at
 LIMIT $1
 `
 
+// push feeds have nothing to fetch
 func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
 	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
 	if err != nil {
@@ -37,6 +38,13 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.SiteUrl,
+			&i.FetchFullContent,
+			&i.ContentSelector,
+			&i.Kind,
+			&i.Selectors,
+			&i.WriteToken,
+			&i.InviteToken,
 		); err != nil {
 			return nil, err
 		}
@@ -67,3 +75,19 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.LastFetchedAt, arg.UpdatedAt)
 	return err
 }
+
+const setFeedSiteURL = `-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET site_url = $2
+WHERE id = $1 AND site_url <> $2
+`
+
+type SetFeedSiteURLParams struct {
+	ID      uuid.UUID
+	SiteUrl string
+}
+
+func (q *Queries) SetFeedSiteURL(ctx context.Context, arg SetFeedSiteURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteURL, arg.ID, arg.SiteUrl)
+	return err
+}
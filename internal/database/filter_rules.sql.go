@@ -0,0 +1,383 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: filter_rules.sql
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addFilterRuleMatches = `-- name: AddFilterRuleMatches :exec
+INSERT INTO filter_rule_matches (rule_id, post_id, created_at)
+SELECT $1, unnest($2::uuid[]), $3
+ON CONFLICT (rule_id, post_id) DO NOTHING
+`
+
+type AddFilterRuleMatchesParams struct {
+	RuleID    uuid.UUID
+	PostIds   []uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) AddFilterRuleMatches(ctx context.Context, arg AddFilterRuleMatchesParams) error {
+	_, err := q.db.ExecContext(ctx, addFilterRuleMatches, arg.RuleID, pq.Array(arg.PostIds), arg.CreatedAt)
+	return err
+}
+
+const createFilterRule = `-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at
+`
+
+type CreateFilterRuleParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	FeedID     uuid.NullUUID
+	Conditions json.RawMessage
+	Hide       bool
+	Star       bool
+	Tags       []string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) CreateFilterRule(ctx context.Context, arg CreateFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, createFilterRule,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.FeedID,
+		arg.Conditions,
+		arg.Hide,
+		arg.Star,
+		pq.Array(arg.Tags),
+		arg.CreatedAt,
+	)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.FeedID,
+		&i.Conditions,
+		&i.Hide,
+		&i.Star,
+		pq.Array(&i.Tags),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteFilterRule = `-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteFilterRuleParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFilterRule(ctx context.Context, arg DeleteFilterRuleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFilterRule, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFilterRuleMatches = `-- name: DeleteFilterRuleMatches :exec
+DELETE FROM filter_rule_matches
+WHERE rule_id = $1
+`
+
+func (q *Queries) DeleteFilterRuleMatches(ctx context.Context, ruleID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFilterRuleMatches, ruleID)
+	return err
+}
+
+const getFilterRule = `-- name: GetFilterRule :one
+SELECT id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at FROM filter_rules
+WHERE id = $1 AND user_id = $2
+`
+
+type GetFilterRuleParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFilterRule(ctx context.Context, arg GetFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, getFilterRule, arg.ID, arg.UserID)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.FeedID,
+		&i.Conditions,
+		&i.Hide,
+		&i.Star,
+		pq.Array(&i.Tags),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getFilterRules = `-- name: GetFilterRules :many
+SELECT
+    filter_rules.id, filter_rules.user_id, filter_rules.name, filter_rules.feed_id, filter_rules.conditions, filter_rules.hide, filter_rules.star, filter_rules.tags, filter_rules.created_at, filter_rules.updated_at,
+    (SELECT COUNT(*) FROM filter_rule_matches WHERE filter_rule_matches.rule_id = filter_rules.id)::bigint AS match_count
+FROM filter_rules
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.name
+`
+
+type GetFilterRulesRow struct {
+	FilterRule FilterRule
+	MatchCount int64
+}
+
+func (q *Queries) GetFilterRules(ctx context.Context, userID uuid.UUID) ([]GetFilterRulesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRules, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFilterRulesRow
+	for rows.Next() {
+		var i GetFilterRulesRow
+		if err := rows.Scan(
+			&i.FilterRule.ID,
+			&i.FilterRule.UserID,
+			&i.FilterRule.Name,
+			&i.FilterRule.FeedID,
+			&i.FilterRule.Conditions,
+			&i.FilterRule.Hide,
+			&i.FilterRule.Star,
+			pq.Array(&i.FilterRule.Tags),
+			&i.FilterRule.CreatedAt,
+			&i.FilterRule.UpdatedAt,
+			&i.MatchCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFilterRulesForFeed = `-- name: GetFilterRulesForFeed :many
+SELECT filter_rules.id, filter_rules.user_id, filter_rules.name, filter_rules.feed_id, filter_rules.conditions, filter_rules.hide, filter_rules.star, filter_rules.tags, filter_rules.created_at, filter_rules.updated_at FROM filter_rules
+JOIN feed_follows ON feed_follows.user_id = filter_rules.user_id AND feed_follows.feed_id = $1
+WHERE filter_rules.feed_id IS NULL OR filter_rules.feed_id = $1
+`
+
+// the rules of the feed's followers that look at its posts
+func (q *Queries) GetFilterRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, getFilterRulesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterRule
+	for rows.Next() {
+		var i FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.FeedID,
+			&i.Conditions,
+			&i.Hide,
+			&i.Star,
+			pq.Array(&i.Tags),
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsOfFeed = `-- name: GetPostsOfFeed :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed FROM posts
+WHERE feed_id = $1
+`
+
+func (q *Queries) GetPostsOfFeed(ctx context.Context, feedID uuid.UUID) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsOfFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Content,
+			&i.SearchVector,
+			&i.DescriptionText,
+			&i.Sanitized,
+			&i.FullContent,
+			&i.FullContentStatus,
+			&i.Pushed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserFilterRulesForFeed = `-- name: GetUserFilterRulesForFeed :many
+SELECT id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at FROM filter_rules
+WHERE user_id = $1 AND (feed_id IS NULL OR feed_id = $2)
+`
+
+type GetUserFilterRulesForFeedParams struct {
+	UserID uuid.UUID
+	FeedID uuid.NullUUID
+}
+
+// the rules of the user that look at the feed's posts
+func (q *Queries) GetUserFilterRulesForFeed(ctx context.Context, arg GetUserFilterRulesForFeedParams) ([]FilterRule, error) {
+	rows, err := q.db.QueryContext(ctx, getUserFilterRulesForFeed, arg.UserID, arg.FeedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterRule
+	for rows.Next() {
+		var i FilterRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.FeedID,
+			&i.Conditions,
+			&i.Hide,
+			&i.Star,
+			pq.Array(&i.Tags),
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostHidden = `-- name: IsPostHidden :one
+SELECT EXISTS (
+    SELECT 1 FROM filter_rule_matches
+    JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+    WHERE filter_rule_matches.post_id = $1 AND filter_rules.user_id = $2 AND filter_rules.hide
+)::boolean
+`
+
+type IsPostHiddenParams struct {
+	PostID uuid.UUID
+	UserID uuid.UUID
+}
+
+// whether one of the user's filter rules hides the post
+func (q *Queries) IsPostHidden(ctx context.Context, arg IsPostHiddenParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPostHidden, arg.PostID, arg.UserID)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const updateFilterRule = `-- name: UpdateFilterRule :one
+UPDATE filter_rules
+SET name = $3, feed_id = $4, conditions = $5, hide = $6, star = $7, tags = $8, updated_at = $9
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at
+`
+
+type UpdateFilterRuleParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	FeedID     uuid.NullUUID
+	Conditions json.RawMessage
+	Hide       bool
+	Star       bool
+	Tags       []string
+	UpdatedAt  time.Time
+}
+
+func (q *Queries) UpdateFilterRule(ctx context.Context, arg UpdateFilterRuleParams) (FilterRule, error) {
+	row := q.db.QueryRowContext(ctx, updateFilterRule,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.FeedID,
+		arg.Conditions,
+		arg.Hide,
+		arg.Star,
+		pq.Array(arg.Tags),
+		arg.UpdatedAt,
+	)
+	var i FilterRule
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.FeedID,
+		&i.Conditions,
+		&i.Hide,
+		&i.Star,
+		pq.Array(&i.Tags),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,282 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: folders.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addFeedFollowToFolder = `-- name: AddFeedFollowToFolder :execrows
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id
+FROM feed_follows, folders
+WHERE feed_follows.id = $1 AND feed_follows.user_id = $2
+    AND folders.id = $3 AND folders.user_id = $2
+ON CONFLICT (feed_follow_id, folder_id) DO UPDATE SET folder_id = feed_follow_folders.folder_id
+`
+
+type AddFeedFollowToFolderParams struct {
+	FeedFollowID uuid.UUID
+	UserID       uuid.UUID
+	FolderID     uuid.UUID
+}
+
+func (q *Queries) AddFeedFollowToFolder(ctx context.Context, arg AddFeedFollowToFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addFeedFollowToFolder, arg.FeedFollowID, arg.UserID, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createFolder = `-- name: CreateFolder :one
+INSERT INTO folders (id, user_id, name, position, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    COALESCE((SELECT MAX(folders.position) FROM folders WHERE folders.user_id = $2), 0) + 1,
+    $4,
+    $5
+)
+RETURNING id, user_id, name, position, created_at, updated_at
+`
+
+type CreateFolderParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateFolder(ctx context.Context, arg CreateFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, createFolder,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteFolder = `-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteFolderParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFolder(ctx context.Context, arg DeleteFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFolder, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFolder = `-- name: GetFolder :one
+SELECT id, user_id, name, position, created_at, updated_at FROM folders
+WHERE id = $1 AND user_id = $2
+`
+
+type GetFolderParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFolder(ctx context.Context, arg GetFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolder, arg.ID, arg.UserID)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getFolderByName = `-- name: GetFolderByName :one
+SELECT id, user_id, name, position, created_at, updated_at FROM folders
+WHERE user_id = $1 AND name = $2
+`
+
+type GetFolderByNameParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) GetFolderByName(ctx context.Context, arg GetFolderByNameParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, getFolderByName, arg.UserID, arg.Name)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getFolders = `-- name: GetFolders :many
+SELECT
+    folders.id, folders.user_id, folders.name, folders.position, folders.created_at, folders.updated_at,
+    ARRAY(
+        SELECT feed_follow_folders.feed_follow_id FROM feed_follow_folders
+        WHERE feed_follow_folders.folder_id = folders.id
+        ORDER BY feed_follow_folders.feed_follow_id
+    )::uuid[] AS feed_follow_ids,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+            WHERE feed_follow_folders.folder_id = folders.id
+        )
+        AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = folders.user_id AND post_reads.post_id = posts.id
+        )
+    )::bigint AS unread_count
+FROM
+    folders
+WHERE
+    folders.user_id = $1
+ORDER BY
+    folders.position, folders.name
+`
+
+type GetFoldersRow struct {
+	Folder        Folder
+	FeedFollowIds []uuid.UUID
+	UnreadCount   int64
+}
+
+func (q *Queries) GetFolders(ctx context.Context, userID uuid.UUID) ([]GetFoldersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFolders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFoldersRow
+	for rows.Next() {
+		var i GetFoldersRow
+		if err := rows.Scan(
+			&i.Folder.ID,
+			&i.Folder.UserID,
+			&i.Folder.Name,
+			&i.Folder.Position,
+			&i.Folder.CreatedAt,
+			&i.Folder.UpdatedAt,
+			pq.Array(&i.FeedFollowIds),
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeFeedFollowFromFolder = `-- name: RemoveFeedFollowFromFolder :execrows
+DELETE FROM feed_follow_folders
+USING folders
+WHERE feed_follow_folders.folder_id = folders.id
+    AND feed_follow_folders.feed_follow_id = $1
+    AND folders.id = $2 AND folders.user_id = $3
+`
+
+type RemoveFeedFollowFromFolderParams struct {
+	FeedFollowID uuid.UUID
+	FolderID     uuid.UUID
+	UserID       uuid.UUID
+}
+
+func (q *Queries) RemoveFeedFollowFromFolder(ctx context.Context, arg RemoveFeedFollowFromFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFeedFollowFromFolder, arg.FeedFollowID, arg.FolderID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFolder = `-- name: RenameFolder :one
+UPDATE folders
+SET name = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, position, created_at, updated_at
+`
+
+type RenameFolderParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) RenameFolder(ctx context.Context, arg RenameFolderParams) (Folder, error) {
+	row := q.db.QueryRowContext(ctx, renameFolder,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.UpdatedAt,
+	)
+	var i Folder
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const setFolderPositions = `-- name: SetFolderPositions :exec
+UPDATE folders
+SET position = new_positions.position
+FROM unnest($2::uuid[]) WITH ORDINALITY AS new_positions(folder_id, position)
+WHERE folders.user_id = $1 AND folders.id = new_positions.folder_id
+`
+
+type SetFolderPositionsParams struct {
+	UserID    uuid.UUID
+	FolderIds []uuid.UUID
+}
+
+func (q *Queries) SetFolderPositions(ctx context.Context, arg SetFolderPositionsParams) error {
+	_, err := q.db.ExecContext(ctx, setFolderPositions, arg.UserID, pq.Array(arg.FolderIds))
+	return err
+}
@@ -0,0 +1,84 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: full_content.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimNextFullContentPost = `-- name: ClaimNextFullContentPost :one
+UPDATE posts
+SET full_content_status = 'fetching'
+WHERE posts.id = (
+    SELECT id FROM posts
+    WHERE full_content_status = 'pending'
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed
+`
+
+// marks the oldest post waiting for its full content as fetching, safe to call from several workers
+func (q *Queries) ClaimNextFullContentPost(ctx context.Context) (Post, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFullContentPost)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Content,
+		&i.SearchVector,
+		&i.DescriptionText,
+		&i.Sanitized,
+		&i.FullContent,
+		&i.FullContentStatus,
+		&i.Pushed,
+	)
+	return i, err
+}
+
+const requeueFetchingFullContent = `-- name: RequeueFetchingFullContent :execrows
+UPDATE posts
+SET full_content_status = 'pending'
+WHERE full_content_status = 'fetching'
+`
+
+// posts that were being fetched when the server stopped are fetched again
+func (q *Queries) RequeueFetchingFullContent(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueFetchingFullContent)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostFullContent = `-- name: SetPostFullContent :exec
+UPDATE posts
+SET full_content = $2, full_content_status = $3
+WHERE id = $1
+`
+
+type SetPostFullContentParams struct {
+	ID                uuid.UUID
+	FullContent       string
+	FullContentStatus string `json:"-"`
+}
+
+func (q *Queries) SetPostFullContent(ctx context.Context, arg SetPostFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostFullContent, arg.ID, arg.FullContent, arg.FullContentStatus)
+	return err
+}
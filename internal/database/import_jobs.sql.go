@@ -0,0 +1,181 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: import_jobs.sql
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimNextImportJob = `-- name: ClaimNextImportJob :one
+UPDATE import_jobs
+SET status = 'running', updated_at = $1
+WHERE id = (
+    SELECT id FROM import_jobs
+    WHERE status = 'pending'
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, kind, status, input, total, processed, report, error, created_at, updated_at, finished_at
+`
+
+// marks the oldest pending job as running, safe to call from several workers
+func (q *Queries) ClaimNextImportJob(ctx context.Context, updatedAt time.Time) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, claimNextImportJob, updatedAt)
+	var i ImportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Status,
+		&i.Input,
+		&i.Total,
+		&i.Processed,
+		&i.Report,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const createImportJob = `-- name: CreateImportJob :one
+INSERT INTO import_jobs (id, user_id, kind, input, total, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, kind, status, input, total, processed, report, error, created_at, updated_at, finished_at
+`
+
+type CreateImportJobParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	Input     json.RawMessage
+	Total     int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateImportJob(ctx context.Context, arg CreateImportJobParams) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, createImportJob,
+		arg.ID,
+		arg.UserID,
+		arg.Kind,
+		arg.Input,
+		arg.Total,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i ImportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Status,
+		&i.Input,
+		&i.Total,
+		&i.Processed,
+		&i.Report,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const finishImportJob = `-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET status = $2, processed = $3, report = $4, error = $5, updated_at = $6, finished_at = $6
+WHERE id = $1
+`
+
+type FinishImportJobParams struct {
+	ID        uuid.UUID
+	Status    string
+	Processed int32
+	Report    json.RawMessage
+	Error     string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) FinishImportJob(ctx context.Context, arg FinishImportJobParams) error {
+	_, err := q.db.ExecContext(ctx, finishImportJob,
+		arg.ID,
+		arg.Status,
+		arg.Processed,
+		arg.Report,
+		arg.Error,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const getImportJob = `-- name: GetImportJob :one
+SELECT id, user_id, kind, status, input, total, processed, report, error, created_at, updated_at, finished_at FROM import_jobs
+WHERE id = $1 AND user_id = $2
+`
+
+type GetImportJobParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetImportJob(ctx context.Context, arg GetImportJobParams) (ImportJob, error) {
+	row := q.db.QueryRowContext(ctx, getImportJob, arg.ID, arg.UserID)
+	var i ImportJob
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Status,
+		&i.Input,
+		&i.Total,
+		&i.Processed,
+		&i.Report,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const requeueRunningImportJobs = `-- name: RequeueRunningImportJobs :execrows
+UPDATE import_jobs
+SET status = 'pending', processed = 0, updated_at = $1
+WHERE status = 'running'
+`
+
+// jobs that were running when the server stopped start over, importing an entry twice is harmless
+func (q *Queries) RequeueRunningImportJobs(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueRunningImportJobs, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateImportJobProgress = `-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET processed = $2, updated_at = $3
+WHERE id = $1
+`
+
+type UpdateImportJobProgressParams struct {
+	ID        uuid.UUID
+	Processed int32
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error {
+	_, err := q.db.ExecContext(ctx, updateImportJobProgress, arg.ID, arg.Processed, arg.UpdatedAt)
+	return err
+}
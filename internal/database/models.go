@@ -6,38 +6,203 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type Feed struct {
+type Digest struct {
 	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
 	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	Email         string
+	Frequency     string
+	SentAt        sql.NullTime
+	Status        string
+	Attempts      int32
+	NextAttemptAt sql.NullTime
+	Error         string
+	Subject       string
+	TextBody      string
+	HtmlBody      string
+	CreatedAt     time.Time
+}
+
+type DigestPost struct {
+	DigestID uuid.UUID
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+}
+
+type DigestSubscription struct {
+	UserID           uuid.UUID
+	Email            string
+	Frequency        string
+	SendTime         string
+	Timezone         string
+	Weekday          int32
+	UnsubscribeToken string
+	BaseUrl          string
+	NextSendAt       time.Time
+	LastSentAt       sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	ConfirmToken     sql.NullString
+	ConfirmedAt      sql.NullTime
+}
+
+type Feed struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	LastFetchedAt    sql.NullTime
+	SiteUrl          string
+	FetchFullContent bool
+	ContentSelector  string
+	Kind             string
+	Selectors        json.RawMessage
+	WriteToken       sql.NullString `json:"-"`
+	InviteToken      sql.NullString `json:"-"`
 }
 
 type FeedFollow struct {
+	ID               uuid.UUID
+	FeedID           uuid.UUID
+	UserID           uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Title            sql.NullString
+	Pinned           bool
+	Notify           string
+	HideFromTimeline bool
+}
+
+type FeedFollowFolder struct {
+	FeedFollowID uuid.UUID
+	FolderID     uuid.UUID
+}
+
+type FilterRule struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	FeedID     uuid.NullUUID
+	Conditions json.RawMessage
+	Hide       bool
+	Star       bool
+	Tags       []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type FilterRuleMatch struct {
+	RuleID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Folder struct {
 	ID        uuid.UUID
-	FeedID    uuid.UUID
 	UserID    uuid.UUID
+	Name      string
+	Position  int32
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type ImportJob struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Kind       string
+	Status     string
+	Input      json.RawMessage
+	Total      int32
+	Processed  int32
+	Report     json.RawMessage
+	Error      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt sql.NullTime
+}
+
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Title             string
+	Url               string
+	Description       string
+	PublishedAt       time.Time
+	FeedID            uuid.UUID
+	Author            string
+	Categories        []string
+	Content           string
+	SearchVector      interface{} `json:"-"`
+	DescriptionText   string
+	Sanitized         bool `json:"-"`
+	FullContent       string
+	FullContentStatus string `json:"-"`
+	Pushed            bool   `json:"-"`
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+type SavedPost struct {
+	UserID   uuid.UUID
+	PostID   uuid.UUID
+	Position int32
+	SavedAt  time.Time
+}
+
+type SavedSearch struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+	Match     string
+	Author    string
+	FeedID    uuid.NullUUID
+	Notify    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type SavedSearchMatch struct {
+	SearchID  uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ShareToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	Token     string
+	CreatedAt time.Time
+	FolderID  uuid.NullUUID
+}
+
+type StreamEvent struct {
+	ID        int64
+	Kind      string
+	UserID    uuid.NullUUID
+	FeedID    uuid.NullUUID
+	Data      json.RawMessage
+	CreatedAt time.Time
+	PostID    uuid.NullUUID
 }
 
 type User struct {
@@ -46,4 +211,32 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 	ApiKey    string
+	IsAdmin   bool
+}
+
+type Webhook struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	FeedID    uuid.NullUUID
+	FolderID  uuid.NullUUID
+	Keyword   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	WebhookID      uuid.UUID
+	PostID         uuid.UUID
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	ResponseStatus sql.NullInt32
+	Error          string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeliveredAt    sql.NullTime
 }
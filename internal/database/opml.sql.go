@@ -0,0 +1,62 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: opml.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getOPMLOutlines = `-- name: GetOPMLOutlines :many
+SELECT
+    COALESCE(feed_follows.title, feeds.name)::text AS title,
+    feeds.url,
+    feeds.site_url,
+    COALESCE(folders.name, '')::text AS folder
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1 AND feeds.kind <> 'push'
+ORDER BY folders.position NULLS FIRST, feed_follows.pinned DESC, lower(COALESCE(feed_follows.title, feeds.name)), feeds.url
+`
+
+type GetOPMLOutlinesRow struct {
+	Title   string
+	Url     string
+	SiteUrl string
+	Folder  string
+}
+
+// a follow in several folders shows up once in each of them, push feeds have no url to subscribe to
+func (q *Queries) GetOPMLOutlines(ctx context.Context, userID uuid.UUID) ([]GetOPMLOutlinesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOPMLOutlines, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOPMLOutlinesRow
+	for rows.Next() {
+		var i GetOPMLOutlinesRow
+		if err := rows.Scan(
+			&i.Title,
+			&i.Url,
+			&i.SiteUrl,
+			&i.Folder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
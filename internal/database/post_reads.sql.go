@@ -0,0 +1,158 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: post_reads.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getUnreadCountsByFeed = `-- name: GetUnreadCountsByFeed :many
+SELECT
+    feed_follows.id AS feed_follow_id,
+    feed_follows.feed_id,
+    COUNT(posts.id) AS unread_count
+FROM
+    feed_follows
+LEFT JOIN
+    posts ON posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    )
+WHERE
+    feed_follows.user_id = $1
+GROUP BY
+    feed_follows.id, feed_follows.feed_id
+ORDER BY
+    feed_follows.id
+`
+
+type GetUnreadCountsByFeedRow struct {
+	FeedFollowID uuid.UUID
+	FeedID       uuid.UUID
+	UnreadCount  int64
+}
+
+func (q *Queries) GetUnreadCountsByFeed(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsByFeedRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsByFeed, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsByFeedRow
+	for rows.Next() {
+		var i GetUnreadCountsByFeedRow
+		if err := rows.Scan(&i.FeedFollowID, &i.FeedID, &i.UnreadCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const importPostRead = `-- name: ImportPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type ImportPostReadParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+// marks a post the user read in another reader as read
+func (q *Queries) ImportPostRead(ctx context.Context, arg ImportPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, importPostRead, arg.UserID, arg.PostID, arg.ReadAt)
+	return err
+}
+
+const markAllPostsRead = `-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT $1, posts.id, $2
+FROM posts
+WHERE posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $1)
+    AND ($3::uuid IS NULL OR posts.feed_id = $3)
+    AND ($4::timestamp IS NULL OR posts.published_at < $4)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type MarkAllPostsReadParams struct {
+	UserID uuid.UUID
+	ReadAt time.Time
+	FeedID uuid.NullUUID
+	Before sql.NullTime
+}
+
+func (q *Queries) MarkAllPostsRead(ctx context.Context, arg MarkAllPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllPostsRead,
+		arg.UserID,
+		arg.ReadAt,
+		arg.FeedID,
+		arg.Before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostsRead = `-- name: MarkPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT $1, posts.id, $2
+FROM posts
+WHERE posts.id = ANY($3::uuid[])
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $1)
+        OR EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = $1 AND post_stars.post_id = posts.id)
+        OR EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = $1 AND saved_posts.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = post_reads.read_at
+`
+
+type MarkPostsReadParams struct {
+	UserID  uuid.UUID
+	ReadAt  time.Time
+	PostIds []uuid.UUID
+}
+
+func (q *Queries) MarkPostsRead(ctx context.Context, arg MarkPostsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsRead, arg.UserID, arg.ReadAt, pq.Array(arg.PostIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markPostsUnread = `-- name: MarkPostsUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = ANY($2::uuid[])
+`
+
+type MarkPostsUnreadParams struct {
+	UserID  uuid.UUID
+	PostIds []uuid.UUID
+}
+
+func (q *Queries) MarkPostsUnread(ctx context.Context, arg MarkPostsUnreadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markPostsUnread, arg.UserID, pq.Array(arg.PostIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,72 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: post_stars.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const importPostStar = `-- name: ImportPostStar :exec
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING
+`
+
+type ImportPostStarParams struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
+}
+
+// stars a post the user starred in another reader, even if they don't follow its feed
+func (q *Queries) ImportPostStar(ctx context.Context, arg ImportPostStarParams) error {
+	_, err := q.db.ExecContext(ctx, importPostStar, arg.UserID, arg.PostID, arg.StarredAt)
+	return err
+}
+
+const starPost = `-- name: StarPost :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT $1, posts.id, $2
+FROM posts
+WHERE posts.id = $3
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $1)
+        OR EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = $1 AND saved_posts.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = post_stars.starred_at
+`
+
+type StarPostParams struct {
+	UserID    uuid.UUID
+	StarredAt time.Time
+	PostID    uuid.UUID
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.StarredAt, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unstarPost = `-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnstarPostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnstarPost(ctx context.Context, arg UnstarPostParams) error {
+	_, err := q.db.ExecContext(ctx, unstarPost, arg.UserID, arg.PostID)
+	return err
+}
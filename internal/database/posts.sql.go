@@ -7,28 +7,38 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, description_text, sanitized, full_content_status, pushed)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, true,
+    CASE WHEN (SELECT feeds.fetch_full_content FROM feeds WHERE feeds.id = $8) THEN 'pending' ELSE '' END,
+    (SELECT feeds.kind = 'push' FROM feeds WHERE feeds.id = $8))
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Author          string
+	Categories      []string
+	Content         string
+	DescriptionText string
 }
 
+// the full content of the post is fetched later if its feed asks for it
+// the posts of push feeds are pushed, their url only has to be unique in the feed
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
@@ -39,6 +49,10 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
+		arg.Content,
+		arg.DescriptionText,
 	)
 	var i Post
 	err := row.Scan(
@@ -50,35 +64,413 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Content,
+		&i.SearchVector,
+		&i.DescriptionText,
+		&i.Sanitized,
+		&i.FullContent,
+		&i.FullContentStatus,
+		&i.Pushed,
+	)
+	return i, err
+}
+
+const getOrCreatePost = `-- name: GetOrCreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, description_text, sanitized)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, true)
+ON CONFLICT (url) WHERE NOT pushed DO UPDATE SET url = posts.url
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed
+`
+
+type GetOrCreatePostParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Author          string
+	Categories      []string
+	Content         string
+	DescriptionText string
+}
+
+// posts that aren't pushed are unique by url, an existing post is returned as it is
+func (q *Queries) GetOrCreatePost(ctx context.Context, arg GetOrCreatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getOrCreatePost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
+		arg.Content,
+		arg.DescriptionText,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Content,
+		&i.SearchVector,
+		&i.DescriptionText,
+		&i.Sanitized,
+		&i.FullContent,
+		&i.FullContentStatus,
+		&i.Pushed,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed FROM posts
+WHERE url = $1 AND NOT pushed
+`
+
+// pushed posts aren't looked up by url, their urls aren't unique
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		pq.Array(&i.Categories),
+		&i.Content,
+		&i.SearchVector,
+		&i.DescriptionText,
+		&i.Sanitized,
+		&i.FullContent,
+		&i.FullContentStatus,
+		&i.Pushed,
 	)
 	return i, err
 }
 
 const getPostsByUser = `-- name: GetPostsByUser :many
 SELECT
-    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed,
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = $1 AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ARRAY(
+        SELECT DISTINCT unnest(filter_rules.tags) FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1
+        ORDER BY 1
+    )::text[] AS tags
 FROM
     posts
-JOIN
-    feeds ON feeds.id = posts.feed_id
-JOIN
-    feed_follows ON feed_follows.feed_id = feeds.id
-JOIN
-    users ON users.id = feed_follows.user_id
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = $1
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = $1
 WHERE
-    users.api_key = $1
+    -- starred posts stay in the timeline after their feed is unfollowed
+    -- follows hidden from the timeline only show up when filtering by feed, folder or saved search
+    (
+        posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = $1
+                AND (NOT feed_follows.hide_from_timeline
+                    OR $2::uuid IS NOT NULL
+                    OR $3::uuid IS NOT NULL
+                    OR $4::uuid IS NOT NULL)
+        )
+        OR post_stars.post_id IS NOT NULL
+    )
+    AND ($2::uuid IS NULL OR posts.feed_id = $2)
+    AND ($5::timestamp IS NULL OR posts.published_at >= $5)
+    AND ($6::timestamp IS NULL OR posts.published_at < $6)
+    AND ($7::text IS NULL OR lower(posts.author) = lower($7))
+    AND ($8::text IS NULL OR posts.categories @> ARRAY[$8::text])
+    AND ($9::boolean IS NULL OR $9 = (post_reads.post_id IS NULL))
+    AND ($10::boolean IS NULL OR $10 = (post_stars.post_id IS NOT NULL))
+    AND ($3::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follow_folders.folder_id = $3 AND feed_follows.user_id = $1
+    ))
+    AND ($4::uuid IS NULL OR posts.id IN (
+        SELECT saved_search_matches.post_id FROM saved_search_matches
+        WHERE saved_search_matches.search_id = $4
+    ))
+    -- hidden by one of the user's filter rules
+    AND ($11::boolean IS NULL OR $11 = EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1 AND filter_rules.hide
+    ))
+    AND ($12::timestamp IS NULL
+        OR (posts.published_at, posts.id) < ($12, $13::uuid))
 ORDER BY
-    posts.published_at DESC
-LIMIT $2
+    posts.published_at DESC, posts.id DESC
+LIMIT $14
 `
 
 type GetPostsByUserParams struct {
-	ApiKey string
-	Limit  int32
+	UserID            uuid.UUID
+	FeedID            uuid.NullUUID
+	FolderID          uuid.NullUUID
+	SearchID          uuid.NullUUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	Author            sql.NullString
+	Category          sql.NullString
+	Unread            sql.NullBool
+	Starred           sql.NullBool
+	Hidden            sql.NullBool
+	BeforePublishedAt sql.NullTime
+	BeforeID          uuid.NullUUID
+	Limit             int32
+}
+
+type GetPostsByUserRow struct {
+	Post    Post
+	Read    bool
+	Starred bool
+	Saved   bool
+	Tags    []string
 }
 
-func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.ApiKey, arg.Limit)
+func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]GetPostsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.SearchID,
+		arg.Since,
+		arg.Until,
+		arg.Author,
+		arg.Category,
+		arg.Unread,
+		arg.Starred,
+		arg.Hidden,
+		arg.BeforePublishedAt,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsByUserRow
+	for rows.Next() {
+		var i GetPostsByUserRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Author,
+			pq.Array(&i.Post.Categories),
+			&i.Post.Content,
+			&i.Post.SearchVector,
+			&i.Post.DescriptionText,
+			&i.Post.Sanitized,
+			&i.Post.FullContent,
+			&i.Post.FullContentStatus,
+			&i.Post.Pushed,
+			&i.Read,
+			&i.Starred,
+			&i.Saved,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByUserAscending = `-- name: GetPostsByUserAscending :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed,
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = $1 AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ARRAY(
+        SELECT DISTINCT unnest(filter_rules.tags) FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1
+        ORDER BY 1
+    )::text[] AS tags
+FROM
+    posts
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = $1
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = $1
+WHERE
+    -- starred posts stay in the timeline after their feed is unfollowed
+    -- follows hidden from the timeline only show up when filtering by feed, folder or saved search
+    (
+        posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = $1
+                AND (NOT feed_follows.hide_from_timeline
+                    OR $2::uuid IS NOT NULL
+                    OR $3::uuid IS NOT NULL
+                    OR $4::uuid IS NOT NULL)
+        )
+        OR post_stars.post_id IS NOT NULL
+    )
+    AND ($2::uuid IS NULL OR posts.feed_id = $2)
+    AND ($5::timestamp IS NULL OR posts.published_at >= $5)
+    AND ($6::timestamp IS NULL OR posts.published_at < $6)
+    AND ($7::text IS NULL OR lower(posts.author) = lower($7))
+    AND ($8::text IS NULL OR posts.categories @> ARRAY[$8::text])
+    AND ($9::boolean IS NULL OR $9 = (post_reads.post_id IS NULL))
+    AND ($10::boolean IS NULL OR $10 = (post_stars.post_id IS NOT NULL))
+    AND ($3::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follow_folders.folder_id = $3 AND feed_follows.user_id = $1
+    ))
+    AND ($4::uuid IS NULL OR posts.id IN (
+        SELECT saved_search_matches.post_id FROM saved_search_matches
+        WHERE saved_search_matches.search_id = $4
+    ))
+    -- hidden by one of the user's filter rules
+    AND ($11::boolean IS NULL OR $11 = EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1 AND filter_rules.hide
+    ))
+    AND ($12::timestamp IS NULL
+        OR (posts.published_at, posts.id) > ($12, $13::uuid))
+ORDER BY
+    posts.published_at ASC, posts.id ASC
+LIMIT $14
+`
+
+type GetPostsByUserAscendingParams struct {
+	UserID           uuid.UUID
+	FeedID           uuid.NullUUID
+	FolderID         uuid.NullUUID
+	SearchID         uuid.NullUUID
+	Since            sql.NullTime
+	Until            sql.NullTime
+	Author           sql.NullString
+	Category         sql.NullString
+	Unread           sql.NullBool
+	Starred          sql.NullBool
+	Hidden           sql.NullBool
+	AfterPublishedAt sql.NullTime
+	AfterID          uuid.NullUUID
+	Limit            int32
+}
+
+type GetPostsByUserAscendingRow struct {
+	Post    Post
+	Read    bool
+	Starred bool
+	Saved   bool
+	Tags    []string
+}
+
+func (q *Queries) GetPostsByUserAscending(ctx context.Context, arg GetPostsByUserAscendingParams) ([]GetPostsByUserAscendingRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsByUserAscending,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.SearchID,
+		arg.Since,
+		arg.Until,
+		arg.Author,
+		arg.Category,
+		arg.Unread,
+		arg.Starred,
+		arg.Hidden,
+		arg.AfterPublishedAt,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsByUserAscendingRow
+	for rows.Next() {
+		var i GetPostsByUserAscendingRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Author,
+			pq.Array(&i.Post.Categories),
+			&i.Post.Content,
+			&i.Post.SearchVector,
+			&i.Post.DescriptionText,
+			&i.Post.Sanitized,
+			&i.Post.FullContent,
+			&i.Post.FullContentStatus,
+			&i.Post.Pushed,
+			&i.Read,
+			&i.Starred,
+			&i.Saved,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnsanitizedPosts = `-- name: GetUnsanitizedPosts :many
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, search_vector, description_text, sanitized, full_content, full_content_status, pushed FROM posts
+WHERE NOT sanitized
+ORDER BY id
+LIMIT $1
+`
+
+// posts from before descriptions were sanitized
+func (q *Queries) GetUnsanitizedPosts(ctx context.Context, limit int32) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getUnsanitizedPosts, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +487,15 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Content,
+			&i.SearchVector,
+			&i.DescriptionText,
+			&i.Sanitized,
+			&i.FullContent,
+			&i.FullContentStatus,
+			&i.Pushed,
 		); err != nil {
 			return nil, err
 		}
@@ -108,3 +509,26 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const setPostSanitized = `-- name: SetPostSanitized :exec
+UPDATE posts
+SET description = $2, content = $3, description_text = $4, sanitized = true
+WHERE id = $1
+`
+
+type SetPostSanitizedParams struct {
+	ID              uuid.UUID
+	Description     string
+	Content         string
+	DescriptionText string
+}
+
+func (q *Queries) SetPostSanitized(ctx context.Context, arg SetPostSanitizedParams) error {
+	_, err := q.db.ExecContext(ctx, setPostSanitized,
+		arg.ID,
+		arg.Description,
+		arg.Content,
+		arg.DescriptionText,
+	)
+	return err
+}
@@ -0,0 +1,146 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: saved_posts.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const getSavedPosts = `-- name: GetSavedPosts :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed,
+    saved_posts.position,
+    saved_posts.saved_at,
+    EXISTS (SELECT 1 FROM post_reads WHERE post_reads.user_id = saved_posts.user_id AND post_reads.post_id = posts.id)::boolean AS read,
+    EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = saved_posts.user_id AND post_stars.post_id = posts.id)::boolean AS starred
+FROM
+    saved_posts
+JOIN
+    posts ON posts.id = saved_posts.post_id
+WHERE
+    saved_posts.user_id = $1
+ORDER BY
+    saved_posts.position
+`
+
+type GetSavedPostsRow struct {
+	Post     Post
+	Position int32
+	SavedAt  time.Time
+	Read     bool
+	Starred  bool
+}
+
+func (q *Queries) GetSavedPosts(ctx context.Context, userID uuid.UUID) ([]GetSavedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSavedPosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSavedPostsRow
+	for rows.Next() {
+		var i GetSavedPostsRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Author,
+			pq.Array(&i.Post.Categories),
+			&i.Post.Content,
+			&i.Post.SearchVector,
+			&i.Post.DescriptionText,
+			&i.Post.Sanitized,
+			&i.Post.FullContent,
+			&i.Post.FullContentStatus,
+			&i.Post.Pushed,
+			&i.Position,
+			&i.SavedAt,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const savePost = `-- name: SavePost :execrows
+INSERT INTO saved_posts (user_id, post_id, position, saved_at)
+SELECT
+    $1,
+    posts.id,
+    COALESCE((SELECT MAX(saved_posts.position) FROM saved_posts WHERE saved_posts.user_id = $1), 0) + 1,
+    $2
+FROM posts
+WHERE posts.id = $3
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $1)
+        OR EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = $1 AND post_stars.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET position = saved_posts.position
+`
+
+type SavePostParams struct {
+	UserID  uuid.UUID
+	SavedAt time.Time
+	PostID  uuid.UUID
+}
+
+func (q *Queries) SavePost(ctx context.Context, arg SavePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, savePost, arg.UserID, arg.SavedAt, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setSavedPostPositions = `-- name: SetSavedPostPositions :exec
+UPDATE saved_posts
+SET position = new_positions.position
+FROM unnest($2::uuid[]) WITH ORDINALITY AS new_positions(post_id, position)
+WHERE saved_posts.user_id = $1 AND saved_posts.post_id = new_positions.post_id
+`
+
+type SetSavedPostPositionsParams struct {
+	UserID  uuid.UUID
+	PostIds []uuid.UUID
+}
+
+func (q *Queries) SetSavedPostPositions(ctx context.Context, arg SetSavedPostPositionsParams) error {
+	_, err := q.db.ExecContext(ctx, setSavedPostPositions, arg.UserID, pq.Array(arg.PostIds))
+	return err
+}
+
+const unsavePost = `-- name: UnsavePost :exec
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2
+`
+
+type UnsavePostParams struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+}
+
+func (q *Queries) UnsavePost(ctx context.Context, arg UnsavePostParams) error {
+	_, err := q.db.ExecContext(ctx, unsavePost, arg.UserID, arg.PostID)
+	return err
+}
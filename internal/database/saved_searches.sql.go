@@ -0,0 +1,336 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: saved_searches.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addSavedSearchMatches = `-- name: AddSavedSearchMatches :exec
+INSERT INTO saved_search_matches (search_id, post_id, created_at)
+SELECT $1, unnest($2::uuid[]), $3
+ON CONFLICT (search_id, post_id) DO NOTHING
+`
+
+type AddSavedSearchMatchesParams struct {
+	SearchID  uuid.UUID
+	PostIds   []uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) AddSavedSearchMatches(ctx context.Context, arg AddSavedSearchMatchesParams) error {
+	_, err := q.db.ExecContext(ctx, addSavedSearchMatches, arg.SearchID, pq.Array(arg.PostIds), arg.CreatedAt)
+	return err
+}
+
+const createSavedSearch = `-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, user_id, name, query, match, author, feed_id, notify, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING id, user_id, name, query, match, author, feed_id, notify, created_at, updated_at
+`
+
+type CreateSavedSearchParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+	Match     string
+	Author    string
+	FeedID    uuid.NullUUID
+	Notify    bool
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateSavedSearch(ctx context.Context, arg CreateSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, createSavedSearch,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Query,
+		arg.Match,
+		arg.Author,
+		arg.FeedID,
+		arg.Notify,
+		arg.CreatedAt,
+	)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Query,
+		&i.Match,
+		&i.Author,
+		&i.FeedID,
+		&i.Notify,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const deleteSavedSearch = `-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteSavedSearchParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteSavedSearch(ctx context.Context, arg DeleteSavedSearchParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSavedSearch, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSavedSearchMatches = `-- name: DeleteSavedSearchMatches :exec
+DELETE FROM saved_search_matches
+WHERE search_id = $1
+`
+
+func (q *Queries) DeleteSavedSearchMatches(ctx context.Context, searchID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteSavedSearchMatches, searchID)
+	return err
+}
+
+const getPostsToSearch = `-- name: GetPostsToSearch :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed FROM posts
+WHERE posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    WHERE feed_follows.user_id = $1
+)
+    AND ($2::uuid IS NULL OR posts.feed_id = $2)
+ORDER BY posts.published_at DESC
+LIMIT $3
+`
+
+type GetPostsToSearchParams struct {
+	UserID uuid.UUID
+	FeedID uuid.NullUUID
+	Limit  int32
+}
+
+// the newest posts of the feeds the user follows, or of one of them, for matching a search that was just saved
+func (q *Queries) GetPostsToSearch(ctx context.Context, arg GetPostsToSearchParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToSearch, arg.UserID, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Author,
+			pq.Array(&i.Categories),
+			&i.Content,
+			&i.SearchVector,
+			&i.DescriptionText,
+			&i.Sanitized,
+			&i.FullContent,
+			&i.FullContentStatus,
+			&i.Pushed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSavedSearch = `-- name: GetSavedSearch :one
+SELECT id, user_id, name, query, match, author, feed_id, notify, created_at, updated_at FROM saved_searches
+WHERE id = $1 AND user_id = $2
+`
+
+type GetSavedSearchParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetSavedSearch(ctx context.Context, arg GetSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, getSavedSearch, arg.ID, arg.UserID)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Query,
+		&i.Match,
+		&i.Author,
+		&i.FeedID,
+		&i.Notify,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSavedSearches = `-- name: GetSavedSearches :many
+SELECT
+    saved_searches.id, saved_searches.user_id, saved_searches.name, saved_searches.query, saved_searches.match, saved_searches.author, saved_searches.feed_id, saved_searches.notify, saved_searches.created_at, saved_searches.updated_at,
+    (
+        SELECT COUNT(*) FROM saved_search_matches
+        WHERE saved_search_matches.search_id = saved_searches.id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_reads
+                WHERE post_reads.user_id = saved_searches.user_id AND post_reads.post_id = saved_search_matches.post_id
+            )
+    )::bigint AS unread_count
+FROM saved_searches
+WHERE saved_searches.user_id = $1
+ORDER BY saved_searches.name
+`
+
+type GetSavedSearchesRow struct {
+	SavedSearch SavedSearch
+	UnreadCount int64
+}
+
+func (q *Queries) GetSavedSearches(ctx context.Context, userID uuid.UUID) ([]GetSavedSearchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSavedSearches, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSavedSearchesRow
+	for rows.Next() {
+		var i GetSavedSearchesRow
+		if err := rows.Scan(
+			&i.SavedSearch.ID,
+			&i.SavedSearch.UserID,
+			&i.SavedSearch.Name,
+			&i.SavedSearch.Query,
+			&i.SavedSearch.Match,
+			&i.SavedSearch.Author,
+			&i.SavedSearch.FeedID,
+			&i.SavedSearch.Notify,
+			&i.SavedSearch.CreatedAt,
+			&i.SavedSearch.UpdatedAt,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSavedSearchesForFeed = `-- name: GetSavedSearchesForFeed :many
+SELECT saved_searches.id, saved_searches.user_id, saved_searches.name, saved_searches.query, saved_searches.match, saved_searches.author, saved_searches.feed_id, saved_searches.notify, saved_searches.created_at, saved_searches.updated_at FROM saved_searches
+JOIN feed_follows ON feed_follows.user_id = saved_searches.user_id AND feed_follows.feed_id = $1
+WHERE saved_searches.feed_id IS NULL OR saved_searches.feed_id = $1
+`
+
+// the searches of the feed's followers that look at its posts
+func (q *Queries) GetSavedSearchesForFeed(ctx context.Context, feedID uuid.UUID) ([]SavedSearch, error) {
+	rows, err := q.db.QueryContext(ctx, getSavedSearchesForFeed, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SavedSearch
+	for rows.Next() {
+		var i SavedSearch
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Query,
+			&i.Match,
+			&i.Author,
+			&i.FeedID,
+			&i.Notify,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateSavedSearch = `-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = $3, query = $4, match = $5, author = $6, feed_id = $7, notify = $8, updated_at = $9
+WHERE id = $1 AND user_id = $2
+RETURNING id, user_id, name, query, match, author, feed_id, notify, created_at, updated_at
+`
+
+type UpdateSavedSearchParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Query     string
+	Match     string
+	Author    string
+	FeedID    uuid.NullUUID
+	Notify    bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateSavedSearch(ctx context.Context, arg UpdateSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, updateSavedSearch,
+		arg.ID,
+		arg.UserID,
+		arg.Name,
+		arg.Query,
+		arg.Match,
+		arg.Author,
+		arg.FeedID,
+		arg.Notify,
+		arg.UpdatedAt,
+	)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.Query,
+		&i.Match,
+		&i.Author,
+		&i.FeedID,
+		&i.Notify,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,128 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: search.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const searchPosts = `-- name: SearchPosts :many
+WITH search AS (
+    SELECT to_tsquery('english', $7) AS query
+)
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.categories, posts.content, posts.search_vector, posts.description_text, posts.sanitized, posts.full_content, posts.full_content_status, posts.pushed,
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = $1 AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ts_rank_cd(posts.search_vector, search.query)::real AS rank,
+    ts_headline('english', posts.title, search.query,
+        'HighlightAll=true, StartSel=' || $2::text || ', StopSel=' || $3::text)::text AS title_headline,
+    ts_headline('english', regexp_replace(posts.description || ' ' || posts.content, '<[^>]*>', ' ', 'g'), search.query,
+        'MaxFragments=2, MinWords=8, MaxWords=24, StartSel=' || $2::text || ', StopSel=' || $3::text)::text AS snippet
+FROM
+    posts
+CROSS JOIN
+    search
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = $1
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = $1
+WHERE
+    posts.search_vector @@ search.query
+    AND ($4::boolean OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = $1
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = $1 AND filter_rules.hide
+    )
+ORDER BY
+    rank DESC, posts.published_at DESC, posts.id DESC
+LIMIT $6 OFFSET $5
+`
+
+type SearchPostsParams struct {
+	UserID   uuid.UUID
+	StartSel string
+	StopSel  string
+	AllFeeds bool
+	Offset   int32
+	Limit    int32
+	Query    string
+}
+
+type SearchPostsRow struct {
+	Post          Post
+	Read          bool
+	Starred       bool
+	Saved         bool
+	Rank          float32
+	TitleHeadline string
+	Snippet       string
+}
+
+// ranked full-text search of the posts in the feeds the user follows, or of every post with all_feeds
+// posts hidden by the user's filter rules are left out
+// headlines have their matches between start_sel and stop_sel, snippet is from the description and content without their html tags
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.UserID,
+		arg.StartSel,
+		arg.StopSel,
+		arg.AllFeeds,
+		arg.Offset,
+		arg.Limit,
+		arg.Query,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Author,
+			pq.Array(&i.Post.Categories),
+			&i.Post.Content,
+			&i.Post.SearchVector,
+			&i.Post.DescriptionText,
+			&i.Post.Sanitized,
+			&i.Post.FullContent,
+			&i.Post.FullContentStatus,
+			&i.Post.Pushed,
+			&i.Read,
+			&i.Starred,
+			&i.Saved,
+			&i.Rank,
+			&i.TitleHeadline,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,145 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: share_tokens.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteShareToken = `-- name: DeleteShareToken :execrows
+DELETE FROM share_tokens
+WHERE user_id = $1 AND kind = $2 AND folder_id IS NOT DISTINCT FROM $3
+`
+
+type DeleteShareTokenParams struct {
+	UserID   uuid.UUID
+	Kind     string
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) DeleteShareToken(ctx context.Context, arg DeleteShareTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteShareToken, arg.UserID, arg.Kind, arg.FolderID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getShareToken = `-- name: GetShareToken :one
+SELECT id, user_id, kind, token, created_at, folder_id FROM share_tokens
+WHERE user_id = $1 AND kind = $2 AND folder_id IS NOT DISTINCT FROM $3
+`
+
+type GetShareTokenParams struct {
+	UserID   uuid.UUID
+	Kind     string
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) GetShareToken(ctx context.Context, arg GetShareTokenParams) (ShareToken, error) {
+	row := q.db.QueryRowContext(ctx, getShareToken, arg.UserID, arg.Kind, arg.FolderID)
+	var i ShareToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Token,
+		&i.CreatedAt,
+		&i.FolderID,
+	)
+	return i, err
+}
+
+const getShareTokenByToken = `-- name: GetShareTokenByToken :one
+SELECT id, user_id, kind, token, created_at, folder_id FROM share_tokens
+WHERE token = $1 AND kind = $2
+`
+
+type GetShareTokenByTokenParams struct {
+	Token string
+	Kind  string
+}
+
+func (q *Queries) GetShareTokenByToken(ctx context.Context, arg GetShareTokenByTokenParams) (ShareToken, error) {
+	row := q.db.QueryRowContext(ctx, getShareTokenByToken, arg.Token, arg.Kind)
+	var i ShareToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Token,
+		&i.CreatedAt,
+		&i.FolderID,
+	)
+	return i, err
+}
+
+const getUserByShareToken = `-- name: GetUserByShareToken :one
+SELECT users.id, users.created_at, users.updated_at, users.name, users.api_key, users.is_admin FROM users
+JOIN share_tokens ON share_tokens.user_id = users.id
+WHERE share_tokens.token = $1 AND share_tokens.kind = $2
+`
+
+type GetUserByShareTokenParams struct {
+	Token string
+	Kind  string
+}
+
+func (q *Queries) GetUserByShareToken(ctx context.Context, arg GetUserByShareTokenParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByShareToken, arg.Token, arg.Kind)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiKey,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const upsertShareToken = `-- name: UpsertShareToken :one
+INSERT INTO share_tokens (id, user_id, kind, token, created_at, folder_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, kind, (COALESCE(folder_id, '00000000-0000-0000-0000-000000000000'))) DO UPDATE
+SET id = EXCLUDED.id, token = EXCLUDED.token, created_at = EXCLUDED.created_at
+RETURNING id, user_id, kind, token, created_at, folder_id
+`
+
+type UpsertShareTokenParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Kind      string
+	Token     string
+	CreatedAt time.Time
+	FolderID  uuid.NullUUID
+}
+
+// a user has one token of each kind, and one for each folder, making a new one replaces the old one
+func (q *Queries) UpsertShareToken(ctx context.Context, arg UpsertShareTokenParams) (ShareToken, error) {
+	row := q.db.QueryRowContext(ctx, upsertShareToken,
+		arg.ID,
+		arg.UserID,
+		arg.Kind,
+		arg.Token,
+		arg.CreatedAt,
+		arg.FolderID,
+	)
+	var i ShareToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Kind,
+		&i.Token,
+		&i.CreatedAt,
+		&i.FolderID,
+	)
+	return i, err
+}
@@ -0,0 +1,134 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: stream_events.sql
+
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createStreamEvent = `-- name: CreateStreamEvent :one
+INSERT INTO stream_events (kind, user_id, feed_id, post_id, data, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, kind, user_id, feed_id, data, created_at, post_id
+`
+
+type CreateStreamEventParams struct {
+	Kind      string
+	UserID    uuid.NullUUID
+	FeedID    uuid.NullUUID
+	PostID    uuid.NullUUID
+	Data      json.RawMessage
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateStreamEvent(ctx context.Context, arg CreateStreamEventParams) (StreamEvent, error) {
+	row := q.db.QueryRowContext(ctx, createStreamEvent,
+		arg.Kind,
+		arg.UserID,
+		arg.FeedID,
+		arg.PostID,
+		arg.Data,
+		arg.CreatedAt,
+	)
+	var i StreamEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.UserID,
+		&i.FeedID,
+		&i.Data,
+		&i.CreatedAt,
+		&i.PostID,
+	)
+	return i, err
+}
+
+const deleteStreamEventsBefore = `-- name: DeleteStreamEventsBefore :execrows
+DELETE FROM stream_events
+WHERE created_at < $1
+`
+
+func (q *Queries) DeleteStreamEventsBefore(ctx context.Context, createdAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteStreamEventsBefore, createdAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLastStreamEventID = `-- name: GetLastStreamEventID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM stream_events
+`
+
+// where a new stream starts, 0 if there are no events
+func (q *Queries) GetLastStreamEventID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastStreamEventID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const getStreamEventsForUser = `-- name: GetStreamEventsForUser :many
+SELECT id, kind, user_id, feed_id, data, created_at, post_id FROM stream_events
+WHERE stream_events.id > $1
+    AND (
+        stream_events.user_id = $2
+        OR stream_events.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = $2 AND feed_follows.notify <> 'none'
+        )
+    )
+    AND (stream_events.post_id IS NULL OR NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = stream_events.post_id AND filter_rules.user_id = $2 AND filter_rules.hide
+    ))
+ORDER BY stream_events.id
+LIMIT $3
+`
+
+type GetStreamEventsForUserParams struct {
+	AfterID int64
+	UserID  uuid.NullUUID
+	Limit   int32
+}
+
+// the user's events after an id, and those of the feeds they follow and haven't muted with notify 'none'
+// events about a post the user's filter rules hide are left out
+func (q *Queries) GetStreamEventsForUser(ctx context.Context, arg GetStreamEventsForUserParams) ([]StreamEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getStreamEventsForUser, arg.AfterID, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StreamEvent
+	for rows.Next() {
+		var i StreamEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.UserID,
+			&i.FeedID,
+			&i.Data,
+			&i.CreatedAt,
+			&i.PostID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
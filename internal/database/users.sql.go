@@ -15,7 +15,7 @@ import (
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, name, api_key)
 VALUES ($1, $2, $3, $4, encode(sha256(random()::text::bytea), 'hex'))
-RETURNING id, created_at, updated_at, name, api_key
+RETURNING id, created_at, updated_at, name, api_key, is_admin
 `
 
 type CreateUserParams struct {
@@ -39,12 +39,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Name,
 		&i.ApiKey,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name, api_key FROM users 
+SELECT id, created_at, updated_at, name, api_key, is_admin FROM users 
 WHERE api_key = $1
 `
 
@@ -57,6 +58,26 @@ func (q *Queries) GetUser(ctx context.Context, apiKey string) (User, error) {
 		&i.UpdatedAt,
 		&i.Name,
 		&i.ApiKey,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, name, api_key, is_admin FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiKey,
+		&i.IsAdmin,
 	)
 	return i, err
 }
@@ -0,0 +1,455 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.18.0
+// source: webhooks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDueWebhookDeliveries = `-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status = 'sending', updated_at = $1
+WHERE id IN (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= $1
+    ORDER BY webhook_deliveries.next_attempt_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_status, error, created_at, updated_at, delivered_at
+`
+
+type ClaimDueWebhookDeliveriesParams struct {
+	Now   time.Time
+	Limit int32
+}
+
+// marks the pending deliveries that are due as sending, safe to call from several workers
+func (q *Queries) ClaimDueWebhookDeliveries(ctx context.Context, arg ClaimDueWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueWebhookDeliveries, arg.Now, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.PostID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.Error,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at
+`
+
+type CreateWebhookParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	FeedID    uuid.NullUUID
+	FolderID  uuid.NullUUID
+	Keyword   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook,
+		arg.ID,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Keyword,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Keyword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, webhook_id, post_id, payload, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5, $5)
+RETURNING id, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_status, error, created_at, updated_at, delivered_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	ID            uuid.UUID
+	WebhookID     uuid.UUID
+	PostID        uuid.UUID
+	Payload       json.RawMessage
+	NextAttemptAt time.Time
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery,
+		arg.ID,
+		arg.WebhookID,
+		arg.PostID,
+		arg.Payload,
+		arg.NextAttemptAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.PostID,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteWebhookParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhook, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishWebhookDeliveryAttempt = `-- name: FinishWebhookDeliveryAttempt :one
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, response_status = $4, error = $5,
+    updated_at = $6, delivered_at = $7
+WHERE id = $1
+RETURNING id, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_status, error, created_at, updated_at, delivered_at
+`
+
+type FinishWebhookDeliveryAttemptParams struct {
+	ID             uuid.UUID
+	Status         string
+	NextAttemptAt  time.Time
+	ResponseStatus sql.NullInt32
+	Error          string
+	UpdatedAt      time.Time
+	DeliveredAt    sql.NullTime
+}
+
+func (q *Queries) FinishWebhookDeliveryAttempt(ctx context.Context, arg FinishWebhookDeliveryAttemptParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, finishWebhookDeliveryAttempt,
+		arg.ID,
+		arg.Status,
+		arg.NextAttemptAt,
+		arg.ResponseStatus,
+		arg.Error,
+		arg.UpdatedAt,
+		arg.DeliveredAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.PostID,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at FROM webhooks
+WHERE id = $1 AND user_id = $2
+`
+
+type GetWebhookParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, arg.ID, arg.UserID)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Keyword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookByID = `-- name: GetWebhookByID :one
+SELECT id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) GetWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookByID, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.FeedID,
+		&i.FolderID,
+		&i.Keyword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDeliveries = `-- name: GetWebhookDeliveries :many
+SELECT id, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_status, error, created_at, updated_at, delivered_at FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetWebhookDeliveriesParams struct {
+	WebhookID uuid.UUID
+	Limit     int32
+}
+
+func (q *Queries) GetWebhookDeliveries(ctx context.Context, arg GetWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookDeliveries, arg.WebhookID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookDelivery
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.PostID,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.Error,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, webhook_id, post_id, payload, status, attempts, next_attempt_at, response_status, error, created_at, updated_at, delivered_at FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2
+`
+
+type GetWebhookDeliveryParams struct {
+	ID        uuid.UUID
+	WebhookID uuid.UUID
+}
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDelivery, arg.ID, arg.WebhookID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.PostID,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.Error,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeliveredAt,
+	)
+	return i, err
+}
+
+const getWebhooks = `-- name: GetWebhooks :many
+SELECT id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetWebhooks(ctx context.Context, userID uuid.UUID) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Keyword,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWebhooksForPost = `-- name: GetWebhooksForPost :many
+SELECT webhooks.id, webhooks.user_id, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.folder_id, webhooks.keyword, webhooks.created_at, webhooks.updated_at FROM webhooks
+JOIN feed_follows ON feed_follows.user_id = webhooks.user_id AND feed_follows.feed_id = $1
+WHERE (webhooks.feed_id IS NULL OR webhooks.feed_id = $1)
+    AND (webhooks.folder_id IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.folder_id = webhooks.folder_id AND feed_follow_folders.feed_follow_id = feed_follows.id
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = $2 AND filter_rules.user_id = webhooks.user_id AND filter_rules.hide
+    )
+`
+
+type GetWebhooksForPostParams struct {
+	FeedID uuid.UUID
+	PostID uuid.UUID
+}
+
+// the webhooks of the feed's followers that take its posts, the keyword is left to the caller
+// followers whose filter rules hide the post get no delivery
+func (q *Queries) GetWebhooksForPost(ctx context.Context, arg GetWebhooksForPostParams) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhooksForPost, arg.FeedID, arg.PostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Webhook
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.FeedID,
+			&i.FolderID,
+			&i.Keyword,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const requeueSendingWebhookDeliveries = `-- name: RequeueSendingWebhookDeliveries :execrows
+UPDATE webhook_deliveries
+SET status = 'pending', updated_at = $1
+WHERE status = 'sending'
+`
+
+// deliveries that were being sent when the server stopped are sent again
+func (q *Queries) RequeueSendingWebhookDeliveries(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueSendingWebhookDeliveries, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
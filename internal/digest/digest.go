@@ -0,0 +1,82 @@
+// Package digest renders the email digests of unread posts as plain text and HTML.
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(texttemplate.FuncMap{"date": date}).ParseFS(templates, "templates/digest.txt"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(htmltemplate.FuncMap{"date": date}).ParseFS(templates, "templates/digest.html"))
+)
+
+// Digest is one email, the posts grouped by folder and then by feed.
+type Digest struct {
+	UserName string
+	// Frequency is "daily" or "weekly".
+	Frequency      string
+	Folders        []Folder
+	UnsubscribeURL string
+}
+
+// Folder is a folder of the user, Name is empty for the feeds in no folder.
+type Folder struct {
+	Name  string
+	Feeds []Feed
+}
+
+type Feed struct {
+	Title string
+	Posts []Post
+}
+
+type Post struct {
+	Title       string
+	URL         string
+	Author      string
+	PublishedAt time.Time
+}
+
+// PostCount is the number of posts in the digest.
+func (d Digest) PostCount() int {
+	count := 0
+	for _, folder := range d.Folders {
+		for _, feed := range folder.Feeds {
+			count += len(feed.Posts)
+		}
+	}
+	return count
+}
+
+// Subject is the subject line of the email.
+func (d Digest) Subject() string {
+	if d.PostCount() == 1 {
+		return fmt.Sprintf("Your %s digest: 1 unread post", d.Frequency)
+	}
+	return fmt.Sprintf("Your %s digest: %d unread posts", d.Frequency, d.PostCount())
+}
+
+// Render renders the plain-text and HTML bodies of the email.
+func Render(d Digest) (text, html string, err error) {
+	textBuf := bytes.Buffer{}
+	if err := textTemplate.Execute(&textBuf, d); err != nil {
+		return "", "", err
+	}
+	htmlBuf := bytes.Buffer{}
+	if err := htmlTemplate.Execute(&htmlBuf, d); err != nil {
+		return "", "", err
+	}
+	return textBuf.String(), htmlBuf.String(), nil
+}
+
+func date(t time.Time) string {
+	return t.UTC().Format("Jan 2, 2006")
+}
@@ -0,0 +1,59 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	published := time.Date(2023, 6, 2, 9, 0, 0, 0, time.UTC)
+	d := Digest{
+		UserName:  "Alice",
+		Frequency: "daily",
+		Folders: []Folder{
+			{Name: "Tech", Feeds: []Feed{
+				{Title: "Go blog", Posts: []Post{
+					{Title: "Go 1.21 <released>", URL: "https://go.dev/blog/go1.21", Author: "Gopher", PublishedAt: published},
+					{Title: "Profile-guided optimization", URL: "https://go.dev/blog/pgo", PublishedAt: published},
+				}},
+			}},
+			{Feeds: []Feed{
+				{Title: "Someone's blog", Posts: []Post{{Title: "Hello", URL: "https://example.com/hello", PublishedAt: published}}},
+			}},
+		},
+		UnsubscribeURL: "https://reader.example.com/v1/digest/unsubscribe/abc",
+	}
+	if d.PostCount() != 3 || d.Subject() != "Your daily digest: 3 unread posts" {
+		t.Errorf("got %d posts, subject %q", d.PostCount(), d.Subject())
+	}
+
+	text, html, err := Render(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Hi Alice,",
+		"== Tech ==",
+		"== Not in a folder ==",
+		"- Go 1.21 <released> by Gopher (Jun 2, 2023)\n  https://go.dev/blog/go1.21",
+		"Unsubscribe from these emails: https://reader.example.com/v1/digest/unsubscribe/abc",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text doesn't contain %q:\n%s", want, text)
+		}
+	}
+	for _, want := range []string{
+		`<a href="https://go.dev/blog/go1.21">Go 1.21 &lt;released&gt;</a> by Gopher`,
+		"Someone&#39;s blog",
+		`<a href="https://reader.example.com/v1/digest/unsubscribe/abc">Unsubscribe</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("html doesn't contain %q:\n%s", want, html)
+		}
+	}
+	// the order of the folders and feeds is kept
+	if strings.Index(text, "Tech") > strings.Index(text, "Not in a folder") {
+		t.Error("expected the folders in order")
+	}
+}
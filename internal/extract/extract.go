@@ -0,0 +1,228 @@
+// Package extract finds the main article in a web page, for feeds that only publish a teaser.
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// ErrNoArticle is returned when the page has no article, or nothing matches the selector.
+var ErrNoArticle = errors.New("extract: no article found in the page")
+
+// the shortest text counted as an article, anything shorter is more likely a teaser or a menu
+const minArticleLength = 200
+
+var (
+	// elements that are never part of the article
+	junkSelector = "script, style, noscript, iframe, object, embed, form, button, input, select, textarea, " +
+		"nav, header, footer, aside, svg, canvas, template, dialog"
+	// classes and ids of page furniture, removed unless they also look like content
+	unlikelyRe = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|` +
+		`header|legends|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|` +
+		`skyscraper|social|sponsor|subscribe|supplemental`)
+	maybeContentRe = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	// classes and ids that make an element more or less likely to be the article
+	positiveRe = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|h-entry|main|page|post|story|text`)
+	negativeRe = regexp.MustCompile(`(?i)byline|comment|contact|foot|masthead|media|meta|outbrain|promo|related|` +
+		`scroll|share|shopping|sidebar|sponsor|tags|tool|widget|nav|menu|advert|popup|cookie|subscribe|newsletter`)
+)
+
+// ValidSelector checks that selector is a CSS selector Article can use.
+func ValidSelector(selector string) error {
+	_, err := cascadia.ParseGroup(selector)
+	return err
+}
+
+// Article returns the html of the main article of page.
+// With a selector the article is every element it matches, in order, otherwise it's found by scoring the
+// paragraphs of the page and picking the element that holds the most text with the fewest links, the way
+// readability does. The html isn't sanitized and its links are as in the page.
+func Article(page []byte, selector string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("script, style, noscript, template").Remove()
+
+	if selector != "" {
+		if err := ValidSelector(selector); err != nil {
+			return "", err
+		}
+		return render(doc.Find(selector))
+	}
+
+	doc.Find(junkSelector).Remove()
+	removeUnlikely(doc)
+	top, scores := topCandidate(doc)
+	if top == nil {
+		return "", ErrNoArticle
+	}
+	return render(withSiblings(top, scores))
+}
+
+// the outer html of every element in the selection, if they have enough text between them
+func render(sel *goquery.Selection) (string, error) {
+	if utf8.RuneCountInString(strings.TrimSpace(sel.Text())) < minArticleLength {
+		return "", ErrNoArticle
+	}
+	out := strings.Builder{}
+	for _, node := range sel.Nodes {
+		if err := html.Render(&out, node); err != nil {
+			return "", err
+		}
+	}
+	return out.String(), nil
+}
+
+// removes the elements whose class or id says they aren't content
+func removeUnlikely(doc *goquery.Document) {
+	doc.Find("body *").Each(func(_ int, s *goquery.Selection) {
+		if s.Is("article, main") {
+			return
+		}
+		names := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikelyRe.MatchString(names) && !maybeContentRe.MatchString(names) {
+			s.Remove()
+		}
+	})
+}
+
+// the element that scores highest, nil if no paragraph is long enough to count
+func topCandidate(doc *goquery.Document) (*html.Node, map[*html.Node]float64) {
+	scores := map[*html.Node]float64{}
+	// in the order they're found, so ties go to the first one in the page
+	candidates := []*html.Node{}
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return
+		}
+		// one for the paragraph, one for each comma and up to three for its length
+		score := 1 + float64(strings.Count(text, ",")) + minFloat(float64(length/100), 3)
+		parent := s.Nodes[0].Parent
+		addScore(parent, score)
+		if parent != nil {
+			addScore(parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, node := range candidates {
+		// links are navigation, not text
+		score := scores[node] * (1 - linkDensity(goquery.NewDocumentFromNode(node).Selection))
+		scores[node] = score
+		if top == nil || score > topScore {
+			top, topScore = node, score
+		}
+	}
+	return top, scores
+}
+
+// the top candidate with the siblings that look like they belong to the same article,
+// like an introduction outside the element with the rest
+func withSiblings(top *html.Node, scores map[*html.Node]float64) *goquery.Selection {
+	if top.Parent == nil {
+		return goquery.NewDocumentFromNode(top).Selection
+	}
+	threshold := maxFloat(10, scores[top]*0.2)
+	nodes := []*html.Node{}
+	for sib := top.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib.Type != html.ElementNode {
+			continue
+		}
+		include := sib == top
+		if score, ok := scores[sib]; ok && score >= threshold {
+			include = true
+		}
+		if sib.Data == "p" {
+			s := goquery.NewDocumentFromNode(sib).Selection
+			text := strings.TrimSpace(s.Text())
+			density := linkDensity(s)
+			if utf8.RuneCountInString(text) > 80 && density < 0.25 {
+				include = true
+			} else if density == 0 && strings.HasSuffix(text, ".") {
+				include = true
+			}
+		}
+		if include {
+			nodes = append(nodes, sib)
+		}
+	}
+	return goquery.NewDocumentFromNode(top.Parent).Selection.Slice(0, 0).AddNodes(nodes...)
+}
+
+// what an element starts with before its paragraphs count, from its tag, class and id
+func initialScore(node *html.Node) float64 {
+	score := 0.0
+	switch node.Data {
+	case "article", "main":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	for _, attr := range node.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if negativeRe.MatchString(attr.Val) {
+			score -= 25
+		}
+		if positiveRe.MatchString(attr.Val) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// how much of the text of s is in links, from 0 to 1
+func linkDensity(s *goquery.Selection) float64 {
+	length := utf8.RuneCountInString(strings.TrimSpace(s.Text()))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += utf8.RuneCountInString(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLength) / float64(length)
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
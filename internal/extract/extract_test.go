@@ -0,0 +1,89 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	page, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestArticle(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		selector string
+		want     []string
+		notWant  []string
+	}{
+		{
+			fixture: "blog.html",
+			want: []string{
+				"Every Go program ships with a profiler",
+				"<pre><code>go tool pprof",
+				`<img src="/images/flamegraph.png"`,
+				"profile again",
+			},
+			notWant: []string{"Archive", "Great post", "Related posts", "Subscribe", "Copyright", "dataLayer"},
+		},
+		{
+			// the first paragraph is outside the story body, but belongs to the article
+			fixture: "news.html",
+			want: []string{
+				"voted on Tuesday",
+				"passed by seven votes",
+				"Construction is expected",
+			},
+			notWant: []string{"Weather", "Advertisement"},
+		},
+		{
+			fixture:  "news.html",
+			selector: ".story-body p",
+			want:     []string{"<p>The plan", "Construction is expected"},
+			notWant:  []string{"voted on Tuesday", "Advertisement"},
+		},
+		{
+			fixture:  "blog.html",
+			selector: "h1.post-title, .post-body",
+			want:     []string{"<h1 class=\"post-title\">Profiling Go programs</h1>", "Flame graphs"},
+			notWant:  []string{"Jane Doe", "Great post"},
+		},
+	}
+	for _, test := range tests {
+		got, err := Article(readFixture(t, test.fixture), test.selector)
+		if err != nil {
+			t.Errorf("%s %q: %v", test.fixture, test.selector, err)
+			continue
+		}
+		for _, want := range test.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s %q: %q is missing from %s", test.fixture, test.selector, want, got)
+			}
+		}
+		for _, notWant := range test.notWant {
+			if strings.Contains(got, notWant) {
+				t.Errorf("%s %q: %q is in %s", test.fixture, test.selector, notWant, got)
+			}
+		}
+	}
+}
+
+func TestArticleNotFound(t *testing.T) {
+	if _, err := Article(readFixture(t, "teaser.html"), ""); !errors.Is(err, ErrNoArticle) {
+		t.Errorf("teaser: got error %v, want ErrNoArticle", err)
+	}
+	if _, err := Article(readFixture(t, "blog.html"), ".no-such-class"); !errors.Is(err, ErrNoArticle) {
+		t.Errorf("selector matching nothing: got error %v, want ErrNoArticle", err)
+	}
+	if _, err := Article(readFixture(t, "blog.html"), "p[["); err == nil || errors.Is(err, ErrNoArticle) {
+		t.Errorf("invalid selector: got error %v", err)
+	}
+}
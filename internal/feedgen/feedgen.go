@@ -0,0 +1,261 @@
+// Package feedgen writes feeds as RSS 2.0, Atom 1.0 or JSON Feed 1.1.
+package feedgen
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Formats a feed can be written in.
+const (
+	RSS      = "rss"
+	Atom     = "atom"
+	JSONFeed = "json"
+)
+
+// ContentTypes are the media types of the formats.
+var ContentTypes = map[string]string{
+	RSS:      "application/rss+xml; charset=utf-8",
+	Atom:     "application/atom+xml; charset=utf-8",
+	JSONFeed: "application/feed+json; charset=utf-8",
+}
+
+// Feed is a feed to write, newest items first.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the url the feed is served at.
+	Link    string
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is one entry of a feed.
+type Item struct {
+	// ID is unique and doesn't change, like urn:uuid:<id>.
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	Author      string
+	Categories  []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Write writes the feed in the format, one of RSS, Atom or JSONFeed.
+func (f *Feed) Write(w io.Writer, format string) error {
+	switch format {
+	case Atom:
+		return f.writeAtom(w)
+	case JSONFeed:
+		return f.writeJSON(w)
+	default:
+		return f.writeRSS(w)
+	}
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          *atomLink `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+func writeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (f *Feed) writeRSS(w io.Writer) error {
+	channel := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		Self:          &atomLink{Href: f.Link, Rel: "self", Type: "application/rss+xml"},
+		Items:         make([]rssItem, 0, len(f.Items)),
+	}
+	for _, item := range f.Items {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			Author:      item.Author,
+			Categories:  item.Categories,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return writeXML(w, rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: channel,
+	})
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+func (f *Feed) writeAtom(w io.Writer) error {
+	feed := atomFeed{
+		ID:       f.Link,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links:    []atomLink{{Href: f.Link, Rel: "self", Type: "application/atom+xml"}},
+		// atom feeds need an author unless every entry has one
+		Author:  atomPerson{Name: f.Title},
+		Entries: make([]atomEntry, 0, len(f.Items)),
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+		}
+		if item.Link != "" {
+			entry.Links = []atomLink{{Href: item.Link, Rel: "alternate", Type: "text/html"}}
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		if item.Description != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Description}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return writeXML(w, feed)
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url,omitempty"`
+	Title         string       `json:"title"`
+	ContentHTML   string       `json:"content_html"`
+	DatePublished string       `json:"date_published"`
+	DateModified  string       `json:"date_modified"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	// the json feed 1.0 field, still read by older readers
+	Author *jsonAuthor `json:"author,omitempty"`
+	Tags   []string    `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	FeedURL     string     `json:"feed_url"`
+	Items       []jsonItem `json:"items"`
+}
+
+func (f *Feed) writeJSON(w io.Writer) error {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		Description: f.Description,
+		FeedURL:     f.Link,
+		Items:       make([]jsonItem, 0, len(f.Items)),
+	}
+	for _, item := range f.Items {
+		jItem := jsonItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.Description,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Tags:          item.Categories,
+		}
+		if item.Author != "" {
+			jItem.Authors = []jsonAuthor{{Name: item.Author}}
+			jItem.Author = &jsonAuthor{Name: item.Author}
+		}
+		feed.Items = append(feed.Items, jItem)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(feed)
+}
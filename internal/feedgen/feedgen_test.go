@@ -0,0 +1,82 @@
+package feedgen
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	return &Feed{
+		Title:       "alice's timeline",
+		Description: "posts from the feeds alice follows",
+		Link:        "https://agg.example.com/v1/output/abc.rss",
+		Updated:     published.Add(time.Hour),
+		Items: []Item{
+			{
+				ID:          "urn:uuid:3a12b21b-b778-4bdf-b027-c6dda54bc550",
+				Title:       "Go 1.21 & more",
+				Link:        "https://go.dev/blog/go1.21",
+				Description: "<p>released</p>",
+				Author:      "Eli",
+				Categories:  []string{"go", "release"},
+				Published:   published,
+				Updated:     published.Add(time.Hour),
+			},
+			{
+				ID:        "urn:uuid:2816a44c-3c97-44d6-9522-545f4cc963dd",
+				Title:     "No link",
+				Published: published.Add(-time.Hour),
+				Updated:   published.Add(-time.Hour),
+			},
+		},
+	}
+}
+
+func TestWriteParses(t *testing.T) {
+	for _, format := range []string{RSS, Atom, JSONFeed} {
+		buf := &bytes.Buffer{}
+		if err := testFeed().Write(buf, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		parsed, err := gofeed.NewParser().Parse(buf)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, buf)
+		}
+		if parsed.Title != "alice's timeline" {
+			t.Errorf("%s: got title %q", format, parsed.Title)
+		}
+		if len(parsed.Items) != 2 {
+			t.Fatalf("%s: got %d items", format, len(parsed.Items))
+		}
+		item := parsed.Items[0]
+		if item.Title != "Go 1.21 & more" || item.Link != "https://go.dev/blog/go1.21" || item.GUID != "urn:uuid:3a12b21b-b778-4bdf-b027-c6dda54bc550" {
+			t.Errorf("%s: got item %+v", format, item)
+		}
+		if item.PublishedParsed == nil || !item.PublishedParsed.Equal(time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: got published %v", format, item.PublishedParsed)
+		}
+		if item.Author == nil || item.Author.Name != "Eli" {
+			t.Errorf("%s: got author %+v", format, item.Author)
+		}
+		if !reflect.DeepEqual(item.Categories, []string{"go", "release"}) {
+			t.Errorf("%s: got categories %v", format, item.Categories)
+		}
+	}
+}
+
+func TestWriteEmptyFeed(t *testing.T) {
+	feed := testFeed()
+	feed.Items = nil
+	buf := &bytes.Buffer{}
+	if err := feed.Write(buf, JSONFeed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"items": []`)) {
+		t.Errorf("json feeds need an items list even when empty, got %s", buf)
+	}
+}
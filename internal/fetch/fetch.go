@@ -0,0 +1,324 @@
+// Package fetch downloads feeds and pages from the web on behalf of the server: politely, one request per host
+// at a time with a pause in between, and never from private or loopback addresses.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxBytes     = 10 << 20
+	defaultHostInterval = time.Second
+	maxRedirects        = 5
+	// the longest a Retry-After makes a host wait
+	maxRetryAfter = time.Hour
+)
+
+// ErrPrivateAddress is returned for urls that resolve to a loopback, private or otherwise internal address.
+var ErrPrivateAddress = errors.New("fetch: refusing to connect to a private address")
+
+// ErrTooLarge is returned when a response body is longer than MaxBytes.
+var ErrTooLarge = errors.New("fetch: response too large")
+
+// StatusError is returned for responses that aren't 2xx.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fetch: %s responded %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Response is a downloaded page or feed.
+type Response struct {
+	// URL is where the body came from, after redirects.
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+// Fetcher downloads urls, it's safe to use from several goroutines.
+type Fetcher struct {
+	UserAgent string
+	// Timeout covers the whole request, including reading the body.
+	Timeout time.Duration
+	// MaxBytes is the longest body read.
+	MaxBytes int64
+	// HostInterval is how long to wait between the end of one request to a host and the start of the next.
+	HostInterval time.Duration
+	// AllowPrivate lets urls resolve to private addresses, only for tests against local servers.
+	AllowPrivate bool
+
+	once   sync.Once
+	client *http.Client
+	// shares client's transport but doesn't follow redirects, for Do
+	noRedirects *http.Client
+
+	mu sync.Mutex
+	// the host's turn, taken by the goroutine holding it until its request is done
+	hosts map[string]chan struct{}
+	// when each host may be requested again
+	next map[string]time.Time
+}
+
+// New returns a Fetcher with the default timeout, size limit and host interval.
+func New(userAgent string) *Fetcher {
+	return &Fetcher{
+		UserAgent:    userAgent,
+		Timeout:      defaultTimeout,
+		MaxBytes:     defaultMaxBytes,
+		HostInterval: defaultHostInterval,
+	}
+}
+
+// Get downloads rawURL, waiting for its host's turn first.
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (*Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("fetch: %q is not an http or https url", rawURL)
+	}
+	f.once.Do(f.init)
+
+	host := strings.ToLower(u.Hostname())
+	if err := f.acquire(ctx, host); err != nil {
+		return nil, err
+	}
+	resp, wait, err := f.get(ctx, u)
+	f.release(host, wait)
+	return resp, err
+}
+
+func (f *Fetcher) init() {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// runs on the resolved address, so a name pointing at an internal address is caught too
+		Control: func(network, address string, c syscall.RawConn) error {
+			if f.AllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Transport: &http.Transport{
+			// no proxy, the dialer has to see the address that is really connected to
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 20 * time.Second,
+			MaxIdleConnsPerHost:   2,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("fetch: too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("fetch: redirected to %q", req.URL.String())
+			}
+			return nil
+		},
+	}
+	f.noRedirects = &http.Client{
+		Transport: f.client.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	f.hosts = map[string]chan struct{}{}
+	f.next = map[string]time.Time{}
+}
+
+// Do sends req with the same refusal of private addresses as Get, for requests that aren't downloads
+// like webhook deliveries. It doesn't wait for the host's turn or follow redirects, a redirect is the response.
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	if (req.URL.Scheme != "http" && req.URL.Scheme != "https") || req.URL.Host == "" {
+		return nil, fmt.Errorf("fetch: %q is not an http or https url", req.URL.String())
+	}
+	f.once.Do(f.init)
+	if f.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	resp, err := f.noRedirects.Do(req)
+	if errors.Is(err, ErrPrivateAddress) {
+		return nil, ErrPrivateAddress
+	}
+	return resp, err
+}
+
+// CheckURL returns an error if rawURL isn't an http or https url, or ErrPrivateAddress if its host
+// is or resolves to a private address, for urls that are saved to be requested later.
+// Names can be pointed elsewhere after the check, requests are checked again when they connect.
+func (f *Fetcher) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("fetch: %q is not an http or https url", rawURL)
+	}
+	if f.AllowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivate(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("fetch: can't resolve %q", host)
+	}
+	for _, addr := range addrs {
+		if isPrivate(addr.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// waits until no other request to host is running and its interval has passed
+func (f *Fetcher) acquire(ctx context.Context, host string) error {
+	for {
+		f.mu.Lock()
+		turn, busy := f.hosts[host]
+		if !busy {
+			f.hosts[host] = make(chan struct{})
+			wait := time.Until(f.next[host])
+			f.mu.Unlock()
+			if wait <= 0 {
+				return nil
+			}
+			select {
+			case <-time.After(wait):
+				return nil
+			case <-ctx.Done():
+				f.mu.Lock()
+				close(f.hosts[host])
+				delete(f.hosts, host)
+				f.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-turn:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// hands the host's turn on, the next request waits the interval or wait if the host asked for longer
+func (f *Fetcher) release(host string, wait time.Duration) {
+	if wait < f.HostInterval {
+		wait = f.HostInterval
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next[host] = time.Now().Add(wait)
+	close(f.hosts[host])
+	delete(f.hosts, host)
+}
+
+// does the request, wait is how long the host asked to be left alone with Retry-After
+func (f *Fetcher) get(ctx context.Context, u *url.URL) (*Response, time.Duration, error) {
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if f.UserAgent != "" {
+		req.Header.Set("User-Agent", f.UserAgent)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) {
+			return nil, 0, ErrPrivateAddress
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		wait := time.Duration(0)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			wait = retryAfter(resp.Header.Get("Retry-After"))
+		}
+		return nil, wait, &StatusError{URL: u.String(), StatusCode: resp.StatusCode}
+	}
+
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, 0, ErrTooLarge
+	}
+	return &Response{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, 0, nil
+}
+
+// parses a Retry-After header, in seconds or as a date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	wait := time.Duration(0)
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		wait = time.Until(date)
+	}
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
+
+// cgnat, shared by carriers and often internal
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// whether ip is an address the server must not be made to connect to
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
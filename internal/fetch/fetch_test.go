@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestFetcher() *Fetcher {
+	f := New("test-agent")
+	f.AllowPrivate = true
+	f.HostInterval = 0
+	return f
+}
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		case "/page":
+			if r.Header.Get("User-Agent") != "test-agent" {
+				t.Errorf("got user agent %q", r.Header.Get("User-Agent"))
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<p>hello</p>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	f := newTestFetcher()
+
+	resp, err := f.Get(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.URL != server.URL+"/page" || resp.ContentType != "text/html" || string(resp.Body) != "<p>hello</p>" {
+		t.Errorf("got %+v", resp)
+	}
+
+	_, err = f.Get(context.Background(), server.URL+"/missing")
+	statusErr := &StatusError{}
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want a 404", err)
+	}
+
+	if _, err := f.Get(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("file url fetched")
+	}
+}
+
+func TestGetRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address fetched")
+	}))
+	defer server.Close()
+	f := New("test-agent")
+
+	for _, u := range []string{server.URL, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)} {
+		if _, err := f.Get(context.Background(), u); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: got error %v, want ErrPrivateAddress", u, err)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hook":
+			if r.Method != http.MethodPost || r.Header.Get("User-Agent") != "test-agent" {
+				t.Errorf("got %s with user agent %q", r.Method, r.Header.Get("User-Agent"))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "/moved":
+			http.Redirect(w, r, "/hook", http.StatusTemporaryRedirect)
+		}
+	}))
+	defer server.Close()
+	f := newTestFetcher()
+
+	for path, want := range map[string]int{"/hook": http.StatusNoContent, "/moved": http.StatusTemporaryRedirect} {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := f.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: got status %d, want %d without following redirects", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestDoRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address requested")
+	}))
+	defer server.Close()
+	f := New("test-agent")
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Do(req); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("got error %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	f := New("test-agent")
+	for _, u := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest/meta-data", "https://[::1]/", "http://10.0.0.5/"} {
+		if err := f.CheckURL(context.Background(), u); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: got error %v, want ErrPrivateAddress", u, err)
+		}
+	}
+	for _, u := range []string{"ftp://example.com/", "javascript:alert(1)", "/relative", "http://"} {
+		if err := f.CheckURL(context.Background(), u); err == nil || errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: got error %v, want it refused as not an http url", u, err)
+		}
+	}
+	if err := f.CheckURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("got error %v for a public address", err)
+	}
+
+	f.AllowPrivate = true
+	if err := f.CheckURL(context.Background(), "http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("got error %v with AllowPrivate", err)
+	}
+}
+
+func TestIsPrivate(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"172.16.0.1":      true,
+		"192.168.1.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fd00::1":         true,
+		"fe80::1":         true,
+		"93.184.216.34":   false,
+		"2606:4700::1111": false,
+	} {
+		if got := isPrivate(net.ParseIP(ip)); got != want {
+			t.Errorf("isPrivate(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestGetTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer server.Close()
+	f := newTestFetcher()
+	f.MaxBytes = 50
+
+	if _, err := f.Get(context.Background(), server.URL); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got error %v, want ErrTooLarge", err)
+	}
+}
+
+func TestGetWaitsBetweenRequestsToAHost(t *testing.T) {
+	mu := sync.Mutex{}
+	times := []time.Time{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+	}))
+	defer server.Close()
+	f := newTestFetcher()
+	f.HostInterval = 50 * time.Millisecond
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Get(context.Background(), server.URL); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(times) != 3 {
+		t.Fatalf("got %d requests", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 45*time.Millisecond {
+			t.Errorf("request %d came %v after the one before", i, gap)
+		}
+	}
+}
+
+func TestGetHonorsRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	f := newTestFetcher()
+
+	if _, err := f.Get(context.Background(), server.URL); err == nil {
+		t.Fatal("no error for a 429")
+	}
+	// the host isn't asked again before the two minutes are up
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.Get(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the wait to time out", err)
+	}
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// the tag feedly puts on entries saved for later, its version of starring
+const feedlySavedTag = "/tag/global.saved"
+
+type feedlyTag struct {
+	ID string `json:"id"`
+}
+
+type feedlyEntry struct {
+	Title        string         `json:"title"`
+	OriginID     string         `json:"originId"`
+	CanonicalURL string         `json:"canonicalUrl"`
+	Canonical    []greaderLink  `json:"canonical"`
+	Alternate    []greaderLink  `json:"alternate"`
+	Published    int64          `json:"published"`
+	Unread       *bool          `json:"unread"`
+	Author       string         `json:"author"`
+	Content      greaderContent `json:"content"`
+	Summary      greaderContent `json:"summary"`
+	Origin       greaderOrigin  `json:"origin"`
+	Tags         []feedlyTag    `json:"tags"`
+}
+
+func (entry feedlyEntry) url() string {
+	if entry.CanonicalURL != "" {
+		return entry.CanonicalURL
+	}
+	if href := firstHref(entry.Canonical, entry.Alternate); href != "" {
+		return href
+	}
+	// rss entries keep their link as the origin id
+	if strings.HasPrefix(entry.OriginID, "http://") || strings.HasPrefix(entry.OriginID, "https://") {
+		return entry.OriginID
+	}
+	return ""
+}
+
+// adds the entries of a feedly json export, either a list of entries or a stream of them
+func (e *Export) addFeedlyJSON(file File) error {
+	entries := []feedlyEntry{}
+	var err error
+	if firstByte(file.Data) == '[' {
+		err = json.Unmarshal(file.Data, &entries)
+	} else {
+		stream := struct {
+			Items []feedlyEntry `json:"items"`
+		}{}
+		err = json.Unmarshal(file.Data, &stream)
+		entries = stream.Items
+	}
+	if err != nil {
+		return fileError(file, fmt.Errorf("not a feedly json export: %w", err))
+	}
+
+	for _, entry := range entries {
+		tags := make([]string, 0, len(entry.Tags))
+		for _, tag := range entry.Tags {
+			tags = append(tags, tag.ID)
+		}
+		content := entry.Content.Content
+		if content == "" {
+			content = entry.Summary.Content
+		}
+		published := time.Time{}
+		if entry.Published > 0 {
+			// feedly timestamps are in milliseconds
+			published = time.UnixMilli(entry.Published).UTC()
+		}
+		e.addItem(Item{
+			FeedURL:     feedURLFromStreamID(entry.Origin.StreamID),
+			FeedTitle:   entry.Origin.Title,
+			SiteURL:     entry.Origin.HTMLURL,
+			Title:       entry.Title,
+			URL:         entry.url(),
+			Content:     content,
+			Author:      entry.Author,
+			PublishedAt: published,
+			Starred:     hasSuffix(tags, feedlySavedTag),
+			Read:        entry.Unread != nil && !*entry.Unread,
+		})
+	}
+	return nil
+}
+
+// Feedly imports a feedly export: the OPML of its subscriptions and the json of its entries,
+// entries saved for later become starred.
+type Feedly struct{}
+
+func (Feedly) Format() string { return "feedly" }
+
+func (Feedly) Import(files []File) (*Export, error) {
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	export := &Export{}
+	for _, file := range files {
+		var err error
+		if isXML(file.Data) {
+			err = export.addOPML(file)
+		} else {
+			err = export.addFeedlyJSON(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
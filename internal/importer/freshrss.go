@@ -0,0 +1,71 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// most bytes read out of a zip export, so a small upload can't expand without end
+const maxZipBytes = 50 << 20
+
+// the first bytes of a zip file
+var zipMagic = []byte("PK\x03\x04")
+
+// the files in a zip export, skipping folders and files that aren't OPML or json
+func unzip(file File) ([]File, error) {
+	archive, err := zip.NewReader(bytes.NewReader(file.Data), int64(len(file.Data)))
+	if err != nil {
+		return nil, fileError(file, fmt.Errorf("not a valid zip file: %w", err))
+	}
+
+	files := []File{}
+	remaining := int64(maxZipBytes)
+	for _, member := range archive.File {
+		ext := strings.ToLower(path.Ext(member.Name))
+		if member.FileInfo().IsDir() || (ext != ".opml" && ext != ".xml" && ext != ".json") {
+			continue
+		}
+		rc, err := member.Open()
+		if err != nil {
+			return nil, fileError(file, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		rc.Close()
+		if err != nil {
+			return nil, fileError(file, err)
+		}
+		remaining -= int64(len(data))
+		if remaining < 0 {
+			return nil, fileError(file, fmt.Errorf("zip holds more than %d bytes", maxZipBytes))
+		}
+		files = append(files, File{Name: member.Name, Data: data})
+	}
+	return files, nil
+}
+
+// FreshRSS imports the zip FreshRSS exports, holding an OPML of the subscriptions,
+// starred.json and a json of every feed's items, all in the Google Reader format.
+// The files can also be uploaded on their own.
+type FreshRSS struct{}
+
+func (FreshRSS) Format() string { return "freshrss" }
+
+func (FreshRSS) Import(files []File) (*Export, error) {
+	expanded := []File{}
+	for _, file := range files {
+		if !bytes.HasPrefix(file.Data, zipMagic) {
+			expanded = append(expanded, file)
+			continue
+		}
+		members, err := unzip(file)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, members...)
+	}
+	return GoogleReader{}.Import(expanded)
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"blog_aggregator/internal/opml"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// stream ids and categories of the Google Reader api, also used by Inoreader and FreshRSS
+const (
+	greaderFeedPrefix  = "feed/"
+	greaderStarredTag  = "/state/com.google/starred"
+	greaderReadTag     = "/state/com.google/read"
+	greaderLabelPrefix = "/label/"
+)
+
+type greaderLink struct {
+	Href string `json:"href"`
+}
+
+type greaderContent struct {
+	Content string `json:"content"`
+}
+
+type greaderOrigin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+type greaderItem struct {
+	Title      string         `json:"title"`
+	Published  int64          `json:"published"`
+	Canonical  []greaderLink  `json:"canonical"`
+	Alternate  []greaderLink  `json:"alternate"`
+	Summary    greaderContent `json:"summary"`
+	Content    greaderContent `json:"content"`
+	Author     string         `json:"author"`
+	Categories []string       `json:"categories"`
+	Origin     greaderOrigin  `json:"origin"`
+}
+
+type greaderCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type greaderSubscription struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	URL        string            `json:"url"`
+	HTMLURL    string            `json:"htmlUrl"`
+	Categories []greaderCategory `json:"categories"`
+}
+
+// a stream of items, like starred.json, or a subscription list
+type greaderFile struct {
+	ID            string                `json:"id"`
+	Items         []greaderItem         `json:"items"`
+	Subscriptions []greaderSubscription `json:"subscriptions"`
+}
+
+func hasSuffix(tags []string, suffix string) bool {
+	for _, tag := range tags {
+		if strings.HasSuffix(tag, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstHref(links ...[]greaderLink) string {
+	for _, list := range links {
+		for _, link := range list {
+			if link.Href != "" {
+				return link.Href
+			}
+		}
+	}
+	return ""
+}
+
+// the feed url of a stream id like feed/https://example.com/rss
+func feedURLFromStreamID(streamID string) string {
+	return strings.TrimPrefix(streamID, greaderFeedPrefix)
+}
+
+// adds the subscriptions and items of a Google Reader api json file,
+// every item in a stream of starred items is starred
+func (e *Export) addGoogleReaderJSON(file File) error {
+	dat := greaderFile{}
+	if err := json.Unmarshal(file.Data, &dat); err != nil {
+		return fileError(file, fmt.Errorf("not a Google Reader json export: %w", err))
+	}
+
+	for _, sub := range dat.Subscriptions {
+		xmlURL := sub.URL
+		if xmlURL == "" {
+			xmlURL = feedURLFromStreamID(sub.ID)
+		}
+		folders := []string{}
+		for _, category := range sub.Categories {
+			label := category.Label
+			if label == "" {
+				if i := strings.LastIndex(category.ID, greaderLabelPrefix); i >= 0 {
+					label = category.ID[i+len(greaderLabelPrefix):]
+				}
+			}
+			if label != "" {
+				folders = append(folders, label)
+			}
+		}
+		if len(folders) == 0 {
+			folders = append(folders, "")
+		}
+		// a feed with several labels is in each of their folders
+		for _, folder := range folders {
+			e.Subscriptions = append(e.Subscriptions, opml.Subscription{
+				Title:   sub.Title,
+				XMLURL:  xmlURL,
+				HTMLURL: sub.HTMLURL,
+				Folder:  folder,
+			})
+		}
+	}
+
+	streamStarred := strings.HasSuffix(dat.ID, greaderStarredTag)
+	for _, item := range dat.Items {
+		content := item.Content.Content
+		if content == "" {
+			content = item.Summary.Content
+		}
+		published := time.Time{}
+		if item.Published > 0 {
+			published = time.Unix(item.Published, 0).UTC()
+		}
+		e.addItem(Item{
+			FeedURL:     feedURLFromStreamID(item.Origin.StreamID),
+			FeedTitle:   item.Origin.Title,
+			SiteURL:     item.Origin.HTMLURL,
+			Title:       item.Title,
+			URL:         firstHref(item.Canonical, item.Alternate),
+			Content:     content,
+			Author:      item.Author,
+			PublishedAt: published,
+			Starred:     streamStarred || hasSuffix(item.Categories, greaderStarredTag),
+			Read:        hasSuffix(item.Categories, greaderReadTag),
+		})
+	}
+	return nil
+}
+
+// GoogleReader imports the json of the Google Reader api, which Inoreader and The Old Reader export:
+// subscription lists, and streams of items like starred.json with their read and starred state.
+// OPML files are accepted alongside them.
+type GoogleReader struct{}
+
+func (GoogleReader) Format() string { return "greader" }
+
+func (GoogleReader) Import(files []File) (*Export, error) {
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	export := &Export{}
+	for _, file := range files {
+		var err error
+		if isXML(file.Data) {
+			err = export.addOPML(file)
+		} else {
+			err = export.addGoogleReaderJSON(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
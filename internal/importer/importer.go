@@ -0,0 +1,144 @@
+// Package importer reads the exports of other feed readers into subscriptions,
+// starred items and read state. Every format is an Importer, looked up by its name.
+package importer
+
+import (
+	"blog_aggregator/internal/opml"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is one uploaded file of an export.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Item is a post the user starred or read in the other reader.
+type Item struct {
+	// FeedURL is the url of the feed the item came from, the feed is created if it doesn't exist.
+	FeedURL   string `json:"feed_url"`
+	FeedTitle string `json:"feed_title"`
+	SiteURL   string `json:"site_url"`
+
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Content     string    `json:"content"`
+	Author      string    `json:"author"`
+	PublishedAt time.Time `json:"published_at"`
+	Starred     bool      `json:"starred"`
+	Read        bool      `json:"read"`
+}
+
+// Export is what an importer found in the files.
+type Export struct {
+	Subscriptions []opml.Subscription `json:"subscriptions"`
+	// Items only holds items that are starred or read, the rest come in with the next fetch.
+	Items []Item `json:"items"`
+}
+
+// adds the item if there is any state to bring over
+func (e *Export) addItem(item Item) {
+	if !item.Starred && !item.Read {
+		return
+	}
+	item.Title = strings.TrimSpace(item.Title)
+	e.Items = append(e.Items, item)
+}
+
+// adds the subscriptions of an OPML file
+func (e *Export) addOPML(file File) error {
+	doc, err := opml.Parse(bytes.NewReader(file.Data))
+	if err != nil {
+		return fileError(file, err)
+	}
+	e.Subscriptions = append(e.Subscriptions, doc.Subscriptions()...)
+	return nil
+}
+
+// Importer reads one format. The files are whatever the user uploaded,
+// an importer tells the kinds of file its format exports apart by their content.
+type Importer interface {
+	// Format is the name of the format, like "feedly".
+	Format() string
+	Import(files []File) (*Export, error)
+}
+
+var importers = map[string]Importer{}
+
+// Register makes an importer available to Get under its format.
+func Register(imp Importer) {
+	importers[imp.Format()] = imp
+}
+
+// Get returns the importer for the format, or false if there is none.
+func Get(format string) (Importer, bool) {
+	imp, ok := importers[format]
+	return imp, ok
+}
+
+// Formats lists the registered formats in alphabetical order.
+func Formats() []string {
+	formats := make([]string, 0, len(importers))
+	for format := range importers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+func init() {
+	Register(OPML{})
+	Register(Feedly{})
+	Register(GoogleReader{})
+	Register(Miniflux{})
+	Register(FreshRSS{})
+}
+
+// ErrNoFiles is returned by an importer that was given nothing to import.
+var ErrNoFiles = errors.New("no files to import")
+
+func fileError(file File, err error) error {
+	if file.Name == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", file.Name, err)
+}
+
+// true if the file looks like xml rather than json
+func isXML(data []byte) bool {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '<'
+}
+
+// the first non space byte of a json file, to tell arrays from objects
+func firstByte(data []byte) byte {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
+}
+
+// OPML imports OPML subscription lists, the format every reader can export subscriptions in.
+type OPML struct{}
+
+func (OPML) Format() string { return "opml" }
+
+func (OPML) Import(files []File) (*Export, error) {
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	export := &Export{}
+	for _, file := range files {
+		if err := export.addOPML(file); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"archive/zip"
+	"blog_aggregator/internal/opml"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const subscriptionsOPML = `<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Tech">
+      <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom" htmlUrl="https://go.dev/blog"/>
+    </outline>
+  </body>
+</opml>`
+
+var goBlog = opml.Subscription{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", HTMLURL: "https://go.dev/blog", Folder: "Tech"}
+
+func TestFormats(t *testing.T) {
+	want := []string{"feedly", "freshrss", "greader", "miniflux", "opml"}
+	if got := Formats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, format := range want {
+		imp, ok := Get(format)
+		if !ok || imp.Format() != format {
+			t.Errorf("Get(%q) = %v, %v", format, imp, ok)
+		}
+		if _, err := imp.Import(nil); err == nil {
+			t.Errorf("%s: expected an error without files", format)
+		}
+	}
+	if _, ok := Get("netnewswire"); ok {
+		t.Error("expected no importer for an unknown format")
+	}
+}
+
+func TestGoogleReader(t *testing.T) {
+	subscriptions := `{"subscriptions": [
+		{"id": "feed/https://go.dev/blog/feed.atom", "title": "Go Blog", "htmlUrl": "https://go.dev/blog",
+		 "categories": [{"id": "user/1/label/Tech", "label": "Tech"}, {"id": "user/1/label/Go"}]},
+		{"id": "feed/https://example.com/rss", "title": "Example"}
+	]}`
+	starred := `{"id": "user/1/state/com.google/starred", "items": [
+		{"title": "Go 1.21", "published": 1691539200,
+		 "alternate": [{"href": "https://go.dev/blog/go1.21", "type": "text/html"}],
+		 "summary": {"content": "<p>released</p>"}, "author": "Eli",
+		 "categories": ["user/1/state/com.google/reading-list"],
+		 "origin": {"streamId": "feed/https://go.dev/blog/feed.atom", "title": "Go Blog", "htmlUrl": "https://go.dev/blog"}}
+	]}`
+	reading := `{"id": "user/1/state/com.google/reading-list", "items": [
+		{"title": "Read one", "canonical": [{"href": "https://example.com/read"}],
+		 "categories": ["user/1/state/com.google/read"], "origin": {"streamId": "feed/https://example.com/rss"}},
+		{"title": "Unread one", "canonical": [{"href": "https://example.com/unread"}],
+		 "categories": [], "origin": {"streamId": "feed/https://example.com/rss"}}
+	]}`
+
+	export, err := GoogleReader{}.Import([]File{
+		{Name: "subscriptions.json", Data: []byte(subscriptions)},
+		{Name: "starred.json", Data: []byte(starred)},
+		{Name: "reading-list.json", Data: []byte(reading)},
+		{Name: "feeds.opml", Data: []byte(subscriptionsOPML)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSubs := []opml.Subscription{
+		{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", HTMLURL: "https://go.dev/blog", Folder: "Tech"},
+		{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", HTMLURL: "https://go.dev/blog", Folder: "Go"},
+		{Title: "Example", XMLURL: "https://example.com/rss"},
+		goBlog,
+	}
+	if !reflect.DeepEqual(export.Subscriptions, wantSubs) {
+		t.Errorf("got  %+v\nwant %+v", export.Subscriptions, wantSubs)
+	}
+
+	wantItems := []Item{
+		{
+			FeedURL: "https://go.dev/blog/feed.atom", FeedTitle: "Go Blog", SiteURL: "https://go.dev/blog",
+			Title: "Go 1.21", URL: "https://go.dev/blog/go1.21", Content: "<p>released</p>", Author: "Eli",
+			PublishedAt: time.Unix(1691539200, 0).UTC(), Starred: true,
+		},
+		{FeedURL: "https://example.com/rss", Title: "Read one", URL: "https://example.com/read", Read: true},
+	}
+	if !reflect.DeepEqual(export.Items, wantItems) {
+		t.Errorf("got  %+v\nwant %+v", export.Items, wantItems)
+	}
+}
+
+func TestFeedly(t *testing.T) {
+	saved := `[
+		{"title": "Saved", "originId": "https://example.com/saved", "published": 1691539200123, "unread": true,
+		 "origin": {"streamId": "feed/https://example.com/rss", "title": "Example"},
+		 "tags": [{"id": "user/1/tag/global.saved"}], "content": {"content": "body"}},
+		{"title": "Read", "canonicalUrl": "https://example.com/read", "unread": false,
+		 "origin": {"streamId": "feed/https://example.com/rss"}},
+		{"title": "Neither", "alternate": [{"href": "https://example.com/neither"}], "unread": true,
+		 "origin": {"streamId": "feed/https://example.com/rss"}}
+	]`
+	export, err := Feedly{}.Import([]File{
+		{Name: "feedly.opml", Data: []byte(subscriptionsOPML)},
+		{Name: "saved.json", Data: []byte(saved)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(export.Subscriptions, []opml.Subscription{goBlog}) {
+		t.Errorf("got subscriptions %+v", export.Subscriptions)
+	}
+	wantItems := []Item{
+		{
+			FeedURL: "https://example.com/rss", FeedTitle: "Example", Title: "Saved", URL: "https://example.com/saved",
+			Content: "body", PublishedAt: time.UnixMilli(1691539200123).UTC(), Starred: true,
+		},
+		{FeedURL: "https://example.com/rss", Title: "Read", URL: "https://example.com/read", Read: true},
+	}
+	if !reflect.DeepEqual(export.Items, wantItems) {
+		t.Errorf("got  %+v\nwant %+v", export.Items, wantItems)
+	}
+
+	if _, err := (Feedly{}).Import([]File{{Name: "bad.json", Data: []byte(`{"items": 1}`)}}); err == nil {
+		t.Error("expected an error for json that isn't a feedly export")
+	}
+}
+
+func TestMiniflux(t *testing.T) {
+	feeds := `[{"feed_url": "https://go.dev/blog/feed.atom", "site_url": "https://go.dev/blog", "title": "Go Blog", "category": {"title": "Tech"}}]`
+	entries := `{"total": 2, "entries": [
+		{"url": "https://go.dev/blog/go1.21", "title": "Go 1.21", "status": "read", "starred": true,
+		 "published_at": "2023-08-08T00:00:00Z", "feed": {"feed_url": "https://go.dev/blog/feed.atom", "title": "Go Blog"}},
+		{"url": "https://go.dev/blog/unread", "title": "Unread", "status": "unread", "starred": false,
+		 "feed": {"feed_url": "https://go.dev/blog/feed.atom"}}
+	]}`
+	export, err := Miniflux{}.Import([]File{
+		{Name: "feeds.json", Data: []byte(feeds)},
+		{Name: "entries.json", Data: []byte(entries)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(export.Subscriptions, []opml.Subscription{goBlog}) {
+		t.Errorf("got subscriptions %+v", export.Subscriptions)
+	}
+	wantItems := []Item{{
+		FeedURL: "https://go.dev/blog/feed.atom", FeedTitle: "Go Blog", Title: "Go 1.21", URL: "https://go.dev/blog/go1.21",
+		PublishedAt: time.Date(2023, 8, 8, 0, 0, 0, 0, time.UTC), Starred: true, Read: true,
+	}}
+	if !reflect.DeepEqual(export.Items, wantItems) {
+		t.Errorf("got  %+v\nwant %+v", export.Items, wantItems)
+	}
+}
+
+func TestFreshRSSZip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	members := map[string]string{
+		"feeds.opml.xml": subscriptionsOPML,
+		"starred.json": `{"id": "user/-/state/com.google/starred", "items": [
+			{"title": "Go 1.21", "alternate": [{"href": "https://go.dev/blog/go1.21"}],
+			 "origin": {"streamId": "feed/https://go.dev/blog/feed.atom"}}
+		]}`,
+		"README.txt": "not imported",
+	}
+	for name, content := range members {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := FreshRSS{}.Import([]File{{Name: "freshrss.zip", Data: buf.Bytes()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(export.Subscriptions, []opml.Subscription{goBlog}) {
+		t.Errorf("got subscriptions %+v", export.Subscriptions)
+	}
+	if len(export.Items) != 1 || !export.Items[0].Starred || export.Items[0].URL != "https://go.dev/blog/go1.21" {
+		t.Errorf("got items %+v", export.Items)
+	}
+}
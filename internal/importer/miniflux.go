@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"blog_aggregator/internal/opml"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// the entry status miniflux gives read entries
+const minifluxStatusRead = "read"
+
+type minifluxCategory struct {
+	Title string `json:"title"`
+}
+
+type minifluxFeed struct {
+	FeedURL  string           `json:"feed_url"`
+	SiteURL  string           `json:"site_url"`
+	Title    string           `json:"title"`
+	Category minifluxCategory `json:"category"`
+}
+
+type minifluxEntry struct {
+	URL         string       `json:"url"`
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	Author      string       `json:"author"`
+	PublishedAt time.Time    `json:"published_at"`
+	Status      string       `json:"status"`
+	Starred     bool         `json:"starred"`
+	Feed        minifluxFeed `json:"feed"`
+}
+
+// adds the feeds of a GET /v1/feeds response or the entries of a GET /v1/entries response
+func (e *Export) addMinifluxJSON(file File) error {
+	if firstByte(file.Data) == '[' {
+		feeds := []minifluxFeed{}
+		if err := json.Unmarshal(file.Data, &feeds); err != nil {
+			return fileError(file, fmt.Errorf("not a miniflux feeds export: %w", err))
+		}
+		for _, feed := range feeds {
+			e.Subscriptions = append(e.Subscriptions, opml.Subscription{
+				Title:   feed.Title,
+				XMLURL:  feed.FeedURL,
+				HTMLURL: feed.SiteURL,
+				Folder:  feed.Category.Title,
+			})
+		}
+		return nil
+	}
+
+	dat := struct {
+		Entries []minifluxEntry `json:"entries"`
+	}{}
+	if err := json.Unmarshal(file.Data, &dat); err != nil {
+		return fileError(file, fmt.Errorf("not a miniflux entries export: %w", err))
+	}
+	for _, entry := range dat.Entries {
+		e.addItem(Item{
+			FeedURL:     entry.Feed.FeedURL,
+			FeedTitle:   entry.Feed.Title,
+			SiteURL:     entry.Feed.SiteURL,
+			Title:       entry.Title,
+			URL:         entry.URL,
+			Content:     entry.Content,
+			Author:      entry.Author,
+			PublishedAt: entry.PublishedAt.UTC(),
+			Starred:     entry.Starred,
+			Read:        entry.Status == minifluxStatusRead,
+		})
+	}
+	return nil
+}
+
+// Miniflux imports the OPML miniflux exports, and the json of its api:
+// GET /v1/feeds for subscriptions with their categories and GET /v1/entries for starred and read entries.
+type Miniflux struct{}
+
+func (Miniflux) Format() string { return "miniflux" }
+
+func (Miniflux) Import(files []File) (*Export, error) {
+	if len(files) == 0 {
+		return nil, ErrNoFiles
+	}
+	export := &Export{}
+	for _, file := range files {
+		var err error
+		if isXML(file.Data) {
+			err = export.addOPML(file)
+		} else {
+			err = export.addMinifluxJSON(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
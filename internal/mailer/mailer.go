@@ -0,0 +1,133 @@
+// Package mailer sends multipart emails with a plain-text and an HTML body over SMTP.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is an email to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are added to the message as they are, like List-Unsubscribe.
+	Headers map[string]string
+}
+
+// Mailer sends messages.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTP sends messages through an SMTP server, using STARTTLS when the server offers it.
+// Username and Password are only used if Username isn't empty, most local catchers take no auth.
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is the sender address, like "Blog Aggregator <digest@example.com>".
+	From string
+}
+
+func (s SMTP) Send(msg Message) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid to address: %w", err)
+	}
+	dat, err := msg.Bytes(s.From, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, from.Address, []string{to.Address}, dat)
+}
+
+// Bytes is the message as sent, a multipart/alternative email with both bodies quoted-printable.
+func (msg Message) Bytes(from string, date time.Time) ([]byte, error) {
+	buf := bytes.Buffer{}
+	body := multipart.NewWriter(&buf)
+
+	headers := map[string]string{
+		"From":         from,
+		"To":           msg.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date":         date.Format(time.RFC1123Z),
+		"Message-ID":   messageID(from),
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + body.Boundary(),
+	}
+	for key, value := range msg.Headers {
+		headers[key] = value
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	head := bytes.Buffer{}
+	for _, key := range keys {
+		fmt.Fprintf(&head, "%s: %s\r\n", key, headers[key])
+	}
+	head.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// a unique Message-ID at the domain of the from address
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	dat := make([]byte, 16)
+	rand.Read(dat)
+	return "<" + hex.EncodeToString(dat) + "@" + domain + ">"
+}
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// a one-message SMTP server like the local catchers, no TLS and no auth
+func fakeSMTP(t *testing.T) (host, port string, received <-chan string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				data := strings.Builder{}
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				ch <- data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	host, port, _ = net.SplitHostPort(l.Addr().String())
+	return host, port, ch
+}
+
+func TestSMTPSend(t *testing.T) {
+	host, port, received := fakeSMTP(t)
+	smtp := SMTP{Host: host, Port: port, From: "Blog Aggregator <digest@example.com>"}
+	err := smtp.Send(Message{
+		To:      "alice@example.com",
+		Subject: "Your daily digest – 2 posts",
+		Text:    "Hello Alice,\nhere are your posts.",
+		HTML:    `<p style="margin:0">Hello Alice,</p>`,
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/unsubscribe>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(<-received))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your daily digest – 2 posts" {
+		t.Errorf("got subject %q, %v", subject, err)
+	}
+	if msg.Header.Get("List-Unsubscribe") != "<https://example.com/unsubscribe>" {
+		t.Errorf("got List-Unsubscribe %q", msg.Header.Get("List-Unsubscribe"))
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("got Message-ID %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got Content-Type %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "Hello Alice,\nhere are your posts."},
+		{"text/html; charset=utf-8", `<p style="margin:0">Hello Alice,</p>`},
+	}
+	for _, want := range want {
+		// NextPart decodes quoted-printable
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dat, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Header.Get("Content-Type") != want.contentType || strings.ReplaceAll(string(dat), "\r\n", "\n") != want.body {
+			t.Errorf("got part %q %q, want %q %q", part.Header.Get("Content-Type"), dat, want.contentType, want.body)
+		}
+	}
+}
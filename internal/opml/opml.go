@@ -0,0 +1,177 @@
+// Package opml reads OPML subscription lists, the format feed readers import and export subscriptions in.
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// OPML is an OPML document.
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head holds the document's metadata.
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+	OwnerName   string `xml:"ownerName,omitempty"`
+}
+
+// Body holds the top level outlines.
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed, if it has an XMLURL, or a folder of other outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Name is the outline's title, or its text if it has no title.
+func (o Outline) Name() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
+
+// Subscription is a feed outline along with the folder it was nested in.
+type Subscription struct {
+	Title   string `json:"title"`
+	XMLURL  string `json:"xml_url"`
+	HTMLURL string `json:"html_url"`
+	// Folder is the names of the outlines the feed is nested in joined with FolderSeparator, empty at the top level.
+	Folder string `json:"folder"`
+}
+
+// FolderSeparator joins the names of nested folders.
+const FolderSeparator = " / "
+
+// Parse reads an OPML document.
+func Parse(r io.Reader) (*OPML, error) {
+	doc := &OPML{}
+	decoder := xml.NewDecoder(r)
+	// exports often declare their encoding, the feed urls and titles are read the same either way
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(doc); err != nil {
+		return nil, fmt.Errorf("not a valid OPML document: %w", err)
+	}
+	return doc, nil
+}
+
+// Subscriptions flattens the outlines into the feeds they hold, in document order.
+// Outlines without an xmlUrl that hold other outlines are folders. Outlines with neither an
+// xmlUrl nor nested outlines are returned with an empty XMLURL so they can be reported.
+func (doc *OPML) Subscriptions() []Subscription {
+	subscriptions := []Subscription{}
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, outline := range outlines {
+			xmlURL := strings.TrimSpace(outline.XMLURL)
+			if xmlURL == "" && len(outline.Outlines) > 0 {
+				walk(outline.Outlines, append(folders[:len(folders):len(folders)], outline.Name()))
+				continue
+			}
+			subscriptions = append(subscriptions, Subscription{
+				Title:   outline.Name(),
+				XMLURL:  xmlURL,
+				HTMLURL: strings.TrimSpace(outline.HTMLURL),
+				Folder:  strings.Join(folders, FolderSeparator),
+			})
+			// a feed outline with children is unusual, the children are still imported into the same folder
+			walk(outline.Outlines, folders)
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+	return subscriptions
+}
+
+// a folder while building a document, feeds are folders without children
+type node struct {
+	outline  Outline
+	children []*node
+	folders  map[string]*node
+}
+
+// the child folder with the name, added after the other children if it's new
+func (n *node) folder(name string) *node {
+	if n.folders == nil {
+		n.folders = map[string]*node{}
+	}
+	if child, ok := n.folders[name]; ok {
+		return child
+	}
+	child := &node{outline: Outline{Text: name, Title: name}}
+	n.folders[name] = child
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *node) outlines() []Outline {
+	var outlines []Outline
+	for _, child := range n.children {
+		outline := child.outline
+		outline.Outlines = child.outlines()
+		outlines = append(outlines, outline)
+	}
+	return outlines
+}
+
+// New builds an OPML 2.0 document of the subscriptions in order, each nested in outlines for its folder.
+// The folders are split on FolderSeparator, so the document imports back into the same folders.
+func New(title, ownerName string, created time.Time, subs []Subscription) *OPML {
+	body := &node{}
+	for _, sub := range subs {
+		parent := body
+		if sub.Folder != "" {
+			for _, name := range strings.Split(sub.Folder, FolderSeparator) {
+				parent = parent.folder(name)
+			}
+		}
+		parent.children = append(parent.children, &node{outline: Outline{
+			Text:    sub.Title,
+			Title:   sub.Title,
+			Type:    "rss",
+			XMLURL:  sub.XMLURL,
+			HTMLURL: sub.HTMLURL,
+		}})
+	}
+
+	return &OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: created.UTC().Format(time.RFC1123Z),
+			OwnerName:   ownerName,
+		},
+		Body: Body{Outlines: body.outlines()},
+	}
+}
+
+// Write writes the document as indented XML with an XML declaration.
+func (doc *OPML) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
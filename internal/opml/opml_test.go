@@ -0,0 +1,88 @@
+package opml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const exported = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Boot.dev" title="The Boot.dev Blog" type="rss" xmlUrl="https://blog.boot.dev/index.xml" htmlUrl="https://blog.boot.dev/"/>
+    <outline text="Tech">
+      <outline text="Go Blog" type="rss" xmlUrl=" https://go.dev/blog/feed.atom "/>
+      <outline text="Languages">
+        <outline text="Rust" type="rss" xmlUrl="https://blog.rust-lang.org/feed.xml"/>
+      </outline>
+      <outline text="Empty folder"></outline>
+    </outline>
+    <outline text="No url"/>
+  </body>
+</opml>`
+
+func TestSubscriptions(t *testing.T) {
+	doc, err := Parse(strings.NewReader(exported))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Head.Title != "Subscriptions" {
+		t.Errorf("got title %q", doc.Head.Title)
+	}
+
+	want := []Subscription{
+		{Title: "The Boot.dev Blog", XMLURL: "https://blog.boot.dev/index.xml", HTMLURL: "https://blog.boot.dev/"},
+		{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", Folder: "Tech"},
+		{Title: "Rust", XMLURL: "https://blog.rust-lang.org/feed.xml", Folder: "Tech / Languages"},
+		{Title: "Empty folder", Folder: "Tech"},
+		{Title: "No url"},
+	}
+	if got := doc.Subscriptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseRejectsOtherXML(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`<rss version="2.0"><channel></channel></rss>`)); err == nil {
+		t.Error("expected an error for a document that isn't OPML")
+	}
+	if _, err := Parse(strings.NewReader(`not xml`)); err == nil {
+		t.Error("expected an error for a document that isn't xml")
+	}
+}
+
+func TestNewRoundTrips(t *testing.T) {
+	subs := []Subscription{
+		{Title: "Top", XMLURL: "https://example.com/top.xml", HTMLURL: "https://example.com/"},
+		{Title: "Go Blog", XMLURL: "https://go.dev/blog/feed.atom", Folder: "Tech"},
+		{Title: "Rust", XMLURL: "https://blog.rust-lang.org/feed.xml", Folder: "Tech / Languages"},
+		{Title: "Zig", XMLURL: "https://ziglang.org/news/index.xml", Folder: "Tech / Languages"},
+		{Title: "Cooking & Food", XMLURL: "https://example.com/food.xml?a=1&b=2", Folder: "Life"},
+	}
+	doc := New("alice's subscriptions", "alice", time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC), subs)
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("missing xml declaration: %s", buf.String())
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Version != "2.0" || parsed.Head.OwnerName != "alice" || parsed.Head.DateCreated != "Thu, 01 Jun 2023 12:00:00 +0000" {
+		t.Errorf("unexpected head %+v version %q", parsed.Head, parsed.Version)
+	}
+	if len(parsed.Body.Outlines) != 3 || parsed.Body.Outlines[1].Text != "Tech" || len(parsed.Body.Outlines[1].Outlines) != 2 {
+		t.Errorf("unexpected nesting %+v", parsed.Body.Outlines)
+	}
+	if got := parsed.Subscriptions(); !reflect.DeepEqual(got, subs) {
+		t.Errorf("got  %+v\nwant %+v", got, subs)
+	}
+}
@@ -0,0 +1,170 @@
+// Package sanitize cleans the html of feed items so clients can render it, and derives plain text from it.
+package sanitize
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+)
+
+// the allowlist: the formatting, links, images, lists and tables of user generated content, nothing that runs
+// every link is made absolute before it, so every link opens in a new tab with rel="noopener"
+var policy = bluemonday.UGCPolicy().
+	RequireNoFollowOnLinks(false).
+	AddTargetBlankToFullyQualifiedLinks(true)
+
+// hosts that only serve tracking pixels and counters
+var trackerHosts = map[string]bool{
+	"ad.doubleclick.net":          true,
+	"counter.theconversation.com": true,
+	"pixel.quantserve.com":        true,
+	"pixel.wp.com":                true,
+	"stats.wordpress.com":         true,
+	"www.google-analytics.com":    true,
+}
+
+// HTML keeps the allowed tags and attributes of s, resolves its relative links against baseURL,
+// the link of the item it's from, and drops tracking pixels.
+// Links are left relative if baseURL isn't an http or https url.
+func HTML(s, baseURL string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		base = nil
+	}
+	return policy.Sanitize(rewrite(s, base))
+}
+
+// rewrites the links of the html to absolute ones and leaves out the tracking pixels
+// the output is only safe once it's through the policy
+func rewrite(s string, base *url.URL) string {
+	out := bytes.Buffer{}
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				// keep what the tokenizer couldn't read for the policy to deal with
+				out.Write(z.Raw())
+			}
+			return out.String()
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(z.Raw())
+			continue
+		}
+
+		token := z.Token()
+		if token.Data == "img" && isTrackingPixel(token, base) {
+			continue
+		}
+		for i, attr := range token.Attr {
+			if (attr.Key == "href" || attr.Key == "src") && base != nil {
+				if ref, err := url.Parse(strings.TrimSpace(attr.Val)); err == nil {
+					token.Attr[i].Val = base.ResolveReference(ref).String()
+				}
+			}
+		}
+		out.WriteString(token.String())
+	}
+}
+
+// true for an image that's 1x1 or smaller, or from a tracker
+func isTrackingPixel(img html.Token, base *url.URL) bool {
+	for _, attr := range img.Attr {
+		switch attr.Key {
+		case "width", "height":
+			size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(attr.Val), "px"))
+			if err == nil && size <= 1 {
+				return true
+			}
+		case "src":
+			src, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil {
+				continue
+			}
+			if base != nil {
+				src = base.ResolveReference(src)
+			}
+			host := strings.ToLower(src.Hostname())
+			if trackerHosts[host] {
+				return true
+			}
+			// feedburner's per-item counters and facebook's pixel
+			if (host == "feeds.feedburner.com" && strings.HasPrefix(src.Path, "/~r/")) || (host == "www.facebook.com" && src.Path == "/tr") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tags that start a new line in the text
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// Text is the plain text of html, for clients that can't render it.
+// Block elements are on their own lines, with no more than one blank line between them.
+func Text(s string) string {
+	lines := []string{}
+	line := strings.Builder{}
+	// whether a blank line goes before the next one
+	blank := false
+	endLine := func() {
+		text := strings.Join(strings.Fields(line.String()), " ")
+		line.Reset()
+		if text == "" {
+			return
+		}
+		if blank && len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		blank = false
+		lines = append(lines, text)
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			endLine()
+			return strings.Join(lines, "\n")
+		}
+		token := z.Token()
+		switch tt {
+		case html.TextToken:
+			if skip == 0 {
+				line.WriteString(token.Data)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			switch {
+			case token.Data == "script" || token.Data == "style":
+				if tt == html.StartTagToken {
+					skip++
+				} else if tt == html.EndTagToken && skip > 0 {
+					skip--
+				}
+			case token.Data == "br" && strings.TrimSpace(line.String()) == "":
+				// <br><br> breaks a paragraph
+				blank = true
+			case token.Data == "p":
+				endLine()
+				blank = true
+			case blockTags[token.Data]:
+				endLine()
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			"scripts and handlers",
+			`<p onclick="steal()">Hi<script>alert(1)</script></p><a href="javascript:alert(1)">x</a>`,
+			`<p>Hi</p>x`,
+		},
+		{
+			"relative links",
+			`<a href="/about">About</a> <img src="img/cat.png" alt="cat">`,
+			`<a href="https://example.com/about" target="_blank" rel="noopener">About</a> <img src="https://example.com/blog/img/cat.png" alt="cat">`,
+		},
+		{
+			"rel and target",
+			`<a href="https://go.dev" rel="opener" target="_self">Go</a>`,
+			`<a href="https://go.dev" target="_blank" rel="noopener">Go</a>`,
+		},
+		{
+			"tracking pixels",
+			`<p>Text</p><img src="https://example.com/p.gif" width="1" height="1"><img src="https://pixel.wp.com/g.gif"><img src="https://feeds.feedburner.com/~r/blog/~4/abc">`,
+			`<p>Text</p>`,
+		},
+		{
+			"styles and iframes",
+			`<div style="position:fixed">a</div><iframe src="https://evil.example"></iframe><b>b</b>`,
+			`<div>a</div><b>b</b>`,
+		},
+		{"empty", "  ", ""},
+	}
+	for _, test := range tests {
+		if got := HTML(test.in, "https://example.com/blog/post"); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHTMLWithoutBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "urn:uuid:7b0d3c0e-6b1e-4b59-9a8f-0d3c2f0c9f11"} {
+		got := HTML(`<a href="/about">About</a>`, baseURL)
+		if got != `<a href="/about">About</a>` {
+			t.Errorf("base %q: got %q", baseURL, got)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"<p>First   paragraph</p><p>Second &amp; last</p>", "First paragraph\n\nSecond & last"},
+		{"Intro<ul><li>one</li><li>two</li></ul>", "Intro\none\ntwo"},
+		{"a<br>b<br><br>c", "a\nb\n\nc"},
+		{"<style>p{}</style>Plain <b>bold</b> text", "Plain bold text"},
+		{"no html", "no html"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := Text(test.in); got != test.want {
+			t.Errorf("Text(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package scrape turns a web page without a feed into feed items, with CSS selectors for the items and their fields.
+package scrape
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// ErrNoItems is returned when the item selector matches nothing in the page.
+var ErrNoItems = errors.New("scrape: no items found in the page")
+
+// Selectors say where the items are in a page, every selector but Item is matched inside an item.
+type Selectors struct {
+	// Item matches each item, required.
+	Item string `json:"item"`
+	// Title matches the title of the item, required.
+	Title string `json:"title"`
+	// Link matches the link to the item, an a element or one that contains it.
+	// Empty for the first link in the item, or the item itself if it's a link.
+	Link string `json:"link"`
+	// Date matches the publication date, read from a datetime or content attribute or the text.
+	// Empty or unparseable dates are left out.
+	Date string `json:"date"`
+	// Summary matches the description of the item, kept as html.
+	Summary string `json:"summary"`
+}
+
+// Validate checks that Item and Title are set and that every selector parses.
+func (s Selectors) Validate() error {
+	if strings.TrimSpace(s.Item) == "" || strings.TrimSpace(s.Title) == "" {
+		return errors.New("item and title selectors are required")
+	}
+	for _, field := range []struct{ name, selector string }{
+		{"item", s.Item}, {"title", s.Title}, {"link", s.Link}, {"date", s.Date}, {"summary", s.Summary},
+	} {
+		if field.selector == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(field.selector); err != nil {
+			return fmt.Errorf("%s selector is invalid: %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+// Item is an item scraped from a page.
+type Item struct {
+	Title string `json:"title"`
+	// Link is absolute, resolved against the url of the page.
+	Link string `json:"link"`
+	// Published is nil when the item has no date, or it couldn't be parsed.
+	Published *time.Time `json:"published"`
+	Summary   string     `json:"summary"`
+}
+
+// Page is the title of a page and its items.
+type Page struct {
+	Title string
+	Items []Item
+}
+
+// Scrape finds the items in page, which was downloaded from pageURL.
+// Items without a title or a link are skipped, as are repeats of a link.
+func Scrape(page []byte, pageURL string, s Selectors) (*Page, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+	items := doc.Find(s.Item)
+	if items.Length() == 0 {
+		return nil, ErrNoItems
+	}
+
+	result := &Page{Title: strings.TrimSpace(doc.Find("title").First().Text()), Items: []Item{}}
+	seen := map[string]bool{}
+	items.Each(func(_ int, sel *goquery.Selection) {
+		item := Item{
+			Title: strings.Join(strings.Fields(sel.Find(s.Title).First().Text()), " "),
+			Link:  link(sel, s.Link, base),
+		}
+		if item.Title == "" || item.Link == "" || seen[item.Link] {
+			return
+		}
+		seen[item.Link] = true
+		if s.Date != "" {
+			item.Published = date(sel.Find(s.Date).First())
+		}
+		if s.Summary != "" {
+			summary, err := sel.Find(s.Summary).First().Html()
+			if err == nil {
+				item.Summary = strings.TrimSpace(summary)
+			}
+		}
+		result.Items = append(result.Items, item)
+	})
+	return result, nil
+}
+
+// the absolute url of the item's link, empty if it has none
+func link(item *goquery.Selection, selector string, base *url.URL) string {
+	var sel *goquery.Selection
+	switch {
+	case selector != "":
+		sel = item.Find(selector).First()
+	case item.Is("a[href]"):
+		sel = item
+	default:
+		sel = item.Find("a[href]").First()
+	}
+	if !sel.Is("a[href]") {
+		sel = sel.Find("a[href]").First()
+	}
+	href, ok := sel.Attr("href")
+	if !ok {
+		return ""
+	}
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	abs := base.ResolveReference(ref)
+	if abs.Scheme != "http" && abs.Scheme != "https" {
+		return ""
+	}
+	abs.Fragment = ""
+	return abs.String()
+}
+
+// how dates are written on pages, tried in order
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"02 Jan 2006",
+	"Monday, January 2, 2006",
+	"Mon, Jan 2, 2006",
+	"January 2006",
+}
+
+// the date in the element's datetime or content attribute or its text, nil if there is none
+func date(sel *goquery.Selection) *time.Time {
+	if sel.Length() == 0 {
+		return nil
+	}
+	text, ok := sel.Attr("datetime")
+	if !ok {
+		text, ok = sel.Attr("content")
+	}
+	if !ok {
+		text = sel.Text()
+	}
+	return parseDate(text)
+}
+
+func parseDate(s string) *time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
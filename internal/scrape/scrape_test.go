@@ -0,0 +1,92 @@
+package scrape
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScrape(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join("testdata", "listing.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Scrape(page, "https://library.example.com/news/", Selectors{
+		Item:    ".news-item",
+		Title:   "h2",
+		Date:    "time, .date",
+		Summary: ".teaser",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Town Library - News" {
+		t.Errorf("got title %q", got.Title)
+	}
+
+	june := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	may := time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)
+	want := []Item{
+		{
+			Title:     "Summer reading challenge starts",
+			Link:      "https://library.example.com/news/summer-reading",
+			Published: &june,
+			Summary:   "<p>Read <strong>ten books</strong> over the summer and win a prize.</p>",
+		},
+		{
+			Title:     "New opening hours",
+			Link:      "https://library.example.com/news/news/opening-hours",
+			Published: &may,
+			Summary:   "<p>We are open later on Thursdays.</p>",
+		},
+		{
+			Title: "Closed for the holiday",
+			Link:  "https://other.example.com/closed",
+		},
+	}
+	if len(got.Items) != len(want) {
+		t.Fatalf("got %d items %+v, want %d", len(got.Items), got.Items, len(want))
+	}
+	for i, item := range got.Items {
+		w := want[i]
+		if item.Title != w.Title || item.Link != w.Link || item.Summary != w.Summary {
+			t.Errorf("item %d: got %+v, want %+v", i, item, w)
+		}
+		if (item.Published == nil) != (w.Published == nil) || (item.Published != nil && !item.Published.Equal(*w.Published)) {
+			t.Errorf("item %d: got published %v, want %v", i, item.Published, w.Published)
+		}
+	}
+
+	// a link selector picks the link out of the item
+	got, err = Scrape(page, "https://library.example.com/news/", Selectors{Item: ".news-item", Title: "h2", Link: "a.more"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Items) != 1 || got.Items[0].Link != "https://other.example.com/closed" {
+		t.Errorf("got items %+v", got.Items)
+	}
+
+	if _, err := Scrape(page, "https://library.example.com/news/", Selectors{Item: "article", Title: "h2"}); !errors.Is(err, ErrNoItems) {
+		t.Errorf("got error %v, want ErrNoItems", err)
+	}
+}
+
+func TestSelectorsValidate(t *testing.T) {
+	for _, test := range []struct {
+		selectors Selectors
+		valid     bool
+	}{
+		{Selectors{Item: "li", Title: "h2"}, true},
+		{Selectors{Item: "li", Title: "h2", Link: "a.more", Date: "time", Summary: "p"}, true},
+		{Selectors{Item: "li"}, false},
+		{Selectors{Title: "h2"}, false},
+		{Selectors{Item: "li[[", Title: "h2"}, false},
+		{Selectors{Item: "li", Title: "h2", Date: ":nope("}, false},
+	} {
+		if err := test.selectors.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v: got error %v", test.selectors, err)
+		}
+	}
+}
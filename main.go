@@ -1,8 +1,14 @@
 package main
 
 import (
+	"blog_aggregator/internal/broker"
 	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/fetch"
+	"blog_aggregator/internal/mailer"
+	"blog_aggregator/internal/sanitize"
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,7 +16,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
@@ -33,8 +38,29 @@ type FeedTuple struct {
 }
 
 type apiConfig struct {
-	DB           *database.Queries
+	DB *database.Queries
+	// the connection behind DB, to run queries in a transaction
+	Conn         *sql.DB
 	FetchedFeeds []FeedTuple
+	// publishes events to the streams of GET /v1/stream
+	Broker broker.Broker
+	// sends the email digests, nil if SMTP_HOST isn't set
+	Mailer mailer.Mailer
+	// downloads feeds and pages, a host at a time and never from private addresses
+	Fetcher *fetch.Fetcher
+}
+
+// runs fn with queries in a transaction, committed if fn returns nil and rolled back otherwise
+func (apiCfg apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := apiCfg.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(apiCfg.DB.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 // wrapper for respondWithJSON for sending errors as the interface used to be converted to json
@@ -71,20 +97,23 @@ type authedHandler func(http.ResponseWriter, *http.Request, database.User)
 
 // gets a user only if authenticated, that is there is a valid apikey for the user present
 // in the Authorization header
-// returns that authenticated user
+// returns that authenticated user, responds 401 if the apikey is missing or unknown
 func (cfg *apiConfig) middlewareAuth(handler authedHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// get apikey from header
 		apikey, err := getAuthTokenFromHeader(r)
 		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err)
-			log.Println(err)
+			respondWithError(w, http.StatusUnauthorized, err)
 			return
 		}
 
 		// get user
 		user, err := cfg.DB.GetUser(context.Background(), apikey)
 		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, http.StatusUnauthorized, errors.New("invalid api key"))
+				return
+			}
 			respondWithError(w, http.StatusInternalServerError, err)
 			log.Println(err)
 			return
@@ -140,23 +169,8 @@ func (apiCfg apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// generate new user's uuid
-	uuid, err := uuid.NewRandom()
-	if err != nil {
-		log.Fatalf("Error generating UUID: %v\n", err)
-		return
-	}
-
-	// create the user
-	newUser := database.CreateUserParams{
-		ID:        uuid,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      params.Name,
-	}
-
-	// put the user in the db
-	databaseUser, err := apiCfg.DB.CreateUser(context.Background(), newUser)
+	// create the user and put it in the db
+	databaseUser, err := apiCfg.createUser(context.Background(), params.Name)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		log.Println(err)
@@ -176,6 +190,23 @@ func (apiCfg apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request
 	respondWithJSON(w, http.StatusOK, retVal)
 }
 
+// create a new user with the given name, the db generates its apikey
+func (apiCfg apiConfig) createUser(ctx context.Context, name string) (database.User, error) {
+	// generate new user's uuid
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return database.User{}, err
+	}
+
+	currTime := time.Now()
+	return apiCfg.DB.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid,
+		CreatedAt: currTime,
+		UpdatedAt: currTime,
+		Name:      name,
+	})
+}
+
 // GET /v1/users/
 // needs Authorization: ApiKey <key>
 // get a user by their apikey
@@ -212,110 +243,138 @@ func (apiCfg apiConfig) createFeedHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// generate new feed's uuid
-	newFeedUUID, err := uuid.NewRandom()
+	// create the feed, or follow the existing one if the url is already in the db
+	createdFeed, createdFeedFollow, feedCreated, err := apiCfg.createFeedAndFollow(context.Background(), user, params.Name, params.Url)
 	if err != nil {
-		log.Fatalf("Error generating UUID: %v\n", err)
+		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	currTime := time.Now()
-
-	// create the new Feed
-	newFeed := database.CreateFeedParams{
-		ID:        newFeedUUID,
-		CreatedAt: currTime,
-		UpdatedAt: currTime,
-		Name:      params.Name,
-		Url:       params.Url,
-		UserID:    user.ID,
+	if !feedCreated {
+		// respond with just the feed_follow
+		respondWithJSON(w, http.StatusOK, returnVal{
+			Feed:        database.Feed{},
+			Feed_follow: createdFeedFollow,
+		})
+		return
 	}
 
-	// put the feed in the db
-	createdFeed, err := apiCfg.DB.CreateFeed(context.Background(), newFeed)
+	// respond with acknowledgement that we created both a new feed and a new feed follow
+	respondWithJSON(w, http.StatusCreated, returnVal{
+		Feed:        createdFeed,
+		Feed_follow: createdFeedFollow,
+	})
+}
+
+// create a new feed owned by the user and a feed_follow to it
+// if a feed with the same url already exists, only a feed_follow to the existing feed is created
+// following a feed that is already followed returns the existing feed_follow
+// feedCreated reports whether a new feed was inserted
+func (apiCfg apiConfig) createFeedAndFollow(ctx context.Context, user database.User, name, url string) (feed database.Feed, feedFollow database.FeedFollow, feedCreated bool, err error) {
+	feed, feedCreated, err = apiCfg.getOrCreateFeed(ctx, user, name, url)
 	if err != nil {
-		// if err is a duplicate url, create a new feed follow
-		// create a new feed_follow that has the feedid of the existing feed with the same id in the db
-		if err.Error() == "pq: duplicate key value violates unique constraint \"feeds_url_key\"" {
-			log.Println("duplicate url, create new feed_follow to existing feed, respond with just the feed_follow")
+		return database.Feed{}, database.FeedFollow{}, false, err
+	}
 
-			// find the feed that already exists whose url is the one that we have
-			allFeeds, err := apiCfg.DB.GetFeeds(context.Background())
-			if err != nil {
-				respondWithError(w, http.StatusInternalServerError, err)
-				return
-			}
+	feedFollow, _, err = apiCfg.followFeed(ctx, user, feed.ID, "")
+	if err != nil {
+		return database.Feed{}, database.FeedFollow{}, false, err
+	}
+	return feed, feedFollow, feedCreated, nil
+}
 
-			var existingFeedToFind database.Feed
-			for _, feed := range allFeeds {
-				if newFeed.Url == feed.Url {
-					existingFeedToFind = feed
-				}
-			}
+// create a new feed owned by the user, or get the feed that already has the url
+// created reports whether a new feed was inserted
+func (apiCfg apiConfig) getOrCreateFeed(ctx context.Context, user database.User, name, url string) (feed database.Feed, created bool, err error) {
+	// generate new feed's uuid
+	newFeedUUID, err := uuid.NewRandom()
+	if err != nil {
+		return database.Feed{}, false, err
+	}
 
-			newFeedFollowUUID, err := uuid.NewRandom()
-			if err != nil {
-				log.Fatalf("Error generating UUID: %v\n", err)
-				return
-			}
-			newFeedFollow := database.CreateFeedFollowParams{
-				ID:        newFeedFollowUUID,
-				FeedID:    existingFeedToFind.ID,
-				UserID:    user.ID,
-				CreatedAt: currTime,
-				UpdatedAt: currTime,
-			}
+	currTime := time.Now()
 
-			// save new feed_follow to db
-			createdFeedFollow, err := apiCfg.DB.CreateFeedFollow(context.Background(), newFeedFollow)
-			if err != nil {
-				respondWithError(w, http.StatusInternalServerError, err)
-				return
-			}
-			// respond with acknowledgement
-			respondWithJSON(w, http.StatusOK, returnVal{
-				Feed:        database.Feed{},
-				Feed_follow: createdFeedFollow,
-			})
-		} else {
-			// an actual error
-			respondWithError(w, http.StatusInternalServerError, err)
-		}
-	} else {
-		// otherwise, this is a unique, new feed (url), next create a new feed_follow
-		// generate new feed_follow's uuid
-		newFeedFollowUUID, err := uuid.NewRandom()
+	// put the feed in the db
+	feed, err = apiCfg.DB.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        newFeedUUID,
+		CreatedAt: currTime,
+		UpdatedAt: currTime,
+		Name:      name,
+		Url:       url,
+		UserID:    user.ID,
+	})
+	if err == nil {
+		return feed, true, nil
+	} else if err.Error() == "pq: duplicate key value violates unique constraint \"feeds_url_key\"" {
+		// duplicate url, find the feed that already exists whose url is the one that we have
+		log.Println("duplicate url, create new feed_follow to existing feed")
+		feed, err = apiCfg.DB.GetFeedByURL(ctx, url)
 		if err != nil {
-			log.Fatalf("Error generating UUID: %v\n", err)
-			return
+			return database.Feed{}, false, err
 		}
+		return feed, false, nil
+	}
+	// an actual error
+	return database.Feed{}, false, err
+}
 
-		// create the new feed follow
-		newFeedFollow := database.CreateFeedFollowParams{
-			ID:        newFeedFollowUUID,
-			FeedID:    newFeedUUID,
-			UserID:    user.ID,
-			CreatedAt: currTime,
-			UpdatedAt: currTime,
-		}
+// whether the user may edit or delete the feed, only its creator and admins can
+func userCanManageFeed(user database.User, feed database.Feed) bool {
+	return user.IsAdmin || feed.UserID == user.ID
+}
 
-		// save new feed follow to db
-		createdFeedFollow, err := apiCfg.DB.CreateFeedFollow(context.Background(), newFeedFollow)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, err)
-			return
-		}
+// create a new feed_follow from the user to the feed
+// if the user already follows the feed the existing feed_follow is returned and created is false
+// push feeds are private, only their creator and admins follow them without the feed's invite token
+// errFeedNotFound if the feed doesn't exist or is a push feed the user can't follow
+func (apiCfg apiConfig) followFeed(ctx context.Context, user database.User, feedID uuid.UUID, inviteToken string) (feedFollow database.FeedFollow, created bool, err error) {
+	feed, err := apiCfg.DB.GetFeed(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.FeedFollow{}, false, errFeedNotFound
+	}
+	if err != nil {
+		return database.FeedFollow{}, false, err
+	}
+	if feed.Kind == feedKindPush && !userCanManageFeed(user, feed) && (!feed.InviteToken.Valid ||
+		subtle.ConstantTimeCompare([]byte(inviteToken), []byte(feed.InviteToken.String)) != 1) {
+		return database.FeedFollow{}, false, errFeedNotFound
+	}
 
-		// respond with acknowledgement that we created both a new feed and a new feed follow
-		respondWithJSON(w, http.StatusCreated, returnVal{
-			Feed:        createdFeed,
-			Feed_follow: createdFeedFollow,
+	newFeedFollowUUID, err := uuid.NewRandom()
+	if err != nil {
+		return database.FeedFollow{}, false, err
+	}
+
+	currTime := time.Now()
+	feedFollow, err = apiCfg.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        newFeedFollowUUID,
+		FeedID:    feedID,
+		UserID:    user.ID,
+		CreatedAt: currTime,
+		UpdatedAt: currTime,
+	})
+	created = true
+	if errors.Is(err, sql.ErrNoRows) {
+		// already following
+		created = false
+		feedFollow, err = apiCfg.DB.GetFeedFollowByFeed(ctx, database.GetFeedFollowByFeedParams{
+			UserID: user.ID,
+			FeedID: feedID,
 		})
 	}
+	if err != nil {
+		return database.FeedFollow{}, false, err
+	}
+
+	// the posts already in the feed get the user's rules applied, again when following twice in case that failed before
+	if err := apiCfg.applyFilterRulesToFeed(ctx, user, feedID); err != nil {
+		return database.FeedFollow{}, false, err
+	}
+	return feedFollow, created, nil
 }
 
 // GET /v1/feeds
-// retrieve all feeds, don't need to be authed
+// retrieve all feeds but the private push feeds, don't need to be authed
 func (apiCfg apiConfig) getAllFeedsHandler(w http.ResponseWriter, r *http.Request) {
 	allFeeds, err := apiCfg.DB.GetFeeds(context.Background())
 	if err != nil {
@@ -327,10 +386,12 @@ func (apiCfg apiConfig) getAllFeedsHandler(w http.ResponseWriter, r *http.Reques
 
 // POST /v1/feed_follows
 // authed
-// expects a feed_id
+// expects a feed_id, and the invite_token of push feeds
+// 404 if the feed doesn't exist or is a push feed and the invite token is wrong
 func (apiCfg apiConfig) createFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	type parameters struct {
-		Feed_id string `json:"feed_id"`
+		Feed_id     string `json:"feed_id"`
+		InviteToken string `json:"invite_token"`
 	}
 
 	// decode the user from JSON into go struct
@@ -348,13 +409,6 @@ func (apiCfg apiConfig) createFeedFollowHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// generate new feed_follow's uuid
-	newUUID, err := uuid.NewRandom()
-	if err != nil {
-		log.Fatalf("Error generating UUID: %v\n", err)
-		return
-	}
-
 	// get feed_id
 	parsedFeedId, err := uuid.Parse(params.Feed_id)
 	if err != nil {
@@ -362,18 +416,12 @@ func (apiCfg apiConfig) createFeedFollowHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// create new feedfollow
-	currTime := time.Now()
-	newFeedFollow := database.CreateFeedFollowParams{
-		ID:        newUUID,
-		FeedID:    parsedFeedId,
-		UserID:    user.ID,
-		CreatedAt: currTime,
-		UpdatedAt: currTime,
+	// create new feedfollow and store in db, or get the existing one if the feed is already followed
+	createdFeedFollow, _, err := apiCfg.followFeed(context.Background(), user, parsedFeedId, params.InviteToken)
+	if errors.Is(err, errFeedNotFound) {
+		respondWithError(w, http.StatusNotFound, err)
+		return
 	}
-
-	// store in db
-	createdFeedFollow, err := apiCfg.DB.CreateFeedFollow(context.Background(), newFeedFollow)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
@@ -395,34 +443,61 @@ func (apiCfg apiConfig) getFeedFollowsHandler(w http.ResponseWriter, r *http.Req
 }
 
 // DELETE /v1/feed_follows/{feedFollowID}
-// deletes the single feed follower specified by its id
-func (apiCfg apiConfig) deleteFeedFollowHandler(w http.ResponseWriter, r *http.Request) {
+// authed
+// deletes the single feed follower specified by its id, 404 if it isn't the user's
+func (apiCfg apiConfig) deleteFeedFollowHandler(w http.ResponseWriter, r *http.Request, user database.User) {
 	idFeedToDelete := chi.URLParam(r, "feedFollowID")
 	parsedFeedId, err := uuid.Parse(idFeedToDelete)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
-	err = apiCfg.DB.DeleteFeedFollow(context.Background(), parsedFeedId)
+	deleted, err := apiCfg.DB.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
+		ID:     parsedFeedId,
+		UserID: user.ID,
+	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("feed follow not found"))
+		return
+	}
 	respondWithJSON(w, http.StatusOK, nil)
 }
 
 // download the .xml file from the url
 // there exists 3 possible formats: RSS, Atom, JSON feed
 // for now, just do RSS
-func getRSSFromURL(url string) (*gofeed.Feed, error) {
+func (apiCfg apiConfig) getRSSFromURL(url string) (*gofeed.Feed, error) {
+	resp, err := apiCfg.Fetcher.Get(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
 	fp := gofeed.NewParser()
-	feed, err := fp.ParseURL(url)
+	feed, err := fp.Parse(bytes.NewReader(resp.Body))
 	if err != nil {
 		return nil, err
 	}
 	return feed, nil
 }
 
+// download a feed the way its kind is fetched
+func (apiCfg apiConfig) fetchFeed(feed database.Feed) (*gofeed.Feed, error) {
+	switch feed.Kind {
+	case feedKindRSS:
+		return apiCfg.getRSSFromURL(feed.Url)
+	case feedKindScraped:
+		selectors, err := feedSelectors(feed)
+		if err != nil {
+			return nil, err
+		}
+		return apiCfg.getScrapedFeed(feed.Url, selectors)
+	}
+	return nil, fmt.Errorf("unknown feed kind %q", feed.Kind)
+}
+
 // continuously pull things from the feed urls
 // delay is in seconds
 func (apiCfg apiConfig) feedFetcherWorker(delay int, fetchBatchSize int32) {
@@ -455,10 +530,26 @@ func (apiCfg apiConfig) feedFetcherWorker(delay int, fetchBatchSize int32) {
 				})
 
 				// fetch new feed from web
-				data, err := getRSSFromURL(feed.Url)
+				data, err := apiCfg.fetchFeed(feed)
 				if err != nil {
 					log.Println("feedFetcherWorker: ", err)
-					return
+					apiCfg.publishFeedEvent(context.Background(), feed.ID, eventFeedError, feedErrorEvent{
+						FeedID: feed.ID,
+						Url:    feed.Url,
+						Error:  err.Error(),
+					})
+					continue
+				}
+
+				// remember the feed's website for exports
+				if data.Link != "" {
+					err := apiCfg.DB.SetFeedSiteURL(context.Background(), database.SetFeedSiteURLParams{
+						ID:      feed.ID,
+						SiteUrl: data.Link,
+					})
+					if err != nil {
+						log.Println("feedFetcherWorker: ", err)
+					}
 				}
 
 				// save the feeds
@@ -469,6 +560,7 @@ func (apiCfg apiConfig) feedFetcherWorker(delay int, fetchBatchSize int32) {
 			}
 			// create the posts
 			apiCfg.CreatePostsFromFetchedFeeds()
+			apiCfg.pruneStreamEvents(context.Background())
 
 			// wait before checking for more possible feeds to grab
 			time.Sleep(time.Duration(delay) * time.Second)
@@ -481,66 +573,226 @@ func (apiCfg apiConfig) feedFetcherWorker(delay int, fetchBatchSize int32) {
 func (apiCfg apiConfig) CreatePostsFromFetchedFeeds() {
 	// each blog's feed may contain many posts, each post gets its own row in the db
 	for _, feedTuple := range apiCfg.FetchedFeeds {
-		feed := feedTuple.Feed
-		feedId := feedTuple.ID
-		for _, item := range feed.Items {
-			newUUID, err := uuid.NewRandom()
-			if err != nil {
-				log.Fatal(err)
-			}
-			currTime := time.Now()
-
-			// create the post
-			_, err = apiCfg.DB.CreatePost(context.Background(), database.CreatePostParams{
-				ID:          newUUID,
-				CreatedAt:   currTime,
-				UpdatedAt:   currTime,
-				Title:       item.Title,
-				Url:         item.Link,
-				Description: item.Description,
-				PublishedAt: *item.PublishedParsed,
-				FeedID:      feedId,
-			})
-			if err != nil {
-				// post with same url, we don't have a post updated at timestamp in rss feeds anyways
-				if err.Error() != "pq: duplicate key value violates unique constraint \"posts_url_key\"" {
-					// log fatal if not an error that we expected
-					log.Fatal(err)
-				}
+		for _, item := range feedTuple.Feed.Items {
+			if _, _, err := apiCfg.createPostFromItem(context.Background(), feedTuple.ID, item); err != nil {
+				// the feed may have been deleted or merged since it was fetched, that mustn't stop the others
+				log.Printf("couldn't create post %q of feed %s: %v", item.Link, feedTuple.ID, err)
 			}
 		}
 	}
 }
 
+// create the post of a feed item and hand it to everything that acts on new posts
+// created is false if a post already has the item's url, we don't have a post updated at timestamp in rss feeds anyways
+func (apiCfg apiConfig) createPostFromItem(ctx context.Context, feedId uuid.UUID, item *gofeed.Item) (post database.Post, created bool, err error) {
+	newUUID, err := uuid.NewRandom()
+	if err != nil {
+		return database.Post{}, false, err
+	}
+	currTime := time.Now()
+
+	// not every item has a publication date, fall back to when it was updated or fetched
+	publishedAt := currTime
+	if item.PublishedParsed != nil {
+		publishedAt = *item.PublishedParsed
+	} else if item.UpdatedParsed != nil {
+		publishedAt = *item.UpdatedParsed
+	}
+
+	author := ""
+	if item.Author != nil {
+		author = item.Author.Name
+	}
+	categories := item.Categories
+	if categories == nil {
+		categories = []string{}
+	}
+
+	// only the allowed html is kept, with links resolved against the item's
+	description := sanitize.HTML(item.Description, item.Link)
+
+	// create the post
+	post, err = apiCfg.DB.CreatePost(ctx, database.CreatePostParams{
+		ID:              newUUID,
+		CreatedAt:       currTime,
+		UpdatedAt:       currTime,
+		Title:           item.Title,
+		Url:             item.Link,
+		Description:     description,
+		PublishedAt:     publishedAt,
+		FeedID:          feedId,
+		Author:          author,
+		Categories:      categories,
+		Content:         sanitize.HTML(item.Content, item.Link),
+		DescriptionText: sanitize.Text(description),
+	})
+	if err != nil {
+		// post with same url, or in the same push feed for pushed posts
+		if err.Error() == "pq: duplicate key value violates unique constraint \"posts_url_key\"" ||
+			err.Error() == "pq: duplicate key value violates unique constraint \"posts_feed_id_url_key\"" {
+			return database.Post{}, false, nil
+		}
+		return database.Post{}, false, err
+	}
+	// the filter rules go first, the posts they hide aren't streamed, sent to webhooks or notified about
+	apiCfg.applyFilterRules(ctx, post)
+	apiCfg.publishNewPostEvent(ctx, post)
+	apiCfg.matchSavedSearches(ctx, post)
+	apiCfg.enqueueWebhookDeliveries(ctx, post)
+	return post, true, nil
+}
+
 // GET /v1/posts
 // get posts for the feeds that the user is subscribed to
 // authenticated endpoint (ofc)
 // default will return the last 50 posts
+// optional query params: limit, sort (newest/oldest), cursor, feed_id, folder_id, search_id, since, until, author, category, unread, starred, hidden
+// the next/prev pages are in the Link header
 func (apiCfg apiConfig) getUserPosts(w http.ResponseWriter, r *http.Request, user database.User) {
-	// optional limit for number of posts to return, get from url param
-	tmp := r.URL.Query().Get("limit")
-	if tmp == "" {
-		tmp = "50"
-	}
-	limit, err := strconv.Atoi(tmp)
+	query, err := parsePostsQuery(r.URL.Query())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err)
+		respondWithError(w, http.StatusBadRequest, err)
+		return
 	}
 
 	// query for the posts
-	posts, err := apiCfg.DB.GetPostsByUser(context.Background(), database.GetPostsByUserParams{
-		ApiKey: user.ApiKey,
-		Limit:  int32(limit),
-	})
+	page, err := apiCfg.getPostsPage(context.Background(), user, query)
 	if err != nil {
+		if errors.Is(err, errSavedSearchNotFound) {
+			respondWithError(w, http.StatusNotFound, err)
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	// return the posts
+	// return the posts, v1 keeps responding with just the db posts
+	var posts []database.Post
+	for _, post := range page.Posts {
+		posts = append(posts, post.Post)
+	}
+	setPostsLinkHeader(w, r, page)
 	respondWithJSON(w, http.StatusOK, posts)
 }
 
+// router & endpoints
+func (apiCfg apiConfig) router() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(cors.AllowAll().Handler)
+
+	v1Router := chi.NewRouter()
+	router.Mount("/", http.FileServer(http.Dir("./front-end"))) // front-end
+
+	router.Mount("/v1", v1Router)
+
+	v2Router := chi.NewRouter()
+	router.Mount("/v2", v2Router)
+
+	v1Router.Get("/readiness", readinessHandler)
+	v1Router.Get("/err", errorHandler)
+	v1Router.Get("/openapi.json", openAPIHandler) // OpenAPI document for the api
+	v1Router.Get("/docs", apiDocsHandler)         // interactive docs rendered from the OpenAPI document
+
+	v1Router.Post("/users", apiCfg.createUserHandler)                    // create a new user
+	v1Router.Get("/users", apiCfg.middlewareAuth(apiCfg.getUserHandler)) // get a user using apikey
+
+	v1Router.Post("/feeds", apiCfg.middlewareAuth(apiCfg.createFeedHandler))            // create a new feed for the authed user
+	v1Router.Get("/feeds", apiCfg.getAllFeedsHandler)                                   // get all feeds
+	v1Router.Patch("/feeds/{feedID}", apiCfg.middlewareAuth(apiCfg.updateFeedHandler))  // rename a feed or change its url
+	v1Router.Delete("/feeds/{feedID}", apiCfg.middlewareAuth(apiCfg.deleteFeedHandler)) // delete a feed or hand it over to a follower
+
+	v1Router.Post("/feeds/{feedID}/items", apiCfg.pushFeedItemsHandler)                                   // push items into a push feed with its write token
+	v1Router.Post("/feeds/{feedID}/write_token", apiCfg.middlewareAuth(apiCfg.rotateWriteTokenHandler))   // replace the write token of a push feed
+	v1Router.Post("/feeds/{feedID}/invite_token", apiCfg.middlewareAuth(apiCfg.rotateInviteTokenHandler)) // replace the invite token others follow a push feed with
+	v1Router.Post("/push_feeds", apiCfg.middlewareAuth(apiCfg.createPushFeedHandler))                     // create a feed with no upstream that items are pushed into
+
+	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.createFeedFollowHandler))                  // create a new feed follow for the authed user
+	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.getFeedFollowsHandler))                     // get all the feed follows for the authed user
+	v1Router.Delete("/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.deleteFeedFollowHandler)) // delete a feed follow
+	v1Router.Patch("/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.updateFeedFollowHandler))  // edit the settings of a feed follow
+
+	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.getUserPosts)) // get relevant posts for user
+
+	v1Router.Post("/posts/{postID}/read", apiCfg.middlewareAuth(apiCfg.markPostReadHandler))          // mark a post read
+	v1Router.Delete("/posts/{postID}/read", apiCfg.middlewareAuth(apiCfg.markPostUnreadHandler))      // mark a post unread
+	v1Router.Post("/posts/read", apiCfg.middlewareAuth(apiCfg.markPostsReadHandler))                  // mark many posts read
+	v1Router.Post("/posts/unread", apiCfg.middlewareAuth(apiCfg.markPostsUnreadHandler))              // mark many posts unread
+	v1Router.Post("/posts/mark_all_read", apiCfg.middlewareAuth(apiCfg.markAllPostsReadHandler))      // mark all posts read, optionally by feed or before a time
+	v1Router.Get("/feed_follows/unread_counts", apiCfg.middlewareAuth(apiCfg.getUnreadCountsHandler)) // unread counts per followed feed
+
+	v1Router.Post("/posts/{postID}/star", apiCfg.middlewareAuth(apiCfg.starPostHandler))     // star a post
+	v1Router.Delete("/posts/{postID}/star", apiCfg.middlewareAuth(apiCfg.unstarPostHandler)) // unstar a post
+
+	v1Router.Post("/read_later", apiCfg.middlewareAuth(apiCfg.savePostHandler))                                                         // add a post to the read later queue
+	v1Router.Get("/read_later", apiCfg.middlewareAuth(apiCfg.getSavedPostsHandler))                                                     // get the read later queue
+	v1Router.Put("/read_later/order", apiCfg.middlewareAuth(apiCfg.reorderSavedPostsHandler))                                           // reorder the read later queue
+	v1Router.Delete("/read_later/{postID}", apiCfg.middlewareAuth(apiCfg.unsavePostHandler))                                            // remove a post from the read later queue
+	v1Router.Get("/opml", apiCfg.middlewareAuth(apiCfg.exportOPMLHandler))                                                              // export the user's follows as OPML
+	v1Router.Put("/opml/share", apiCfg.middlewareAuth(apiCfg.shareBlogrollHandler))                                                     // share the user's follows as a public blogroll
+	v1Router.Get("/opml/share", apiCfg.middlewareAuth(apiCfg.getBlogrollShareHandler))                                                  // get the user's blogroll url
+	v1Router.Delete("/opml/share", apiCfg.middlewareAuth(apiCfg.unshareBlogrollHandler))                                                // stop sharing the user's blogroll
+	v1Router.Get("/blogrolls/{token}", apiCfg.getBlogrollHandler)                                                                       // a shared blogroll as OPML
+	v1Router.Put("/output_feed", apiCfg.middlewareAuth(apiCfg.shareOutputFeedHandler))                                                  // make the timeline readable as a feed at a private url
+	v1Router.Get("/output_feed", apiCfg.middlewareAuth(apiCfg.getOutputFeedShareHandler))                                               // get the urls of the timeline's output feed
+	v1Router.Delete("/output_feed", apiCfg.middlewareAuth(apiCfg.unshareOutputFeedHandler))                                             // stop serving the timeline's output feed
+	v1Router.Put("/folders/{folderID}/output_feed", apiCfg.middlewareAuth(apiCfg.shareOutputFeedHandler))                               // make a folder readable as a feed at a private url
+	v1Router.Get("/folders/{folderID}/output_feed", apiCfg.middlewareAuth(apiCfg.getOutputFeedShareHandler))                            // get the urls of a folder's output feed
+	v1Router.Delete("/folders/{folderID}/output_feed", apiCfg.middlewareAuth(apiCfg.unshareOutputFeedHandler))                          // stop serving a folder's output feed
+	v1Router.Get("/output/{token}", apiCfg.getOutputFeedHandler)                                                                        // an output feed as rss, atom or json feed
+	v1Router.Get("/stream", apiCfg.middlewareAuth(apiCfg.streamHandler))                                                                // push new posts, read state changes and feed errors as they happen
+	v1Router.Post("/webhooks", apiCfg.middlewareAuth(apiCfg.createWebhookHandler))                                                      // register a url the new posts of followed feeds are POSTed to
+	v1Router.Get("/webhooks", apiCfg.middlewareAuth(apiCfg.getWebhooksHandler))                                                         // get the user's webhooks
+	v1Router.Get("/webhooks/{webhookID}", apiCfg.middlewareAuth(apiCfg.getWebhookHandler))                                              // get a webhook
+	v1Router.Delete("/webhooks/{webhookID}", apiCfg.middlewareAuth(apiCfg.deleteWebhookHandler))                                        // delete a webhook
+	v1Router.Get("/webhooks/{webhookID}/deliveries", apiCfg.middlewareAuth(apiCfg.getWebhookDeliveriesHandler))                         // the delivery log of a webhook
+	v1Router.Post("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", apiCfg.middlewareAuth(apiCfg.redeliverWebhookHandler))     // send a delivery again
+	v1Router.Put("/digest", apiCfg.middlewareAuth(apiCfg.putDigestHandler))                                                             // opt into email digests of unread posts or change their schedule
+	v1Router.Get("/digest", apiCfg.middlewareAuth(apiCfg.getDigestHandler))                                                             // get the user's digest schedule
+	v1Router.Delete("/digest", apiCfg.middlewareAuth(apiCfg.deleteDigestHandler))                                                       // stop the email digests
+	v1Router.Get("/digest/history", apiCfg.middlewareAuth(apiCfg.getDigestHistoryHandler))                                              // the digests and the posts in each
+	v1Router.Get("/digest/confirm/{token}", apiCfg.getConfirmDigestHandler)                                                             // page of the confirmation link sent to a new digest address
+	v1Router.Post("/digest/confirm/{token}", apiCfg.confirmDigestHandler)                                                               // confirm the digest address without an api key
+	v1Router.Get("/digest/unsubscribe/{token}", apiCfg.getUnsubscribeDigestHandler)                                                     // page of the unsubscribe link in the digests
+	v1Router.Post("/digest/unsubscribe/{token}", apiCfg.unsubscribeDigestHandler)                                                       // unsubscribe from the digests without an api key
+	v1Router.Get("/search", apiCfg.middlewareAuth(apiCfg.searchPostsHandler))                                                           // full-text search of the posts in the followed feeds
+	v1Router.Post("/rules", apiCfg.middlewareAuth(apiCfg.createFilterRuleHandler))                                                      // create a rule that hides, tags or stars posts
+	v1Router.Get("/rules", apiCfg.middlewareAuth(apiCfg.getFilterRulesHandler))                                                         // get the user's filter rules
+	v1Router.Post("/rules/dry_run", apiCfg.middlewareAuth(apiCfg.dryRunFilterRuleHandler))                                              // show the recent posts a rule would affect
+	v1Router.Patch("/rules/{ruleID}", apiCfg.middlewareAuth(apiCfg.updateFilterRuleHandler))                                            // change a filter rule
+	v1Router.Delete("/rules/{ruleID}", apiCfg.middlewareAuth(apiCfg.deleteFilterRuleHandler))                                           // delete a filter rule
+	v1Router.Post("/scraped_feeds", apiCfg.middlewareAuth(apiCfg.createScrapedFeedHandler))                                             // create a feed of a web page without one, with css selectors for its items
+	v1Router.Post("/scraped_feeds/preview", apiCfg.middlewareAuth(apiCfg.previewScrapedFeedHandler))                                    // show the items selectors find in a page without saving anything
+	v1Router.Post("/searches", apiCfg.middlewareAuth(apiCfg.createSavedSearchHandler))                                                  // save a search, shown as a virtual feed by GET /v1/posts?search_id=
+	v1Router.Get("/searches", apiCfg.middlewareAuth(apiCfg.getSavedSearchesHandler))                                                    // get the user's saved searches
+	v1Router.Patch("/searches/{searchID}", apiCfg.middlewareAuth(apiCfg.updateSavedSearchHandler))                                      // change a saved search
+	v1Router.Delete("/searches/{searchID}", apiCfg.middlewareAuth(apiCfg.deleteSavedSearchHandler))                                     // delete a saved search
+	v1Router.Post("/opml", apiCfg.middlewareAuth(apiCfg.importOPMLHandler))                                                             // import subscriptions from an OPML file
+	v1Router.Post("/imports", apiCfg.middlewareAuth(apiCfg.importHandler))                                                              // import subscriptions, starred items and read state from another reader
+	v1Router.Get("/imports/{jobID}", apiCfg.middlewareAuth(apiCfg.getImportJobHandler))                                                 // get the status of an import job
+	v1Router.Post("/folders", apiCfg.middlewareAuth(apiCfg.createFolderHandler))                                                        // create a folder
+	v1Router.Get("/folders", apiCfg.middlewareAuth(apiCfg.getFoldersHandler))                                                           // get the user's folders
+	v1Router.Put("/folders/order", apiCfg.middlewareAuth(apiCfg.reorderFoldersHandler))                                                 // reorder the user's folders
+	v1Router.Patch("/folders/{folderID}", apiCfg.middlewareAuth(apiCfg.renameFolderHandler))                                            // rename a folder
+	v1Router.Delete("/folders/{folderID}", apiCfg.middlewareAuth(apiCfg.deleteFolderHandler))                                           // delete a folder
+	v1Router.Put("/folders/{folderID}/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.addFeedFollowToFolderHandler))         // put a feed follow in a folder
+	v1Router.Delete("/folders/{folderID}/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.removeFeedFollowFromFolderHandler)) // take a feed follow out of a folder
+
+	// v2 serves the same resources with explicit snake_case response types
+	v2Router.Post("/users", apiCfg.createUserHandlerV2)                    // create a new user
+	v2Router.Get("/users", apiCfg.middlewareAuth(apiCfg.getUserHandlerV2)) // get a user using apikey
+
+	v2Router.Post("/feeds", apiCfg.middlewareAuth(apiCfg.createFeedHandlerV2)) // create a new feed for the authed user
+	v2Router.Get("/feeds", apiCfg.getAllFeedsHandlerV2)                        // get all feeds
+
+	v2Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.createFeedFollowHandlerV2))                  // create a new feed follow for the authed user
+	v2Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.getFeedFollowsHandlerV2))                     // get all the feed follows for the authed user
+	v2Router.Delete("/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.deleteFeedFollowHandlerV2)) // delete a feed follow
+
+	v2Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.getUserPostsV2)) // get relevant posts for user
+
+	return router
+}
+
 func main() {
 	// environment stuff
 	godotenv.Load() // load .env
@@ -556,41 +808,57 @@ func main() {
 
 	// apiConfig struct
 	apiCfg := apiConfig{
-		DB: dbQueries,
+		DB:      dbQueries,
+		Conn:    db,
+		Broker:  broker.NewMemory(),
+		Fetcher: fetch.New("blog_aggregator"),
+	}
+
+	// email digests are sent through the SMTP server in SMTP_HOST, like a local catcher on port 1025
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpConfig := mailer.SMTP{
+			Host:     smtpHost,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+		if smtpConfig.Port == "" {
+			smtpConfig.Port = "587"
+		}
+		if smtpConfig.From == "" {
+			smtpConfig.From = "blog_aggregator <digest@localhost>"
+		}
+		apiCfg.Mailer = smtpConfig
 	}
 
-	// router & endpoints
-	router := chi.NewRouter()
-	router.Use(cors.AllowAll().Handler)
-
-	v1Router := chi.NewRouter()
-	router.Mount("/", http.FileServer(http.Dir("./front-end"))) // front-end
-
-	router.Mount("/v1", v1Router)
-
-	v1Router.Get("/readiness", readinessHandler)
-	v1Router.Get("/err", errorHandler)
+	// sanitize the posts stored before descriptions were sanitized, before anything can serve them
+	if err := apiCfg.sanitizeOldPosts(context.Background()); err != nil {
+		log.Fatal("couldn't sanitize the old posts, error:", err)
+	}
 
-	v1Router.Post("/users", apiCfg.createUserHandler)                    // create a new user
-	v1Router.Get("/users", apiCfg.middlewareAuth(apiCfg.getUserHandler)) // get a user using apikey
+	// worker to continuously fetch feeds
+	apiCfg.feedFetcherWorker(10, 10)
 
-	v1Router.Post("/feeds", apiCfg.middlewareAuth(apiCfg.createFeedHandler)) // create a new feed for the authed user
-	v1Router.Get("/feeds", apiCfg.getAllFeedsHandler)                        // get all feeds
+	// worker to fetch the full content of the posts of feeds that only publish a teaser
+	apiCfg.fullContentWorker(2, 10)
 
-	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.createFeedFollowHandler)) // create a new feed follow for the authed user
-	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.getFeedFollowsHandler))    // get all the feed follows for the authed user
-	v1Router.Delete("/feed_follows/{feedFollowID}", apiCfg.deleteFeedFollowHandler)       // delete a feed follow
+	// worker to run the imports too big to run during a request
+	apiCfg.importWorker(2)
 
-	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.getUserPosts)) // get relevant posts for user
+	// worker to send and retry the webhook deliveries
+	apiCfg.webhookWorker(5)
 
-	// worker to continuously fetch feeds
-	apiCfg.feedFetcherWorker(10, 10)
+	// worker to send the email digests as they come due
+	if apiCfg.Mailer != nil {
+		apiCfg.digestWorker(60)
+	}
 
 	// start the server to listen
 	log.Println("launching server")
 	srv := http.Server{
 		Addr:    fmt.Sprintf(":%v", port),
-		Handler: router,
+		Handler: apiCfg.router(),
 	}
 	srv.ListenAndServe()
 }
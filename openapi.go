@@ -0,0 +1,33 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// the OpenAPI 3 document describing every route registered in router()
+// keep it in sync when adding routes, openapi_test.go fails otherwise
+//
+//go:embed api/openapi.json
+var openAPISpec []byte
+
+// self contained page that renders openAPISpec, no external scripts
+//
+//go:embed api/docs.html
+var apiDocsPage []byte
+
+// GET /v1/openapi.json
+// serves the OpenAPI document
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
+
+// GET /v1/docs
+// serves the interactive api docs page
+func apiDocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(apiDocsPage)
+}
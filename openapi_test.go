@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+// the methods and paths documented in api/openapi.json
+func specOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("api/openapi.json is not valid json: %v", err)
+	}
+	operations := map[string]bool{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations
+}
+
+// the methods and paths registered on the api routers, the front-end file server is skipped
+func routerOperations(t *testing.T) map[string]bool {
+	t.Helper()
+	operations := map[string]bool{}
+	err := chi.Walk(apiConfig{}.router(), func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/v1/") && !strings.HasPrefix(route, "/v2/") {
+			return nil
+		}
+		// mounted routers show up with a trailing /* and the route without it
+		route = strings.TrimSuffix(route, "/*")
+		operations[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return operations
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestOpenAPISpecCoversRegisteredRoutes(t *testing.T) {
+	documented := specOperations(t)
+	for _, operation := range sortedKeys(routerOperations(t)) {
+		if !documented[operation] {
+			t.Errorf("%s is registered but missing from api/openapi.json", operation)
+		}
+	}
+}
+
+func TestOpenAPISpecHasNoUnregisteredRoutes(t *testing.T) {
+	registered := routerOperations(t)
+	for _, operation := range sortedKeys(specOperations(t)) {
+		if !registered[operation] {
+			t.Errorf("%s is in api/openapi.json but not registered on the router", operation)
+		}
+	}
+}
+
+func TestOpenAPISpecRefsResolve(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	const prefix = `"$ref": "#/components/schemas/`
+	for _, part := range strings.Split(string(openAPISpec), prefix)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("$ref to undefined schema %s", name)
+		}
+	}
+}
+
+func TestOpenAPIHandlers(t *testing.T) {
+	server := httptest.NewServer(apiConfig{}.router())
+	defer server.Close()
+
+	for path, contentType := range map[string]string{
+		"/v1/openapi.json": "application/json",
+		"/v1/docs":         "text/html; charset=utf-8",
+	} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: got status %d", path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != contentType {
+			t.Errorf("GET %s: got Content-Type %q, want %q", path, got, contentType)
+		}
+	}
+}
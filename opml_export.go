@@ -0,0 +1,122 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/opml"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// the public url of a blogroll token
+func blogrollURL(token string) string {
+	return "/v1/blogrolls/" + token
+}
+
+// responds with the user's follows as an OPML document, nested in their folders
+func (apiCfg apiConfig) respondWithOPML(w http.ResponseWriter, user database.User, title string) {
+	rows, err := apiCfg.DB.GetOPMLOutlines(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	subs := make([]opml.Subscription, 0, len(rows))
+	for _, row := range rows {
+		subs = append(subs, opml.Subscription{
+			Title:   row.Title,
+			XMLURL:  row.Url,
+			HTMLURL: row.SiteUrl,
+			Folder:  row.Folder,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := opml.New(title, user.Name, time.Now(), subs).Write(w); err != nil {
+		log.Println("writing opml: ", err)
+	}
+}
+
+// GET /v1/opml
+// authed
+// the user's follows as an OPML 2.0 document, with their folders as nested outlines
+func (apiCfg apiConfig) exportOPMLHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.opml"`)
+	apiCfg.respondWithOPML(w, user, user.Name+"'s subscriptions")
+}
+
+// PUT /v1/opml/share
+// authed
+// makes the user's follows public as a blogroll at GET /v1/blogrolls/{token}
+// a new token is made each time, the old url stops working
+func (apiCfg apiConfig) shareBlogrollHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	shareToken, err := apiCfg.rotateShareToken(context.Background(), user, shareTokenKindBlogroll, uuid.NullUUID{})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newShareTokenResponse(shareToken, blogrollURL(shareToken.Token)))
+}
+
+// GET /v1/opml/share
+// authed
+// the user's blogroll url, 404 if it isn't shared
+func (apiCfg apiConfig) getBlogrollShareHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	shareToken, err := apiCfg.DB.GetShareToken(context.Background(), database.GetShareTokenParams{
+		UserID: user.ID,
+		Kind:   shareTokenKindBlogroll,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("blogroll is not shared"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newShareTokenResponse(shareToken, blogrollURL(shareToken.Token)))
+}
+
+// DELETE /v1/opml/share
+// authed
+// stops sharing the user's blogroll, 404 if it isn't shared
+func (apiCfg apiConfig) unshareBlogrollHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	deleted, err := apiCfg.DB.DeleteShareToken(context.Background(), database.DeleteShareTokenParams{
+		UserID: user.ID,
+		Kind:   shareTokenKindBlogroll,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("blogroll is not shared"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v1/blogrolls/{token}
+// public, the follows of the user that shared the blogroll as an OPML document
+func (apiCfg apiConfig) getBlogrollHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.DB.GetUserByShareToken(context.Background(), database.GetUserByShareTokenParams{
+		Token: chi.URLParam(r, "token"),
+		Kind:  shareTokenKindBlogroll,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("blogroll not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	apiCfg.respondWithOPML(w, user, user.Name+"'s blogroll")
+}
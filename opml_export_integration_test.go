@@ -0,0 +1,111 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/opml"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExportOPML(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	base := "https://example.com/" + uuid.NewString()
+	doc := fmt.Sprintf(`<?xml version="1.0"?>
+<opml version="2.0">
+  <body>
+    <outline text="Top" xmlUrl="%[1]s/top.xml" htmlUrl="%[1]s/top"/>
+    <outline text="Tech">
+      <outline text="Go" xmlUrl="%[1]s/go.xml"/>
+      <outline text="Languages">
+        <outline text="Rust" xmlUrl="%[1]s/rust.xml"/>
+      </outline>
+    </outline>
+  </body>
+</opml>`, base)
+	if _, err := alice.ImportOPML(ctx, strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	// exporting gives back what was imported
+	exported, err := alice.ExportOPML(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := opml.Parse(bytes.NewReader(exported))
+	if err != nil {
+		t.Fatalf("export isn't valid OPML: %v\n%s", err, exported)
+	}
+	want := []opml.Subscription{
+		{Title: "Top", XMLURL: base + "/top.xml", HTMLURL: base + "/top"},
+		{Title: "Go", XMLURL: base + "/go.xml", Folder: "Tech"},
+		{Title: "Rust", XMLURL: base + "/rust.xml", Folder: "Tech / Languages"},
+	}
+	got := parsed.Subscriptions()
+	if len(got) != len(want) {
+		t.Fatalf("got subscriptions %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("subscription %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// not shared yet
+	anonymous := client.New(server.URL)
+	if _, err := alice.GetBlogrollShare(ctx); !client.IsNotFound(err) {
+		t.Errorf("expected 404 before sharing, got %v", err)
+	}
+
+	share, err := alice.ShareBlogroll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.URL != "/v1/blogrolls/"+share.Token {
+		t.Errorf("unexpected share url %q", share.URL)
+	}
+	blogroll, err := anonymous.GetBlogroll(ctx, share.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err = opml.Parse(bytes.NewReader(blogroll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Subscriptions()) != len(want) {
+		t.Errorf("blogroll should list every follow, got %+v", parsed.Subscriptions())
+	}
+
+	// sharing again rotates the token
+	rotated, err := alice.ShareBlogroll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.Token == share.Token {
+		t.Error("sharing again should make a new token")
+	}
+	if _, err := anonymous.GetBlogroll(ctx, share.Token); !client.IsNotFound(err) {
+		t.Errorf("old token should be gone, got %v", err)
+	}
+	current, err := alice.GetBlogrollShare(ctx)
+	if err != nil || current.Token != rotated.Token {
+		t.Errorf("expected the rotated token, got %+v, %v", current, err)
+	}
+
+	if err := alice.UnshareBlogroll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.GetBlogroll(ctx, rotated.Token); !client.IsNotFound(err) {
+		t.Errorf("unshared blogroll should be gone, got %v", err)
+	}
+	if err := alice.UnshareBlogroll(ctx); !client.IsNotFound(err) {
+		t.Errorf("expected 404 unsharing twice, got %v", err)
+	}
+}
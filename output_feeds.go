@@ -0,0 +1,282 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/feedgen"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// how many of the newest posts an output feed holds
+const outputFeedLength = defaultPostsLimit
+
+// the extensions output feed urls can end in
+var outputFeedExtensions = map[string]string{
+	".rss":  feedgen.RSS,
+	".xml":  feedgen.RSS,
+	".atom": feedgen.Atom,
+	".json": feedgen.JSONFeed,
+}
+
+// returned when the folder in the url isn't a uuid, or doesn't exist or isn't the user's
+var (
+	errInvalidFolderID = errors.New("folderID must be a valid uuid")
+	errFolderNotFound  = errors.New("folder not found")
+)
+
+type outputFeedTokenResponse struct {
+	shareTokenResponse
+	// null for the whole timeline
+	FolderID *uuid.UUID `json:"folder_id"`
+	RSSURL   string     `json:"rss_url"`
+	AtomURL  string     `json:"atom_url"`
+	JSONURL  string     `json:"json_url"`
+}
+
+func newOutputFeedTokenResponse(shareToken database.ShareToken) outputFeedTokenResponse {
+	base := "/v1/output/" + shareToken.Token
+	resp := outputFeedTokenResponse{
+		shareTokenResponse: newShareTokenResponse(shareToken, base),
+		RSSURL:             base + ".rss",
+		AtomURL:            base + ".atom",
+		JSONURL:            base + ".json",
+	}
+	if shareToken.FolderID.Valid {
+		resp.FolderID = &shareToken.FolderID.UUID
+	}
+	return resp
+}
+
+// the folder an output feed request is about, from the {folderID} url param
+// not valid for the routes about the whole timeline
+func (apiCfg apiConfig) outputFeedFolderID(r *http.Request, user database.User) (uuid.NullUUID, error) {
+	if chi.URLParam(r, "folderID") == "" {
+		return uuid.NullUUID{}, nil
+	}
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		return uuid.NullUUID{}, errInvalidFolderID
+	}
+	_, err = apiCfg.DB.GetFolder(context.Background(), database.GetFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.NullUUID{}, errFolderNotFound
+	}
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: folderID, Valid: true}, nil
+}
+
+// responds to an error from outputFeedFolderID
+func respondWithFolderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidFolderID):
+		respondWithError(w, http.StatusBadRequest, err)
+	case errors.Is(err, errFolderNotFound):
+		respondWithError(w, http.StatusNotFound, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// PUT /v1/output_feed and PUT /v1/folders/{folderID}/output_feed
+// authed
+// makes the user's timeline, or one folder of it, readable as a feed at a private url without the api key
+// a new token is made each time, the old urls stop working
+// 404 if the folder isn't the user's
+func (apiCfg apiConfig) shareOutputFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := apiCfg.outputFeedFolderID(r, user)
+	if err != nil {
+		respondWithFolderError(w, err)
+		return
+	}
+
+	shareToken, err := apiCfg.rotateShareToken(context.Background(), user, shareTokenKindOutputFeed, folderID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newOutputFeedTokenResponse(shareToken))
+}
+
+// GET /v1/output_feed and GET /v1/folders/{folderID}/output_feed
+// authed
+// the urls of the output feed, 404 if there is none
+func (apiCfg apiConfig) getOutputFeedShareHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := apiCfg.outputFeedFolderID(r, user)
+	if err != nil {
+		respondWithFolderError(w, err)
+		return
+	}
+
+	shareToken, err := apiCfg.DB.GetShareToken(context.Background(), database.GetShareTokenParams{
+		UserID:   user.ID,
+		Kind:     shareTokenKindOutputFeed,
+		FolderID: folderID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("output feed is not shared"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newOutputFeedTokenResponse(shareToken))
+}
+
+// DELETE /v1/output_feed and DELETE /v1/folders/{folderID}/output_feed
+// authed
+// makes the output feed's urls stop working, 404 if there is none
+func (apiCfg apiConfig) unshareOutputFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := apiCfg.outputFeedFolderID(r, user)
+	if err != nil {
+		respondWithFolderError(w, err)
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteShareToken(context.Background(), database.DeleteShareTokenParams{
+		UserID:   user.ID,
+		Kind:     shareTokenKindOutputFeed,
+		FolderID: folderID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("output feed is not shared"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// splits {token} into the token and the format of its extension,
+// without an extension the format is picked from the Accept header and defaults to rss
+func outputFeedFormat(param, accept string) (token, format string, ok bool) {
+	if i := strings.LastIndex(param, "."); i >= 0 {
+		format, ok := outputFeedExtensions[strings.ToLower(param[i:])]
+		return param[:i], format, ok
+	}
+	switch {
+	case strings.Contains(accept, "application/atom+xml"):
+		return param, feedgen.Atom, true
+	case strings.Contains(accept, "application/feed+json"), strings.Contains(accept, "application/json"):
+		return param, feedgen.JSONFeed, true
+	default:
+		return param, feedgen.RSS, true
+	}
+}
+
+// the absolute url the request was made to, for the feed's self link
+func requestURL(r *http.Request) string {
+	return requestOrigin(r) + r.URL.Path
+}
+
+// the scheme and host the request was made to
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// GET /v1/output/{token}, /v1/output/{token}.rss, .atom or .json
+// public, the newest posts of the timeline, or folder, the token was made for
+// as RSS 2.0, Atom 1.0 or JSON Feed, picked by the extension or the Accept header
+// answers If-None-Match and If-Modified-Since with 304 when nothing changed
+func (apiCfg apiConfig) getOutputFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	token, format, ok := outputFeedFormat(chi.URLParam(r, "token"), r.Header.Get("Accept"))
+	if !ok {
+		respondWithError(w, http.StatusNotFound, errors.New("output feed not found"))
+		return
+	}
+
+	shareToken, err := apiCfg.DB.GetShareTokenByToken(ctx, database.GetShareTokenByTokenParams{
+		Token: token,
+		Kind:  shareTokenKindOutputFeed,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("output feed not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	user, err := apiCfg.DB.GetUserByID(ctx, shareToken.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	feed := feedgen.Feed{
+		Title:       user.Name + "'s timeline",
+		Description: "posts from the feeds " + user.Name + " follows",
+		Link:        requestURL(r),
+		Updated:     shareToken.CreatedAt,
+	}
+	if shareToken.FolderID.Valid {
+		folder, err := apiCfg.DB.GetFolder(ctx, database.GetFolderParams{
+			ID:     shareToken.FolderID.UUID,
+			UserID: user.ID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		feed.Title = user.Name + "'s " + folder.Name
+		feed.Description = "posts from the feeds in " + user.Name + "'s " + folder.Name + " folder"
+	}
+
+	query := newPostsQuery()
+	query.Limit = outputFeedLength
+	query.FolderID = shareToken.FolderID
+	page, err := apiCfg.getPostsPage(ctx, user, query)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, timelinePost := range page.Posts {
+		post := timelinePost.Post
+		feed.Items = append(feed.Items, feedgen.Item{
+			ID:          "urn:uuid:" + post.ID.String(),
+			Title:       post.Title,
+			Link:        post.Url,
+			Description: post.Description,
+			Author:      post.Author,
+			Categories:  post.Categories,
+			Published:   post.PublishedAt,
+			Updated:     post.UpdatedAt,
+		})
+		if post.UpdatedAt.After(feed.Updated) {
+			feed.Updated = post.UpdatedAt
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := feed.Write(buf, format); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	w.Header().Set("Content-Type", feedgen.ContentTypes[format])
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Vary", "Accept")
+	// handles the conditional headers and HEAD
+	http.ServeContent(w, r, "", feed.Updated, bytes.NewReader(buf.Bytes()))
+}
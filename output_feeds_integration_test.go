@@ -0,0 +1,187 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestOutputFeeds(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	// a starred post to show up in the timeline
+	base := "https://example.com/" + uuid.NewString()
+	starred := fmt.Sprintf(`{"id": "user/1/state/com.google/starred", "items": [
+		{"title": "Starred", "published": 1691539200, "alternate": [{"href": "%[1]s/starred"}],
+		 "origin": {"streamId": "feed/%[1]s/feed.xml"}}
+	]}`, base)
+	if _, err := alice.Import(ctx, client.FormatGoogleReader, client.ImportFile{Name: "starred.json", Body: strings.NewReader(starred)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := alice.GetOutputFeedShare(ctx, uuid.Nil); !client.IsNotFound(err) {
+		t.Errorf("expected 404 before sharing, got %v", err)
+	}
+	share, err := alice.ShareOutputFeed(ctx, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.FolderID != nil || share.RSSURL != share.URL+".rss" {
+		t.Errorf("unexpected share %+v", share)
+	}
+
+	anonymous := client.New(server.URL)
+	for _, format := range []string{client.OutputRSS, client.OutputAtom, client.OutputJSON} {
+		dat, err := anonymous.GetOutputFeed(ctx, share.Token, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		feed, err := gofeed.NewParser().Parse(bytes.NewReader(dat))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(feed.Items) != 1 || feed.Items[0].Link != base+"/starred" {
+			t.Errorf("%s: got items %+v", format, feed.Items)
+		}
+	}
+
+	// conditional get
+	resp, err := http.Get(server.URL + share.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" || resp.Header.Get("Last-Modified") == "" {
+		t.Fatalf("expected 200 with ETag and Last-Modified, got %d %v", resp.StatusCode, resp.Header)
+	}
+	req, _ := http.NewRequest(http.MethodGet, server.URL+share.URL, nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", resp.StatusCode)
+	}
+
+	// folders get their own token, only for their owner
+	folder, err := alice.CreateFolder(ctx, "Empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folderShare, err := alice.ShareOutputFeed(ctx, folder.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folderShare.FolderID == nil || *folderShare.FolderID != folder.ID || folderShare.Token == share.Token {
+		t.Errorf("unexpected folder share %+v", folderShare)
+	}
+	dat, err := anonymous.GetOutputFeed(ctx, folderShare.Token, client.OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(dat))
+	if err != nil || len(feed.Items) != 0 {
+		t.Errorf("expected an empty folder feed, got %+v, %v", feed, err)
+	}
+	if _, err := bob.ShareOutputFeed(ctx, folder.ID); !client.IsNotFound(err) {
+		t.Errorf("expected 404 sharing someone else's folder, got %v", err)
+	}
+
+	// sharing again rotates, unsharing stops serving
+	rotated, err := alice.ShareOutputFeed(ctx, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.GetOutputFeed(ctx, share.Token, client.OutputRSS); !client.IsNotFound(err) {
+		t.Errorf("old token should be gone, got %v", err)
+	}
+	if err := alice.UnshareOutputFeed(ctx, uuid.Nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.GetOutputFeed(ctx, rotated.Token, client.OutputRSS); !client.IsNotFound(err) {
+		t.Errorf("unshared feed should be gone, got %v", err)
+	}
+	if _, err := anonymous.GetOutputFeed(ctx, folderShare.Token, client.OutputRSS); err != nil {
+		t.Errorf("the folder's feed should still work, got %v", err)
+	}
+}
+
+// folder feeds only serve the folder's feeds, and no feed serves posts the user doesn't see in their timeline
+func TestOutputFeedContents(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	anonymous := client.New(server.URL)
+
+	tech, _ := newTestFeedWithPosts(t, apiCfg, alice, "Generics", "SPONSORED: an IDE")
+	newTestFeedWithPosts(t, apiCfg, alice, "Elections")
+	quiet, _ := newTestFeedWithPosts(t, apiCfg, alice, "Quiet")
+	followIDs := map[uuid.UUID]uuid.UUID{}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, follow := range follows {
+		followIDs[follow.FeedID] = follow.ID
+	}
+
+	hide := true
+	sponsored := []client.FilterCondition{{Field: client.FilterFieldTitle, Op: client.FilterOpMatches, Value: "/sponsored/i"}}
+	if _, err := alice.CreateFilterRule(ctx, client.FilterRuleParams{Name: "no ads", Conditions: &sponsored, Hide: &hide}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.UpdateFeedFollow(ctx, followIDs[quiet.ID], client.UpdateFeedFollowOptions{HideFromTimeline: &hide}); err != nil {
+		t.Fatal(err)
+	}
+	folder, err := alice.CreateFolder(ctx, "Tech")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.AddToFolder(ctx, folder.ID, followIDs[tech.ID]); err != nil {
+		t.Fatal(err)
+	}
+
+	titles := func(folderID uuid.UUID) []string {
+		t.Helper()
+		share, err := alice.ShareOutputFeed(ctx, folderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dat, err := anonymous.GetOutputFeed(ctx, share.Token, client.OutputRSS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		feed, err := gofeed.NewParser().Parse(bytes.NewReader(dat))
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles := []string{}
+		for _, item := range feed.Items {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+
+	if got := titles(folder.ID); len(got) != 1 || got[0] != "Generics" {
+		t.Errorf("got folder feed %v, want only the folder's visible post", got)
+	}
+	if got := titles(uuid.Nil); len(got) != 2 || got[0] != "Elections" || got[1] != "Generics" {
+		t.Errorf("got timeline feed %v, want the posts that aren't hidden, newest first", got)
+	}
+}
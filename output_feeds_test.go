@@ -0,0 +1,30 @@
+package main
+
+import (
+	"blog_aggregator/internal/feedgen"
+	"testing"
+)
+
+func TestOutputFeedFormat(t *testing.T) {
+	tests := []struct {
+		param, accept string
+		token, format string
+		ok            bool
+	}{
+		{"abc", "", "abc", feedgen.RSS, true},
+		{"abc", "application/atom+xml", "abc", feedgen.Atom, true},
+		{"abc", "application/feed+json, */*;q=0.8", "abc", feedgen.JSONFeed, true},
+		{"abc", "application/json", "abc", feedgen.JSONFeed, true},
+		{"abc.rss", "application/atom+xml", "abc", feedgen.RSS, true},
+		{"abc.xml", "", "abc", feedgen.RSS, true},
+		{"abc.ATOM", "", "abc", feedgen.Atom, true},
+		{"abc.json", "", "abc", feedgen.JSONFeed, true},
+		{"abc.html", "", "abc", "", false},
+	}
+	for _, test := range tests {
+		token, format, ok := outputFeedFormat(test.param, test.accept)
+		if ok != test.ok || (ok && (token != test.token || format != test.format)) {
+			t.Errorf("outputFeedFormat(%q, %q) = %q, %q, %v", test.param, test.accept, token, format, ok)
+		}
+	}
+}
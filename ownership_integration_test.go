@@ -0,0 +1,319 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// sends a request as the user with the api key and returns the status code
+func statusAs(t *testing.T, server *httptest.Server, apiKey, method, path string, body interface{}) int {
+	t.Helper()
+	dat, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(method, server.URL+path, bytes.NewReader(dat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "ApiKey "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// another user's resources respond 404 and are left as they were
+func TestOwnershipChecks(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	feed, err := alice.CreateFeed(ctx, "Example", "https://example.com/"+uuid.NewString()+"/index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow := feed.FeedFollow
+	folder, err := alice.CreateFolder(ctx, "Alice's")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.AddToFolder(ctx, folder.ID, follow.ID); err != nil {
+		t.Fatal(err)
+	}
+	otherFolder, err := alice.CreateFolder(ctx, "Also alice's")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no posts have been fetched, a post id nobody can see stands in for another user's post
+	unseenPost := uuid.NewString()
+	folderPath := "/v1/folders/" + folder.ID.String()
+	followPath := "/feed_follows/" + follow.ID.String()
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{http.MethodPatch, "/v1/feeds/" + feed.Feed.ID.String(), map[string]string{"name": "Bob's now"}, http.StatusNotFound},
+		{http.MethodDelete, "/v1/feeds/" + feed.Feed.ID.String(), nil, http.StatusNotFound},
+		{http.MethodDelete, "/v1" + followPath, nil, http.StatusNotFound},
+		{http.MethodDelete, "/v2" + followPath, nil, http.StatusNotFound},
+		{http.MethodPatch, "/v1" + followPath, map[string]interface{}{"pinned": true}, http.StatusNotFound},
+		{http.MethodPatch, folderPath, map[string]string{"name": "Bob's now"}, http.StatusNotFound},
+		{http.MethodDelete, folderPath, nil, http.StatusNotFound},
+		{http.MethodPut, "/v1/folders/" + otherFolder.ID.String() + followPath, nil, http.StatusNotFound},
+		{http.MethodDelete, folderPath + followPath, nil, http.StatusNotFound},
+		{http.MethodPut, "/v1/folders/order", map[string]interface{}{"folder_ids": []uuid.UUID{otherFolder.ID, folder.ID}}, http.StatusBadRequest},
+		{http.MethodPost, "/v1/posts/" + unseenPost + "/read", nil, http.StatusNotFound},
+		{http.MethodPost, "/v1/posts/" + unseenPost + "/star", nil, http.StatusNotFound},
+		{http.MethodPost, "/v1/read_later", map[string]string{"post_id": unseenPost}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := statusAs(t, server, bob.APIKey(), tt.method, tt.path, tt.body); got != tt.want {
+			t.Errorf("bob %s %s responded %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	// alice's feed, follow and folders are untouched
+	feeds, err := alice.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, f := range feeds {
+		found = found || (f.ID == feed.Feed.ID && f.Name == feed.Feed.Name && f.UserID == feed.Feed.UserID)
+	}
+	if !found {
+		t.Errorf("alice's feed changed or is gone")
+	}
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].ID != follow.ID || follows[0].Pinned {
+		t.Errorf("alice's follows changed: %+v", follows)
+	}
+	folders, err := alice.ListFolders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 || folders[0].ID != folder.ID || folders[0].Name != folder.Name ||
+		len(folders[0].FeedFollowIDs) != 1 || len(folders[1].FeedFollowIDs) != 0 {
+		t.Errorf("alice's folders changed: %+v", folders)
+	}
+
+	// the owner can still delete it
+	if err := alice.UnfollowFeed(ctx, follow.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.UnfollowFeed(ctx, follow.ID); !client.IsNotFound(err) {
+		t.Errorf("deleting a deleted follow: got %v, want a 404", err)
+	}
+}
+
+// the creator of a feed can rename it, merge it into another feed by its url and hand it over by deleting it
+func TestFeedManagement(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	first, err := alice.CreateFeed(ctx, "First", "https://example.com/"+uuid.NewString()+"/index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := alice.CreateFeed(ctx, "Second", "https://example.com/"+uuid.NewString()+"/index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.FollowFeed(ctx, first.Feed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Renamed"
+	renamed, err := alice.UpdateFeed(ctx, first.Feed.ID, client.UpdateFeedOptions{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Merged || renamed.Feed.Name != name || renamed.Feed.ID != first.Feed.ID {
+		t.Errorf("unexpected rename response %+v", renamed)
+	}
+
+	// moving the first feed to the second's url merges the first into the second
+	merged, err := alice.UpdateFeed(ctx, first.Feed.ID, client.UpdateFeedOptions{URL: &second.Feed.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !merged.Merged || merged.Feed.ID != second.Feed.ID {
+		t.Errorf("expected a merge into %s, got %+v", second.Feed.ID, merged)
+	}
+	aliceFollows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliceFollows) != 1 || aliceFollows[0].FeedID != second.Feed.ID {
+		t.Errorf("alice should follow only the merged feed, got %+v", aliceFollows)
+	}
+	bobFollows, err := bob.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bobFollows) != 1 || bobFollows[0].FeedID != second.Feed.ID {
+		t.Errorf("bob's follow should have moved to the merged feed, got %+v", bobFollows)
+	}
+
+	// bob still follows it, so deleting hands the feed over to him
+	if err := alice.DeleteFeed(ctx, second.Feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := bob.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handedOver *client.Feed
+	for i := range feeds {
+		if feeds[i].ID == second.Feed.ID {
+			handedOver = &feeds[i]
+		}
+	}
+	bobUser, err := bob.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handedOver == nil || handedOver.UserID != bobUser.ID {
+		t.Fatalf("expected the feed to be handed over to bob, got %+v", handedOver)
+	}
+
+	// nobody else needs it now, so deleting removes it
+	if err := bob.DeleteFeed(ctx, second.Feed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := bob.DeleteFeed(ctx, second.Feed.ID); !client.IsNotFound(err) {
+		t.Errorf("deleting a deleted feed: got %v, want a 404", err)
+	}
+}
+
+// an admin deleting someone else's feed hands it over like the creator would, instead of taking the followers' posts with it
+func TestAdminDeletesFeed(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+	admin := newTestUser(t, server, "admin")
+	adminUser, err := admin.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.Conn.Exec("UPDATE users SET is_admin = true WHERE id = $1", adminUser.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	followed, posts := newTestFeedWithPosts(t, apiCfg, alice, "Kept")
+	if _, err := bob.FollowFeed(ctx, followed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.DeleteFeed(ctx, followed.ID); err != nil {
+		t.Fatal(err)
+	}
+	page, err := bob.ListPosts(ctx, &client.ListPostsOptions{FeedID: followed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != posts[0].ID {
+		t.Errorf("got posts %+v, want bob's posts kept", page.Items)
+	}
+	bobUser, err := bob.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeds, err := bob.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feed := range feeds {
+		if feed.ID == followed.ID && feed.UserID != bobUser.ID {
+			t.Errorf("got owner %s, want the feed handed over to bob", feed.UserID)
+		}
+	}
+	aliceFollows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliceFollows) != 0 {
+		t.Errorf("got follows %+v, want the creator's follow removed", aliceFollows)
+	}
+
+	// nobody else follows this one, so it's deleted
+	unfollowed, _ := newTestFeedWithPosts(t, apiCfg, alice, "Gone")
+	if err := admin.DeleteFeed(ctx, unfollowed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.DeleteFeed(ctx, unfollowed.ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404 for the deleted feed", err)
+	}
+}
+
+// merging a feed into someone else's by its url moves the follows over but not the posts
+func TestMergeLeavesPostsBehind(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	alice := newTestUser(t, server, "alice")
+	mallory := newTestUser(t, server, "mallory")
+
+	target, targetPosts := newTestFeedWithPosts(t, apiCfg, alice, "Real")
+	merged, _ := newTestFeedWithPosts(t, apiCfg, mallory, "Injected", "Also injected")
+
+	resp, err := mallory.UpdateFeed(ctx, merged.ID, client.UpdateFeedOptions{URL: &target.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Merged || resp.Feed.ID != target.ID {
+		t.Fatalf("expected a merge into %s, got %+v", target.ID, resp)
+	}
+
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: target.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != targetPosts[0].ID {
+		t.Errorf("got posts %+v, want only the target feed's own post", page.Items)
+	}
+	malloryFollows, err := mallory.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(malloryFollows) != 1 || malloryFollows[0].FeedID != target.ID {
+		t.Errorf("mallory's follow should have moved to the target feed, got %+v", malloryFollows)
+	}
+
+	// a fetch of the merged feed that was still underway is logged and skipped
+	published := time.Now().UTC()
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: merged.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Late", Link: "https://example.com/" + uuid.NewString(), PublishedParsed: &published},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+}
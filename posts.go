@@ -0,0 +1,300 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPostsLimit = 50
+	maxPostsLimit     = 1000
+
+	sortNewest = "newest"
+	sortOldest = "oldest"
+)
+
+// position in the timeline that a page starts after, handed to clients as an opaque string
+// pages are keyset paginated on (published_at, id)
+type postsCursor struct {
+	PublishedAt time.Time `json:"p"`
+	ID          uuid.UUID `json:"i"`
+	// sort the cursor was made for, so it isn't reused with the other sort
+	Sort string `json:"s"`
+	// true if the page before the cursor is wanted (the prev link), otherwise the page after it
+	Backward bool `json:"b,omitempty"`
+}
+
+func encodePostsCursor(cursor postsCursor) string {
+	dat, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodePostsCursor(s string) (postsCursor, error) {
+	cursor := postsCursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(dat, &cursor); err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// the query parameters of GET /v1/posts and GET /v2/posts
+type postsQuery struct {
+	Limit    int
+	Sort     string
+	Cursor   *postsCursor
+	FeedID   uuid.NullUUID
+	FolderID uuid.NullUUID
+	SearchID uuid.NullUUID
+	Since    sql.NullTime
+	Until    sql.NullTime
+	Author   sql.NullString
+	Category sql.NullString
+	Unread   sql.NullBool
+	Starred  sql.NullBool
+	Hidden   sql.NullBool
+}
+
+// the timeline as it is without query parameters
+func newPostsQuery() postsQuery {
+	return postsQuery{
+		Limit: defaultPostsLimit,
+		Sort:  sortNewest,
+		// posts hidden by the user's filter rules only show up when asked for
+		Hidden: sql.NullBool{Bool: false, Valid: true},
+	}
+}
+
+// parses and validates the timeline query parameters
+// errors are the client's fault and meant to be responded with a 400
+func parsePostsQuery(values url.Values) (postsQuery, error) {
+	query := newPostsQuery()
+
+	if tmp := values.Get("limit"); tmp != "" {
+		limit, err := strconv.Atoi(tmp)
+		if err != nil || limit < 1 || limit > maxPostsLimit {
+			return query, fmt.Errorf("limit must be an integer between 1 and %d", maxPostsLimit)
+		}
+		query.Limit = limit
+	}
+
+	if tmp := values.Get("sort"); tmp != "" {
+		if tmp != sortNewest && tmp != sortOldest {
+			return query, fmt.Errorf("sort must be %s or %s", sortNewest, sortOldest)
+		}
+		query.Sort = tmp
+	}
+
+	if tmp := values.Get("cursor"); tmp != "" {
+		cursor, err := decodePostsCursor(tmp)
+		if err != nil {
+			return query, err
+		}
+		if cursor.Sort != query.Sort {
+			return query, errors.New("cursor was made for a different sort")
+		}
+		query.Cursor = &cursor
+	}
+
+	for param, dest := range map[string]*uuid.NullUUID{"feed_id": &query.FeedID, "folder_id": &query.FolderID, "search_id": &query.SearchID} {
+		if tmp := values.Get(param); tmp != "" {
+			id, err := uuid.Parse(tmp)
+			if err != nil {
+				return query, fmt.Errorf("%s must be a valid uuid", param)
+			}
+			*dest = uuid.NullUUID{UUID: id, Valid: true}
+		}
+	}
+
+	for param, dest := range map[string]*sql.NullTime{"since": &query.Since, "until": &query.Until} {
+		if tmp := values.Get(param); tmp != "" {
+			t, err := time.Parse(time.RFC3339, tmp)
+			if err != nil {
+				return query, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+			}
+			*dest = sql.NullTime{Time: t.UTC(), Valid: true}
+		}
+	}
+
+	if tmp := values.Get("author"); tmp != "" {
+		query.Author = sql.NullString{String: tmp, Valid: true}
+	}
+	if tmp := values.Get("category"); tmp != "" {
+		query.Category = sql.NullString{String: tmp, Valid: true}
+	}
+	for param, dest := range map[string]*sql.NullBool{"unread": &query.Unread, "starred": &query.Starred, "hidden": &query.Hidden} {
+		if tmp := values.Get(param); tmp != "" {
+			parsed, err := strconv.ParseBool(tmp)
+			if err != nil {
+				return query, fmt.Errorf("%s must be true or false", param)
+			}
+			*dest = sql.NullBool{Bool: parsed, Valid: true}
+		}
+	}
+	return query, nil
+}
+
+// a post in a user's timeline along with that user's state for it
+type timelinePost struct {
+	Post    database.Post
+	Read    bool
+	Starred bool
+	Saved   bool
+	// from the user's filter rules that matched the post
+	Tags []string
+}
+
+// one page of the timeline, in the requested sort
+// Next and Prev are cursors, empty if there is no page in that direction
+type postsPage struct {
+	Posts []timelinePost
+	Next  string
+	Prev  string
+}
+
+// gets a page of posts from the feeds the user follows and the posts they starred
+// errSavedSearchNotFound if the query's saved search isn't the user's
+func (apiCfg apiConfig) getPostsPage(ctx context.Context, user database.User, query postsQuery) (postsPage, error) {
+	if query.SearchID.Valid {
+		_, err := apiCfg.DB.GetSavedSearch(ctx, database.GetSavedSearchParams{
+			ID:     query.SearchID.UUID,
+			UserID: user.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return postsPage{}, errSavedSearchNotFound
+		}
+		if err != nil {
+			return postsPage{}, err
+		}
+	}
+
+	// walking newest first and going forward, or oldest first and going back, reads the timeline descending
+	backward := query.Cursor != nil && query.Cursor.Backward
+	descending := (query.Sort == sortNewest) != backward
+
+	// one extra row tells whether there is another page in the direction read
+	limit := int32(query.Limit + 1)
+	var boundary sql.NullTime
+	var boundaryID uuid.NullUUID
+	if query.Cursor != nil {
+		boundary = sql.NullTime{Time: query.Cursor.PublishedAt, Valid: true}
+		boundaryID = uuid.NullUUID{UUID: query.Cursor.ID, Valid: true}
+	}
+
+	posts := []timelinePost{}
+	if descending {
+		rows, err := apiCfg.DB.GetPostsByUser(ctx, database.GetPostsByUserParams{
+			UserID:            user.ID,
+			FeedID:            query.FeedID,
+			FolderID:          query.FolderID,
+			SearchID:          query.SearchID,
+			Since:             query.Since,
+			Until:             query.Until,
+			Author:            query.Author,
+			Category:          query.Category,
+			Unread:            query.Unread,
+			Starred:           query.Starred,
+			Hidden:            query.Hidden,
+			BeforePublishedAt: boundary,
+			BeforeID:          boundaryID,
+			Limit:             limit,
+		})
+		if err != nil {
+			return postsPage{}, err
+		}
+		for _, row := range rows {
+			posts = append(posts, timelinePost{Post: row.Post, Read: row.Read, Starred: row.Starred, Saved: row.Saved, Tags: row.Tags})
+		}
+	} else {
+		rows, err := apiCfg.DB.GetPostsByUserAscending(ctx, database.GetPostsByUserAscendingParams{
+			UserID:           user.ID,
+			FeedID:           query.FeedID,
+			FolderID:         query.FolderID,
+			SearchID:         query.SearchID,
+			Since:            query.Since,
+			Until:            query.Until,
+			Author:           query.Author,
+			Category:         query.Category,
+			Unread:           query.Unread,
+			Starred:          query.Starred,
+			Hidden:           query.Hidden,
+			AfterPublishedAt: boundary,
+			AfterID:          boundaryID,
+			Limit:            limit,
+		})
+		if err != nil {
+			return postsPage{}, err
+		}
+		for _, row := range rows {
+			posts = append(posts, timelinePost{Post: row.Post, Read: row.Read, Starred: row.Starred, Saved: row.Saved, Tags: row.Tags})
+		}
+	}
+
+	more := len(posts) > query.Limit
+	if more {
+		posts = posts[:query.Limit]
+	}
+	if backward {
+		// read away from the cursor, flip back into the requested sort
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	page := postsPage{Posts: posts}
+	if len(posts) == 0 {
+		return page, nil
+	}
+	hasNext := more
+	hasPrev := query.Cursor != nil
+	if backward {
+		// came back from the next page
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		last := posts[len(posts)-1].Post
+		page.Next = encodePostsCursor(postsCursor{PublishedAt: last.PublishedAt, ID: last.ID, Sort: query.Sort})
+	}
+	if hasPrev {
+		first := posts[0].Post
+		page.Prev = encodePostsCursor(postsCursor{PublishedAt: first.PublishedAt, ID: first.ID, Sort: query.Sort, Backward: true})
+	}
+	return page, nil
+}
+
+// url of the same request with the cursor replaced
+func cursorURL(r *http.Request, cursor string) string {
+	values := r.URL.Query()
+	values.Set("cursor", cursor)
+	u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+	return u.String()
+}
+
+// sets the Link header with the next and prev pages of the timeline
+func setPostsLinkHeader(w http.ResponseWriter, r *http.Request, page postsPage) {
+	links := []string{}
+	if page.Next != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, cursorURL(r, page.Next)))
+	}
+	if page.Prev != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, cursorURL(r, page.Prev)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
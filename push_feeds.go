@@ -0,0 +1,312 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// the most items one request can push
+const maxPushItems = 100
+
+var (
+	// returned from inside a transaction for write and invite tokens of feeds that aren't push feeds
+	errNotPushFeed = errors.New("only push feeds have write and invite tokens")
+	// returned from inside a transaction when the url of a push feed is changed
+	errPushFeedURL = errors.New("push feeds have no url")
+	// the same for an unknown feed and a wrong token, so feed ids can't be probed
+	errInvalidWriteToken = errors.New("invalid write token")
+)
+
+// the url push feeds get instead of one to fetch, unique like the urls of the other feeds
+func pushFeedURL(feedID uuid.UUID) string {
+	return "urn:uuid:" + feedID.String()
+}
+
+// a write token that items are pushed into a push feed with, or an invite token that others follow it with
+func newPushFeedToken() (string, error) {
+	dat := make([]byte, 24)
+	if _, err := rand.Read(dat); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(dat), nil
+}
+
+type writeTokenResponse struct {
+	WriteToken string `json:"write_token"`
+}
+
+type inviteTokenResponse struct {
+	InviteToken string `json:"invite_token"`
+}
+
+// POST /v1/push_feeds
+// authed
+// expects {"name": "..."}
+// creates a feed with no upstream that items are pushed into with POST /v1/feeds/{feedID}/items, and follows it
+// push feeds aren't listed by GET /v1/feeds, other users follow them by id with the invite token
+// responds 201 with the feed, the follow, the write token and the invite token, which are only returned here and when they're rotated
+func (apiCfg apiConfig) createPushFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	type returnVal struct {
+		Feed        feedResponse       `json:"feed"`
+		FeedFollow  feedFollowResponse `json:"feed_follow"`
+		WriteToken  string             `json:"write_token"`
+		InviteToken string             `json:"invite_token"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, errors.New("name cannot be empty"))
+		return
+	}
+
+	token, err := newPushFeedToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	inviteToken, err := newPushFeedToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	feedID := uuid.New()
+	now := time.Now()
+	feed, err := apiCfg.DB.CreatePushFeed(context.Background(), database.CreatePushFeedParams{
+		ID:          feedID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Name:        params.Name,
+		Url:         pushFeedURL(feedID),
+		UserID:      user.ID,
+		WriteToken:  sql.NullString{String: token, Valid: true},
+		InviteToken: sql.NullString{String: inviteToken, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	feedFollow, _, err := apiCfg.followFeed(context.Background(), user, feed.ID, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, returnVal{
+		Feed:        newFeedResponse(feed),
+		FeedFollow:  newFeedFollowResponse(feedFollow),
+		WriteToken:  token,
+		InviteToken: inviteToken,
+	})
+}
+
+// replaces a token of the push feed with set and returns the new one
+// errFeedNotFound if the user can't manage the feed, errNotPushFeed if it isn't a push feed
+func (apiCfg apiConfig) rotatePushFeedToken(user database.User, feedID uuid.UUID, set func(ctx context.Context, q *database.Queries, feedID uuid.UUID, token string) error) (string, error) {
+	token, err := newPushFeedToken()
+	if err != nil {
+		return "", err
+	}
+	err = apiCfg.inTx(context.Background(), func(q *database.Queries) error {
+		ctx := context.Background()
+		feed, err := getManagedFeed(ctx, q, user, feedID)
+		if err != nil {
+			return err
+		}
+		if feed.Kind != feedKindPush {
+			return errNotPushFeed
+		}
+		return set(ctx, q, feed.ID, token)
+	})
+	return token, err
+}
+
+func respondWithRotateTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errFeedNotFound):
+		respondWithError(w, http.StatusNotFound, err)
+	case errors.Is(err, errNotPushFeed):
+		respondWithError(w, http.StatusBadRequest, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// POST /v1/feeds/{feedID}/write_token
+// authed
+// replaces the write token of a push feed, the old one stops working at once
+// only the user who created the feed or an admin can, 404 for anyone else, 400 if it isn't a push feed
+func (apiCfg apiConfig) rotateWriteTokenHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuidFromURL(r, "feedID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	token, err := apiCfg.rotatePushFeedToken(user, feedID, func(ctx context.Context, q *database.Queries, feedID uuid.UUID, token string) error {
+		_, err := q.SetFeedWriteToken(ctx, database.SetFeedWriteTokenParams{
+			ID:         feedID,
+			WriteToken: sql.NullString{String: token, Valid: true},
+			UpdatedAt:  time.Now(),
+		})
+		return err
+	})
+	if err != nil {
+		respondWithRotateTokenError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, writeTokenResponse{WriteToken: token})
+}
+
+// POST /v1/feeds/{feedID}/invite_token
+// authed
+// replaces the invite token of a push feed, the old one can't be used to follow it anymore, the followers keep following
+// only the user who created the feed or an admin can, 404 for anyone else, 400 if it isn't a push feed
+func (apiCfg apiConfig) rotateInviteTokenHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuidFromURL(r, "feedID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	token, err := apiCfg.rotatePushFeedToken(user, feedID, func(ctx context.Context, q *database.Queries, feedID uuid.UUID, token string) error {
+		_, err := q.SetFeedInviteToken(ctx, database.SetFeedInviteTokenParams{
+			ID:          feedID,
+			InviteToken: sql.NullString{String: token, Valid: true},
+			UpdatedAt:   time.Now(),
+		})
+		return err
+	})
+	if err != nil {
+		respondWithRotateTokenError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, inviteTokenResponse{InviteToken: token})
+}
+
+// an item pushed into a push feed
+type pushItem struct {
+	Title string `json:"title"`
+	// optional, an item with the url of a post already in the feed isn't posted again
+	Url         string     `json:"url"`
+	Description string     `json:"description"`
+	Content     string     `json:"content"`
+	Author      string     `json:"author"`
+	Categories  []string   `json:"categories"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// the feed item a pushed item is posted as, items without a url get one of their own
+func (item pushItem) feedItem() *gofeed.Item {
+	feedItem := &gofeed.Item{
+		Title:           strings.TrimSpace(item.Title),
+		Link:            item.Url,
+		Description:     item.Description,
+		Content:         item.Content,
+		Categories:      item.Categories,
+		PublishedParsed: item.PublishedAt,
+	}
+	if feedItem.Link == "" {
+		feedItem.Link = "urn:uuid:" + uuid.NewString()
+	}
+	if item.Author != "" {
+		feedItem.Author = &gofeed.Person{Name: item.Author}
+	}
+	return feedItem
+}
+
+// POST /v1/feeds/{feedID}/items
+// authed with the feed's write token instead of an api key, like Authorization: Bearer <write token>
+// expects {"items": [{"title": "...", "url": "...", "description": "...", "content": "...", "author": "...", "categories": ["..."], "published_at": "..."}]}
+// only title is required, published_at defaults to now, description and content are html, sanitized like fetched posts
+// the items become posts of the push feed and reach its followers like the posts of any other feed
+// up to 100 items at a time, items whose url already has a post in the feed are skipped and listed in duplicates
+// the urls only have to be unique in the feed, pushing one doesn't keep other feeds from posting it
+// responds 201 with the created posts, 200 if they were all duplicates
+func (apiCfg apiConfig) pushFeedItemsHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Items []pushItem `json:"items"`
+	}
+	type returnVal struct {
+		Posts      []postResponse `json:"posts"`
+		Duplicates []string       `json:"duplicates"`
+	}
+
+	feedID, err := uuidFromURL(r, "feedID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	token, err := getAuthTokenFromHeader(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err)
+		return
+	}
+	feed, err := apiCfg.DB.GetFeed(context.Background(), feedID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err != nil || feed.Kind != feedKindPush || !feed.WriteToken.Valid ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(feed.WriteToken.String)) != 1 {
+		respondWithError(w, http.StatusUnauthorized, errInvalidWriteToken)
+		return
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	if len(params.Items) == 0 || len(params.Items) > maxPushItems {
+		respondWithError(w, http.StatusBadRequest, fmt.Errorf("items must have between 1 and %d items", maxPushItems))
+		return
+	}
+	for i, item := range params.Items {
+		if strings.TrimSpace(item.Title) == "" {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("item %d: title cannot be empty", i))
+			return
+		}
+		if item.Url != "" && !isFeedURL(item.Url) {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("item %d: url must be an http or https url", i))
+			return
+		}
+	}
+
+	retVal := returnVal{Posts: []postResponse{}, Duplicates: []string{}}
+	for _, item := range params.Items {
+		post, created, err := apiCfg.createPostFromItem(context.Background(), feed.ID, item.feedItem())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if !created {
+			retVal.Duplicates = append(retVal.Duplicates, item.Url)
+			continue
+		}
+		retVal.Posts = append(retVal.Posts, newPostResponse(timelinePost{Post: post}))
+	}
+
+	code := http.StatusOK
+	if len(retVal.Posts) > 0 {
+		code = http.StatusCreated
+	}
+	respondWithJSON(w, code, retVal)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestPushFeeds(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+	ci := client.New(server.URL)
+
+	created, err := alice.CreatePushFeed(ctx, "Release notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := created.Feed
+	if feed.Kind != client.FeedKindPush || feed.URL != "urn:uuid:"+feed.ID.String() || created.WriteToken == "" || created.InviteToken == "" || created.FeedFollow.FeedID != feed.ID {
+		t.Fatalf("got %+v", created)
+	}
+
+	// push feeds are private, they're followed by id with the invite token
+	feeds, err := bob.ListFeeds(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, listed := range feeds {
+		if listed.ID == feed.ID {
+			t.Error("push feed is listed")
+		}
+	}
+	if _, err := bob.FollowFeed(ctx, feed.ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404 without the invite token", err)
+	}
+	if _, err := bob.FollowPushFeed(ctx, feed.ID, created.WriteToken); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404 for a wrong invite token", err)
+	}
+	if _, err := bob.FollowPushFeed(ctx, feed.ID, created.InviteToken); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ci.PushItems(ctx, feed.ID, "wrong", []client.PushItem{{Title: "Nope"}})
+	if !client.IsUnauthorized(err) {
+		t.Fatalf("got error %v, want a 401", err)
+	}
+	_, err = ci.PushItems(ctx, uuid.New(), created.WriteToken, []client.PushItem{{Title: "Nope"}})
+	if !client.IsUnauthorized(err) {
+		t.Fatalf("got error %v, want a 401 for another feed", err)
+	}
+
+	releaseURL := "https://example.com/releases/" + uuid.NewString()
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	pushed, err := ci.PushItems(ctx, feed.ID, created.WriteToken, []client.PushItem{
+		{
+			Title:       "v1.2.0",
+			URL:         releaseURL,
+			Description: `<p>Fixes <a href="/issues/1">#1</a><script>alert(1)</script></p>`,
+			Categories:  []string{"release"},
+			PublishedAt: &published,
+		},
+		{Title: "Incident resolved", Description: "Database failover finished", Author: "on-call"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed.Posts) != 2 || len(pushed.Duplicates) != 0 {
+		t.Fatalf("got %+v", pushed)
+	}
+	release := pushed.Posts[0]
+	if release.URL != releaseURL || release.Description != `<p>Fixes <a href="https://example.com/issues/1" target="_blank" rel="noopener">#1</a></p>` || !release.PublishedAt.Equal(published) {
+		t.Errorf("got post %+v", release)
+	}
+	if incident := pushed.Posts[1]; !strings.HasPrefix(incident.URL, "urn:uuid:") || incident.Author != "on-call" {
+		t.Errorf("got post %+v", incident)
+	}
+
+	// the items reach the followers' timelines
+	page, err := bob.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Fatalf("got posts %+v", page.Items)
+	}
+
+	// an item is only posted once
+	pushed, err = ci.PushItems(ctx, feed.ID, created.WriteToken, []client.PushItem{{Title: "v1.2.0", URL: releaseURL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed.Posts) != 0 || len(pushed.Duplicates) != 1 || pushed.Duplicates[0] != releaseURL {
+		t.Errorf("got %+v", pushed)
+	}
+
+	for _, items := range [][]client.PushItem{nil, {{Title: " "}}, {{Title: "Bad url", URL: "ftp://example.com"}}} {
+		_, err = ci.PushItems(ctx, feed.ID, created.WriteToken, items)
+		if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("items %+v: got error %v, want a 400", items, err)
+		}
+	}
+
+	// only the creator can rotate the token, the old one stops working
+	if _, err := bob.RotateWriteToken(ctx, feed.ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+	token, err := alice.RotateWriteToken(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ci.PushItems(ctx, feed.ID, created.WriteToken, []client.PushItem{{Title: "Old token"}}); !client.IsUnauthorized(err) {
+		t.Errorf("got error %v, want a 401", err)
+	}
+	if _, err := ci.PushItems(ctx, feed.ID, token, []client.PushItem{{Title: "New token"}}); err != nil {
+		t.Error(err)
+	}
+
+	// rotating the invite token keeps the followers, the old one can't be used anymore
+	if _, err := bob.RotateInviteToken(ctx, feed.ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+	inviteToken, err := alice.RotateInviteToken(ctx, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carol := newTestUser(t, server, "carol")
+	if _, err := carol.FollowPushFeed(ctx, feed.ID, created.InviteToken); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404 for the old invite token", err)
+	}
+	if _, err := carol.FollowPushFeed(ctx, feed.ID, inviteToken); err != nil {
+		t.Error(err)
+	}
+	follows, err := bob.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	following := false
+	for _, follow := range follows {
+		following = following || follow.FeedID == feed.ID
+	}
+	if !following {
+		t.Error("expected bob to keep following after the invite token is rotated")
+	}
+
+	// push feeds have no url to change or export
+	newURL := "https://example.com/feed.xml"
+	_, err = alice.UpdateFeed(ctx, feed.ID, client.UpdateFeedOptions{URL: &newURL})
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v, want a 400", err)
+	}
+	opml, err := alice.ExportOPML(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(opml, []byte(feed.ID.String())) {
+		t.Errorf("push feed exported: %s", opml)
+	}
+}
+
+// pushing a url doesn't keep other feeds from posting it
+func TestPushedURLsAreScopedToTheFeed(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+	ci := client.New(server.URL)
+
+	created, err := alice.CreatePushFeed(ctx, "Squatter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := alice.CreatePushFeed(ctx, "Other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "https://example.com/" + uuid.NewString()
+	postURL := base + "/announcement"
+	for _, pushFeed := range []*client.CreatePushFeedResponse{created, other} {
+		pushed, err := ci.PushItems(ctx, pushFeed.Feed.ID, pushFeed.WriteToken, []client.PushItem{{Title: "Fake announcement", URL: postURL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pushed.Posts) != 1 {
+			t.Fatalf("expected the url to be posted in each push feed, got %+v", pushed)
+		}
+	}
+
+	// bob's feed still gets the real post
+	fetched, err := bob.CreateFeed(ctx, "Blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: fetched.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Announcement", Link: postURL},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+	page, err := bob.ListPosts(ctx, &client.ListPostsOptions{FeedID: fetched.Feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Title != "Announcement" || page.Items[0].URL != postURL {
+		t.Errorf("expected the fetched post, got %+v", page.Items)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// most post ids accepted by the bulk read state endpoints
+const maxBulkPostIDs = 1000
+
+type updatedCountResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+type unreadCountResponse struct {
+	FeedFollowID uuid.UUID `json:"feed_follow_id"`
+	FeedID       uuid.UUID `json:"feed_id"`
+	UnreadCount  int64     `json:"unread_count"`
+}
+
+// parses a uuid url param like {postID}
+func uuidFromURL(r *http.Request, param string) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s must be a valid uuid", param)
+	}
+	return id, nil
+}
+
+// parses the {postID} url param
+func postIDFromURL(r *http.Request) (uuid.UUID, error) {
+	return uuidFromURL(r, "postID")
+}
+
+// decodes a {"post_ids": [...]} body
+func decodePostIDs(r *http.Request) ([]uuid.UUID, error) {
+	type parameters struct {
+		PostIDs []uuid.UUID `json:"post_ids"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		return nil, errors.New("decoding json went wrong")
+	}
+	if len(params.PostIDs) == 0 {
+		return nil, errors.New("post_ids cannot be empty")
+	}
+	if len(params.PostIDs) > maxBulkPostIDs {
+		return nil, fmt.Errorf("at most %d post_ids at a time", maxBulkPostIDs)
+	}
+	return params.PostIDs, nil
+}
+
+// POST /v1/posts/{postID}/read
+// authed
+// marks a post from a followed feed as read, 404 if the user can't see the post
+func (apiCfg apiConfig) markPostReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := postIDFromURL(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := apiCfg.DB.MarkPostsRead(context.Background(), database.MarkPostsReadParams{
+		UserID:  user.ID,
+		ReadAt:  time.Now(),
+		PostIds: []uuid.UUID{postID},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("post not found"))
+		return
+	}
+	apiCfg.publishUserEvent(context.Background(), user, eventReadState, readStateEvent{Read: true, PostIDs: []uuid.UUID{postID}})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /v1/posts/{postID}/read
+// authed
+// marks a post as unread again
+func (apiCfg apiConfig) markPostUnreadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := postIDFromURL(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := apiCfg.DB.MarkPostsUnread(context.Background(), database.MarkPostsUnreadParams{
+		UserID:  user.ID,
+		PostIds: []uuid.UUID{postID},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated > 0 {
+		apiCfg.publishUserEvent(context.Background(), user, eventReadState, readStateEvent{Read: false, PostIDs: []uuid.UUID{postID}})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /v1/posts/read
+// authed
+// marks the posts in {"post_ids": [...]} as read, ids of posts the user can't see are skipped
+// responds with how many posts are now read
+func (apiCfg apiConfig) markPostsReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDs, err := decodePostIDs(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := apiCfg.DB.MarkPostsRead(context.Background(), database.MarkPostsReadParams{
+		UserID:  user.ID,
+		ReadAt:  time.Now(),
+		PostIds: postIDs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated > 0 {
+		apiCfg.publishUserEvent(context.Background(), user, eventReadState, readStateEvent{Read: true, PostIDs: postIDs})
+	}
+	respondWithJSON(w, http.StatusOK, updatedCountResponse{Updated: updated})
+}
+
+// POST /v1/posts/unread
+// authed
+// marks the posts in {"post_ids": [...]} as unread
+// responds with how many posts were read before
+func (apiCfg apiConfig) markPostsUnreadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDs, err := decodePostIDs(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := apiCfg.DB.MarkPostsUnread(context.Background(), database.MarkPostsUnreadParams{
+		UserID:  user.ID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated > 0 {
+		apiCfg.publishUserEvent(context.Background(), user, eventReadState, readStateEvent{Read: false, PostIDs: postIDs})
+	}
+	respondWithJSON(w, http.StatusOK, updatedCountResponse{Updated: updated})
+}
+
+// POST /v1/posts/mark_all_read
+// authed
+// marks every post in the followed feeds as read
+// optional body {"feed_id": "...", "before": "RFC 3339"} limits it to one feed and/or posts published before a time
+func (apiCfg apiConfig) markAllPostsReadHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FeedID *uuid.UUID `json:"feed_id"`
+		Before *time.Time `json:"before"`
+	}
+
+	// the body is optional
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	markParams := database.MarkAllPostsReadParams{
+		UserID: user.ID,
+		ReadAt: time.Now(),
+	}
+	if params.FeedID != nil {
+		markParams.FeedID = uuid.NullUUID{UUID: *params.FeedID, Valid: true}
+	}
+	if params.Before != nil {
+		markParams.Before = sql.NullTime{Time: params.Before.UTC(), Valid: true}
+	}
+
+	updated, err := apiCfg.DB.MarkAllPostsRead(context.Background(), markParams)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated > 0 {
+		apiCfg.publishUserEvent(context.Background(), user, eventReadState, readStateEvent{
+			Read:   true,
+			All:    true,
+			FeedID: params.FeedID,
+			Before: params.Before,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, updatedCountResponse{Updated: updated})
+}
+
+// GET /v1/feed_follows/unread_counts
+// authed
+// number of unread posts in each feed the user follows
+func (apiCfg apiConfig) getUnreadCountsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := apiCfg.DB.GetUnreadCountsByFeed(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	retVal := make([]unreadCountResponse, 0, len(counts))
+	for _, count := range counts {
+		retVal = append(retVal, unreadCountResponse{
+			FeedFollowID: count.FeedFollowID,
+			FeedID:       count.FeedID,
+			UnreadCount:  count.UnreadCount,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, retVal)
+}
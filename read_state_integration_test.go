@@ -0,0 +1,115 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReadState(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	feed, posts := newTestFeedWithPosts(t, apiCfg, alice, "One", "Two", "Three")
+	other, otherPosts := newTestFeedWithPosts(t, apiCfg, alice, "Other")
+
+	unreadCount := func(feedID uuid.UUID) int64 {
+		t.Helper()
+		counts, err := alice.UnreadCounts(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, count := range counts {
+			if count.FeedID == feedID {
+				return count.UnreadCount
+			}
+		}
+		t.Fatalf("feed %s missing from unread counts %+v", feedID, counts)
+		return 0
+	}
+	if n := unreadCount(feed.ID); n != 3 {
+		t.Errorf("got %d unread, want 3", n)
+	}
+
+	if err := alice.MarkPostRead(ctx, posts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	unread := true
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID, Unread: &unread})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("got %d unread posts, want 2", len(page.Items))
+	}
+	if n := unreadCount(feed.ID); n != 2 {
+		t.Errorf("got %d unread, want 2", n)
+	}
+
+	// read state is per user, and posts of feeds the user doesn't follow can't be marked
+	if err := bob.MarkPostRead(ctx, posts[1].ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+	if n, err := bob.MarkPostsRead(ctx, []uuid.UUID{posts[1].ID}); err != nil || n != 0 {
+		t.Errorf("got %d, %v, want nothing marked", n, err)
+	}
+
+	// the bulk endpoints skip ids that aren't the user's
+	n, err := alice.MarkPostsRead(ctx, []uuid.UUID{posts[0].ID, posts[1].ID, uuid.New()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d read, want 2", n)
+	}
+	n, err = alice.MarkPostsUnread(ctx, []uuid.UUID{posts[0].ID, posts[2].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("got %d were read, want 1", n)
+	}
+	if err := alice.MarkPostUnread(ctx, posts[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := unreadCount(feed.ID); n != 3 {
+		t.Errorf("got %d unread, want 3", n)
+	}
+
+	// mark all read can be limited to a feed and to posts published before a time
+	n, err = alice.MarkAllPostsRead(ctx, &client.MarkAllReadOptions{FeedID: feed.ID, Before: posts[2].PublishedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d marked, want 2", n)
+	}
+	if n := unreadCount(feed.ID); n != 1 {
+		t.Errorf("got %d unread, want 1", n)
+	}
+	if n := unreadCount(other.ID); n != 1 {
+		t.Errorf("got %d unread in the other feed, want 1", n)
+	}
+	n, err = alice.MarkAllPostsRead(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("got %d marked, want 2", n)
+	}
+
+	page, err = alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: other.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != otherPosts[0].ID || !page.Items[0].Read {
+		t.Errorf("got posts %+v, want the read post", page.Items)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/sanitize"
+	"context"
+	"log"
+)
+
+// how many of the posts from before sanitizing to go through at a time
+const sanitizeBatchSize = 500
+
+// sanitizes the posts stored before descriptions were sanitized at ingestion
+// run at startup before the server and the fetchers start, so unsanitized html is never served
+func (apiCfg apiConfig) sanitizeOldPosts(ctx context.Context) error {
+	total := 0
+	for {
+		posts, err := apiCfg.DB.GetUnsanitizedPosts(ctx, sanitizeBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			description := sanitize.HTML(post.Description, post.Url)
+			err := apiCfg.DB.SetPostSanitized(ctx, database.SetPostSanitizedParams{
+				ID:              post.ID,
+				Description:     description,
+				Content:         sanitize.HTML(post.Content, post.Url),
+				DescriptionText: sanitize.Text(description),
+			})
+			if err != nil {
+				// stop rather than get the same posts again forever, the next start picks up from here
+				return err
+			}
+		}
+		total += len(posts)
+		if len(posts) < sanitizeBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("sanitizeOldPosts: sanitized %d posts\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestPostsAreSanitized(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedID := created.Feed.ID
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: feedID, Feed: &gofeed.Feed{Items: []*gofeed.Item{{
+		Title:       "Post",
+		Description: `<p onclick="steal()">Read <a href="more">more</a><script>steal()</script></p><img src="/pixel.gif" width="1" height="1">`,
+		Content:     `<p><img src="cat.png" alt="cat"></p>`,
+		Link:        base + "/posts/post",
+	}}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 {
+		t.Fatalf("got posts %+v", page.Items)
+	}
+	post := page.Items[0]
+	want := `<p>Read <a href="` + base + `/posts/more" target="_blank" rel="noopener">more</a></p>`
+	if post.Description != want {
+		t.Errorf("got description %q, want %q", post.Description, want)
+	}
+	if post.DescriptionText != "Read more" {
+		t.Errorf("got description text %q", post.DescriptionText)
+	}
+
+	// posts from before sanitizing get sanitized at startup
+	_, err = apiCfg.Conn.Exec(
+		"UPDATE posts SET description = $2, content = $3, description_text = '', sanitized = false WHERE id = $1",
+		post.ID, `<p>Old <b>post</b><script>steal()</script></p>`, `<iframe src="https://example.com"></iframe><p>Content</p>`,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiCfg.sanitizeOldPosts(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var description, content, descriptionText string
+	var sanitized bool
+	err = apiCfg.Conn.QueryRow(
+		"SELECT description, content, description_text, sanitized FROM posts WHERE id = $1", post.ID,
+	).Scan(&description, &content, &descriptionText, &sanitized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "<p>Old <b>post</b></p>" || content != "<p>Content</p>" || descriptionText != "Old post" || !sanitized {
+		t.Errorf("got description %q, content %q, description text %q, sanitized %v", description, content, descriptionText, sanitized)
+	}
+}
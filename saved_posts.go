@@ -0,0 +1,207 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// a post in the user's read later queue
+type savedPostResponse struct {
+	Position int          `json:"position"`
+	SavedAt  time.Time    `json:"saved_at"`
+	Post     postResponse `json:"post"`
+}
+
+// POST /v1/posts/{postID}/star
+// authed
+// stars a post, starred posts stay in the timeline even after their feed is unfollowed
+// 404 if the user can't see the post
+func (apiCfg apiConfig) starPostHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := postIDFromURL(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	updated, err := apiCfg.DB.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		StarredAt: time.Now(),
+		PostID:    postID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("post not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /v1/posts/{postID}/star
+// authed
+// unstars a post
+func (apiCfg apiConfig) unstarPostHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := postIDFromURL(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = apiCfg.DB.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// the user's read later queue in order
+func (apiCfg apiConfig) getSavedPosts(ctx context.Context, user database.User) ([]savedPostResponse, error) {
+	rows, err := apiCfg.DB.GetSavedPosts(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	savedPosts := make([]savedPostResponse, 0, len(rows))
+	for _, row := range rows {
+		savedPosts = append(savedPosts, savedPostResponse{
+			Position: int(row.Position),
+			SavedAt:  row.SavedAt.UTC(),
+			Post:     newPostResponse(timelinePost{Post: row.Post, Read: row.Read, Starred: row.Starred, Saved: true}),
+		})
+	}
+	return savedPosts, nil
+}
+
+// POST /v1/read_later
+// authed
+// expects a post_id, adds the post to the end of the read later queue
+// saved posts stay in the queue even after their feed is unfollowed
+// responds with the queue, 404 if the user can't see the post
+func (apiCfg apiConfig) savePostHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		PostID uuid.UUID `json:"post_id"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	updated, err := apiCfg.DB.SavePost(context.Background(), database.SavePostParams{
+		UserID:  user.ID,
+		PostID:  params.PostID,
+		SavedAt: time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("post not found"))
+		return
+	}
+
+	savedPosts, err := apiCfg.getSavedPosts(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, savedPosts)
+}
+
+// GET /v1/read_later
+// authed
+// get the read later queue in order
+func (apiCfg apiConfig) getSavedPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	savedPosts, err := apiCfg.getSavedPosts(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, savedPosts)
+}
+
+// DELETE /v1/read_later/{postID}
+// authed
+// removes a post from the read later queue
+func (apiCfg apiConfig) unsavePostHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := postIDFromURL(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	err = apiCfg.DB.UnsavePost(context.Background(), database.UnsavePostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /v1/read_later/order
+// authed
+// expects {"post_ids": [...]} with every post in the queue in its new order
+// responds with the reordered queue
+func (apiCfg apiConfig) reorderSavedPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDs, err := decodePostIDs(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the new order has to hold exactly the posts in the queue
+	current, err := apiCfg.DB.GetSavedPosts(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	inQueue := map[uuid.UUID]bool{}
+	for _, row := range current {
+		inQueue[row.Post.ID] = true
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, postID := range postIDs {
+		if !inQueue[postID] || seen[postID] {
+			respondWithError(w, http.StatusBadRequest, errors.New("post_ids must list every post in the read later queue once"))
+			return
+		}
+		seen[postID] = true
+	}
+	if len(seen) != len(inQueue) {
+		respondWithError(w, http.StatusBadRequest, errors.New("post_ids must list every post in the read later queue once"))
+		return
+	}
+
+	err = apiCfg.DB.SetSavedPostPositions(context.Background(), database.SetSavedPostPositionsParams{
+		UserID:  user.ID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	savedPosts, err := apiCfg.getSavedPosts(context.Background(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, savedPosts)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStarredAndSavedPosts(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	feed, posts := newTestFeedWithPosts(t, apiCfg, alice, "One", "Two", "Three")
+
+	// posts of feeds the user doesn't follow can't be starred or saved
+	if err := bob.StarPost(ctx, posts[0].ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+	if _, err := bob.SavePost(ctx, posts[0].ID); !client.IsNotFound(err) {
+		t.Errorf("got error %v, want a 404", err)
+	}
+
+	if err := alice.StarPost(ctx, posts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.SavePost(ctx, posts[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	queue, err := alice.SavePost(ctx, posts[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 || queue[0].Post.ID != posts[1].ID || queue[1].Post.ID != posts[2].ID {
+		t.Fatalf("got queue %+v", queue)
+	}
+
+	// the new order has to list every post in the queue once
+	for _, ids := range [][]uuid.UUID{{posts[2].ID}, {posts[2].ID, posts[2].ID}, {posts[2].ID, posts[0].ID}} {
+		_, err := alice.ReorderSavedPosts(ctx, ids)
+		if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("order %v: got error %v, want a 400", ids, err)
+		}
+	}
+	queue, err = alice.ReorderSavedPosts(ctx, []uuid.UUID{posts[2].ID, posts[1].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 || queue[0].Post.ID != posts[2].ID || queue[1].Post.ID != posts[1].ID {
+		t.Errorf("got queue %+v", queue)
+	}
+
+	// starred and saved posts stay after the feed is unfollowed
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, follow := range follows {
+		if follow.FeedID == feed.ID {
+			if err := alice.UnfollowFeed(ctx, follow.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != posts[0].ID || !page.Items[0].Starred {
+		t.Errorf("got posts %+v, want only the starred post", page.Items)
+	}
+	queue, err = alice.ListSavedPosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 {
+		t.Errorf("got queue %+v", queue)
+	}
+
+	if err := alice.UnstarPost(ctx, posts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.UnsavePost(ctx, posts[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	queue, err = alice.ListSavedPosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Post.ID != posts[2].ID {
+		t.Errorf("got queue %+v", queue)
+	}
+
+	// deleting a feed nobody else follows takes the stars and saves on its posts with it
+	other, otherPosts := newTestFeedWithPosts(t, apiCfg, alice, "Other")
+	if err := alice.StarPost(ctx, otherPosts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.SavePost(ctx, otherPosts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.DeleteFeed(ctx, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	queue, err = alice.ListSavedPosts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Post.ID != posts[2].ID {
+		t.Errorf("got queue %+v after deleting the feed", queue)
+	}
+
+	// anything else that deletes posts, like pruning old ones, can't take a saved post with it
+	if _, err := apiCfg.Conn.ExecContext(ctx, "DELETE FROM posts WHERE id = $1", posts[2].ID); err == nil {
+		t.Error("expected deleting a saved post to be restricted")
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	searchMatchAny = "any"
+	searchMatchAll = "all"
+
+	// newest posts of the followed feeds a search is run on when it's saved
+	searchBackfillPosts = 5000
+)
+
+var errSavedSearchNotFound = errors.New("saved search not found")
+
+type savedSearchResponse struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Query  string    `json:"query"`
+	Match  string    `json:"match"`
+	Author string    `json:"author"`
+	// null to search every followed feed
+	FeedID      *uuid.UUID `json:"feed_id"`
+	Notify      bool       `json:"notify"`
+	UnreadCount int64      `json:"unread_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func newSavedSearchResponse(search database.SavedSearch, unreadCount int64) savedSearchResponse {
+	resp := savedSearchResponse{
+		ID:          search.ID,
+		Name:        search.Name,
+		Query:       search.Query,
+		Match:       search.Match,
+		Author:      search.Author,
+		Notify:      search.Notify,
+		UnreadCount: unreadCount,
+		CreatedAt:   search.CreatedAt.UTC(),
+		UpdatedAt:   search.UpdatedAt.UTC(),
+	}
+	if search.FeedID.Valid {
+		resp.FeedID = &search.FeedID.UUID
+	}
+	return resp
+}
+
+// data of a search_match event
+type searchMatchEvent struct {
+	SearchID   uuid.UUID    `json:"search_id"`
+	SearchName string       `json:"search_name"`
+	Post       postResponse `json:"post"`
+}
+
+// splits a query into its lowercased terms, words and "quoted phrases"
+func parseSearchQuery(query string) ([]string, error) {
+	terms := []string{}
+	for {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			break
+		}
+		var term string
+		if query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				return nil, errors.New("query has an unterminated quote")
+			}
+			term, query = query[1:end+1], query[end+2:]
+		} else {
+			end := strings.IndexAny(query, " \t\n\"")
+			if end < 0 {
+				end = len(query)
+			}
+			term, query = query[:end], query[end:]
+		}
+		if term = strings.ToLower(strings.Join(strings.Fields(term), " ")); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, errors.New("query must have a keyword or phrase")
+	}
+	return terms, nil
+}
+
+// the title and the plain text of the description, lowercased and with the whitespace collapsed, for matching keywords
+// in the html of the description they'd match tags and attributes
+func postKeywordText(post database.Post) string {
+	return strings.ToLower(strings.Join(strings.Fields(post.Title+" "+post.DescriptionText), " "))
+}
+
+// true if the text has the lowercased term as whole words, so "go" isn't found in "google"
+func containsWords(text, term string) bool {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	for i := 0; i <= len(text); {
+		j := strings.Index(text[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWord(before) && !isWord(after) {
+			return true
+		}
+		i = start + 1
+	}
+	return false
+}
+
+// true if the post is one the search looks for
+// terms are matched case-insensitively as whole words in the title or description, the author exactly but ignoring case
+func savedSearchMatches(search database.SavedSearch, post database.Post) bool {
+	if search.FeedID.Valid && search.FeedID.UUID != post.FeedID {
+		return false
+	}
+	if search.Author != "" && !strings.EqualFold(search.Author, post.Author) {
+		return false
+	}
+	terms, err := parseSearchQuery(search.Query)
+	if err != nil {
+		return false
+	}
+	text := postKeywordText(post)
+	for _, term := range terms {
+		found := containsWords(text, term)
+		if found && search.Match != searchMatchAll {
+			return true
+		}
+		if !found && search.Match == searchMatchAll {
+			return false
+		}
+	}
+	return search.Match == searchMatchAll
+}
+
+// records the post as a match of every search it matches, and notifies the searches that ask for it
+// failing is logged and doesn't fail the fetch
+func (apiCfg apiConfig) matchSavedSearches(ctx context.Context, post database.Post) {
+	searches, err := apiCfg.DB.GetSavedSearchesForFeed(ctx, post.FeedID)
+	if err != nil {
+		log.Println("matchSavedSearches: ", err)
+		return
+	}
+	for _, search := range searches {
+		if !savedSearchMatches(search, post) {
+			continue
+		}
+		err := apiCfg.DB.AddSavedSearchMatches(ctx, database.AddSavedSearchMatchesParams{
+			SearchID:  search.ID,
+			PostIds:   []uuid.UUID{post.ID},
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			log.Println("matchSavedSearches: ", err)
+			continue
+		}
+		if search.Notify {
+			apiCfg.publishEvent(ctx, eventSearchMatch, uuid.NullUUID{UUID: search.UserID, Valid: true}, uuid.NullUUID{}, uuid.NullUUID{UUID: post.ID, Valid: true}, searchMatchEvent{
+				SearchID:   search.ID,
+				SearchName: search.Name,
+				Post:       newPostResponse(timelinePost{Post: post}),
+			})
+		}
+	}
+}
+
+// runs a search that was just saved on the newest posts of the followed feeds, replacing its matches
+func (apiCfg apiConfig) backfillSavedSearch(ctx context.Context, search database.SavedSearch) error {
+	posts, err := apiCfg.DB.GetPostsToSearch(ctx, database.GetPostsToSearchParams{
+		UserID: search.UserID,
+		FeedID: search.FeedID,
+		Limit:  searchBackfillPosts,
+	})
+	if err != nil {
+		return err
+	}
+	postIDs := []uuid.UUID{}
+	for _, post := range posts {
+		if savedSearchMatches(search, post) {
+			postIDs = append(postIDs, post.ID)
+		}
+	}
+
+	return apiCfg.inTx(ctx, func(q *database.Queries) error {
+		if err := q.DeleteSavedSearchMatches(ctx, search.ID); err != nil {
+			return err
+		}
+		return q.AddSavedSearchMatches(ctx, database.AddSavedSearchMatchesParams{
+			SearchID:  search.ID,
+			PostIds:   postIDs,
+			CreatedAt: time.Now(),
+		})
+	})
+}
+
+// the unread count of one of the user's searches
+func (apiCfg apiConfig) savedSearchUnreadCount(ctx context.Context, user database.User, searchID uuid.UUID) (int64, error) {
+	rows, err := apiCfg.DB.GetSavedSearches(ctx, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		if row.SavedSearch.ID == searchID {
+			return row.UnreadCount, nil
+		}
+	}
+	return 0, errSavedSearchNotFound
+}
+
+// the fields of a saved search in a request body, nil for the ones left out
+type savedSearchParams struct {
+	Name   *string `json:"name"`
+	Query  *string `json:"query"`
+	Match  *string `json:"match"`
+	Author *string `json:"author"`
+	// a uuid, or null to search every followed feed
+	FeedID json.RawMessage `json:"feed_id"`
+	Notify *bool           `json:"notify"`
+}
+
+// applies the params to the search and validates the result
+// errors are the client's fault and meant to be responded with a 400
+func (apiCfg apiConfig) applySavedSearchParams(ctx context.Context, user database.User, search *database.SavedSearch, params savedSearchParams) error {
+	if params.Name != nil {
+		search.Name = strings.TrimSpace(*params.Name)
+	}
+	if params.Query != nil {
+		search.Query = strings.TrimSpace(*params.Query)
+	}
+	if params.Match != nil {
+		search.Match = *params.Match
+	}
+	if params.Author != nil {
+		search.Author = strings.TrimSpace(*params.Author)
+	}
+	if params.FeedID != nil {
+		feedID := uuid.NullUUID{}
+		if err := json.Unmarshal(params.FeedID, &feedID); err != nil {
+			return errors.New("feed_id must be a valid uuid or null")
+		}
+		search.FeedID = feedID
+	}
+	if params.Notify != nil {
+		search.Notify = *params.Notify
+	}
+
+	if search.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if _, err := parseSearchQuery(search.Query); err != nil {
+		return err
+	}
+	if search.Match != searchMatchAny && search.Match != searchMatchAll {
+		return errors.New("match must be any or all")
+	}
+	if search.FeedID.Valid {
+		_, err := apiCfg.DB.GetFeedFollowByFeed(ctx, database.GetFeedFollowByFeedParams{
+			UserID: user.ID,
+			FeedID: search.FeedID.UUID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("feed_id must be a feed you follow")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// true if err is the unique (user_id, name) violation of the saved_searches table
+func isDuplicateSavedSearchName(err error) bool {
+	return err.Error() == "pq: duplicate key value violates unique constraint \"saved_searches_user_id_name_key\""
+}
+
+// POST /v1/searches
+// authed
+// expects a name and a query of keywords and "quoted phrases", optionally match (any or all), author, feed_id and notify
+// the matching posts of the followed feeds are shown by GET /v1/posts?search_id=, with notify new matches are sent to GET /v1/stream
+// 409 if the user already has a search with that name
+func (apiCfg apiConfig) createSavedSearchHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	params := savedSearchParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	ctx := context.Background()
+	search := database.SavedSearch{Match: searchMatchAny}
+	if err := apiCfg.applySavedSearchParams(ctx, user, &search, params); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	search, err := apiCfg.DB.CreateSavedSearch(ctx, database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      search.Name,
+		Query:     search.Query,
+		Match:     search.Match,
+		Author:    search.Author,
+		FeedID:    search.FeedID,
+		Notify:    search.Notify,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		if isDuplicateSavedSearchName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("saved search with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	apiCfg.respondWithSavedSearch(w, http.StatusCreated, user, search)
+}
+
+// matches the search on the existing posts and responds with it
+func (apiCfg apiConfig) respondWithSavedSearch(w http.ResponseWriter, code int, user database.User, search database.SavedSearch) {
+	ctx := context.Background()
+	if err := apiCfg.backfillSavedSearch(ctx, search); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	unreadCount, err := apiCfg.savedSearchUnreadCount(ctx, user, search.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, code, newSavedSearchResponse(search, unreadCount))
+}
+
+// GET /v1/searches
+// authed
+// get the user's saved searches by name, with the number of unread posts they match
+func (apiCfg apiConfig) getSavedSearchesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := apiCfg.DB.GetSavedSearches(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	searches := make([]savedSearchResponse, 0, len(rows))
+	for _, row := range rows {
+		searches = append(searches, newSavedSearchResponse(row.SavedSearch, row.UnreadCount))
+	}
+	respondWithJSON(w, http.StatusOK, searches)
+}
+
+// PATCH /v1/searches/{searchID}
+// authed
+// expects any of the fields of POST /v1/searches, the search is run again on the existing posts
+// 404 if the search isn't the user's, 409 if the user already has a search with the new name
+func (apiCfg apiConfig) updateSavedSearchHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	searchID, err := uuidFromURL(r, "searchID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	params := savedSearchParams{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	ctx := context.Background()
+	search, err := apiCfg.DB.GetSavedSearch(ctx, database.GetSavedSearchParams{
+		ID:     searchID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errSavedSearchNotFound)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := apiCfg.applySavedSearchParams(ctx, user, &search, params); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	search, err = apiCfg.DB.UpdateSavedSearch(ctx, database.UpdateSavedSearchParams{
+		ID:        search.ID,
+		UserID:    user.ID,
+		Name:      search.Name,
+		Query:     search.Query,
+		Match:     search.Match,
+		Author:    search.Author,
+		FeedID:    search.FeedID,
+		Notify:    search.Notify,
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		if isDuplicateSavedSearchName(err) {
+			respondWithError(w, http.StatusConflict, errors.New("saved search with that name already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	apiCfg.respondWithSavedSearch(w, http.StatusOK, user, search)
+}
+
+// DELETE /v1/searches/{searchID}
+// authed
+// deletes the saved search, the posts it matched are kept
+func (apiCfg apiConfig) deleteSavedSearchHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	searchID, err := uuidFromURL(r, "searchID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteSavedSearch(context.Background(), database.DeleteSavedSearchParams{
+		ID:     searchID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errSavedSearchNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
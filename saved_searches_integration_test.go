@@ -0,0 +1,121 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSavedSearches(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(items ...*gofeed.Item) {
+		apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: items}}}
+		apiCfg.CreatePostsFromFetchedFeeds()
+	}
+	fetch(&gofeed.Item{Title: "Generic types in Go", Link: base + "/generics"})
+
+	// saving a search matches the posts already fetched
+	notify := true
+	search, err := alice.CreateSavedSearch(ctx, client.SavedSearchParams{Name: "generics", Query: `"generic types" rust`, Notify: &notify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Match != "any" || search.UnreadCount != 1 || search.FeedID != nil {
+		t.Errorf("got search %+v", search)
+	}
+	var apiErr *client.Error
+	_, err = alice.CreateSavedSearch(ctx, client.SavedSearchParams{Name: "generics", Query: "go"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 for a duplicate name, got %v", err)
+	}
+	_, err = alice.CreateSavedSearch(ctx, client.SavedSearchParams{Name: "empty", Query: `""`})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty query, got %v", err)
+	}
+
+	// a new matching post is notified, the one that doesn't match isn't
+	stream, err := alice.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	fetch(
+		&gofeed.Item{Title: "Release notes", Link: base + "/release"},
+		&gofeed.Item{Title: "Rust and Go", Description: "compared", Link: base + "/rust"},
+	)
+	for {
+		event, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Event != client.EventSearchMatch {
+			continue
+		}
+		match := client.SearchMatchEvent{}
+		if err := json.Unmarshal(event.Data, &match); err != nil {
+			t.Fatal(err)
+		}
+		if match.SearchID != search.ID || match.Post.Title != "Rust and Go" {
+			t.Errorf("got search_match %+v", match)
+		}
+		break
+	}
+
+	// the search is a virtual feed of the posts it matched
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{SearchID: search.ID, Sort: client.SortOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Title != "Generic types in Go" || page.Items[1].Title != "Rust and Go" {
+		t.Errorf("got posts %+v", page.Items)
+	}
+	if _, err := bob.ListPosts(ctx, &client.ListPostsOptions{SearchID: search.ID}); !client.IsNotFound(err) {
+		t.Errorf("expected 404 for someone else's search, got %v", err)
+	}
+
+	// changing the search runs it again
+	search, err = alice.UpdateSavedSearch(ctx, search.ID, client.SavedSearchParams{Match: "all", Query: "rust go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Name != "generics" || !search.Notify || search.UnreadCount != 1 {
+		t.Errorf("got updated search %+v", search)
+	}
+	searches, err := alice.ListSavedSearches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 1 || searches[0].ID != search.ID || searches[0].UnreadCount != 1 {
+		t.Errorf("got searches %+v", searches)
+	}
+
+	if err := bob.DeleteSavedSearch(ctx, search.ID); !client.IsNotFound(err) {
+		t.Errorf("expected 404 deleting someone else's search, got %v", err)
+	}
+	if err := alice.DeleteSavedSearch(ctx, search.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.ListPosts(ctx, &client.ListPostsOptions{SearchID: search.ID}); !client.IsNotFound(err) {
+		t.Errorf("expected 404 for a deleted search, got %v", err)
+	}
+}
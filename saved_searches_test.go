@@ -0,0 +1,64 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"golang", []string{"golang"}},
+		{"  Go   Rust ", []string{"go", "rust"}},
+		{`"Generic  Types" go`, []string{"generic types", "go"}},
+		{`release"notes"`, []string{"release", "notes"}},
+	}
+	for _, test := range tests {
+		got, err := parseSearchQuery(test.query)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseSearchQuery(%q) = %q, %v, want %q", test.query, got, err, test.want)
+		}
+	}
+	for _, query := range []string{"", "   ", `""`, `"unterminated`} {
+		if _, err := parseSearchQuery(query); err == nil {
+			t.Errorf("parseSearchQuery(%q) should fail", query)
+		}
+	}
+}
+
+func TestSavedSearchMatches(t *testing.T) {
+	feedID := uuid.New()
+	post := database.Post{
+		FeedID:          feedID,
+		Title:           "Releasing Go 1.21",
+		Description:     `<p>with <a href="https://golang.org">min</a> and max</p>` + "\n<p>builtins</p>",
+		DescriptionText: "with min and max\nbuiltins",
+		Author:          "Rob Pike",
+	}
+	tests := []struct {
+		search database.SavedSearch
+		want   bool
+	}{
+		{database.SavedSearch{Query: "rust GO", Match: searchMatchAny}, true},
+		{database.SavedSearch{Query: "rust go", Match: searchMatchAll}, false},
+		{database.SavedSearch{Query: `go "max builtins"`, Match: searchMatchAll}, true},
+		{database.SavedSearch{Query: `"go max"`, Match: searchMatchAny}, false},
+		{database.SavedSearch{Query: "golang href", Match: searchMatchAny}, false},
+		{database.SavedSearch{Query: "release", Match: searchMatchAny}, false},
+		{database.SavedSearch{Query: `"go 1.21"`, Match: searchMatchAny}, true},
+		{database.SavedSearch{Query: "go", Match: searchMatchAny, Author: "rob pike"}, true},
+		{database.SavedSearch{Query: "go", Match: searchMatchAny, Author: "Russ Cox"}, false},
+		{database.SavedSearch{Query: "go", Match: searchMatchAny, FeedID: uuid.NullUUID{UUID: feedID, Valid: true}}, true},
+		{database.SavedSearch{Query: "go", Match: searchMatchAny, FeedID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}, false},
+	}
+	for _, test := range tests {
+		if got := savedSearchMatches(test.search, post); got != test.want {
+			t.Errorf("savedSearchMatches(%+v) = %v", test.search, got)
+		}
+	}
+}
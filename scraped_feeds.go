@@ -0,0 +1,170 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/sanitize"
+	"blog_aggregator/internal/scrape"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+// returned from inside a transaction when selectors are set on a feed that isn't scraped
+var errNotScrapedFeed = errors.New("only scraped feeds have selectors")
+
+// the selectors of a scraped feed
+func feedSelectors(feed database.Feed) (scrape.Selectors, error) {
+	selectors := scrape.Selectors{}
+	err := json.Unmarshal(feed.Selectors, &selectors)
+	return selectors, err
+}
+
+// downloads the page of a scraped feed and turns its items into a feed, like getRSSFromURL does for rss
+func (apiCfg apiConfig) getScrapedFeed(url string, selectors scrape.Selectors) (*gofeed.Feed, error) {
+	resp, err := apiCfg.Fetcher.Get(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	page, err := scrape.Scrape(resp.Body, resp.URL, selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &gofeed.Feed{Title: page.Title, Link: url, Items: make([]*gofeed.Item, 0, len(page.Items))}
+	for _, item := range page.Items {
+		feed.Items = append(feed.Items, &gofeed.Item{
+			Title:           item.Title,
+			Link:            item.Link,
+			Description:     item.Summary,
+			PublishedParsed: item.Published,
+		})
+	}
+	return feed, nil
+}
+
+// POST /v1/scraped_feeds
+// authed
+// expects {"name": "...", "url": "...", "selectors": {"item": "...", "title": "...", "link": "...", "date": "...", "summary": "..."}}
+// creates a feed of the page at url, polled like the other feeds, with a post for each item the selectors find in it
+// item and title are required, the other selectors are matched inside each item
+// responds 201 with the feed and the user's follow of it, 409 if a feed already has the url
+func (apiCfg apiConfig) createScrapedFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name      string           `json:"name"`
+		Url       string           `json:"url"`
+		Selectors scrape.Selectors `json:"selectors"`
+	}
+	type returnVal struct {
+		Feed       feedResponse       `json:"feed"`
+		FeedFollow feedFollowResponse `json:"feed_follow"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, errors.New("name cannot be empty"))
+		return
+	}
+	if !isFeedURL(params.Url) {
+		respondWithError(w, http.StatusBadRequest, errors.New("url must be an http or https url"))
+		return
+	}
+	if err := params.Selectors.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+	selectors, err := json.Marshal(params.Selectors)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	feed, err := apiCfg.DB.CreateScrapedFeed(context.Background(), database.CreateScrapedFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Name:      params.Name,
+		Url:       params.Url,
+		UserID:    user.ID,
+		Selectors: selectors,
+	})
+	if err != nil {
+		if err.Error() == "pq: duplicate key value violates unique constraint \"feeds_url_key\"" {
+			respondWithError(w, http.StatusConflict, errors.New("feed with that url already exists"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	feedFollow, _, err := apiCfg.followFeed(context.Background(), user, feed.ID, "")
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, returnVal{
+		Feed:       newFeedResponse(feed),
+		FeedFollow: newFeedFollowResponse(feedFollow),
+	})
+}
+
+// POST /v1/scraped_feeds/preview
+// authed
+// expects {"url": "...", "selectors": {...}} like POST /v1/scraped_feeds, nothing is saved
+// downloads the page and responds with its title and the items the selectors find, summaries sanitized like posts are
+// 502 if the page can't be downloaded
+func (apiCfg apiConfig) previewScrapedFeedHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Url       string           `json:"url"`
+		Selectors scrape.Selectors `json:"selectors"`
+	}
+	type returnVal struct {
+		Title string        `json:"title"`
+		Items []scrape.Item `json:"items"`
+	}
+
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	if !isFeedURL(params.Url) {
+		respondWithError(w, http.StatusBadRequest, errors.New("url must be an http or https url"))
+		return
+	}
+	if err := params.Selectors.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := apiCfg.Fetcher.Get(r.Context(), params.Url)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, fmt.Errorf("couldn't download the page: %w", err))
+		return
+	}
+	retVal := returnVal{Items: []scrape.Item{}}
+	page, err := scrape.Scrape(resp.Body, resp.URL, params.Selectors)
+	if err != nil && !errors.Is(err, scrape.ErrNoItems) {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if page != nil {
+		retVal.Title = page.Title
+		for _, item := range page.Items {
+			item.Summary = sanitize.HTML(item.Summary, item.Link)
+			retVal.Items = append(retVal.Items, item)
+		}
+	}
+	respondWithJSON(w, http.StatusOK, retVal)
+}
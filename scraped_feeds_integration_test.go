@@ -0,0 +1,135 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+const scrapedFeedTestPage = `<!DOCTYPE html>
+<html>
+<head><title>Library news</title></head>
+<body>
+  <nav><a href="/">Home</a></nav>
+  <div class="news">
+    <div class="entry">
+      <h2><a href="/news/summer-reading">Summer reading</a></h2>
+      <time datetime="2024-06-01T09:00:00Z">June 1</time>
+      <p class="teaser">Read ten books <script>alert(1)</script>and win a prize.</p>
+    </div>
+    <div class="entry">
+      <h2><a href="/news/opening-hours">Opening hours</a></h2>
+      <p class="teaser">Open later on Thursdays.</p>
+    </div>
+  </div>
+</body>
+</html>`
+
+func TestScrapedFeeds(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(scrapedFeedTestPage))
+	}))
+	t.Cleanup(site.Close)
+	pageURL := site.URL + "/news?" + uuid.NewString()
+	selectors := client.Selectors{Item: ".entry", Title: "h2", Date: "time", Summary: ".teaser"}
+
+	// invalid selectors are refused
+	_, err := alice.PreviewScrapedFeed(ctx, pageURL, client.Selectors{Item: ".entry"})
+	apiErr := &client.Error{}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got error %v, want a 400", err)
+	}
+
+	preview, err := alice.PreviewScrapedFeed(ctx, pageURL, selectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Title != "Library news" || len(preview.Items) != 2 {
+		t.Fatalf("got preview %+v", preview)
+	}
+	first := preview.Items[0]
+	if first.Title != "Summer reading" || first.Link != site.URL+"/news/summer-reading" || first.Published == nil || first.Summary != "Read ten books and win a prize." {
+		t.Errorf("got item %+v", first)
+	}
+	if preview.Items[1].Published != nil {
+		t.Errorf("got item %+v", preview.Items[1])
+	}
+
+	// selectors that match nothing preview no items
+	preview, err = alice.PreviewScrapedFeed(ctx, pageURL, client.Selectors{Item: "article", Title: "h2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Items) != 0 {
+		t.Errorf("got items %+v", preview.Items)
+	}
+
+	created, err := alice.CreateScrapedFeed(ctx, "Library", pageURL, selectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Feed.Kind != client.FeedKindScraped || created.Feed.Selectors == nil || *created.Feed.Selectors != selectors {
+		t.Fatalf("got feed %+v", created.Feed)
+	}
+	_, err = alice.CreateScrapedFeed(ctx, "Library again", pageURL, selectors)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("got error %v, want a 409", err)
+	}
+
+	// the fetcher polls it like any other feed
+	feed, err := apiCfg.DB.GetFeed(ctx, created.Feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := apiCfg.fetchFeed(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: feed.ID, Feed: data}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	page, err := alice.ListPosts(ctx, &client.ListPostsOptions{FeedID: feed.ID, Sort: client.SortOldest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Title != "Summer reading" || page.Items[0].URL != site.URL+"/news/summer-reading" {
+		t.Fatalf("got posts %+v", page.Items)
+	}
+	if page.Items[0].Description != "Read ten books and win a prize." || !page.Items[0].PublishedAt.Equal(*first.Published) {
+		t.Errorf("got post %+v", page.Items[0])
+	}
+
+	// only scraped feeds have selectors
+	rss, err := alice.CreateFeed(ctx, "Blog", "https://example.com/"+uuid.NewString()+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rss.Feed.Kind != client.FeedKindRSS || rss.Feed.Selectors != nil {
+		t.Errorf("got feed %+v", rss.Feed)
+	}
+	_, err = alice.UpdateFeed(ctx, rss.Feed.ID, client.UpdateFeedOptions{Selectors: &selectors})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got error %v, want a 400", err)
+	}
+
+	changed := client.Selectors{Item: ".entry", Title: "p.teaser", Link: "h2 a"}
+	updated, err := alice.UpdateFeed(ctx, feed.ID, client.UpdateFeedOptions{Selectors: &changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Feed.Selectors == nil || *updated.Feed.Selectors != changed {
+		t.Errorf("got feed %+v", updated.Feed)
+	}
+}
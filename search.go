@@ -0,0 +1,212 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+
+	searchScopeFollowed = "followed"
+	searchScopeAll      = "all"
+
+	// marks the matches in the headlines from the db, replaced with <mark> once the rest is escaped
+	searchHighlightStart = "\x02"
+	searchHighlightStop  = "\x03"
+)
+
+// position in the search results that a page starts at, handed to clients as an opaque string
+// results are ranked so pages are by offset, unlike the timeline
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+func encodeSearchCursor(cursor searchCursor) string {
+	dat, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	cursor := searchCursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(dat, &cursor); err != nil || cursor.Offset < 0 {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+type searchResultResponse struct {
+	postResponse
+	Rank float32 `json:"rank"`
+	// the title and a snippet of the description and content as html, the matches are in <mark>
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"`
+}
+
+// turns a search box query into a postgres tsquery, every term has to match
+// terms are words, "quoted phrases", word* prefixes and -excluded words or phrases
+// punctuation is dropped like the english text search parser does, so the tsquery is always valid
+func toTSQuery(query string) (string, error) {
+	terms := []string{}
+	positive := false
+	for {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			break
+		}
+		negated := false
+		if query[0] == '-' {
+			negated = true
+			query = query[1:]
+		}
+		var term string
+		prefix := false
+		if query != "" && query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				return "", errors.New("q has an unterminated quote")
+			}
+			term, query = query[1:end+1], query[end+2:]
+		} else {
+			end := strings.IndexAny(query, " \t\n\"")
+			if end < 0 {
+				end = len(query)
+			}
+			term, query = query[:end], query[end:]
+			prefix = strings.HasSuffix(term, "*")
+		}
+
+		words := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if len(words) == 0 {
+			continue
+		}
+		for i, word := range words {
+			words[i] = "'" + word + "'"
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+		tsquery := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			tsquery = "(" + tsquery + ")"
+		}
+		if negated {
+			tsquery = "!" + tsquery
+		} else {
+			positive = true
+		}
+		terms = append(terms, tsquery)
+	}
+	if !positive {
+		return "", errors.New("q must have a word or phrase to search for")
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// escapes a headline from the db and turns its highlight markers into <mark> tags
+// the db has already stripped the tags, entities are decoded first so they aren't escaped twice
+func highlightHTML(headline string) string {
+	headline = strings.Join(strings.Fields(headline), " ")
+	headline = html.EscapeString(html.UnescapeString(headline))
+	return strings.NewReplacer(searchHighlightStart, "<mark>", searchHighlightStop, "</mark>").Replace(headline)
+}
+
+// GET /v1/search
+// authed
+// full-text search of the title, description and content of the posts in the feeds the user follows
+// expects q, words must all match, "quoted phrases" match in order, word* matches prefixes and -word excludes
+// optional query params: limit (1 to 100, default 20), cursor, scope (followed or all, all is only for admins)
+// the results are best match first, the next/prev pages are in the Link header
+func (apiCfg apiConfig) searchPostsHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	values := r.URL.Query()
+	tsquery, err := toTSQuery(values.Get("q"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := defaultSearchLimit
+	if tmp := values.Get("limit"); tmp != "" {
+		limit, err = strconv.Atoi(tmp)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("limit must be an integer between 1 and %d", maxSearchLimit))
+			return
+		}
+	}
+	cursor := searchCursor{}
+	if tmp := values.Get("cursor"); tmp != "" {
+		cursor, err = decodeSearchCursor(tmp)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	scope := searchScopeFollowed
+	if tmp := values.Get("scope"); tmp != "" {
+		if tmp != searchScopeFollowed && tmp != searchScopeAll {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("scope must be %s or %s", searchScopeFollowed, searchScopeAll))
+			return
+		}
+		scope = tmp
+	}
+	if scope == searchScopeAll && !user.IsAdmin {
+		respondWithError(w, http.StatusForbidden, errors.New("only admins can search every feed"))
+		return
+	}
+
+	// one extra row tells whether there is a next page
+	rows, err := apiCfg.DB.SearchPosts(context.Background(), database.SearchPostsParams{
+		UserID:   user.ID,
+		Query:    tsquery,
+		AllFeeds: scope == searchScopeAll,
+		StartSel: searchHighlightStart,
+		StopSel:  searchHighlightStop,
+		Limit:    int32(limit + 1),
+		Offset:   int32(cursor.Offset),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	page := postsPage{}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		page.Next = encodeSearchCursor(searchCursor{Offset: cursor.Offset + limit})
+	}
+	if cursor.Offset > 0 {
+		prev := cursor.Offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		page.Prev = encodeSearchCursor(searchCursor{Offset: prev})
+	}
+
+	results := make([]searchResultResponse, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, searchResultResponse{
+			postResponse:   newPostResponse(timelinePost{Post: row.Post, Read: row.Read, Starred: row.Starred, Saved: row.Saved}),
+			Rank:           row.Rank,
+			TitleHighlight: highlightHTML(row.TitleHeadline),
+			Snippet:        highlightHTML(row.Snippet),
+		})
+	}
+	setPostsLinkHeader(w, r, page)
+	respondWithJSON(w, http.StatusOK, results)
+}
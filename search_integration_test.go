@@ -0,0 +1,116 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSearchPosts(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+	bob := newTestUser(t, server, "bob")
+
+	// unique words so other tests' posts don't match
+	word := "zq" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Generics in " + word, Description: "<p>Type parameters &amp; constraints</p>", Link: base + "/generics"},
+		{Title: "Release notes", Description: "A short teaser", Content: "<p>The " + word + " release adds type parameters</p>", Link: base + "/release"},
+		{Title: "Unrelated", Description: "Nothing to see", Link: base + "/unrelated"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	// the title weighs more than the content
+	page, err := alice.Search(ctx, word, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].URL != base+"/generics" || page.Items[1].URL != base+"/release" {
+		t.Fatalf("got results %+v", page.Items)
+	}
+	if page.Items[0].TitleHighlight != "Generics in <mark>"+word+"</mark>" {
+		t.Errorf("got title highlight %q", page.Items[0].TitleHighlight)
+	}
+	if !strings.Contains(page.Items[1].Snippet, "<mark>"+word+"</mark>") || strings.Contains(page.Items[1].Snippet, "<p>") {
+		t.Errorf("got snippet %q", page.Items[1].Snippet)
+	}
+
+	// phrases, prefixes and exclusions
+	for q, want := range map[string]int{
+		`"type parameters" ` + word: 2,
+		word[:8] + "*":              2,
+		word + " -release":          1,
+		word + ` "parameters type"`: 0,
+	} {
+		page, err := alice.Search(ctx, q, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Items) != want {
+			t.Errorf("search %q: got %d results", q, len(page.Items))
+		}
+	}
+
+	// pages follow the rank
+	page, err = alice.Search(ctx, word, &client.SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].URL != base+"/generics" || page.Next == "" {
+		t.Fatalf("got first page %+v", page)
+	}
+	page, err = alice.Search(ctx, word, &client.SearchOptions{Limit: 1, Cursor: page.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].URL != base+"/release" || page.Next != "" || page.Prev == "" {
+		t.Errorf("got second page %+v", page)
+	}
+
+	// bob doesn't follow the feed, and has to be an admin to search every feed
+	page, err = bob.Search(ctx, word, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("bob got results %+v", page.Items)
+	}
+	var apiErr *client.Error
+	_, err = bob.Search(ctx, word, &client.SearchOptions{Scope: client.SearchScopeAll})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for scope=all, got %v", err)
+	}
+	bobUser, err := bob.GetUser(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.Conn.Exec("UPDATE users SET is_admin = true WHERE id = $1", bobUser.ID); err != nil {
+		t.Fatal(err)
+	}
+	page, err = bob.Search(ctx, word, &client.SearchOptions{Scope: client.SearchScopeAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("admin got results %+v", page.Items)
+	}
+
+	if _, err := alice.Search(ctx, `"unterminated`, nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unterminated quote, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestToTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"golang", "'golang'"},
+		{"Go  generics", "'go' & 'generics'"},
+		{`"type parameters" go`, "('type' <-> 'parameters') & 'go'"},
+		{"gener*", "'gener':*"},
+		{"go -rust", "'go' & !'rust'"},
+		{`go -"release notes"`, "'go' & !('release' <-> 'notes')"},
+		{"it's go1.21!", "('it' <-> 's') & ('go1' <-> '21')"},
+		{"go - & |", "'go'"},
+	}
+	for _, test := range tests {
+		got, err := toTSQuery(test.query)
+		if err != nil || got != test.want {
+			t.Errorf("toTSQuery(%q) = %q, %v, want %q", test.query, got, err, test.want)
+		}
+	}
+	for _, query := range []string{"", "  ", "-rust", `"!?"`, `"unterminated`} {
+		if _, err := toTSQuery(query); err == nil {
+			t.Errorf("toTSQuery(%q) should fail", query)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	headline := "Tom &amp; Jerry use \x02Go\x03 <script\n\x02generics\x03"
+	want := "Tom &amp; Jerry use <mark>Go</mark> &lt;script <mark>generics</mark>"
+	if got := highlightHTML(headline); got != want {
+		t.Errorf("highlightHTML = %q, want %q", got, want)
+	}
+}
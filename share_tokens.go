@@ -0,0 +1,47 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// what a share token makes public
+const (
+	shareTokenKindBlogroll   = "blogroll"
+	shareTokenKindOutputFeed = "output_feed"
+)
+
+type shareTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newShareTokenResponse(shareToken database.ShareToken, url string) shareTokenResponse {
+	return shareTokenResponse{
+		Token:     shareToken.Token,
+		URL:       url,
+		CreatedAt: shareToken.CreatedAt.UTC(),
+	}
+}
+
+// makes a new token of the kind for the user, or for one of their folders, replacing the one they had
+func (apiCfg apiConfig) rotateShareToken(ctx context.Context, user database.User, kind string, folderID uuid.NullUUID) (database.ShareToken, error) {
+	dat := make([]byte, 24)
+	if _, err := rand.Read(dat); err != nil {
+		return database.ShareToken{}, err
+	}
+	return apiCfg.DB.UpsertShareToken(ctx, database.UpsertShareTokenParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Kind:      kind,
+		Token:     hex.EncodeToString(dat),
+		CreatedAt: time.Now(),
+		FolderID:  folderID,
+	})
+}
@@ -0,0 +1,168 @@
+-- name: UpsertDigestSubscription :one
+-- changing the schedule keeps the unsubscribe link and when the last digest was sent
+-- a new address has to be confirmed again, and an unconfirmed one gets a new confirmation link
+INSERT INTO digest_subscriptions (
+    user_id, email, frequency, send_time, timezone, weekday, unsubscribe_token, confirm_token, base_url, next_send_at, created_at, updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email, frequency = EXCLUDED.frequency, send_time = EXCLUDED.send_time,
+    timezone = EXCLUDED.timezone, weekday = EXCLUDED.weekday, base_url = EXCLUDED.base_url,
+    next_send_at = EXCLUDED.next_send_at, updated_at = EXCLUDED.updated_at,
+    confirm_token = CASE
+        WHEN digest_subscriptions.email = EXCLUDED.email AND digest_subscriptions.confirmed_at IS NOT NULL THEN NULL
+        ELSE EXCLUDED.confirm_token
+    END,
+    confirmed_at = CASE
+        WHEN digest_subscriptions.email = EXCLUDED.email THEN digest_subscriptions.confirmed_at
+        ELSE NULL
+    END
+RETURNING *;
+
+-- name: GetDigestSubscriptionByConfirmToken :one
+SELECT * FROM digest_subscriptions
+WHERE confirm_token = $1;
+
+-- name: ConfirmDigestSubscription :exec
+UPDATE digest_subscriptions
+SET confirm_token = NULL, confirmed_at = $2
+WHERE user_id = $1;
+
+-- name: GetDigestSubscription :one
+SELECT * FROM digest_subscriptions
+WHERE user_id = $1;
+
+-- name: DeleteDigestSubscription :execrows
+DELETE FROM digest_subscriptions
+WHERE user_id = $1;
+
+-- name: DeleteDigestSubscriptionByToken :execrows
+DELETE FROM digest_subscriptions
+WHERE unsubscribe_token = $1;
+
+-- name: GetDueDigestSubscriptions :many
+-- only the confirmed ones get digests
+SELECT * FROM digest_subscriptions
+WHERE next_send_at <= $1 AND confirmed_at IS NOT NULL
+ORDER BY next_send_at
+LIMIT $2;
+
+-- name: ClaimDigestSubscription :execrows
+-- moves a due subscription to its next send time, 0 rows if another worker got to it first
+UPDATE digest_subscriptions
+SET next_send_at = sqlc.arg('next_send_at')
+WHERE user_id = sqlc.arg('user_id') AND next_send_at = sqlc.arg('due_at');
+
+-- name: SetDigestNextSendAt :exec
+UPDATE digest_subscriptions
+SET next_send_at = $2
+WHERE user_id = $1;
+
+-- name: SetDigestLastSentAt :exec
+UPDATE digest_subscriptions
+SET last_sent_at = $2
+WHERE user_id = $1;
+
+-- name: GetDigestPosts :many
+-- unread posts of the feeds the user follows and gets notified about, that weren't in a digest yet
+-- and aren't hidden by the user's filter rules
+-- each post is under the first of the user's folders its feed is in, or under none
+SELECT
+    sqlc.embed(posts),
+    COALESCE(feed_follows.title, feeds.name)::text AS feed_title,
+    COALESCE((
+        SELECT folders.name FROM folders
+        JOIN feed_follow_folders ON feed_follow_folders.folder_id = folders.id
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+        ORDER BY folders.position
+        LIMIT 1
+    ), '')::text AS folder_name,
+    COALESCE((
+        SELECT folders.position FROM folders
+        JOIN feed_follow_folders ON feed_follow_folders.folder_id = folders.id
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+        ORDER BY folders.position
+        LIMIT 1
+    ), 2147483647)::int AS folder_position
+FROM posts
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg('user_id')
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE feed_follows.notify = 'all'
+    AND posts.created_at > sqlc.arg('since')
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = sqlc.arg('user_id') AND post_reads.post_id = posts.id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM digest_posts
+        WHERE digest_posts.user_id = sqlc.arg('user_id') AND digest_posts.post_id = posts.id
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id') AND filter_rules.hide
+    )
+ORDER BY folder_position, folder_name, feed_title, posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CreateDigest :one
+-- the digest is sending, by the worker that records it
+INSERT INTO digests (id, user_id, email, frequency, subject, text_body, html_body, status, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, 'sending', $8)
+RETURNING *;
+
+-- name: ClaimDueDigests :many
+-- marks the pending digests that are due to be sent again as sending, safe to call from several workers
+UPDATE digests
+SET status = 'sending'
+WHERE id IN (
+    SELECT digests.id FROM digests
+    WHERE digests.status = 'pending' AND digests.next_attempt_at <= sqlc.arg('now')::timestamp
+    ORDER BY digests.next_attempt_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishDigestAttempt :one
+-- the bodies are only kept while the digest is to be sent again
+UPDATE digests
+SET status = sqlc.arg('status'), attempts = attempts + 1, next_attempt_at = sqlc.arg('next_attempt_at'),
+    error = sqlc.arg('error'), sent_at = sqlc.arg('sent_at'),
+    text_body = CASE WHEN sqlc.arg('status') = 'pending' THEN text_body ELSE '' END,
+    html_body = CASE WHEN sqlc.arg('status') = 'pending' THEN html_body ELSE '' END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: RequeueSendingDigests :execrows
+-- digests that were being sent when the server stopped are sent again
+UPDATE digests
+SET status = 'pending', next_attempt_at = $1
+WHERE status = 'sending';
+
+-- name: DeleteDigestPostsOfDigest :exec
+-- the posts of a digest that failed can be in the next one
+DELETE FROM digest_posts
+WHERE digest_id = $1;
+
+-- name: AddDigestPosts :exec
+INSERT INTO digest_posts (digest_id, user_id, post_id)
+SELECT sqlc.arg('digest_id'), sqlc.arg('user_id'), unnest(sqlc.arg('post_ids')::uuid[])
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetDigests :many
+SELECT
+    sqlc.embed(digests),
+    ARRAY(
+        SELECT digest_posts.post_id FROM digest_posts
+        WHERE digest_posts.digest_id = digests.id
+        ORDER BY digest_posts.post_id
+    )::uuid[] AS post_ids
+FROM digests
+WHERE digests.user_id = $1
+ORDER BY digests.created_at DESC
+LIMIT $2;
+
+-- name: GetDigestSubscriptionByToken :one
+SELECT * FROM digest_subscriptions
+WHERE unsubscribe_token = $1;
@@ -1,13 +1,58 @@
 -- name: CreateFeedFollow :one
+-- returns no rows if the user already follows the feed
 INSERT INTO feed_follows (id, feed_id, user_id, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING
 RETURNING *;
 
 -- name: GetFeedFollows :many
 SELECT * FROM feed_follows
 WHERE user_id = $1
-ORDER BY id ;
+ORDER BY pinned DESC, id ;
 
--- name: DeleteFeedFollow :exec
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFeedFollowByFeed :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: UpdateFeedFollowSettings :one
+UPDATE feed_follows
+SET title = $3, pinned = $4, notify = $5, hide_from_timeline = $6, updated_at = $7
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFeedFollow :execrows
+DELETE FROM feed_follows
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteFeedFollowByFeed :exec
 DELETE FROM feed_follows
-WHERE id = $1;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollowFoldersToFeed :exec
+-- users following both feeds keep the folders of the follow that is going away
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT to_follows.id, feed_follow_folders.folder_id
+FROM feed_follow_folders
+JOIN feed_follows AS from_follows ON from_follows.id = feed_follow_folders.feed_follow_id
+JOIN feed_follows AS to_follows ON to_follows.user_id = from_follows.user_id AND to_follows.feed_id = sqlc.arg('to_feed_id')
+WHERE from_follows.feed_id = sqlc.arg('from_feed_id')
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
+
+-- name: MoveFeedFollowsToFeed :exec
+-- follows of users that already follow the other feed are dropped
+WITH dropped AS (
+    DELETE FROM feed_follows AS from_follows
+    USING feed_follows AS to_follows
+    WHERE from_follows.feed_id = sqlc.arg('from_feed_id')
+        AND to_follows.feed_id = sqlc.arg('to_feed_id')
+        AND to_follows.user_id = from_follows.user_id
+    RETURNING from_follows.id
+)
+UPDATE feed_follows
+SET feed_id = sqlc.arg('to_feed_id')
+WHERE feed_follows.feed_id = sqlc.arg('from_feed_id')
+    AND feed_follows.id NOT IN (SELECT dropped.id FROM dropped);
@@ -3,6 +3,104 @@ INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
+-- name: CreateScrapedFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, kind, selectors)
+VALUES ($1, $2, $3, $4, $5, $6, 'scraped', $7)
+RETURNING *;
+
+-- name: CreatePushFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, kind, write_token, invite_token)
+VALUES ($1, $2, $3, $4, $5, $6, 'push', $7, $8)
+RETURNING *;
+
 -- name: GetFeeds :many
+-- push feeds are private, they're followed by id
 SELECT * FROM feeds
-ORDER BY id;
\ No newline at end of file
+WHERE kind <> 'push'
+ORDER BY id;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds
+WHERE url = $1;
+
+-- name: GetFeed :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: UpdateFeedName :one
+UPDATE feeds
+SET name = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateFeedURL :one
+-- the new url is fetched on the next run
+UPDATE feeds
+SET url = $2, updated_at = $3, last_fetched_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateFeedFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2, content_selector = $3, updated_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateFeedSelectors :one
+UPDATE feeds
+SET selectors = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedWriteToken :one
+UPDATE feeds
+SET write_token = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedInviteToken :one
+UPDATE feeds
+SET invite_token = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedOwner :exec
+UPDATE feeds
+SET user_id = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: GetNextFeedOwner :one
+-- who a feed goes to when its owner deletes it: the earliest follower,
+-- otherwise a user that starred or saved one of its posts
+SELECT users.id
+FROM users
+LEFT JOIN feed_follows ON feed_follows.user_id = users.id AND feed_follows.feed_id = sqlc.arg('feed_id')
+WHERE users.id <> sqlc.arg('excluded_user_id')
+    AND (
+        feed_follows.id IS NOT NULL
+        OR EXISTS (
+            SELECT 1 FROM post_stars JOIN posts ON posts.id = post_stars.post_id
+            WHERE post_stars.user_id = users.id AND posts.feed_id = sqlc.arg('feed_id')
+        )
+        OR EXISTS (
+            SELECT 1 FROM saved_posts JOIN posts ON posts.id = saved_posts.post_id
+            WHERE saved_posts.user_id = users.id AND posts.feed_id = sqlc.arg('feed_id')
+        )
+    )
+ORDER BY feed_follows.created_at NULLS LAST, users.created_at
+LIMIT 1;
+
+-- name: DeleteFeed :exec
+-- posts that are starred or saved have to be unstarred and unsaved first
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeletePostStarsOfFeed :exec
+DELETE FROM post_stars
+USING posts
+WHERE posts.id = post_stars.post_id AND posts.feed_id = $1;
+
+-- name: DeleteSavedPostsOfFeed :exec
+DELETE FROM saved_posts
+USING posts
+WHERE posts.id = saved_posts.post_id AND posts.feed_id = $1;
@@ -1,10 +1,15 @@
 -- name: GetNextFeedsToFetch :many
+-- push feeds have nothing to fetch
 SELECT * FROM feeds
-WHERE last_fetched_at IS NULL or last_fetched_at < NOW() - INTERVAL '60 minutes'
+WHERE kind <> 'push' AND (last_fetched_at IS NULL or last_fetched_at < NOW() - INTERVAL '60 minutes')
 ORDER BY last_fetched_at
 LIMIT $1;
 
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = $2, updated_at = $3
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET site_url = $2
+WHERE id = $1 AND site_url <> $2;
@@ -0,0 +1,58 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, user_id, name, feed_id, conditions, hide, star, tags, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING *;
+
+-- name: GetFilterRules :many
+SELECT
+    sqlc.embed(filter_rules),
+    (SELECT COUNT(*) FROM filter_rule_matches WHERE filter_rule_matches.rule_id = filter_rules.id)::bigint AS match_count
+FROM filter_rules
+WHERE filter_rules.user_id = $1
+ORDER BY filter_rules.name;
+
+-- name: GetFilterRule :one
+SELECT * FROM filter_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateFilterRule :one
+UPDATE filter_rules
+SET name = $3, feed_id = $4, conditions = $5, hide = $6, star = $7, tags = $8, updated_at = $9
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFilterRulesForFeed :many
+-- the rules of the feed's followers that look at its posts
+SELECT filter_rules.* FROM filter_rules
+JOIN feed_follows ON feed_follows.user_id = filter_rules.user_id AND feed_follows.feed_id = sqlc.arg('feed_id')
+WHERE filter_rules.feed_id IS NULL OR filter_rules.feed_id = sqlc.arg('feed_id');
+
+-- name: GetUserFilterRulesForFeed :many
+-- the rules of the user that look at the feed's posts
+SELECT * FROM filter_rules
+WHERE user_id = sqlc.arg('user_id') AND (feed_id IS NULL OR feed_id = sqlc.arg('feed_id'));
+
+-- name: GetPostsOfFeed :many
+SELECT * FROM posts
+WHERE feed_id = $1;
+
+-- name: AddFilterRuleMatches :exec
+INSERT INTO filter_rule_matches (rule_id, post_id, created_at)
+SELECT sqlc.arg('rule_id'), unnest(sqlc.arg('post_ids')::uuid[]), sqlc.arg('created_at')
+ON CONFLICT (rule_id, post_id) DO NOTHING;
+
+-- name: DeleteFilterRuleMatches :exec
+DELETE FROM filter_rule_matches
+WHERE rule_id = $1;
+
+-- name: IsPostHidden :one
+-- whether one of the user's filter rules hides the post
+SELECT EXISTS (
+    SELECT 1 FROM filter_rule_matches
+    JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+    WHERE filter_rule_matches.post_id = $1 AND filter_rules.user_id = $2 AND filter_rules.hide
+)::boolean;
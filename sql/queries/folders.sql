@@ -0,0 +1,77 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, user_id, name, position, created_at, updated_at)
+VALUES (
+    sqlc.arg('id'),
+    sqlc.arg('user_id'),
+    sqlc.arg('name'),
+    COALESCE((SELECT MAX(folders.position) FROM folders WHERE folders.user_id = sqlc.arg('user_id')), 0) + 1,
+    sqlc.arg('created_at'),
+    sqlc.arg('updated_at')
+)
+RETURNING *;
+
+-- name: GetFolders :many
+SELECT
+    sqlc.embed(folders),
+    ARRAY(
+        SELECT feed_follow_folders.feed_follow_id FROM feed_follow_folders
+        WHERE feed_follow_folders.folder_id = folders.id
+        ORDER BY feed_follow_folders.feed_follow_id
+    )::uuid[] AS feed_follow_ids,
+    (
+        SELECT COUNT(*) FROM posts
+        WHERE posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+            WHERE feed_follow_folders.folder_id = folders.id
+        )
+        AND NOT EXISTS (
+            SELECT 1 FROM post_reads
+            WHERE post_reads.user_id = folders.user_id AND post_reads.post_id = posts.id
+        )
+    )::bigint AS unread_count
+FROM
+    folders
+WHERE
+    folders.user_id = $1
+ORDER BY
+    folders.position, folders.name;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: SetFolderPositions :exec
+UPDATE folders
+SET position = new_positions.position
+FROM unnest(sqlc.arg('folder_ids')::uuid[]) WITH ORDINALITY AS new_positions(folder_id, position)
+WHERE folders.user_id = sqlc.arg('user_id') AND folders.id = new_positions.folder_id;
+
+-- name: AddFeedFollowToFolder :execrows
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id
+FROM feed_follows, folders
+WHERE feed_follows.id = sqlc.arg('feed_follow_id') AND feed_follows.user_id = sqlc.arg('user_id')
+    AND folders.id = sqlc.arg('folder_id') AND folders.user_id = sqlc.arg('user_id')
+ON CONFLICT (feed_follow_id, folder_id) DO UPDATE SET folder_id = feed_follow_folders.folder_id;
+
+-- name: RemoveFeedFollowFromFolder :execrows
+DELETE FROM feed_follow_folders
+USING folders
+WHERE feed_follow_folders.folder_id = folders.id
+    AND feed_follow_folders.feed_follow_id = sqlc.arg('feed_follow_id')
+    AND folders.id = sqlc.arg('folder_id') AND folders.user_id = sqlc.arg('user_id');
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetFolder :one
+SELECT * FROM folders
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,23 @@
+-- name: ClaimNextFullContentPost :one
+-- marks the oldest post waiting for its full content as fetching, safe to call from several workers
+UPDATE posts
+SET full_content_status = 'fetching'
+WHERE posts.id = (
+    SELECT id FROM posts
+    WHERE full_content_status = 'pending'
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetPostFullContent :exec
+UPDATE posts
+SET full_content = $2, full_content_status = $3
+WHERE id = $1;
+
+-- name: RequeueFetchingFullContent :execrows
+-- posts that were being fetched when the server stopped are fetched again
+UPDATE posts
+SET full_content_status = 'pending'
+WHERE full_content_status = 'fetching';
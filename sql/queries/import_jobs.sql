@@ -0,0 +1,37 @@
+-- name: CreateImportJob :one
+INSERT INTO import_jobs (id, user_id, kind, input, total, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetImportJob :one
+SELECT * FROM import_jobs
+WHERE id = $1 AND user_id = $2;
+
+-- name: ClaimNextImportJob :one
+-- marks the oldest pending job as running, safe to call from several workers
+UPDATE import_jobs
+SET status = 'running', updated_at = $1
+WHERE id = (
+    SELECT id FROM import_jobs
+    WHERE status = 'pending'
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateImportJobProgress :exec
+UPDATE import_jobs
+SET processed = $2, updated_at = $3
+WHERE id = $1;
+
+-- name: FinishImportJob :exec
+UPDATE import_jobs
+SET status = $2, processed = $3, report = $4, error = $5, updated_at = $6, finished_at = $6
+WHERE id = $1;
+
+-- name: RequeueRunningImportJobs :execrows
+-- jobs that were running when the server stopped start over, importing an entry twice is harmless
+UPDATE import_jobs
+SET status = 'pending', processed = 0, updated_at = $1
+WHERE status = 'running';
@@ -0,0 +1,13 @@
+-- name: GetOPMLOutlines :many
+-- a follow in several folders shows up once in each of them, push feeds have no url to subscribe to
+SELECT
+    COALESCE(feed_follows.title, feeds.name)::text AS title,
+    feeds.url,
+    feeds.site_url,
+    COALESCE(folders.name, '')::text AS folder
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1 AND feeds.kind <> 'push'
+ORDER BY folders.position NULLS FIRST, feed_follows.pinned DESC, lower(COALESCE(feed_follows.title, feeds.name)), feeds.url;
@@ -0,0 +1,50 @@
+-- name: MarkPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg('user_id'), posts.id, sqlc.arg('read_at')
+FROM posts
+WHERE posts.id = ANY(sqlc.arg('post_ids')::uuid[])
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id'))
+        OR EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = sqlc.arg('user_id') AND post_stars.post_id = posts.id)
+        OR EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET read_at = post_reads.read_at;
+
+-- name: MarkPostsUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = sqlc.arg('user_id') AND post_id = ANY(sqlc.arg('post_ids')::uuid[]);
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg('user_id'), posts.id, sqlc.arg('read_at')
+FROM posts
+WHERE posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id'))
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+    AND (sqlc.narg('before')::timestamp IS NULL OR posts.published_at < sqlc.narg('before'))
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetUnreadCountsByFeed :many
+SELECT
+    feed_follows.id AS feed_follow_id,
+    feed_follows.feed_id,
+    COUNT(posts.id) AS unread_count
+FROM
+    feed_follows
+LEFT JOIN
+    posts ON posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    )
+WHERE
+    feed_follows.user_id = $1
+GROUP BY
+    feed_follows.id, feed_follows.feed_id
+ORDER BY
+    feed_follows.id;
+
+-- name: ImportPostRead :exec
+-- marks a post the user read in another reader as read
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
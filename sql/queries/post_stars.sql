@@ -0,0 +1,20 @@
+-- name: StarPost :execrows
+INSERT INTO post_stars (user_id, post_id, starred_at)
+SELECT sqlc.arg('user_id'), posts.id, sqlc.arg('starred_at')
+FROM posts
+WHERE posts.id = sqlc.arg('post_id')
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id'))
+        OR EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET starred_at = post_stars.starred_at;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: ImportPostStar :exec
+-- stars a post the user starred in another reader, even if they don't follow its feed
+INSERT INTO post_stars (user_id, post_id, starred_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
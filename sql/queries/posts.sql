@@ -1,21 +1,152 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+-- the full content of the post is fetched later if its feed asks for it
+-- the posts of push feeds are pushed, their url only has to be unique in the feed
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, description_text, sanitized, full_content_status, pushed)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, true,
+    CASE WHEN (SELECT feeds.fetch_full_content FROM feeds WHERE feeds.id = $8) THEN 'pending' ELSE '' END,
+    (SELECT feeds.kind = 'push' FROM feeds WHERE feeds.id = $8))
 RETURNING *;
 
 -- name: GetPostsByUser :many
 SELECT
-    posts.*
+    sqlc.embed(posts),
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ARRAY(
+        SELECT DISTINCT unnest(filter_rules.tags) FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id')
+        ORDER BY 1
+    )::text[] AS tags
 FROM
     posts
-JOIN
-    feeds ON feeds.id = posts.feed_id
-JOIN
-    feed_follows ON feed_follows.feed_id = feeds.id
-JOIN
-    users ON users.id = feed_follows.user_id
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
 WHERE
-    users.api_key = $1
+    -- starred posts stay in the timeline after their feed is unfollowed
+    -- follows hidden from the timeline only show up when filtering by feed, folder or saved search
+    (
+        posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = sqlc.arg('user_id')
+                AND (NOT feed_follows.hide_from_timeline
+                    OR sqlc.narg('feed_id')::uuid IS NOT NULL
+                    OR sqlc.narg('folder_id')::uuid IS NOT NULL
+                    OR sqlc.narg('search_id')::uuid IS NOT NULL)
+        )
+        OR post_stars.post_id IS NOT NULL
+    )
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+    AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+    AND (sqlc.narg('author')::text IS NULL OR lower(posts.author) = lower(sqlc.narg('author')))
+    AND (sqlc.narg('category')::text IS NULL OR posts.categories @> ARRAY[sqlc.narg('category')::text])
+    AND (sqlc.narg('unread')::boolean IS NULL OR sqlc.narg('unread') = (post_reads.post_id IS NULL))
+    AND (sqlc.narg('starred')::boolean IS NULL OR sqlc.narg('starred') = (post_stars.post_id IS NOT NULL))
+    AND (sqlc.narg('folder_id')::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follow_folders.folder_id = sqlc.narg('folder_id') AND feed_follows.user_id = sqlc.arg('user_id')
+    ))
+    AND (sqlc.narg('search_id')::uuid IS NULL OR posts.id IN (
+        SELECT saved_search_matches.post_id FROM saved_search_matches
+        WHERE saved_search_matches.search_id = sqlc.narg('search_id')
+    ))
+    -- hidden by one of the user's filter rules
+    AND (sqlc.narg('hidden')::boolean IS NULL OR sqlc.narg('hidden') = EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id') AND filter_rules.hide
+    ))
+    AND (sqlc.narg('before_published_at')::timestamp IS NULL
+        OR (posts.published_at, posts.id) < (sqlc.narg('before_published_at'), sqlc.narg('before_id')::uuid))
 ORDER BY
-    posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+    posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetPostsByUserAscending :many
+SELECT
+    sqlc.embed(posts),
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ARRAY(
+        SELECT DISTINCT unnest(filter_rules.tags) FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id')
+        ORDER BY 1
+    )::text[] AS tags
+FROM
+    posts
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
+WHERE
+    -- starred posts stay in the timeline after their feed is unfollowed
+    -- follows hidden from the timeline only show up when filtering by feed, folder or saved search
+    (
+        posts.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = sqlc.arg('user_id')
+                AND (NOT feed_follows.hide_from_timeline
+                    OR sqlc.narg('feed_id')::uuid IS NOT NULL
+                    OR sqlc.narg('folder_id')::uuid IS NOT NULL
+                    OR sqlc.narg('search_id')::uuid IS NOT NULL)
+        )
+        OR post_stars.post_id IS NOT NULL
+    )
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+    AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+    AND (sqlc.narg('author')::text IS NULL OR lower(posts.author) = lower(sqlc.narg('author')))
+    AND (sqlc.narg('category')::text IS NULL OR posts.categories @> ARRAY[sqlc.narg('category')::text])
+    AND (sqlc.narg('unread')::boolean IS NULL OR sqlc.narg('unread') = (post_reads.post_id IS NULL))
+    AND (sqlc.narg('starred')::boolean IS NULL OR sqlc.narg('starred') = (post_stars.post_id IS NOT NULL))
+    AND (sqlc.narg('folder_id')::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follow_folders.folder_id = sqlc.narg('folder_id') AND feed_follows.user_id = sqlc.arg('user_id')
+    ))
+    AND (sqlc.narg('search_id')::uuid IS NULL OR posts.id IN (
+        SELECT saved_search_matches.post_id FROM saved_search_matches
+        WHERE saved_search_matches.search_id = sqlc.narg('search_id')
+    ))
+    -- hidden by one of the user's filter rules
+    AND (sqlc.narg('hidden')::boolean IS NULL OR sqlc.narg('hidden') = EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id') AND filter_rules.hide
+    ))
+    AND (sqlc.narg('after_published_at')::timestamp IS NULL
+        OR (posts.published_at, posts.id) > (sqlc.narg('after_published_at'), sqlc.narg('after_id')::uuid))
+ORDER BY
+    posts.published_at ASC, posts.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetPostByURL :one
+-- pushed posts aren't looked up by url, their urls aren't unique
+SELECT * FROM posts
+WHERE url = $1 AND NOT pushed;
+
+-- name: GetOrCreatePost :one
+-- posts that aren't pushed are unique by url, an existing post is returned as it is
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories, content, description_text, sanitized)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, true)
+ON CONFLICT (url) WHERE NOT pushed DO UPDATE SET url = posts.url
+RETURNING *;
+
+-- name: GetUnsanitizedPosts :many
+-- posts from before descriptions were sanitized
+SELECT * FROM posts
+WHERE NOT sanitized
+ORDER BY id
+LIMIT $1;
+
+-- name: SetPostSanitized :exec
+UPDATE posts
+SET description = $2, content = $3, description_text = $4, sanitized = true
+WHERE id = $1;
@@ -0,0 +1,40 @@
+-- name: SavePost :execrows
+INSERT INTO saved_posts (user_id, post_id, position, saved_at)
+SELECT
+    sqlc.arg('user_id'),
+    posts.id,
+    COALESCE((SELECT MAX(saved_posts.position) FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id')), 0) + 1,
+    sqlc.arg('saved_at')
+FROM posts
+WHERE posts.id = sqlc.arg('post_id')
+    AND (
+        posts.feed_id IN (SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id'))
+        OR EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = sqlc.arg('user_id') AND post_stars.post_id = posts.id)
+    )
+ON CONFLICT (user_id, post_id) DO UPDATE SET position = saved_posts.position;
+
+-- name: UnsavePost :exec
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetSavedPosts :many
+SELECT
+    sqlc.embed(posts),
+    saved_posts.position,
+    saved_posts.saved_at,
+    EXISTS (SELECT 1 FROM post_reads WHERE post_reads.user_id = saved_posts.user_id AND post_reads.post_id = posts.id)::boolean AS read,
+    EXISTS (SELECT 1 FROM post_stars WHERE post_stars.user_id = saved_posts.user_id AND post_stars.post_id = posts.id)::boolean AS starred
+FROM
+    saved_posts
+JOIN
+    posts ON posts.id = saved_posts.post_id
+WHERE
+    saved_posts.user_id = $1
+ORDER BY
+    saved_posts.position;
+
+-- name: SetSavedPostPositions :exec
+UPDATE saved_posts
+SET position = new_positions.position
+FROM unnest(sqlc.arg('post_ids')::uuid[]) WITH ORDINALITY AS new_positions(post_id, position)
+WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = new_positions.post_id;
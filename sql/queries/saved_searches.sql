@@ -0,0 +1,59 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, user_id, name, query, match, author, feed_id, notify, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING *;
+
+-- name: GetSavedSearches :many
+SELECT
+    sqlc.embed(saved_searches),
+    (
+        SELECT COUNT(*) FROM saved_search_matches
+        WHERE saved_search_matches.search_id = saved_searches.id
+            AND NOT EXISTS (
+                SELECT 1 FROM post_reads
+                WHERE post_reads.user_id = saved_searches.user_id AND post_reads.post_id = saved_search_matches.post_id
+            )
+    )::bigint AS unread_count
+FROM saved_searches
+WHERE saved_searches.user_id = $1
+ORDER BY saved_searches.name;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = $3, query = $4, match = $5, author = $6, feed_id = $7, notify = $8, updated_at = $9
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetSavedSearchesForFeed :many
+-- the searches of the feed's followers that look at its posts
+SELECT saved_searches.* FROM saved_searches
+JOIN feed_follows ON feed_follows.user_id = saved_searches.user_id AND feed_follows.feed_id = sqlc.arg('feed_id')
+WHERE saved_searches.feed_id IS NULL OR saved_searches.feed_id = sqlc.arg('feed_id');
+
+-- name: GetPostsToSearch :many
+-- the newest posts of the feeds the user follows, or of one of them, for matching a search that was just saved
+SELECT posts.* FROM posts
+WHERE posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg('user_id')
+)
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id'))
+ORDER BY posts.published_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: AddSavedSearchMatches :exec
+INSERT INTO saved_search_matches (search_id, post_id, created_at)
+SELECT sqlc.arg('search_id'), unnest(sqlc.arg('post_ids')::uuid[]), sqlc.arg('created_at')
+ON CONFLICT (search_id, post_id) DO NOTHING;
+
+-- name: DeleteSavedSearchMatches :exec
+DELETE FROM saved_search_matches
+WHERE search_id = $1;
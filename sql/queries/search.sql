@@ -0,0 +1,38 @@
+-- name: SearchPosts :many
+-- ranked full-text search of the posts in the feeds the user follows, or of every post with all_feeds
+-- posts hidden by the user's filter rules are left out
+-- headlines have their matches between start_sel and stop_sel, snippet is from the description and content without their html tags
+WITH search AS (
+    SELECT to_tsquery('english', sqlc.arg('query')) AS query
+)
+SELECT
+    sqlc.embed(posts),
+    (post_reads.post_id IS NOT NULL)::boolean AS read,
+    (post_stars.post_id IS NOT NULL)::boolean AS starred,
+    EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.user_id = sqlc.arg('user_id') AND saved_posts.post_id = posts.id)::boolean AS saved,
+    ts_rank_cd(posts.search_vector, search.query)::real AS rank,
+    ts_headline('english', posts.title, search.query,
+        'HighlightAll=true, StartSel=' || sqlc.arg('start_sel')::text || ', StopSel=' || sqlc.arg('stop_sel')::text)::text AS title_headline,
+    ts_headline('english', regexp_replace(posts.description || ' ' || posts.content, '<[^>]*>', ' ', 'g'), search.query,
+        'MaxFragments=2, MinWords=8, MaxWords=24, StartSel=' || sqlc.arg('start_sel')::text || ', StopSel=' || sqlc.arg('stop_sel')::text)::text AS snippet
+FROM
+    posts
+CROSS JOIN
+    search
+LEFT JOIN
+    post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = sqlc.arg('user_id')
+LEFT JOIN
+    post_stars ON post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg('user_id')
+WHERE
+    posts.search_vector @@ search.query
+    AND (sqlc.arg('all_feeds')::boolean OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows WHERE feed_follows.user_id = sqlc.arg('user_id')
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = posts.id AND filter_rules.user_id = sqlc.arg('user_id') AND filter_rules.hide
+    )
+ORDER BY
+    rank DESC, posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
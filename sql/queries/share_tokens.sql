@@ -0,0 +1,24 @@
+-- name: UpsertShareToken :one
+-- a user has one token of each kind, and one for each folder, making a new one replaces the old one
+INSERT INTO share_tokens (id, user_id, kind, token, created_at, folder_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, kind, (COALESCE(folder_id, '00000000-0000-0000-0000-000000000000'))) DO UPDATE
+SET id = EXCLUDED.id, token = EXCLUDED.token, created_at = EXCLUDED.created_at
+RETURNING *;
+
+-- name: GetShareToken :one
+SELECT * FROM share_tokens
+WHERE user_id = $1 AND kind = $2 AND folder_id IS NOT DISTINCT FROM sqlc.narg('folder_id');
+
+-- name: GetShareTokenByToken :one
+SELECT * FROM share_tokens
+WHERE token = $1 AND kind = $2;
+
+-- name: GetUserByShareToken :one
+SELECT users.* FROM users
+JOIN share_tokens ON share_tokens.user_id = users.id
+WHERE share_tokens.token = $1 AND share_tokens.kind = $2;
+
+-- name: DeleteShareToken :execrows
+DELETE FROM share_tokens
+WHERE user_id = $1 AND kind = $2 AND folder_id IS NOT DISTINCT FROM sqlc.narg('folder_id');
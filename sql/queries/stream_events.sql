@@ -0,0 +1,32 @@
+-- name: CreateStreamEvent :one
+INSERT INTO stream_events (kind, user_id, feed_id, post_id, data, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetLastStreamEventID :one
+-- where a new stream starts, 0 if there are no events
+SELECT COALESCE(MAX(id), 0)::bigint FROM stream_events;
+
+-- name: GetStreamEventsForUser :many
+-- the user's events after an id, and those of the feeds they follow and haven't muted with notify 'none'
+-- events about a post the user's filter rules hide are left out
+SELECT * FROM stream_events
+WHERE stream_events.id > sqlc.arg('after_id')
+    AND (
+        stream_events.user_id = sqlc.arg('user_id')
+        OR stream_events.feed_id IN (
+            SELECT feed_follows.feed_id FROM feed_follows
+            WHERE feed_follows.user_id = sqlc.arg('user_id') AND feed_follows.notify <> 'none'
+        )
+    )
+    AND (stream_events.post_id IS NULL OR NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = stream_events.post_id AND filter_rules.user_id = sqlc.arg('user_id') AND filter_rules.hide
+    ))
+ORDER BY stream_events.id
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteStreamEventsBefore :execrows
+DELETE FROM stream_events
+WHERE created_at < $1;
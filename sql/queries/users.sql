@@ -5,4 +5,8 @@ RETURNING *;
 
 -- name: GetUser :one
 SELECT * FROM users 
-WHERE api_key = $1;
\ No newline at end of file
+WHERE api_key = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
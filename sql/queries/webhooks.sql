@@ -0,0 +1,78 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, user_id, url, secret, feed_id, folder_id, keyword, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetWebhooks :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks
+WHERE id = $1;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhooksForPost :many
+-- the webhooks of the feed's followers that take its posts, the keyword is left to the caller
+-- followers whose filter rules hide the post get no delivery
+SELECT webhooks.* FROM webhooks
+JOIN feed_follows ON feed_follows.user_id = webhooks.user_id AND feed_follows.feed_id = sqlc.arg('feed_id')
+WHERE (webhooks.feed_id IS NULL OR webhooks.feed_id = sqlc.arg('feed_id'))
+    AND (webhooks.folder_id IS NULL OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.folder_id = webhooks.folder_id AND feed_follow_folders.feed_follow_id = feed_follows.id
+    ))
+    AND NOT EXISTS (
+        SELECT 1 FROM filter_rule_matches
+        JOIN filter_rules ON filter_rules.id = filter_rule_matches.rule_id
+        WHERE filter_rule_matches.post_id = sqlc.arg('post_id') AND filter_rules.user_id = webhooks.user_id AND filter_rules.hide
+    );
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, webhook_id, post_id, payload, next_attempt_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5, $5)
+RETURNING *;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2;
+
+-- name: ClaimDueWebhookDeliveries :many
+-- marks the pending deliveries that are due as sending, safe to call from several workers
+UPDATE webhook_deliveries
+SET status = 'sending', updated_at = sqlc.arg('now')
+WHERE id IN (
+    SELECT webhook_deliveries.id FROM webhook_deliveries
+    WHERE webhook_deliveries.status = 'pending' AND webhook_deliveries.next_attempt_at <= sqlc.arg('now')
+    ORDER BY webhook_deliveries.next_attempt_at
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishWebhookDeliveryAttempt :one
+UPDATE webhook_deliveries
+SET status = $2, attempts = attempts + 1, next_attempt_at = $3, response_status = $4, error = $5,
+    updated_at = $6, delivered_at = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: RequeueSendingWebhookDeliveries :execrows
+-- deliveries that were being sent when the server stopped are sent again
+UPDATE webhook_deliveries
+SET status = 'pending', updated_at = $1
+WHERE status = 'sending';
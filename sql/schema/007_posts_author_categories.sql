@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE posts
+ADD author TEXT NOT NULL DEFAULT '',
+ADD categories TEXT[] NOT NULL DEFAULT '{}';
+
+-- keyset pagination of the timeline on (published_at, id) per followed feed
+CREATE INDEX posts_feed_id_published_at_id_idx ON posts (feed_id, published_at DESC, id DESC);
+CREATE INDEX posts_categories_idx ON posts USING GIN (categories);
+CREATE INDEX feed_follows_user_id_idx ON feed_follows (user_id);
+
+-- +goose Down
+DROP INDEX feed_follows_user_id_idx;
+DROP INDEX posts_categories_idx;
+DROP INDEX posts_feed_id_published_at_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN categories,
+DROP COLUMN author;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- starred and saved posts are kept when their feed is unfollowed,
+-- RESTRICT makes sure nothing that prunes posts can delete them
+CREATE TABLE post_stars (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE RESTRICT,
+  starred_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, post_id)
+);
+
+-- the read later queue, ordered by position
+CREATE TABLE saved_posts (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE RESTRICT,
+  position INTEGER NOT NULL,
+  saved_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX saved_posts_user_id_position_idx ON saved_posts (user_id, position);
+
+-- +goose Down
+DROP TABLE saved_posts;
+DROP TABLE post_stars;
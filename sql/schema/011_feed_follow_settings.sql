@@ -0,0 +1,14 @@
+-- +goose Up
+-- per user overrides of a followed feed, title is null to use the feed's name
+ALTER TABLE feed_follows
+  ADD COLUMN title TEXT,
+  ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT false,
+  ADD COLUMN notify TEXT NOT NULL DEFAULT 'all' CHECK (notify IN ('all', 'none')),
+  ADD COLUMN hide_from_timeline BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE feed_follows
+  DROP COLUMN hide_from_timeline,
+  DROP COLUMN notify,
+  DROP COLUMN pinned,
+  DROP COLUMN title;
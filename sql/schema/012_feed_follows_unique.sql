@@ -0,0 +1,28 @@
+-- +goose Up
+-- keep the oldest follow of each feed per user, the folders of the others move onto it
+CREATE TEMPORARY TABLE feed_follow_duplicates AS
+SELECT
+  id,
+  first_value(id) OVER (PARTITION BY user_id, feed_id ORDER BY created_at, id) AS kept_id
+FROM feed_follows;
+
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follow_duplicates.kept_id, feed_follow_folders.folder_id
+FROM feed_follow_folders
+JOIN feed_follow_duplicates ON feed_follow_duplicates.id = feed_follow_folders.feed_follow_id
+WHERE feed_follow_duplicates.id <> feed_follow_duplicates.kept_id
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
+
+DELETE FROM feed_follows
+USING feed_follow_duplicates
+WHERE feed_follows.id = feed_follow_duplicates.id AND feed_follow_duplicates.id <> feed_follow_duplicates.kept_id;
+
+DROP TABLE feed_follow_duplicates;
+
+ALTER TABLE feed_follows ADD CONSTRAINT feed_follows_user_id_feed_id_key UNIQUE (user_id, feed_id);
+-- the unique index starts with user_id and covers lookups by user
+DROP INDEX feed_follows_user_id_idx;
+
+-- +goose Down
+CREATE INDEX feed_follows_user_id_idx ON feed_follows (user_id);
+ALTER TABLE feed_follows DROP CONSTRAINT feed_follows_user_id_feed_id_key;
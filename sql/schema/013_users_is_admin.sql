@@ -0,0 +1,6 @@
+-- +goose Up
+-- admins can edit and delete feeds created by other users
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN is_admin;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- imports too big to run during the request, picked up by the import worker
+CREATE TABLE import_jobs (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+  -- what to import, written when the job is created
+  input JSONB NOT NULL,
+  total INTEGER NOT NULL,
+  processed INTEGER NOT NULL DEFAULT 0,
+  -- the outcome of each entry, null until the job is done
+  report JSONB NOT NULL DEFAULT 'null',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  finished_at TIMESTAMP
+);
+
+CREATE INDEX import_jobs_status_created_at_idx ON import_jobs (status, created_at);
+
+-- +goose Down
+DROP TABLE import_jobs;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- the feed's website, the link in the fetched feed
+ALTER TABLE feeds ADD COLUMN site_url TEXT NOT NULL DEFAULT '';
+
+-- unguessable tokens that make something of a user's readable without their api key
+CREATE TABLE share_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  token TEXT NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, kind)
+);
+
+-- +goose Down
+DROP TABLE share_tokens;
+ALTER TABLE feeds DROP COLUMN site_url;
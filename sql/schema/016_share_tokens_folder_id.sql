@@ -0,0 +1,13 @@
+-- +goose Up
+-- tokens can make one of the user's folders readable instead of everything of the kind
+ALTER TABLE share_tokens ADD COLUMN folder_id UUID REFERENCES folders(id) ON DELETE CASCADE;
+ALTER TABLE share_tokens DROP CONSTRAINT share_tokens_user_id_kind_key;
+-- one token of each kind for the user and one for each of their folders
+CREATE UNIQUE INDEX share_tokens_user_id_kind_folder_id_idx
+ON share_tokens (user_id, kind, (COALESCE(folder_id, '00000000-0000-0000-0000-000000000000')));
+
+-- +goose Down
+DROP INDEX share_tokens_user_id_kind_folder_id_idx;
+DELETE FROM share_tokens WHERE folder_id IS NOT NULL;
+ALTER TABLE share_tokens ADD CONSTRAINT share_tokens_user_id_kind_key UNIQUE (user_id, kind);
+ALTER TABLE share_tokens DROP COLUMN folder_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- events pushed to GET /v1/stream, kept for a while so streams can resume from the last id they saw
+CREATE TABLE stream_events (
+  id BIGSERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,
+  -- set for events about one user
+  user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+  -- set for events about everyone following a feed
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  data JSONB NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX stream_events_created_at_idx ON stream_events (created_at);
+
+-- +goose Down
+DROP TABLE stream_events;
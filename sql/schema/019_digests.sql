@@ -0,0 +1,47 @@
+-- +goose Up
+-- a user's opt in to email digests of their unread posts, sent at send_time in their timezone
+CREATE TABLE digest_subscriptions (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  frequency TEXT NOT NULL CHECK (frequency IN ('daily', 'weekly')),
+  -- HH:MM local time
+  send_time TEXT NOT NULL,
+  timezone TEXT NOT NULL,
+  -- day of a weekly digest, 0 is sunday
+  weekday INTEGER NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 0 AND 6),
+  -- the unsubscribe link works without an api key
+  unsubscribe_token TEXT NOT NULL UNIQUE,
+  -- where the server was reached when subscribing, the links in the emails start with it
+  base_url TEXT NOT NULL,
+  next_send_at TIMESTAMP NOT NULL,
+  last_sent_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX digest_subscriptions_next_send_at_idx ON digest_subscriptions (next_send_at);
+
+-- the digests sent, and the posts in each so no post is sent twice
+CREATE TABLE digests (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  frequency TEXT NOT NULL,
+  sent_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX digests_user_id_sent_at_idx ON digests (user_id, sent_at);
+
+CREATE TABLE digest_posts (
+  digest_id UUID NOT NULL REFERENCES digests(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX digest_posts_digest_id_idx ON digest_posts (digest_id);
+
+-- +goose Down
+DROP TABLE digest_posts;
+DROP TABLE digests;
+DROP TABLE digest_subscriptions;
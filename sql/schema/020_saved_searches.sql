@@ -0,0 +1,33 @@
+-- +goose Up
+-- keywords and phrases watched across the feeds a user follows, shown as virtual feeds in the timeline
+CREATE TABLE saved_searches (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- keywords and "quoted phrases"
+  query TEXT NOT NULL,
+  -- whether a post needs any or all of the terms in query
+  match TEXT NOT NULL DEFAULT 'any' CHECK (match IN ('any', 'all')),
+  -- empty for any author
+  author TEXT NOT NULL DEFAULT '',
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  -- publish a stream event when a new post matches
+  notify BOOLEAN NOT NULL DEFAULT false,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, name)
+);
+
+-- the posts each search matched, filled in as posts are created and when a search is saved
+CREATE TABLE saved_search_matches (
+  search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (search_id, post_id)
+);
+
+CREATE INDEX saved_search_matches_post_id_idx ON saved_search_matches (post_id);
+
+-- +goose Down
+DROP TABLE saved_search_matches;
+DROP TABLE saved_searches;
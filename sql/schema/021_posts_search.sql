@@ -0,0 +1,19 @@
+-- +goose Up
+-- the full content of the post when the feed has one, description is often just a summary
+ALTER TABLE posts ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- full-text search of the posts, the title weighs the most and the content the least
+ALTER TABLE posts ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') ||
+  setweight(to_tsvector('english', description), 'B') ||
+  setweight(to_tsvector('english', content), 'C')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+
+ALTER TABLE posts
+DROP COLUMN search_vector,
+DROP COLUMN content;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- rules a user sets up to hide, tag or star the posts of the feeds they follow
+CREATE TABLE filter_rules (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  -- only the posts of this feed, null for every followed feed
+  feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+  -- [{"field": "title", "op": "matches", "value": "/sponsored/i"}], a post has to match all of them
+  conditions JSONB NOT NULL DEFAULT '[]',
+  -- what happens to the matching posts
+  hide BOOLEAN NOT NULL DEFAULT false,
+  star BOOLEAN NOT NULL DEFAULT false,
+  tags TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, name)
+);
+
+-- the posts each rule matched, filled in as posts are created and when a rule is saved
+CREATE TABLE filter_rule_matches (
+  rule_id UUID NOT NULL REFERENCES filter_rules(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (rule_id, post_id)
+);
+
+CREATE INDEX filter_rule_matches_post_id_idx ON filter_rule_matches (post_id);
+
+-- +goose Down
+DROP TABLE filter_rule_matches;
+DROP TABLE filter_rules;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- description and content are sanitized html from now on, description_text is the description as plain text
+-- posts from before are sanitized in the background by the server, until then sanitized is false
+ALTER TABLE posts
+ADD COLUMN description_text TEXT NOT NULL DEFAULT '',
+ADD COLUMN sanitized BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX posts_not_sanitized_idx ON posts (id) WHERE NOT sanitized;
+
+-- +goose Down
+DROP INDEX posts_not_sanitized_idx;
+
+ALTER TABLE posts
+DROP COLUMN sanitized,
+DROP COLUMN description_text;
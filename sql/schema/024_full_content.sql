@@ -0,0 +1,26 @@
+-- +goose Up
+-- feeds that only publish a teaser can have the page of each new post downloaded and its article extracted
+ALTER TABLE feeds
+ADD COLUMN fetch_full_content BOOLEAN NOT NULL DEFAULT false,
+-- css selector for the article, empty to find it automatically
+ADD COLUMN content_selector TEXT NOT NULL DEFAULT '';
+
+-- '' when the feed doesn't fetch full content, otherwise pending until the worker gets to it, then fetching
+-- and done or failed
+ALTER TABLE posts
+ADD COLUMN full_content TEXT NOT NULL DEFAULT '',
+ADD COLUMN full_content_status TEXT NOT NULL DEFAULT ''
+  CHECK (full_content_status IN ('', 'pending', 'fetching', 'done', 'failed'));
+
+CREATE INDEX posts_full_content_pending_idx ON posts (created_at) WHERE full_content_status = 'pending';
+
+-- +goose Down
+DROP INDEX posts_full_content_pending_idx;
+
+ALTER TABLE posts
+DROP COLUMN full_content_status,
+DROP COLUMN full_content;
+
+ALTER TABLE feeds
+DROP COLUMN content_selector,
+DROP COLUMN fetch_full_content;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- rss feeds are parsed as rss, atom or json feed, scraped feeds are web pages turned into items with css selectors
+ALTER TABLE feeds
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'rss' CHECK (kind IN ('rss', 'scraped')),
+-- {"item": "...", "title": "...", "link": "...", "date": "...", "summary": "..."} for scraped feeds
+ADD COLUMN selectors JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN selectors,
+DROP COLUMN kind;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- push feeds have no upstream, items are pushed into them through the api with the feed's write token
+-- their url is urn:uuid:<id> so it stays unique, and they're never fetched
+ALTER TABLE feeds DROP CONSTRAINT feeds_kind_check;
+ALTER TABLE feeds ADD CONSTRAINT feeds_kind_check CHECK (kind IN ('rss', 'scraped', 'push'));
+ALTER TABLE feeds ADD COLUMN write_token TEXT UNIQUE;
+
+-- +goose Down
+DELETE FROM feeds WHERE kind = 'push';
+ALTER TABLE feeds DROP COLUMN write_token;
+ALTER TABLE feeds DROP CONSTRAINT feeds_kind_check;
+ALTER TABLE feeds ADD CONSTRAINT feeds_kind_check CHECK (kind IN ('rss', 'scraped'));
@@ -0,0 +1,38 @@
+-- +goose Up
+-- digests are recorded before they're emailed and sent from the record, retried until they're sent or out of attempts
+-- the bodies are kept until then, the digests from before were sent on their first attempt
+ALTER TABLE digests
+  ADD COLUMN status TEXT NOT NULL DEFAULT 'sent' CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+  ADD COLUMN attempts INTEGER NOT NULL DEFAULT 1,
+  ADD COLUMN next_attempt_at TIMESTAMP,
+  ADD COLUMN error TEXT NOT NULL DEFAULT '',
+  ADD COLUMN subject TEXT NOT NULL DEFAULT '',
+  ADD COLUMN text_body TEXT NOT NULL DEFAULT '',
+  ADD COLUMN html_body TEXT NOT NULL DEFAULT '',
+  ADD COLUMN created_at TIMESTAMP;
+UPDATE digests SET created_at = sent_at;
+ALTER TABLE digests
+  ALTER COLUMN status SET DEFAULT 'pending',
+  ALTER COLUMN attempts SET DEFAULT 0,
+  ALTER COLUMN created_at SET NOT NULL,
+  ALTER COLUMN sent_at DROP NOT NULL;
+
+DROP INDEX digests_user_id_sent_at_idx;
+CREATE INDEX digests_user_id_created_at_idx ON digests (user_id, created_at);
+CREATE INDEX digests_status_next_attempt_at_idx ON digests (status, next_attempt_at);
+
+-- +goose Down
+DELETE FROM digests WHERE sent_at IS NULL;
+DROP INDEX digests_status_next_attempt_at_idx;
+DROP INDEX digests_user_id_created_at_idx;
+CREATE INDEX digests_user_id_sent_at_idx ON digests (user_id, sent_at);
+ALTER TABLE digests
+  ALTER COLUMN sent_at SET NOT NULL,
+  DROP COLUMN created_at,
+  DROP COLUMN html_body,
+  DROP COLUMN text_body,
+  DROP COLUMN subject,
+  DROP COLUMN error,
+  DROP COLUMN next_attempt_at,
+  DROP COLUMN attempts,
+  DROP COLUMN status;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- the post an event is about, streams leave out the posts the user's filter rules hide
+ALTER TABLE stream_events ADD COLUMN post_id UUID REFERENCES posts(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE stream_events DROP COLUMN post_id;
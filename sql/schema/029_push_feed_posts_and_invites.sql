@@ -0,0 +1,35 @@
+-- +goose Up
+-- the urls of pushed posts are only unique within their push feed, so a push can't take the url
+-- of a post that another feed will have
+ALTER TABLE posts ADD COLUMN pushed BOOLEAN NOT NULL DEFAULT false;
+UPDATE posts SET pushed = true FROM feeds WHERE feeds.id = posts.feed_id AND feeds.kind = 'push';
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+CREATE UNIQUE INDEX posts_url_key ON posts (url) WHERE NOT pushed;
+CREATE UNIQUE INDEX posts_feed_id_url_key ON posts (feed_id, url) WHERE pushed;
+
+-- push feeds are followed with the invite token, by others than who created them
+ALTER TABLE feeds ADD COLUMN invite_token TEXT UNIQUE;
+UPDATE feeds SET invite_token = encode(sha256((random()::text || id::text)::bytea), 'hex') WHERE kind = 'push';
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN invite_token;
+-- the pushed posts whose url is taken by an older post, stars and saves restrict deleting them
+DELETE FROM post_stars USING posts
+WHERE posts.id = post_stars.post_id AND posts.pushed AND EXISTS (
+  SELECT 1 FROM posts AS other
+  WHERE other.url = posts.url AND other.id <> posts.id AND (NOT other.pushed OR other.created_at < posts.created_at)
+);
+DELETE FROM saved_posts USING posts
+WHERE posts.id = saved_posts.post_id AND posts.pushed AND EXISTS (
+  SELECT 1 FROM posts AS other
+  WHERE other.url = posts.url AND other.id <> posts.id AND (NOT other.pushed OR other.created_at < posts.created_at)
+);
+DELETE FROM posts
+WHERE pushed AND EXISTS (
+  SELECT 1 FROM posts AS other
+  WHERE other.url = posts.url AND other.id <> posts.id AND (NOT other.pushed OR other.created_at < posts.created_at)
+);
+DROP INDEX posts_feed_id_url_key;
+DROP INDEX posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts DROP COLUMN pushed;
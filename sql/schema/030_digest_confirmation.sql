@@ -0,0 +1,12 @@
+-- +goose Up
+-- digests only go to an address once the link in the confirmation email sent to it is opened
+-- confirm_token is that link's, null once confirmed, the subscriptions from before count as confirmed
+ALTER TABLE digest_subscriptions
+  ADD COLUMN confirm_token TEXT UNIQUE,
+  ADD COLUMN confirmed_at TIMESTAMP;
+UPDATE digest_subscriptions SET confirmed_at = created_at;
+
+-- +goose Down
+ALTER TABLE digest_subscriptions
+  DROP COLUMN confirmed_at,
+  DROP COLUMN confirm_token;
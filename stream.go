@@ -0,0 +1,345 @@
+package main
+
+import (
+	"blog_aggregator/internal/broker"
+	"blog_aggregator/internal/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	eventNewPost     = "new_post"
+	eventReadState   = "read_state"
+	eventFeedError   = "feed_error"
+	eventSearchMatch = "search_match"
+
+	// events a stream can fall behind by before it's dropped and has to resume
+	streamBuffer = 256
+	// events read from the db at a time when a stream resumes
+	streamReplayBatch = 500
+	// how often a stream pings the client and reloads the feeds the user follows
+	streamPingInterval    = 30 * time.Second
+	streamFollowsInterval = 30 * time.Second
+	// how long events are kept for streams to resume from
+	streamEventRetention = 24 * time.Hour
+)
+
+// data of a read_state event, either the posts in PostIDs or every post
+// in the followed feeds (or FeedID's) published before Before changed
+type readStateEvent struct {
+	Read    bool        `json:"read"`
+	PostIDs []uuid.UUID `json:"post_ids,omitempty"`
+	All     bool        `json:"all,omitempty"`
+	FeedID  *uuid.UUID  `json:"feed_id,omitempty"`
+	Before  *time.Time  `json:"before,omitempty"`
+}
+
+// data of a feed_error event
+type feedErrorEvent struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Url    string    `json:"url"`
+	Error  string    `json:"error"`
+}
+
+// what a stream sends for every event
+type streamEventResponse struct {
+	ID    int64           `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// saves an event and publishes it to the streams, for one user or for the followers of a feed
+// events about a post (postID) don't go to the users whose filter rules hide it
+// failing to publish is logged and doesn't fail what caused the event
+func (apiCfg apiConfig) publishEvent(ctx context.Context, kind string, userID, feedID, postID uuid.NullUUID, data interface{}) {
+	dat, err := json.Marshal(data)
+	if err != nil {
+		log.Println("publishEvent: ", err)
+		return
+	}
+	event, err := apiCfg.DB.CreateStreamEvent(ctx, database.CreateStreamEventParams{
+		Kind:      kind,
+		UserID:    userID,
+		FeedID:    feedID,
+		PostID:    postID,
+		Data:      dat,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Println("publishEvent: ", err)
+		return
+	}
+	if apiCfg.Broker != nil {
+		apiCfg.Broker.Publish(newBrokerEvent(event))
+	}
+}
+
+// publishes an event only the user's streams get
+func (apiCfg apiConfig) publishUserEvent(ctx context.Context, user database.User, kind string, data interface{}) {
+	apiCfg.publishEvent(ctx, kind, uuid.NullUUID{UUID: user.ID, Valid: true}, uuid.NullUUID{}, uuid.NullUUID{}, data)
+}
+
+// publishes an event the streams of everyone following the feed get
+func (apiCfg apiConfig) publishFeedEvent(ctx context.Context, feedID uuid.UUID, kind string, data interface{}) {
+	apiCfg.publishEvent(ctx, kind, uuid.NullUUID{}, uuid.NullUUID{UUID: feedID, Valid: true}, uuid.NullUUID{}, data)
+}
+
+// publishes the new_post event of a post to the followers of its feed, after the filter rules are applied to it
+func (apiCfg apiConfig) publishNewPostEvent(ctx context.Context, post database.Post) {
+	apiCfg.publishEvent(ctx, eventNewPost, uuid.NullUUID{}, uuid.NullUUID{UUID: post.FeedID, Valid: true}, uuid.NullUUID{UUID: post.ID, Valid: true}, newPostResponse(timelinePost{Post: post}))
+}
+
+func newBrokerEvent(event database.StreamEvent) broker.Event {
+	return broker.Event{
+		ID:     event.ID,
+		Kind:   event.Kind,
+		UserID: event.UserID,
+		FeedID: event.FeedID,
+		PostID: event.PostID,
+		Data:   event.Data,
+	}
+}
+
+// sends events over one transport
+type streamWriter interface {
+	Send(event broker.Event) error
+	Ping() error
+}
+
+// server-sent events, the default transport
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s sseWriter) Send(event broker.Event) error {
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Kind, event.Data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func (s sseWriter) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// websocket transport, every event is a text message of a streamEventResponse
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (s wsWriter) Send(event broker.Event) error {
+	return s.conn.WriteJSON(streamEventResponse{ID: event.ID, Event: event.Kind, Data: event.Data})
+}
+
+func (s wsWriter) Ping() error {
+	return s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+}
+
+// the ids of the feeds the user follows and wants events of, follows with notify 'none' are left out
+func (apiCfg apiConfig) streamedFeedIDs(ctx context.Context, user database.User) (map[uuid.UUID]bool, error) {
+	feedFollows, err := apiCfg.DB.GetFeedFollows(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	feedIDs := make(map[uuid.UUID]bool, len(feedFollows))
+	for _, feedFollow := range feedFollows {
+		if feedFollow.Notify != notifyNone {
+			feedIDs[feedFollow.FeedID] = true
+		}
+	}
+	return feedIDs, nil
+}
+
+// sends the user's events after lastID from the db, then the ones published from now on,
+// until ctx is done, sending fails, or the subscription is dropped for falling behind
+func (apiCfg apiConfig) runStream(ctx context.Context, user database.User, lastID int64, sub *broker.Subscription, out streamWriter) error {
+	// subscribed before replaying so nothing published in between is missed, ids already sent are skipped
+	for {
+		events, err := apiCfg.DB.GetStreamEventsForUser(ctx, database.GetStreamEventsForUserParams{
+			AfterID: lastID,
+			UserID:  uuid.NullUUID{UUID: user.ID, Valid: true},
+			Limit:   streamReplayBatch,
+		})
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := out.Send(newBrokerEvent(event)); err != nil {
+				return err
+			}
+			lastID = event.ID
+		}
+		if len(events) < streamReplayBatch {
+			break
+		}
+	}
+
+	feedIDs, err := apiCfg.streamedFeedIDs(ctx, user)
+	if err != nil {
+		return err
+	}
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	reload := time.NewTicker(streamFollowsInterval)
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return errors.New("stream fell behind")
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			forUser := event.UserID.Valid && event.UserID.UUID == user.ID
+			forFeed := event.FeedID.Valid && feedIDs[event.FeedID.UUID]
+			if !forUser && !forFeed {
+				continue
+			}
+			if event.PostID.Valid {
+				hidden, err := apiCfg.DB.IsPostHidden(ctx, database.IsPostHiddenParams{
+					PostID: event.PostID.UUID,
+					UserID: user.ID,
+				})
+				if err != nil {
+					return err
+				}
+				if hidden {
+					continue
+				}
+			}
+			if err := out.Send(event); err != nil {
+				return err
+			}
+			lastID = event.ID
+		case <-ping.C:
+			if err := out.Ping(); err != nil {
+				return err
+			}
+		case <-reload.C:
+			reloaded, err := apiCfg.streamedFeedIDs(ctx, user)
+			if err != nil {
+				return err
+			}
+			feedIDs = reloaded
+		}
+	}
+}
+
+var streamUpgrader = websocket.Upgrader{}
+
+// GET /v1/stream
+// authed
+// pushes new_post and feed_error events of the feeds the user follows and their read_state events
+// as server-sent events, or as websocket messages if the request is a websocket upgrade
+// feeds followed with notify 'none' send no events, nor do posts the user's filter rules hide
+// Last-Event-ID (or ?last_event_id= for websockets) resumes after the last event the client saw,
+// without it the stream starts with the events from now on
+func (apiCfg apiConfig) streamHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	if apiCfg.Broker == nil {
+		respondWithError(w, http.StatusServiceUnavailable, errors.New("streaming is not available"))
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || parsed < 0 {
+			respondWithError(w, http.StatusBadRequest, errors.New("Last-Event-ID must be an event id"))
+			return
+		}
+		lastID = parsed
+	} else {
+		// read before subscribing, whatever is published in between is replayed from the db
+		latest, err := apiCfg.DB.GetLastStreamEventID(r.Context())
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		lastID = latest
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		apiCfg.streamWebSocket(w, r, user, lastID)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	sub := apiCfg.Broker.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// stops proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if err := apiCfg.runStream(r.Context(), user, lastID, sub, sseWriter{w: w, flusher: flusher}); err != nil {
+		log.Println("streamHandler: ", err)
+	}
+}
+
+func (apiCfg apiConfig) streamWebSocket(w http.ResponseWriter, r *http.Request, user database.User, lastID int64) {
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has responded already
+		return
+	}
+	defer conn.Close()
+	sub := apiCfg.Broker.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	// reading handles pings and closes from the client, the stream ends when the connection does
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	err = apiCfg.runStream(ctx, user, lastID, sub, wsWriter{conn: conn})
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err != nil {
+		log.Println("streamHandler: ", err)
+		msg = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "resume with last_event_id")
+	}
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+}
+
+// deletes events too old to resume from
+func (apiCfg apiConfig) pruneStreamEvents(ctx context.Context) {
+	if _, err := apiCfg.DB.DeleteStreamEventsBefore(ctx, time.Now().Add(-streamEventRetention)); err != nil {
+		log.Println("pruneStreamEvents: ", err)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestStream(t *testing.T) {
+	server := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	created, err := client.New(server.URL).CreateUser(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := client.New(server.URL, client.WithAPIKey(created.APIKey))
+
+	// a starred post to change the read state of
+	base := "https://example.com/" + uuid.NewString()
+	starred := fmt.Sprintf(`{"id": "user/1/state/com.google/starred", "items": [
+		{"title": "Starred", "alternate": [{"href": "%[1]s/starred"}], "origin": {"streamId": "feed/%[1]s/feed.xml"}}
+	]}`, base)
+	imported, err := alice.Import(ctx, client.FormatGoogleReader, client.ImportFile{Name: "starred.json", Body: strings.NewReader(starred)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	postID := *imported.Report.ItemResults[0].PostID
+
+	stream, err := alice.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.MarkPostRead(ctx, postID); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.MarkPostUnread(ctx, postID); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []*client.StreamEvent{}
+	for len(events) < 2 {
+		event, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	stream.Close()
+	for i, wantRead := range []bool{true, false} {
+		readState := client.ReadStateEvent{}
+		if err := json.Unmarshal(events[i].Data, &readState); err != nil {
+			t.Fatal(err)
+		}
+		if events[i].Event != client.EventReadState || readState.Read != wantRead || len(readState.PostIDs) != 1 || readState.PostIDs[0] != postID {
+			t.Errorf("event %d: got %+v, data %+v", i, events[i], readState)
+		}
+	}
+
+	// resuming after the first event replays the second
+	stream, err = alice.Stream(ctx, events[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := stream.Next()
+	stream.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ID != events[1].ID {
+		t.Errorf("expected event %d to be replayed, got %+v", events[1].ID, replayed)
+	}
+
+	// the same over a websocket
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + fmt.Sprintf("/v1/stream?last_event_id=%d", events[0].ID)
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, http.Header{"Authorization": {"ApiKey " + created.APIKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	message := streamEventResponse{}
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	if message.ID != events[1].ID || message.Event != eventReadState {
+		t.Errorf("got websocket message %+v", message)
+	}
+
+	// a new stream doesn't replay the events from before it
+	stream, err = alice.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.MarkPostRead(ctx, postID); err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := stream.Next()
+	stream.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.ID <= events[1].ID || fresh.Event != client.EventReadState {
+		t.Errorf("got %+v, want the event from after the stream started", fresh)
+	}
+
+	// no api key, no stream
+	if _, err := client.New(server.URL).Stream(ctx, 0); !client.IsUnauthorized(err) {
+		t.Errorf("expected 401 without an api key, got %v", err)
+	}
+}
+
+// feeds followed with notify none send no events, live or replayed
+func TestStreamSkipsMutedFeeds(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	alice := newTestUser(t, server, "alice")
+
+	muted, _ := newTestFeedWithPosts(t, apiCfg, alice)
+	// its post makes sure there's an event to resume after
+	loud, _ := newTestFeedWithPosts(t, apiCfg, alice, "Old")
+	follows, err := alice.ListFeedFollows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	none := client.NotifyNone
+	for _, follow := range follows {
+		if follow.FeedID == muted.ID {
+			if _, err := alice.UpdateFeedFollow(ctx, follow.ID, client.UpdateFeedFollowOptions{Notify: &none}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stream, err := alice.Stream(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	startedAfter, err := apiCfg.DB.GetLastStreamEventID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, feed := range []*client.Feed{muted, loud} {
+		apiCfg.FetchedFeeds = []FeedTuple{{ID: feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+			{Title: "New", Link: "https://example.com/" + uuid.NewString()},
+		}}}}
+		apiCfg.CreatePostsFromFetchedFeeds()
+	}
+
+	checkLoud := func(event *client.StreamEvent) {
+		t.Helper()
+		post := client.Post{}
+		if err := json.Unmarshal(event.Data, &post); err != nil {
+			t.Fatal(err)
+		}
+		if event.Event != client.EventNewPost || post.FeedID != loud.ID {
+			t.Errorf("got %+v, want the new post of the feed that isn't muted", event)
+		}
+	}
+	event, err := stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLoud(event)
+
+	replay, err := alice.Stream(ctx, startedAfter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+	event, err = replay.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLoud(event)
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/scrape"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+// the /v2 api responds with these types instead of the sqlc structs
+// keys are snake_case, timestamps are RFC 3339, nullable fields are null
+// and secrets (the user's apikey) are never serialized except once when the user is created
+
+type userResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+// only returned by POST /v2/users, the one time the apikey is handed out
+type createdUserResponse struct {
+	userResponse
+	ApiKey string `json:"api_key"`
+}
+
+type feedResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	UserID        uuid.UUID  `json:"user_id"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	// the website the feed belongs to, empty until the feed is fetched
+	SiteUrl string `json:"site_url"`
+	// whether the page of each new post is downloaded for its full content, and the css selector for the article
+	FetchFullContent bool   `json:"fetch_full_content"`
+	ContentSelector  string `json:"content_selector"`
+	// rss or scraped, the selectors are null unless the feed is scraped
+	Kind      string            `json:"kind"`
+	Selectors *scrape.Selectors `json:"selectors"`
+}
+
+type feedFollowResponse struct {
+	ID        uuid.UUID `json:"id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// null to show the feed's name
+	Title            *string `json:"title"`
+	Pinned           bool    `json:"pinned"`
+	Notify           string  `json:"notify"`
+	HideFromTimeline bool    `json:"hide_from_timeline"`
+}
+
+type postResponse struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	// the description without the html, for clients that can't render it
+	DescriptionText string `json:"description_text"`
+	// the article from the post's page, if its feed fetches full content
+	FullContent string    `json:"full_content"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	Author      string    `json:"author"`
+	Categories  []string  `json:"categories"`
+	Read        bool      `json:"read"`
+	Starred     bool      `json:"starred"`
+	Saved       bool      `json:"saved"`
+	Tags        []string  `json:"tags"`
+}
+
+// converts a nullable db timestamp into a pointer so that it marshals to null
+func nullTimeToPtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
+
+// converts a nullable db string into a pointer so that it marshals to null
+func nullStringToPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func newUserResponse(user database.User) userResponse {
+	return userResponse{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt.UTC(),
+		UpdatedAt: user.UpdatedAt.UTC(),
+		Name:      user.Name,
+	}
+}
+
+func newFeedResponse(feed database.Feed) feedResponse {
+	resp := feedResponse{
+		ID:               feed.ID,
+		CreatedAt:        feed.CreatedAt.UTC(),
+		UpdatedAt:        feed.UpdatedAt.UTC(),
+		Name:             feed.Name,
+		Url:              feed.Url,
+		UserID:           feed.UserID,
+		LastFetchedAt:    nullTimeToPtr(feed.LastFetchedAt),
+		SiteUrl:          feed.SiteUrl,
+		FetchFullContent: feed.FetchFullContent,
+		ContentSelector:  feed.ContentSelector,
+		Kind:             feed.Kind,
+	}
+	if feed.Kind == feedKindScraped {
+		if selectors, err := feedSelectors(feed); err == nil {
+			resp.Selectors = &selectors
+		}
+	}
+	return resp
+}
+
+func newFeedFollowResponse(feedFollow database.FeedFollow) feedFollowResponse {
+	return feedFollowResponse{
+		ID:               feedFollow.ID,
+		FeedID:           feedFollow.FeedID,
+		UserID:           feedFollow.UserID,
+		CreatedAt:        feedFollow.CreatedAt.UTC(),
+		UpdatedAt:        feedFollow.UpdatedAt.UTC(),
+		Title:            nullStringToPtr(feedFollow.Title),
+		Pinned:           feedFollow.Pinned,
+		Notify:           feedFollow.Notify,
+		HideFromTimeline: feedFollow.HideFromTimeline,
+	}
+}
+
+func newPostResponse(timelinePost timelinePost) postResponse {
+	post := timelinePost.Post
+	resp := postResponse{
+		ID:              post.ID,
+		CreatedAt:       post.CreatedAt.UTC(),
+		UpdatedAt:       post.UpdatedAt.UTC(),
+		Title:           post.Title,
+		Url:             post.Url,
+		Description:     post.Description,
+		DescriptionText: post.DescriptionText,
+		FullContent:     post.FullContent,
+		PublishedAt:     post.PublishedAt.UTC(),
+		FeedID:          post.FeedID,
+		Author:          post.Author,
+		Categories:      post.Categories,
+		Read:            timelinePost.Read,
+		Starred:         timelinePost.Starred,
+		Saved:           timelinePost.Saved,
+		Tags:            timelinePost.Tags,
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	return resp
+}
+
+// POST /v2/users
+// create a new user, the response is the only time the apikey is returned
+func (apiCfg apiConfig) createUserHandlerV2(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	// decode the user from JSON into go struct
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	// make sure name isn't empty
+	if len(params.Name) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("name cannot be empty"))
+		return
+	}
+
+	databaseUser, err := apiCfg.createUser(context.Background(), params.Name)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, createdUserResponse{
+		userResponse: newUserResponse(databaseUser),
+		ApiKey:       databaseUser.ApiKey,
+	})
+}
+
+// GET /v2/users
+// needs Authorization: ApiKey <key>
+// get the authed user, without their apikey
+func (apiCfg apiConfig) getUserHandlerV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, newUserResponse(user))
+}
+
+// POST /v2/feeds
+// needs Authorization: ApiKey <key>
+// create a new feed and a feed_follow to it
+// if the url already exists the existing feed is returned with status 200 instead of 201
+func (apiCfg apiConfig) createFeedHandlerV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	}
+	type returnVal struct {
+		Feed       feedResponse       `json:"feed"`
+		FeedFollow feedFollowResponse `json:"feed_follow"`
+	}
+
+	// decode the feed from JSON into go struct
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	// make sure name and url are not empty
+	if len(params.Name) == 0 || len(params.Url) == 0 {
+		respondWithError(w, http.StatusBadRequest, errors.New("name and url cannot be empty"))
+		return
+	}
+
+	feed, feedFollow, feedCreated, err := apiCfg.createFeedAndFollow(context.Background(), user, params.Name, params.Url)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	code := http.StatusOK
+	if feedCreated {
+		code = http.StatusCreated
+	}
+	respondWithJSON(w, code, returnVal{
+		Feed:       newFeedResponse(feed),
+		FeedFollow: newFeedFollowResponse(feedFollow),
+	})
+}
+
+// GET /v2/feeds
+// retrieve all feeds but the private push feeds, don't need to be authed
+func (apiCfg apiConfig) getAllFeedsHandlerV2(w http.ResponseWriter, r *http.Request) {
+	allFeeds, err := apiCfg.DB.GetFeeds(context.Background())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	retVal := make([]feedResponse, 0, len(allFeeds))
+	for _, feed := range allFeeds {
+		retVal = append(retVal, newFeedResponse(feed))
+	}
+	respondWithJSON(w, http.StatusOK, retVal)
+}
+
+// POST /v2/feed_follows
+// authed
+// expects a feed_id, and the invite_token of push feeds
+// if the user already follows the feed the existing feed follow is returned with status 200 instead of 201
+// 404 if the feed doesn't exist or is a push feed and the invite token is wrong
+func (apiCfg apiConfig) createFeedFollowHandlerV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FeedID      string `json:"feed_id"`
+		InviteToken string `json:"invite_token"`
+	}
+
+	// decode the feed follow from JSON into go struct
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+
+	parsedFeedID, err := uuid.Parse(params.FeedID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("feed_id must be a valid uuid"))
+		return
+	}
+
+	feedFollow, created, err := apiCfg.followFeed(context.Background(), user, parsedFeedID, params.InviteToken)
+	if errors.Is(err, errFeedNotFound) {
+		respondWithError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	respondWithJSON(w, code, newFeedFollowResponse(feedFollow))
+}
+
+// GET /v2/feed_follows
+// authed
+// get all feed follows of the authed user
+func (apiCfg apiConfig) getFeedFollowsHandlerV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedFollows, err := apiCfg.DB.GetFeedFollows(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	retVal := make([]feedFollowResponse, 0, len(feedFollows))
+	for _, feedFollow := range feedFollows {
+		retVal = append(retVal, newFeedFollowResponse(feedFollow))
+	}
+	respondWithJSON(w, http.StatusOK, retVal)
+}
+
+// DELETE /v2/feed_follows/{feedFollowID}
+// authed
+// deletes the single feed follow specified by its id, responds 204 with no body
+// 404 if the feed follow isn't the user's
+func (apiCfg apiConfig) deleteFeedFollowHandlerV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	parsedFeedFollowID, err := uuid.Parse(chi.URLParam(r, "feedFollowID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("feedFollowID must be a valid uuid"))
+		return
+	}
+	deleted, err := apiCfg.DB.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
+		ID:     parsedFeedFollowID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errors.New("feed follow not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v2/posts
+// authed
+// get posts for the feeds that the user follows, newest first unless sort=oldest
+// same query params as GET /v1/posts, the next/prev pages are in the Link header
+func (apiCfg apiConfig) getUserPostsV2(w http.ResponseWriter, r *http.Request, user database.User) {
+	query, err := parsePostsQuery(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := apiCfg.getPostsPage(context.Background(), user, query)
+	if err != nil {
+		if errors.Is(err, errSavedSearchNotFound) {
+			respondWithError(w, http.StatusNotFound, err)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	retVal := make([]postResponse, 0, len(page.Posts))
+	for _, post := range page.Posts {
+		retVal = append(retVal, newPostResponse(post))
+	}
+	setPostsLinkHeader(w, r, page)
+	respondWithJSON(w, http.StatusOK, retVal)
+}
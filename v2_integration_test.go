@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// sends a request as the user with the api key, checks the status code and decodes the json response into out
+func jsonAs(t *testing.T, server *httptest.Server, apiKey, method, path string, body interface{}, wantStatus int, out interface{}) {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, server.URL+path, &reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s: got status %d, want %d", method, path, resp.StatusCode, wantStatus)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// the v2 endpoints respond with the snake_case response types, never the db structs
+func TestV2ResponseTypes(t *testing.T) {
+	server := newTestServer(t)
+
+	var created map[string]interface{}
+	jsonAs(t, server, "", http.MethodPost, "/v2/users", map[string]string{"name": "alice"}, http.StatusCreated, &created)
+	apiKey, _ := created["api_key"].(string)
+	if apiKey == "" || created["id"] == nil || created["ApiKey"] != nil {
+		t.Fatalf("got created user %v", created)
+	}
+
+	var user map[string]interface{}
+	jsonAs(t, server, apiKey, http.MethodGet, "/v2/users", nil, http.StatusOK, &user)
+	if _, ok := user["api_key"]; ok || user["id"] != created["id"] || user["name"] != "alice" {
+		t.Errorf("got user %v", user)
+	}
+
+	var feed struct {
+		Feed       map[string]interface{} `json:"feed"`
+		FeedFollow map[string]interface{} `json:"feed_follow"`
+	}
+	feedURL := "https://example.com/" + uuid.NewString() + "/index.xml"
+	jsonAs(t, server, apiKey, http.MethodPost, "/v2/feeds", map[string]string{"name": "Example", "url": feedURL}, http.StatusCreated, &feed)
+	if v, ok := feed.Feed["last_fetched_at"]; !ok || v != nil {
+		t.Errorf("got last_fetched_at %v, want null", v)
+	}
+	if feed.Feed["url"] != feedURL || feed.Feed["user_id"] != created["id"] || feed.FeedFollow["feed_id"] != feed.Feed["id"] {
+		t.Errorf("got %+v", feed)
+	}
+	if v, ok := feed.FeedFollow["title"]; !ok || v != nil {
+		t.Errorf("got follow title %v, want null", v)
+	}
+
+	// the same url again is the existing feed
+	jsonAs(t, server, apiKey, http.MethodPost, "/v2/feeds", map[string]string{"name": "Again", "url": feedURL}, http.StatusOK, nil)
+
+	var feeds []map[string]interface{}
+	jsonAs(t, server, "", http.MethodGet, "/v2/feeds", nil, http.StatusOK, &feeds)
+	found := false
+	for _, listed := range feeds {
+		if _, ok := listed["ID"]; ok {
+			t.Fatalf("got db struct keys %v", listed)
+		}
+		found = found || listed["id"] == feed.Feed["id"]
+	}
+	if !found {
+		t.Errorf("feed %v missing from GET /v2/feeds", feed.Feed["id"])
+	}
+
+	var follows []map[string]interface{}
+	jsonAs(t, server, apiKey, http.MethodGet, "/v2/feed_follows", nil, http.StatusOK, &follows)
+	if len(follows) != 1 || follows[0]["id"] != feed.FeedFollow["id"] || follows[0]["notify"] == nil {
+		t.Errorf("got follows %v", follows)
+	}
+
+	var posts []map[string]interface{}
+	jsonAs(t, server, apiKey, http.MethodGet, "/v2/posts", nil, http.StatusOK, &posts)
+	if posts == nil || len(posts) != 0 {
+		t.Errorf("got posts %v, want an empty list", posts)
+	}
+
+	bob := map[string]interface{}{}
+	jsonAs(t, server, "", http.MethodPost, "/v2/users", map[string]string{"name": "bob"}, http.StatusCreated, &bob)
+	bobKey := bob["api_key"].(string)
+	jsonAs(t, server, bobKey, http.MethodPost, "/v2/feed_follows", map[string]string{"feed_id": "not a uuid"}, http.StatusBadRequest, nil)
+	var bobFollow map[string]interface{}
+	jsonAs(t, server, bobKey, http.MethodPost, "/v2/feed_follows", map[string]interface{}{"feed_id": feed.Feed["id"]}, http.StatusCreated, &bobFollow)
+	jsonAs(t, server, bobKey, http.MethodPost, "/v2/feed_follows", map[string]interface{}{"feed_id": feed.Feed["id"]}, http.StatusOK, nil)
+
+	// only the owner of a follow can delete it
+	followPath := "/v2/feed_follows/" + bobFollow["id"].(string)
+	jsonAs(t, server, apiKey, http.MethodDelete, followPath, nil, http.StatusNotFound, nil)
+	jsonAs(t, server, bobKey, http.MethodDelete, followPath, nil, http.StatusNoContent, nil)
+	jsonAs(t, server, bobKey, http.MethodDelete, followPath, nil, http.StatusNotFound, nil)
+}
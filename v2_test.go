@@ -0,0 +1,97 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// marshals v to json and back into a map so the keys and nulls can be checked
+func jsonFields(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	dat, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(dat, &fields); err != nil {
+		t.Fatal(err)
+	}
+	return fields
+}
+
+func sortedFieldNames(fields map[string]interface{}) []string {
+	names := map[string]bool{}
+	for name := range fields {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
+
+func TestUserResponseHasNoAPIKey(t *testing.T) {
+	user := database.User{ID: uuid.New(), Name: "alice", ApiKey: "secret"}
+	fields := jsonFields(t, newUserResponse(user))
+	want := []string{"created_at", "id", "name", "updated_at"}
+	if got := sortedFieldNames(fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("got keys %v, want %v", got, want)
+	}
+}
+
+func TestFeedResponse(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	feed := database.Feed{
+		ID:        uuid.New(),
+		CreatedAt: time.Date(2024, 5, 1, 14, 0, 0, 0, berlin),
+		Name:      "Example",
+		Url:       "https://example.com/index.xml",
+		Kind:      feedKindRSS,
+	}
+
+	fields := jsonFields(t, newFeedResponse(feed))
+	if fields["last_fetched_at"] != nil {
+		t.Errorf("got last_fetched_at %v, want null for a feed that was never fetched", fields["last_fetched_at"])
+	}
+	if fields["created_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("got created_at %v, want it in utc", fields["created_at"])
+	}
+	if fields["selectors"] != nil {
+		t.Errorf("got selectors %v, want null for an rss feed", fields["selectors"])
+	}
+	for _, key := range []string{"ID", "Url", "LastFetchedAt"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("got db struct key %s", key)
+		}
+	}
+
+	feed.LastFetchedAt = sql.NullTime{Time: time.Date(2024, 5, 2, 8, 0, 0, 0, berlin), Valid: true}
+	fields = jsonFields(t, newFeedResponse(feed))
+	if fields["last_fetched_at"] != "2024-05-02T06:00:00Z" {
+		t.Errorf("got last_fetched_at %v", fields["last_fetched_at"])
+	}
+}
+
+func TestFeedFollowResponse(t *testing.T) {
+	follow := database.FeedFollow{ID: uuid.New(), FeedID: uuid.New(), UserID: uuid.New(), Notify: "none"}
+	fields := jsonFields(t, newFeedFollowResponse(follow))
+	if v, ok := fields["title"]; !ok || v != nil {
+		t.Errorf("got title %v, want null when the follow has no title of its own", v)
+	}
+
+	follow.Title = sql.NullString{String: "Mine", Valid: true}
+	fields = jsonFields(t, newFeedFollowResponse(follow))
+	if fields["title"] != "Mine" || fields["notify"] != "none" {
+		t.Errorf("got %v", fields)
+	}
+}
+
+func TestPostResponseTags(t *testing.T) {
+	fields := jsonFields(t, newPostResponse(timelinePost{Post: database.Post{ID: uuid.New()}}))
+	if tags, ok := fields["tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("got tags %v, want an empty list", fields["tags"])
+	}
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"blog_aggregator/internal/database"
+	"blog_aggregator/internal/fetch"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+)
+
+const (
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusFailed    = "failed"
+
+	// attempts before a delivery is given up on, the wait doubles after each one
+	webhookMaxAttempts = 8
+	webhookRetryDelay  = time.Minute
+	// deliveries the worker sends at once
+	webhookDeliveryBatch = 20
+
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+// how long an endpoint gets to respond, a var so tests don't have to wait that long
+var webhookTimeout = 10 * time.Second
+
+// returned when the webhook in the url isn't a uuid, or doesn't exist or isn't the user's
+var (
+	errInvalidWebhookID = errors.New("webhookID must be a valid uuid")
+	errWebhookNotFound  = errors.New("webhook not found")
+)
+
+type webhookResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	// null to take posts from every followed feed, or every folder
+	FeedID   *uuid.UUID `json:"feed_id"`
+	FolderID *uuid.UUID `json:"folder_id"`
+	Keyword  string     `json:"keyword"`
+}
+
+func newWebhookResponse(webhook database.Webhook) webhookResponse {
+	resp := webhookResponse{
+		ID:        webhook.ID,
+		CreatedAt: webhook.CreatedAt.UTC(),
+		UpdatedAt: webhook.UpdatedAt.UTC(),
+		Url:       webhook.Url,
+		Secret:    webhook.Secret,
+		Keyword:   webhook.Keyword,
+	}
+	if webhook.FeedID.Valid {
+		resp.FeedID = &webhook.FeedID.UUID
+	}
+	if webhook.FolderID.Valid {
+		resp.FolderID = &webhook.FolderID.UUID
+	}
+	return resp
+}
+
+type webhookDeliveryResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	WebhookID uuid.UUID       `json:"webhook_id"`
+	PostID    uuid.UUID       `json:"post_id"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// null once the delivery is delivered or failed
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	// the outcome of the last attempt, response_status is null if there was no response
+	ResponseStatus *int       `json:"response_status"`
+	Error          string     `json:"error"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
+
+func newWebhookDeliveryResponse(delivery database.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:        delivery.ID,
+		WebhookID: delivery.WebhookID,
+		PostID:    delivery.PostID,
+		Payload:   delivery.Payload,
+		Status:    delivery.Status,
+		Attempts:  int(delivery.Attempts),
+		Error:     delivery.Error,
+		CreatedAt: delivery.CreatedAt.UTC(),
+		UpdatedAt: delivery.UpdatedAt.UTC(),
+	}
+	if delivery.Status != webhookStatusDelivered && delivery.Status != webhookStatusFailed {
+		nextAttemptAt := delivery.NextAttemptAt.UTC()
+		resp.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.ResponseStatus.Valid {
+		responseStatus := int(delivery.ResponseStatus.Int32)
+		resp.ResponseStatus = &responseStatus
+	}
+	if delivery.DeliveredAt.Valid {
+		deliveredAt := delivery.DeliveredAt.Time.UTC()
+		resp.DeliveredAt = &deliveredAt
+	}
+	return resp
+}
+
+// the body POSTed to a webhook
+type webhookPayload struct {
+	Event     string       `json:"event"`
+	WebhookID uuid.UUID    `json:"webhook_id"`
+	CreatedAt time.Time    `json:"created_at"`
+	Post      postResponse `json:"post"`
+}
+
+// true if the post has the webhook's keyword as whole words in its title or description, ignoring case
+func webhookMatches(webhook database.Webhook, post database.Post) bool {
+	if webhook.Keyword == "" {
+		return true
+	}
+	keyword := strings.ToLower(strings.Join(strings.Fields(webhook.Keyword), " "))
+	return containsWords(postKeywordText(post), keyword)
+}
+
+// the X-Webhook-Signature of a delivery, the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// how long to wait after the attempt-th failed attempt
+func webhookBackoff(attempt int) time.Duration {
+	return webhookRetryDelay << (attempt - 1)
+}
+
+// queues a delivery of the post to every webhook that takes it
+// failing to queue is logged and doesn't fail the fetch
+func (apiCfg apiConfig) enqueueWebhookDeliveries(ctx context.Context, post database.Post) {
+	webhooks, err := apiCfg.DB.GetWebhooksForPost(ctx, database.GetWebhooksForPostParams{
+		FeedID: post.FeedID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		log.Println("enqueueWebhookDeliveries: ", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if !webhookMatches(webhook, post) {
+			continue
+		}
+		currTime := time.Now()
+		payload, err := json.Marshal(webhookPayload{
+			Event:     eventNewPost,
+			WebhookID: webhook.ID,
+			CreatedAt: currTime.UTC(),
+			Post:      newPostResponse(timelinePost{Post: post}),
+		})
+		if err != nil {
+			log.Println("enqueueWebhookDeliveries: ", err)
+			continue
+		}
+		_, err = apiCfg.DB.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			PostID:        post.ID,
+			Payload:       payload,
+			NextAttemptAt: currTime,
+		})
+		if err != nil {
+			log.Println("enqueueWebhookDeliveries: ", err)
+		}
+	}
+}
+
+// makes one attempt at a claimed delivery and records how it went
+func (apiCfg apiConfig) sendWebhookDelivery(ctx context.Context, delivery database.WebhookDelivery) (database.WebhookDelivery, error) {
+	params := database.FinishWebhookDeliveryAttemptParams{
+		ID:            delivery.ID,
+		Status:        webhookStatusDelivered,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+
+	webhook, err := apiCfg.DB.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+	timestamp := time.Now().Unix()
+	// the timeout is only for the request, the outcome is recorded even when it ran out
+	reqCtx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "blog_aggregator-webhooks")
+	req.Header.Set("X-Webhook-Event", eventNewPost)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", signWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	// refuses private addresses on every attempt and doesn't follow redirects, so a webhook can't reach internal services
+	resp, err := apiCfg.Fetcher.Do(req)
+	if err != nil {
+		params.Error = err.Error()
+	} else {
+		resp.Body.Close()
+		params.ResponseStatus = sql.NullInt32{Int32: int32(resp.StatusCode), Valid: true}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			params.Error = "responded with " + resp.Status
+		}
+	}
+
+	currTime := time.Now()
+	params.UpdatedAt = currTime
+	attempts := int(delivery.Attempts) + 1
+	switch {
+	case params.Error == "":
+		params.DeliveredAt = sql.NullTime{Time: currTime, Valid: true}
+	case attempts >= webhookMaxAttempts:
+		params.Status = webhookStatusFailed
+	default:
+		params.Status = webhookStatusPending
+		params.NextAttemptAt = currTime.Add(webhookBackoff(attempts))
+	}
+	return apiCfg.DB.FinishWebhookDeliveryAttempt(ctx, params)
+}
+
+// sends the deliveries that are due, returns how many were attempted
+func (apiCfg apiConfig) sendDueWebhookDeliveries(ctx context.Context) (int, error) {
+	deliveries, err := apiCfg.DB.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		Now:   time.Now(),
+		Limit: webhookDeliveryBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	wg := sync.WaitGroup{}
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery database.WebhookDelivery) {
+			defer wg.Done()
+			if _, err := apiCfg.sendWebhookDelivery(ctx, delivery); err != nil {
+				log.Println("webhookWorker: ", err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// worker that sends webhook deliveries as they come due
+// deliveries that were being sent when the server stopped are sent again
+func (apiCfg apiConfig) webhookWorker(delay int) {
+	requeued, err := apiCfg.DB.RequeueSendingWebhookDeliveries(context.Background(), time.Now())
+	if err != nil {
+		log.Println("webhookWorker: ", err)
+	} else if requeued > 0 {
+		log.Printf("webhookWorker: resending %d interrupted webhook deliveries\n", requeued)
+	}
+
+	go func(delay int) {
+		for {
+			sent, err := apiCfg.sendDueWebhookDeliveries(context.Background())
+			if err != nil {
+				log.Println("webhookWorker: ", err)
+			}
+			if sent == 0 {
+				// nothing due, wait before checking again
+				time.Sleep(time.Duration(delay) * time.Second)
+			}
+		}
+	}(delay)
+}
+
+// gets the webhook in the {webhookID} url param if it's the user's, errWebhookNotFound otherwise
+func (apiCfg apiConfig) webhookFromURL(r *http.Request, user database.User) (database.Webhook, error) {
+	webhookID, err := uuid.Parse(chi.URLParam(r, "webhookID"))
+	if err != nil {
+		return database.Webhook{}, errInvalidWebhookID
+	}
+	webhook, err := apiCfg.DB.GetWebhook(context.Background(), database.GetWebhookParams{
+		ID:     webhookID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Webhook{}, errWebhookNotFound
+	}
+	return webhook, err
+}
+
+// responds to an error from webhookFromURL
+func respondWithWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidWebhookID):
+		respondWithError(w, http.StatusBadRequest, err)
+	case errors.Is(err, errWebhookNotFound):
+		respondWithError(w, http.StatusNotFound, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// POST /v1/webhooks
+// authed
+// expects a url, and optionally a feed_id, folder_id and keyword to only send some posts
+// the posts the fetcher finds in the user's followed feeds are POSTed to the url, signed with the secret in the response
+// 400 if the url isn't http(s) or points at a private address, the feed isn't followed by the user or the folder isn't theirs
+func (apiCfg apiConfig) createWebhookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Url      string     `json:"url"`
+		FeedID   *uuid.UUID `json:"feed_id"`
+		FolderID *uuid.UUID `json:"folder_id"`
+		Keyword  string     `json:"keyword"`
+	}
+	params := parameters{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, errors.New("decoding json went wrong"))
+		return
+	}
+	target, err := url.Parse(params.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		respondWithError(w, http.StatusBadRequest, errors.New("url must be an http or https url"))
+		return
+	}
+
+	ctx := context.Background()
+	if err := apiCfg.Fetcher.CheckURL(ctx, target.String()); err != nil {
+		if errors.Is(err, fetch.ErrPrivateAddress) {
+			respondWithError(w, http.StatusBadRequest, errors.New("url must not point at a private or loopback address"))
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, errors.New("url's host can't be resolved"))
+		return
+	}
+	feedID := uuid.NullUUID{}
+	if params.FeedID != nil {
+		_, err := apiCfg.DB.GetFeedFollowByFeed(ctx, database.GetFeedFollowByFeedParams{
+			UserID: user.ID,
+			FeedID: *params.FeedID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, errors.New("feed_id must be a feed you follow"))
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		feedID = uuid.NullUUID{UUID: *params.FeedID, Valid: true}
+	}
+	folderID := uuid.NullUUID{}
+	if params.FolderID != nil {
+		_, err := apiCfg.DB.GetFolder(ctx, database.GetFolderParams{
+			ID:     *params.FolderID,
+			UserID: user.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusBadRequest, errors.New("folder_id must be one of your folders"))
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+		folderID = uuid.NullUUID{UUID: *params.FolderID, Valid: true}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	webhook, err := apiCfg.DB.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Url:       target.String(),
+		Secret:    hex.EncodeToString(secret),
+		FeedID:    feedID,
+		FolderID:  folderID,
+		Keyword:   strings.TrimSpace(params.Keyword),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newWebhookResponse(webhook))
+}
+
+// GET /v1/webhooks
+// authed
+// get the user's webhooks, oldest first
+func (apiCfg apiConfig) getWebhooksHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	webhooks, err := apiCfg.DB.GetWebhooks(context.Background(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := make([]webhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		resp = append(resp, newWebhookResponse(webhook))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// GET /v1/webhooks/{webhookID}
+// authed
+// get one of the user's webhooks, 404 if it isn't theirs
+func (apiCfg apiConfig) getWebhookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	webhook, err := apiCfg.webhookFromURL(r, user)
+	if err != nil {
+		respondWithWebhookError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, newWebhookResponse(webhook))
+}
+
+// DELETE /v1/webhooks/{webhookID}
+// authed
+// deletes the webhook and its delivery log, deliveries not sent yet are dropped
+func (apiCfg apiConfig) deleteWebhookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	webhookID, err := uuidFromURL(r, "webhookID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteWebhook(context.Background(), database.DeleteWebhookParams{
+		ID:     webhookID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, errWebhookNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /v1/webhooks/{webhookID}/deliveries
+// authed
+// the webhook's delivery log, newest first
+// optional query param: limit (default 50, at most 200)
+func (apiCfg apiConfig) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit := defaultDeliveriesLimit
+	if tmp := r.URL.Query().Get("limit"); tmp != "" {
+		parsed, err := strconv.Atoi(tmp)
+		if err != nil || parsed < 1 || parsed > maxDeliveriesLimit {
+			respondWithError(w, http.StatusBadRequest, fmt.Errorf("limit must be an integer between 1 and %d", maxDeliveriesLimit))
+			return
+		}
+		limit = parsed
+	}
+	webhook, err := apiCfg.webhookFromURL(r, user)
+	if err != nil {
+		respondWithWebhookError(w, err)
+		return
+	}
+
+	deliveries, err := apiCfg.DB.GetWebhookDeliveries(context.Background(), database.GetWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(delivery))
+	}
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// POST /v1/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver
+// authed
+// queues the delivery's payload to be sent again right away, as a new delivery with its own attempts
+// responds with the new delivery, 404 if the webhook isn't the user's or the delivery isn't the webhook's
+func (apiCfg apiConfig) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request, user database.User) {
+	webhook, err := apiCfg.webhookFromURL(r, user)
+	if err != nil {
+		respondWithWebhookError(w, err)
+		return
+	}
+	deliveryID, err := uuidFromURL(r, "deliveryID")
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := context.Background()
+	delivery, err := apiCfg.DB.GetWebhookDelivery(ctx, database.GetWebhookDeliveryParams{
+		ID:        deliveryID,
+		WebhookID: webhook.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, errors.New("delivery not found"))
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	redelivery, err := apiCfg.DB.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		ID:            uuid.New(),
+		WebhookID:     webhook.ID,
+		PostID:        delivery.PostID,
+		Payload:       delivery.Payload,
+		NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, newWebhookDeliveryResponse(redelivery))
+}
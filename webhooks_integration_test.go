@@ -0,0 +1,235 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/fetch"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestWebhooks(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+	alice := newTestUser(t, server, "alice")
+
+	// fails the first delivery and takes the rest
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	deliveries := make(chan received, 10)
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		deliveries <- received{header: r.Header, body: body}
+	}))
+	t.Cleanup(receiver.Close)
+
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := alice.CreateWebhook(ctx, client.CreateWebhookParams{URL: receiver.URL, FeedID: &created.Feed.ID, Keyword: "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apiErr *client.Error
+	_, err = alice.CreateWebhook(ctx, client.CreateWebhookParams{URL: "ftp://example.com"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an ftp url, got %v", err)
+	}
+
+	// the fetcher finds two posts, only one has the keyword
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Golang news", Link: base + "/golang"},
+		{Title: "Other news", Link: base + "/other"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+
+	if _, err := apiCfg.sendDueWebhookDeliveries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	log, err := alice.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 {
+		t.Fatalf("expected 1 delivery, got %d", len(log))
+	}
+	failed := log[0]
+	if failed.Status != client.DeliveryPending || failed.Attempts != 1 || failed.ResponseStatus == nil || *failed.ResponseStatus != 500 || failed.NextAttemptAt == nil {
+		t.Errorf("expected the failed delivery to be retried later, got %+v", failed)
+	}
+	if failed.Payload.Post.Title != "Golang news" {
+		t.Errorf("expected the golang post, got %+v", failed.Payload.Post)
+	}
+
+	// redelivering sends the payload again right away
+	redelivery, err := alice.RedeliverWebhook(ctx, webhook.ID, failed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiCfg.sendDueWebhookDeliveries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-deliveries:
+		if err := client.VerifyWebhookSignature(webhook.Secret, got.header, got.body, time.Minute); err != nil {
+			t.Error(err)
+		}
+		if got.header.Get("X-Webhook-Delivery") != redelivery.ID.String() {
+			t.Errorf("expected delivery %s, got %s", redelivery.ID, got.header.Get("X-Webhook-Delivery"))
+		}
+		payload := client.WebhookPayload{}
+		if err := json.Unmarshal(got.body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.Event != client.EventNewPost || payload.WebhookID != webhook.ID || payload.Post.ID != failed.PostID {
+			t.Errorf("got payload %+v", payload)
+		}
+	default:
+		t.Fatal("expected the redelivery to be received")
+	}
+	log, err = alice.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 2 || log[0].ID != redelivery.ID || log[0].Status != client.DeliveryDelivered || log[0].DeliveredAt == nil {
+		t.Errorf("expected the redelivery to be delivered, got %+v", log)
+	}
+
+	// bob can't see or redeliver alice's webhook
+	bob := newTestUser(t, server, "bob")
+	if _, err := bob.RedeliverWebhook(ctx, webhook.ID, failed.ID); !client.IsNotFound(err) {
+		t.Errorf("expected 404 for someone else's webhook, got %v", err)
+	}
+	if err := alice.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.GetWebhook(ctx, webhook.ID); !client.IsNotFound(err) {
+		t.Errorf("expected 404 after delete, got %v", err)
+	}
+}
+
+func TestWebhooksRefusePrivateAddresses(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	ctx := context.Background()
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	t.Cleanup(receiver.Close)
+
+	// the default fetcher, unlike the tests' one, refuses private addresses
+	strict := apiCfg
+	strict.Fetcher = fetch.New("blog_aggregator-test")
+	strictServer := httptest.NewServer(strict.router())
+	t.Cleanup(strictServer.Close)
+	alice := newTestUser(t, strictServer, "alice")
+	var apiErr *client.Error
+	for _, target := range []string{receiver.URL, "http://127.0.0.1/hook", "http://localhost/hook", "http://[::1]/hook", "http://10.0.0.1/hook"} {
+		_, err := alice.CreateWebhook(ctx, client.CreateWebhookParams{URL: target})
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %v", target, err)
+		}
+	}
+	webhooks, err := alice.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(webhooks) != 0 {
+		t.Errorf("expected no webhooks, got %+v", webhooks)
+	}
+
+	// a webhook that got in anyway, e.g. its host resolves to a private address since, isn't delivered to
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	bob := newTestUser(t, server, "bob")
+	base := "https://example.com/" + uuid.NewString()
+	created, err := bob.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := bob.CreateWebhook(ctx, client.CreateWebhookParams{URL: receiver.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Go news", Link: base + "/news"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+	if _, err := strict.sendDueWebhookDeliveries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Errorf("expected the receiver not to be called, got %d calls", calls)
+	}
+	log, err := bob.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].Status != client.DeliveryPending || log[0].Attempts != 1 || log[0].ResponseStatus != nil || log[0].Error != fetch.ErrPrivateAddress.Error() {
+		t.Errorf("expected a failed attempt, got %+v", log)
+	}
+}
+
+// an endpoint slower than the timeout gets a failed attempt recorded, not a delivery stuck in sending
+func TestWebhooksTimeOut(t *testing.T) {
+	apiCfg := newTestAPIConfig(t)
+	server := httptest.NewServer(apiCfg.router())
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	timeout := webhookTimeout
+	webhookTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { webhookTimeout = timeout })
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	t.Cleanup(receiver.Close)
+
+	alice := newTestUser(t, server, "alice")
+	base := "https://example.com/" + uuid.NewString()
+	created, err := alice.CreateFeed(ctx, "Go blog", base+"/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhook, err := alice.CreateWebhook(ctx, client.CreateWebhookParams{URL: receiver.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiCfg.FetchedFeeds = []FeedTuple{{ID: created.Feed.ID, Feed: &gofeed.Feed{Items: []*gofeed.Item{
+		{Title: "Go news", Link: base + "/news"},
+	}}}}
+	apiCfg.CreatePostsFromFetchedFeeds()
+	if _, err := apiCfg.sendDueWebhookDeliveries(ctx); err != nil {
+		t.Fatal(err)
+	}
+	log, err := alice.ListWebhookDeliveries(ctx, webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 || log[0].Status != client.DeliveryPending || log[0].Attempts != 1 || log[0].Error == "" {
+		t.Errorf("expected a timed out attempt, got %+v", log)
+	}
+}
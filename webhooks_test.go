@@ -0,0 +1,69 @@
+package main
+
+import (
+	"blog_aggregator/client"
+	"blog_aggregator/internal/database"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	post := database.Post{
+		Title:           "Releasing Go 1.21",
+		Description:     `<p>with <a href="https://golang.org">min</a> and max builtins</p>`,
+		DescriptionText: "with min and max builtins",
+	}
+	tests := []struct {
+		keyword string
+		want    bool
+	}{
+		{"", true},
+		{"go", true},
+		{"RELEASING", true},
+		{"builtins", true},
+		{"rust", false},
+		{"release", false},
+		{"golang", false},
+		{"href", false},
+		{"go 1.21", true},
+	}
+	for _, test := range tests {
+		if got := webhookMatches(database.Webhook{Keyword: test.keyword}, post); got != test.want {
+			t.Errorf("webhookMatches with keyword %q = %v", test.keyword, got)
+		}
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for i, delay := range want {
+		if got := webhookBackoff(i + 1); got != delay {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", i+1, got, delay)
+		}
+	}
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"event":"new_post"}`)
+	timestamp := time.Now().Unix()
+	header := http.Header{}
+	header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	header.Set("X-Webhook-Signature", signWebhookPayload("secret", timestamp, body))
+
+	if err := client.VerifyWebhookSignature("secret", header, body, time.Minute); err != nil {
+		t.Error(err)
+	}
+	if err := client.VerifyWebhookSignature("other secret", header, body, 0); err == nil {
+		t.Error("expected a different secret to fail")
+	}
+	if err := client.VerifyWebhookSignature("secret", header, []byte(`{"event":"other"}`), 0); err == nil {
+		t.Error("expected a different body to fail")
+	}
+	header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp-3600, 10))
+	header.Set("X-Webhook-Signature", signWebhookPayload("secret", timestamp-3600, body))
+	if err := client.VerifyWebhookSignature("secret", header, body, time.Minute); err == nil {
+		t.Error("expected an old delivery to fail")
+	}
+}